@@ -12,14 +12,19 @@ import (
 	"chess-ws-go/internal/auth"
 	"chess-ws-go/internal/config"
 	"chess-ws-go/internal/handlers"
+	"chess-ws-go/internal/logging"
 	"chess-ws-go/internal/middleware"
+	"chess-ws-go/internal/models"
 	"chess-ws-go/internal/platform"
 	"chess-ws-go/internal/repositories"
 	"chess-ws-go/internal/services"
 	"chess-ws-go/internal/stats"
+	"chess-ws-go/internal/storage"
+	"chess-ws-go/migrations"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
+	"golang.org/x/time/rate"
 )
 
 func NewServer(
@@ -27,43 +32,81 @@ func NewServer(
 	messageService *services.MessageService,
 	gameService *services.GameService,
 	userRepo repositories.UserRepository,
+	gameRepo repositories.GameRepository,
+	friendRepo repositories.FriendshipRepository,
+	puzzleRepo repositories.PuzzleRepository,
 	authService *services.AuthService,
+	notifier *services.NotificationService,
+	auditLogger *services.AuditLogger,
+	statsCollector *stats.Collector,
 	db *sql.DB,
-) http.Handler {
+) (http.Handler, *handlers.WebSocketHandler) {
 
-	router := gin.Default()
+	// Request logging is handled by LoggingMiddleware, which redacts secrets
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(middleware.BodyLimit(cfg.MaxRequestBodyBytes))
+	if err := middleware.TrustProxies(router, cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
 
 	// Public routes
 	router.GET("/health", handlers.NewHealthHandler(db).HealthCheck)
 
+	// Uploaded avatars are served straight from disk
+	avatarStore, err := storage.NewFileStore(cfg.AvatarDir, cfg.AvatarURLPrefix)
+	if err != nil {
+		log.Fatalf("Invalid avatar storage: %v", err)
+	}
+	router.Static(cfg.AvatarURLPrefix, cfg.AvatarDir)
+
 	// Auth routes
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandler(authService, &cfg.JWT)
+	userService := services.NewUserService(userRepo, auditLogger)
+	userHandler := handlers.NewUserHandler(userService, authService, cfg.MaxPageSize)
 	authGroup := router.Group("/auth")
 	{
 		authGroup.GET("/status", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"status": "Authentication service running"})
 		})
-		authGroup.POST("/login", authHandler.Login)
+		// Unauthenticated endpoints that can be brute forced share a per-IP limit
+		authLimit := middleware.RateLimit(rate.Every(time.Minute/time.Duration(cfg.AuthRateLimit)), cfg.AuthRateBurst)
+
+		authGroup.POST("/login", authLimit, authHandler.Login)
 		authGroup.POST("/refresh", authHandler.RefreshToken)
-		authGroup.POST("/register", authHandler.Register)
+		authGroup.POST("/register", authLimit, authHandler.Register)
 		authGroup.GET("/verify", authHandler.VerifyEmail)
 
 		// User management routes
-		userService := services.NewUserService(userRepo)
-		userHandler := handlers.NewUserHandler(userService, authService)
 		authGroup.PUT("/profile", userHandler.UpdateProfile)
 		authGroup.DELETE("/account", userHandler.DeleteAccount)
 		authGroup.GET("/users", userHandler.ListUsers)
-		authGroup.POST("/password-reset", userHandler.RequestPasswordReset)
-		authGroup.POST("/password-reset/confirm", userHandler.ConfirmPasswordReset)
+		authGroup.POST("/password-reset", authLimit, userHandler.RequestPasswordReset)
+		authGroup.POST("/password-reset/confirm", authLimit, userHandler.ConfirmPasswordReset)
+	}
+
+	wsHandler := handlers.NewWebSocketHandler(messageService, gameService, userRepo, cfg)
+	wsHandler.SetNotifier(notifier)
+	if cfg.MetricsEnabled {
+		wsHandler.ObserveMoves(statsCollector.ObserveMoveHandling)
+		wsHandler.CountMessages(statsCollector.CountMessage, statsCollector.CountRejectedMessage)
+		router.GET("/metrics", handlers.NewMetricsHandler(statsCollector, wsHandler).Metrics)
+	}
+
+	ipFilter := middleware.NewIPFilter(cfg.IPAllowList, cfg.IPDenyList)
+
+	// Public games can be watched without an account, read-only
+	if cfg.GuestSpectators {
+		router.GET("/ws/spectate", middleware.DenyListed(ipFilter), middleware.RateLimit(1, 5), func(c *gin.Context) {
+			wsHandler.SpectateHandler(c.Writer, c.Request)
+		})
 	}
 
 	// Protected routes
 	protected := router.Group("")
-	protected.Use(middleware.AuthMiddleware(&cfg.JWT))
+	protected.Use(middleware.AuthMiddleware(&cfg.JWT, ipFilter))
 	{
 		// WebSocket route with authentication
-		wsHandler := handlers.NewWebSocketHandler(messageService, gameService, userRepo, cfg)
 		protected.GET("/ws", func(c *gin.Context) {
 			// Extract user info from context
 			userID := c.GetString("user_id")
@@ -76,33 +119,111 @@ func NewServer(
 			c.Request = c.Request.WithContext(
 				context.WithValue(c.Request.Context(), "username", username),
 			)
+			if role, exists := c.Get("role"); exists {
+				c.Request = c.Request.WithContext(
+					context.WithValue(c.Request.Context(), "role", role),
+				)
+			}
 
 			wsHandler.UpgradeHandler(c.Writer, c.Request)
 		})
 
-		// Game management routes (will be implemented later)
+		// Session management
+		sessionGroup := protected.Group("/auth/sessions")
+		{
+			sessionGroup.GET("", authHandler.ListSessions)
+			sessionGroup.DELETE("/:id", authHandler.RevokeSession)
+		}
+
+		// Friend routes, using WebSocket connections for presence
+		friendService := services.NewFriendService(friendRepo, userRepo, wsHandler)
+		friendHandler := handlers.NewFriendHandler(friendService)
+		exportHandler := handlers.NewExportHandler(services.NewExportService(userRepo, gameRepo, gameService, auditLogger))
+		avatarHandler := handlers.NewAvatarHandler(services.NewAvatarService(userRepo, avatarStore, cfg.MaxAvatarBytes))
+		userGroup := protected.Group("/user")
+		{
+			userGroup.GET("/friends", friendHandler.ListFriends)
+			userGroup.GET("/friends/requests", friendHandler.ListRequests)
+			userGroup.POST("/friends/requests", friendHandler.SendRequest)
+			userGroup.POST("/friends/requests/:id/accept", friendHandler.AcceptRequest)
+			userGroup.DELETE("/friends/:id", friendHandler.RemoveFriend)
+			userGroup.POST("/email/confirm", userHandler.ConfirmEmailChange)
+			userGroup.GET("/notifications", userHandler.GetNotificationPreferences)
+			userGroup.PUT("/notifications", userHandler.UpdateNotificationPreferences)
+			userGroup.POST("/avatar", avatarHandler.UploadAvatar)
+			userGroup.DELETE("/avatar", avatarHandler.RemoveAvatar)
+
+			// Exports read every table a user appears in, so they're limited per user
+			exportLimit := middleware.RateLimit(rate.Every(30*time.Minute), 2)
+			userGroup.GET("/export", exportLimit, exportHandler.Export)
+		}
+
+		// Game lookup is open to any authenticated user
+		analysisService := services.NewAnalysisService(gameService, services.NoopEvaluator{})
+		gameHandler := handlers.NewGameHandler(gameService, analysisService, userRepo, wsHandler)
+		protected.GET("/game/:id", gameHandler.GetGame)
+		protected.GET("/game/:id/live", middleware.RateLimit(2, 10), gameHandler.GetLiveGame) // Polling is limited to 2 requests per second
+		protected.GET("/game/:id/analysis", gameHandler.GetAnalysis)
+		protected.GET("/game/:id/events", gameHandler.GetGameEvents)
+		protected.POST("/analysis", gameHandler.AnalyzeMoves)
+
+		// Head-to-head records between any two users
+		rivalryHandler := handlers.NewRivalryHandler(services.NewRivalryService(userRepo, gameRepo))
+		protected.GET("/users/:username/vs/:opponent", rivalryHandler.HeadToHead)
+
+		// Puzzle routes
+		puzzleService := services.NewPuzzleService(puzzleRepo, userRepo)
+		puzzleHandler := handlers.NewPuzzleHandler(puzzleService)
+		puzzleGroup := protected.Group("/puzzles")
+		{
+			puzzleGroup.GET("/next", puzzleHandler.NextPuzzle)
+			puzzleGroup.GET("/:id", puzzleHandler.GetPuzzle)
+			puzzleGroup.POST("/move", puzzleHandler.SubmitMove)
+		}
+
+		// Tournament routes
+		tournamentService := services.NewTournamentService(gameService, userRepo)
+		tournamentHandler := handlers.NewTournamentHandler(tournamentService)
+		tournamentGroup := protected.Group("/tournaments")
+		{
+			tournamentGroup.POST("", tournamentHandler.CreateTournament)
+			tournamentGroup.GET("/:id", tournamentHandler.GetTournament)
+			tournamentGroup.POST("/:id/register", tournamentHandler.Register)
+			tournamentGroup.POST("/:id/rounds", tournamentHandler.StartRound)
+			tournamentGroup.POST("/:id/bracket", tournamentHandler.StartBracket)
+			tournamentGroup.GET("/:id/bracket", tournamentHandler.GetBracket)
+			tournamentGroup.POST("/:id/results", tournamentHandler.ReportResult)
+		}
+
+		// Game management routes
 		gameGroup := protected.Group("/game")
 		{
-			// These routes will require CREATE_GAME permission
+			// These routes require CREATE_GAME permission
 			gameGroup.Use(middleware.RequirePermission(auth.PermissionCreateGame))
-			// gameGroup.POST("/create", gameHandler.CreateGame)
+			gameGroup.POST("/create", gameHandler.CreateGame)
 		}
 
-		// Admin routes (will be implemented later)
+		// Admin routes
+		adminHandler := handlers.NewAdminHandler(auditLogger, wsHandler)
 		adminGroup := protected.Group("/admin")
 		{
 			// These routes will require ADMIN role
 			adminGroup.Use(middleware.RequireRole(auth.RoleAdmin))
+			adminGroup.GET("/audit", adminHandler.GetAuditLog)
+			manageUsers := middleware.RequirePermission(auth.PermissionManageUsers)
+			adminGroup.POST("/games/:id/terminate", manageUsers, adminHandler.TerminateGame)
+			adminGroup.POST("/games/:id/abort", manageUsers, adminHandler.AbortGame)
+			adminGroup.POST("/users/:id/disconnect", manageUsers, adminHandler.DisconnectUser)
 			// adminGroup.GET("/stats", adminHandler.GetStats)
 		}
 	}
 
 	// Middleware
 	var handler http.Handler = router
-	handler = middleware.LoggingMiddleware(handler)
+	handler = middleware.LoggingMiddleware(cfg.LogRedactedParams, cfg.TrustedProxies)(handler)
 	handler = middleware.CorsMiddleware(cfg.AllowedOrigins)(handler)
 
-	return handler
+	return handler, wsHandler
 }
 
 func main() {
@@ -111,6 +232,23 @@ func main() {
 		log.Fatalf("Error loading config: %v", err)
 	}
 
+	// Configure leveled logging
+	logLevel, err := logging.ParseLevel(config.LogLevel)
+	if err != nil {
+		log.Printf("Invalid LOG_LEVEL, defaulting to info: %v", err)
+	}
+	logging.SetLevel(logLevel)
+	logging.SetSampleRate(config.LogSampleRate)
+	logging.Infof("Effective configuration: %s", config)
+	models.DefaultRating = config.DefaultRating
+	models.RatingFloor = config.RatingFloor
+	models.DeflationBonus = config.DeflationBonus
+	models.PlacementGames = config.PlacementGames
+	models.PlacementKFactor = config.PlacementKFactor
+	if err := auth.SetPasswordPreset(config.PasswordPreset); err != nil {
+		log.Fatalf("Error configuring password hashing: %v", err)
+	}
+
 	// Platform initialization (Database connection)
 	db, err := platform.ConnectDB(config.DatabaseURL)
 	if err != nil {
@@ -118,31 +256,82 @@ func main() {
 	}
 	defer db.Close() // Close the database connection when the server exits
 
+	// Bring the schema up to date before anything touches it
+	if err := migrations.Up(context.Background(), db); err != nil {
+		log.Fatal("Error applying database migrations:", err)
+	}
+
+	// Optional read replica for user reads
+	var replicax *sqlx.DB
+	if config.DatabaseReplicaURL != "" {
+		replica, err := platform.ConnectDB(config.DatabaseReplicaURL)
+		if err != nil {
+			log.Fatal("Error connecting to read replica:", err)
+		}
+		defer replica.Close()
+		replicax = sqlx.NewDb(replica, "postgres")
+	}
+
 	// Initialize repositories
+	repositories.SetQueryTimeout(config.DBQueryTimeout)
 	dbx := sqlx.NewDb(db, "postgres") // Assuming PostgreSQL, adjust if using a different database
-	userRepo := repositories.NewSQLUserRepository(dbx)
+	// Rating reads during matchmaking and game-over handling go through a
+	// cache. Every service shares it, so all writes evict what it holds.
+	userRepo := repositories.NewCachedUserRepository(
+		repositories.NewSQLUserRepositoryWithReplica(dbx, replicax), config.UserCacheSize, config.UserCacheTTL)
+	friendRepo := repositories.NewSQLFriendshipRepository(dbx)
+	auditRepo := repositories.NewSQLAuditRepository(dbx)
+	gameRepo := repositories.NewSQLGameRepository(dbx)
+	puzzleRepo := repositories.NewSQLPuzzleRepository(dbx)
 
 	// Initialize services
-	gameService := services.NewGameService()
+	gameService := services.NewGameService(gameRepo)
+	gameService.SetServerClock(config.ServerClock())
+	gameService.SetAbortThreshold(config.AbortPlies)
+	gameService.SetMaxDuration(config.MaxGameDuration)
+	gameService.SetCorrespondenceRepository(repositories.NewSQLCorrespondenceRepository(dbx))
+	if restored, err := gameService.RestoreCorrespondenceGames(context.Background()); err != nil {
+		logging.Errorf("Failed to restore correspondence games: %v", err)
+	} else if restored > 0 {
+		logging.Infof("Restored %d correspondence games in progress", restored)
+	}
 	messageService := services.NewMessageService(gameService)
-	authService := services.NewAuthService(userRepo, &config.JWT)
+	auditLogger := services.NewAuditLogger(auditRepo)
+	authService := services.NewAuthService(userRepo, &config.JWT, auditLogger)
+	authService.SetReservedUsernames(config.ReservedUsernames)
+	// No mail provider is integrated yet, so emails of every kind are only logged
+	notifier := services.NewNotificationService(userRepo, services.LogEmailSender{})
+	authService.SetNotifier(notifier)
+	gameService.SetNotifier(notifier)
+	if config.TokenPurgeInterval > 0 {
+		authService.StartTokenPurger(config.TokenPurgeInterval)
+	}
 
 	// Initialize stats collector
 	statsCollector := stats.NewCollector(
 		30*time.Second, // Collect stats every 30 seconds
-		gameService.GetActiveGamesCount,
+		gameService.GameCounts,
 		messageService.GetActiveConnectionsCount,
 	)
 	statsCollector.Start()
+	if config.MetricsEnabled {
+		gameService.SetMoveObserver(statsCollector.ObserveMoveProcessing)
+	}
 
 	// Create server
-	server := NewServer(config, messageService, gameService, userRepo, authService, db)
+	server, wsHandler := NewServer(config, messageService, gameService, userRepo, gameRepo, friendRepo, puzzleRepo, authService, notifier, auditLogger, statsCollector, db)
 
 	// Configure HTTP server
 	srv := &http.Server{
 		Addr:    config.ServerAddress,
 		Handler: server,
+		// The upgrader's handshake timeout only starts once the headers are
+		// in, so slow header reads need their own bound
+		ReadHeaderTimeout: config.WSHandshakeTimeout,
 	}
+	// Hijacked WebSocket connections aren't closed by Shutdown itself
+	srv.RegisterOnShutdown(wsHandler.Shutdown)
+	wsHandler.StartDeadlineSweeper()
 
 	// Start server in a goroutine
 	go func() {
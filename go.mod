@@ -33,10 +33,11 @@ require (
 	golang.org/x/text v0.22.0 // indirect
 	golang.org/x/time v0.10.0
 	google.golang.org/protobuf v1.36.5 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/corentings/chess/v2 v2.0.5
 	github.com/gin-gonic/gin v1.10.0
 	github.com/joho/godotenv v1.5.1
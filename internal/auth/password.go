@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -24,10 +25,62 @@ var DefaultPasswordConfig = &PasswordConfig{
 	keyLen:  32,        // Length of the generated key
 }
 
-// HashPassword hashes a password using Argon2id
+// FastPasswordConfig keeps tests and development machines quick. It's far
+// too cheap to brute force for production use.
+var FastPasswordConfig = &PasswordConfig{
+	time:    1,
+	memory:  8 * 1024, // 8MB
+	threads: 1,
+	keyLen:  32,
+}
+
+// StrongPasswordConfig is for deployments that can afford more memory and
+// time per login in exchange for costlier offline attacks
+var StrongPasswordConfig = &PasswordConfig{
+	time:    4,
+	memory:  256 * 1024, // 256MB
+	threads: 4,
+	keyLen:  32,
+}
+
+// Names of the password hashing presets, trading login cost for resistance
+// to offline attacks. Fast is meant for tests and development only.
+const (
+	PasswordPresetFast    = "fast"
+	PasswordPresetDefault = "default"
+	PasswordPresetStrong  = "strong"
+)
+
+var passwordPresets = map[string]*PasswordConfig{
+	PasswordPresetFast:    FastPasswordConfig,
+	PasswordPresetDefault: DefaultPasswordConfig,
+	PasswordPresetStrong:  StrongPasswordConfig,
+}
+
+// presetConfig is what HashPassword uses when it isn't given a configuration
+var presetConfig atomic.Pointer[PasswordConfig]
+
+func init() {
+	presetConfig.Store(DefaultPasswordConfig)
+}
+
+// SetPasswordPreset picks the configuration HashPassword uses by default.
+// Hashes made under any preset keep verifying, since each hash records its
+// own parameters.
+func SetPasswordPreset(name string) error {
+	c, ok := passwordPresets[name]
+	if !ok {
+		return fmt.Errorf("unknown password preset %q", name)
+	}
+	presetConfig.Store(c)
+	return nil
+}
+
+// HashPassword hashes a password using Argon2id, with the configured preset
+// when c is nil
 func HashPassword(password string, c *PasswordConfig) (string, error) {
 	if c == nil {
-		c = DefaultPasswordConfig
+		c = presetConfig.Load()
 	}
 
 	// Generate a random salt
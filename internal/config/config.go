@@ -5,78 +5,657 @@ package config
 import (
 	"fmt"
 	"log"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	"chess-ws-go/internal/auth"
+
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	DatabaseURL    string
-	ServerAddress  string
-	AllowedOrigins string
-	JWT            JWTConfig
+	DatabaseURL         string
+	DatabaseReplicaURL  string // Optional read replica
+	ServerAddress       string
+	AllowedOrigins      string
+	AllowedWSOrigins    string // Origins WebSocket upgrades are accepted from, like AllowedOrigins for REST; empty refuses browsers
+	LogRedactedParams   []string
+	ReservedUsernames   []string // Names nobody may register, matched ignoring case and separators
+	LogLevel            string
+	LogSampleRate       float64       // Fraction of request logs kept (warnings and errors are never sampled)
+	UserCacheSize       int           // Users kept in the rating cache (0 disables it)
+	UserCacheTTL        time.Duration // How long a cached user stays fresh
+	DefaultRating       int           // Starting rating of new users in every category
+	RatingFloor         int           // Lowest a rating can fall through losses
+	DeflationBonus      int           // Points added to wins by players rated below DefaultRating
+	PlacementGames      int           // Rated games new users play with provisional ratings (0 establishes them at once)
+	PlacementKFactor    int           // Elo K-factor of players with provisional ratings
+	DBQueryTimeout      time.Duration // Deadline for queries whose context has none (0 disables it)
+	TimeOddsRatingGap   int           // Rating difference at which matchmaking applies time odds (0 disables it)
+	MaxConcurrentGames  int           // Unfinished games a non-admin user may play at once (0 disables the cap)
+	WaitingTimeout      time.Duration // How long a player waits in matchmaking before giving up (0 waits forever)
+	DisconnectGrace     time.Duration // How long a disconnected player's clock is frozen per game before it runs again
+	AbandonTimeout      time.Duration // How long a disconnected player has to return before forfeiting
+	IdleGameTimeout     time.Duration // How long a game may go without a move before its players are warned (0 disables it)
+	IdleGameGrace       time.Duration // How long after the warning an idle game is aborted or forfeited
+	GameCleanupDelay    time.Duration // How long a finished game stays in memory for rematches and chat (0 keeps it until restart)
+	MaxGameDuration     time.Duration // Longest a clocked game may last before the server draws it (0 disables the cap)
+	AddTimeAmount       time.Duration // Time a player gives their opponent with add_time (0 disables it)
+	AddTimeCasualOnly   bool          // Refuse add_time in rated games
+	AbortPlies          int           // Games resigned, abandoned, timed out or agreed drawn before this many plies are aborted
+	ClockAuthority      string        // ClockServer or ClockClient
+	AuthRateLimit       int           // Login, registration and password reset requests per minute per IP
+	AuthRateBurst       int           // Requests allowed in a burst before AuthRateLimit applies
+	IPAllowList         []*net.IPNet  // Addresses exempt from authentication rate limits
+	IPDenyList          []*net.IPNet  // Addresses refused on authenticated routes
+	TrustedProxies      []*net.IPNet  // Peers whose X-Forwarded-For and X-Real-IP headers are believed
+	WSHandshakeTimeout  time.Duration // Deadline for completing the WebSocket upgrade
+	WSIntentTimeout     time.Duration // How long a new WebSocket may stay silent before it's closed (0 disables it)
+	WSStrictMessages    bool          // Reject WebSocket messages carrying fields the protocol doesn't define
+	MaxConnections      int           // WebSockets open at once before upgrades are refused with 503 (0 disables the cap)
+	MaxGameSpectators   int           // Spectators one game may have (0 disables the cap)
+	MaxSpectators       int           // Spectators across all games (0 disables the cap)
+	MetricsEnabled      bool          // Serve /metrics and time move processing
+	MaxRequestBodyBytes int64         // Largest HTTP request body accepted; bigger ones get 413
+	MaxPageSize         int           // Largest page size list endpoints honor; bigger limits are clamped
+	AvatarDir           string        // Directory uploaded avatars are stored in
+	AvatarURLPrefix     string        // URL path avatars are served under
+	MaxAvatarBytes      int           // Largest avatar upload accepted
+	PasswordPreset      string        // auth.PasswordPresetFast, auth.PasswordPresetDefault or auth.PasswordPresetStrong
+	TokenPurgeInterval  time.Duration // How often expired refresh, verification and reset tokens are purged (0 disables it)
+	GuestSpectators     bool          // Serve /ws/spectate, letting anyone watch public games without an account
+	JWT                 JWTConfig
 }
 
+// Clock authorities decide who keeps time in a game. Server clocks time
+// moves on arrival, so they can't be tampered with but charge players for
+// their network latency. Client clocks trust each player's time_update
+// reports, which is fairer on a low-latency LAN but lets a modified client
+// stop its own clock.
+const (
+	ClockServer = "server"
+	ClockClient = "client"
+)
+
+// Refresh token delivery modes
+const (
+	TokenDeliveryBody   = "body"   // Returned in the JSON response body
+	TokenDeliveryCookie = "cookie" // Set as an HttpOnly cookie scoped to /auth/refresh
+)
+
 type JWTConfig struct {
 	SecretKey            string
 	AccessTokenDuration  time.Duration
 	RefreshTokenDuration time.Duration
+	AllowQueryToken      bool   // Accept ?token= on requests (convenient in dev, leaks into logs)
+	RefreshTokenDelivery string // TokenDeliveryBody or TokenDeliveryCookie
+	CookieSecure         bool   // Mark token cookies Secure (HTTPS only)
 }
 
-func LoadConfig() (*Config, error) {
+// DefaultMinSecretLength is the shortest JWT signing key accepted, in bytes.
+// HS256 keys shorter than the 256-bit hash output can be brute forced.
+const DefaultMinSecretLength = 32
+
+// weakSecretMarkers are fragments of placeholder or commonly used secrets
+var weakSecretMarkers = []string{"secret", "changeme", "change-me", "password", "your-", "example", "default", "123456"}
+
+// isWeakSecret reports whether a key looks like a placeholder or a common
+// value, or repeats a single character
+func isWeakSecret(key string) bool {
+	lower := strings.ToLower(key)
+	for _, marker := range weakSecretMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return strings.Count(key, key[:1]) == len(key)
+}
+
+// ValidationError reports every problem found while loading the configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// envReader reads optional environment variables, falling back to defaults.
+// Required variables that are missing are always problems; malformed
+// optional values are problems in strict mode and warnings otherwise.
+type envReader struct {
+	strict   bool
+	problems []string
+	file     map[string]string // Settings from CONFIG_FILE, for variables the environment leaves unset
+}
+
+// get returns a variable from the environment, or from the config file if
+// the environment doesn't set it
+func (r *envReader) get(key string) string {
+	if value, set := os.LookupEnv(key); set {
+		return value
+	}
+	return r.file[key]
+}
+
+// fail records a problem that stops the configuration from loading
+func (r *envReader) fail(format string, args ...interface{}) {
+	r.problems = append(r.problems, fmt.Sprintf(format, args...))
+}
+
+// invalid reports a malformed optional value that falls back to its default
+func (r *envReader) invalid(key string, value string, reason string) {
+	if r.strict {
+		r.fail("%s=%q: %s", key, value, reason)
+		return
+	}
+	log.Printf("Warning: ignoring %s=%q: %s", key, value, reason)
+}
+
+// required returns a variable that must be set
+func (r *envReader) required(key string) string {
+	value := r.get(key)
+	if value == "" {
+		r.fail("%s environment variable not set", key)
+	}
+	return value
+}
+
+// intVar reads an integer, falling back to def if it's unset or not valid
+func (r *envReader) intVar(key string, def int, valid func(int) bool, reason string) int {
+	value := r.get(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		r.invalid(key, value, "not an integer")
+		return def
+	}
+	if valid != nil && !valid(n) {
+		r.invalid(key, value, reason)
+		return def
+	}
+	return n
+}
+
+// floatVar reads a number, falling back to def if it's unset or not valid
+func (r *envReader) floatVar(key string, def float64, valid func(float64) bool, reason string) float64 {
+	value := r.get(key)
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		r.invalid(key, value, "not a number")
+		return def
+	}
+	if valid != nil && !valid(f) {
+		r.invalid(key, value, reason)
+		return def
+	}
+	return f
+}
+
+// durationVar reads a duration, falling back to def if it's unset or not valid
+func (r *envReader) durationVar(key string, def time.Duration, valid func(time.Duration) bool, reason string) time.Duration {
+	value := r.get(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		r.invalid(key, value, "not a duration")
+		return def
+	}
+	if valid != nil && !valid(d) {
+		r.invalid(key, value, reason)
+		return def
+	}
+	return d
+}
+
+// boolVar reads a boolean, falling back to def if it's unset or malformed
+func (r *envReader) boolVar(key string, def bool) bool {
+	value := r.get(key)
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		r.invalid(key, value, "not a boolean")
+		return def
+	}
+	return b
+}
 
-	errEnv := godotenv.Load()
-	if errEnv != nil {
-		log.Fatal("Error loading .env file")
+// oneOf reads a value that must be one of the given choices, the first of
+// which is the default
+func (r *envReader) oneOf(key string, choices ...string) string {
+	value := r.get(key)
+	if value == "" {
+		return choices[0]
+	}
+	for _, choice := range choices {
+		if value == choice {
+			return value
+		}
 	}
+	r.invalid(key, value, "must be one of "+strings.Join(choices, ", "))
+	return choices[0]
+}
 
-	databaseURL := os.Getenv("DATABASE_URL")
-	if databaseURL == "" {
-		return nil, fmt.Errorf("DATABASE_URL environment variable not set")
+// cidrList reads a comma-separated list of CIDR ranges. A bare address is
+// taken as a single-address range. Malformed entries are always problems,
+// since silently dropping part of an access list is unsafe.
+func (r *envReader) cidrList(key string) []*net.IPNet {
+	value := r.get(key)
+	if value == "" {
+		return nil
 	}
 
-	serverAddress := os.Getenv("SERVER_ADDRESS")
+	var ranges []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				r.fail("%s: invalid address %q", key, entry)
+				continue
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			entry = fmt.Sprintf("%s/%d", entry, bits)
+		}
+
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			r.fail("%s: invalid CIDR range %q", key, entry)
+			continue
+		}
+		ranges = append(ranges, ipNet)
+	}
+	return ranges
+}
+
+func positive[T int | float64 | time.Duration](v T) bool    { return v > 0 }
+func nonNegative[T int | float64 | time.Duration](v T) bool { return v >= 0 }
+
+// loadConfigFile reads a YAML or JSON file mapping environment variable names
+// to values. Lists become comma-separated values. The process environment
+// is left alone; the values are only consulted for variables it doesn't set.
+func loadConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	// JSON is a subset of YAML, so one decoder covers both
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(settings))
+	for key, value := range settings {
+		var str string
+		switch v := value.(type) {
+		case nil:
+			continue
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			str = strings.Join(items, ",")
+		default:
+			str = fmt.Sprint(v)
+		}
+		values[key] = str
+	}
+
+	return values, nil
+}
+
+// ServerClock reports whether games are timed by the server
+func (c *Config) ServerClock() bool {
+	return c.ClockAuthority == ClockServer
+}
+
+// redacted stands in for secrets in the configuration summary
+const redacted = "REDACTED"
+
+// dsnPassword matches the password in a key/value Postgres connection string
+var dsnPassword = regexp.MustCompile(`(?i)(\bpassword\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactDSN hides the password in a database connection string, given as a
+// URL or as key/value pairs. A URL that can't be parsed is hidden entirely,
+// since there's no telling where its password is.
+func redactDSN(dsn string) string {
+	if dsn == "" || !strings.Contains(dsn, "://") {
+		return dsnPassword.ReplaceAllString(dsn, "${1}"+redacted)
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return redacted
+	}
+	if _, set := u.User.Password(); set {
+		u.User = url.UserPassword(u.User.Username(), redacted)
+	}
+	query := u.Query()
+	for key := range query {
+		if strings.Contains(strings.ToLower(key), "password") {
+			query.Set(key, redacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// joinCIDRs lists address ranges for the configuration summary
+func joinCIDRs(ranges []*net.IPNet) string {
+	items := make([]string, len(ranges))
+	for i, r := range ranges {
+		items[i] = r.String()
+	}
+	return strings.Join(items, ",")
+}
+
+// String summarizes the effective configuration as KEY=value pairs named
+// after the environment variables that set them, for logging at startup.
+// The JWT signing key and database passwords are shown as REDACTED.
+func (c *Config) String() string {
+	settings := []struct {
+		key   string
+		value interface{}
+	}{
+		{"DATABASE_URL", redactDSN(c.DatabaseURL)},
+		{"DATABASE_REPLICA_URL", redactDSN(c.DatabaseReplicaURL)},
+		{"SERVER_ADDRESS", c.ServerAddress},
+		{"ALLOWED_ORIGINS", c.AllowedOrigins},
+		{"ALLOWED_WS_ORIGINS", c.AllowedWSOrigins},
+		{"LOG_LEVEL", c.LogLevel},
+		{"LOG_SAMPLE_RATE", c.LogSampleRate},
+		{"LOG_REDACTED_PARAMS", strings.Join(c.LogRedactedParams, ",")},
+		{"USER_CACHE_SIZE", c.UserCacheSize},
+		{"USER_CACHE_TTL", c.UserCacheTTL},
+		{"DB_QUERY_TIMEOUT", c.DBQueryTimeout},
+		{"DEFAULT_RATING", c.DefaultRating},
+		{"RATING_FLOOR", c.RatingFloor},
+		{"RATING_DEFLATION_BONUS", c.DeflationBonus},
+		{"PLACEMENT_GAMES", c.PlacementGames},
+		{"PLACEMENT_K_FACTOR", c.PlacementKFactor},
+		{"TIME_ODDS_RATING_GAP", c.TimeOddsRatingGap},
+		{"MAX_CONCURRENT_GAMES", c.MaxConcurrentGames},
+		{"WAITING_TIMEOUT", c.WaitingTimeout},
+		{"DISCONNECT_GRACE", c.DisconnectGrace},
+		{"ABANDON_TIMEOUT", c.AbandonTimeout},
+		{"IDLE_GAME_TIMEOUT", c.IdleGameTimeout},
+		{"IDLE_GAME_GRACE", c.IdleGameGrace},
+		{"GAME_CLEANUP_DELAY", c.GameCleanupDelay},
+		{"MAX_GAME_DURATION", c.MaxGameDuration},
+		{"ADD_TIME_AMOUNT", c.AddTimeAmount},
+		{"ADD_TIME_CASUAL_ONLY", c.AddTimeCasualOnly},
+		{"ABORT_MOVE_THRESHOLD", c.AbortPlies},
+		{"CLOCK_AUTHORITY", c.ClockAuthority},
+		{"AUTH_RATE_LIMIT", c.AuthRateLimit},
+		{"AUTH_RATE_BURST", c.AuthRateBurst},
+		{"IP_ALLOW_LIST", joinCIDRs(c.IPAllowList)},
+		{"IP_DENY_LIST", joinCIDRs(c.IPDenyList)},
+		{"TRUSTED_PROXIES", joinCIDRs(c.TrustedProxies)},
+		{"WS_HANDSHAKE_TIMEOUT", c.WSHandshakeTimeout},
+		{"WS_INTENT_TIMEOUT", c.WSIntentTimeout},
+		{"WS_STRICT_MESSAGES", c.WSStrictMessages},
+		{"WS_MAX_CONNECTIONS", c.MaxConnections},
+		{"WS_GUEST_SPECTATORS", c.GuestSpectators},
+		{"MAX_SPECTATORS_PER_GAME", c.MaxGameSpectators},
+		{"MAX_SPECTATORS", c.MaxSpectators},
+		{"METRICS_ENABLED", c.MetricsEnabled},
+		{"MAX_REQUEST_BODY_BYTES", c.MaxRequestBodyBytes},
+		{"MAX_PAGE_SIZE", c.MaxPageSize},
+		{"AVATAR_DIR", c.AvatarDir},
+		{"AVATAR_URL_PREFIX", c.AvatarURLPrefix},
+		{"MAX_AVATAR_BYTES", c.MaxAvatarBytes},
+		{"PASSWORD_HASH_PRESET", c.PasswordPreset},
+		{"TOKEN_PURGE_INTERVAL", c.TokenPurgeInterval},
+		{"JWT_SECRET_KEY", redacted},
+		{"JWT_ACCESS_TOKEN_DURATION", c.JWT.AccessTokenDuration},
+		{"JWT_REFRESH_TOKEN_DURATION", c.JWT.RefreshTokenDuration},
+		{"JWT_ALLOW_QUERY_TOKEN", c.JWT.AllowQueryToken},
+		{"JWT_REFRESH_TOKEN_DELIVERY", c.JWT.RefreshTokenDelivery},
+		{"JWT_COOKIE_SECURE", c.JWT.CookieSecure},
+	}
+
+	pairs := make([]string, len(settings))
+	for i, setting := range settings {
+		value := fmt.Sprint(setting.value)
+		if value == "" || strings.ContainsAny(value, " \t\"") {
+			value = strconv.Quote(value)
+		}
+		pairs[i] = setting.key + "=" + value
+	}
+	return strings.Join(pairs, " ")
+}
+
+// LoadConfig reads the configuration from the environment, an optional .env
+// file and an optional CONFIG_FILE, in that order of precedence. Every
+// problem found is reported at once in a *ValidationError.
+func LoadConfig() (*Config, error) {
+	// A .env file is optional; in production the variables usually come
+	// from the real environment
+	if err := godotenv.Load(); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error loading .env file: %w", err)
+		}
+		log.Println("No .env file found, reading configuration from the environment")
+	}
+
+	r := &envReader{}
+
+	// Settings from CONFIG_FILE fill in whatever the environment leaves unset
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		file, err := loadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		r.file = file
+	}
+
+	r.strict = r.boolVar("CONFIG_STRICT", false)
+
+	databaseURL := r.required("DATABASE_URL")
+
+	serverAddress := r.get("SERVER_ADDRESS")
 	if serverAddress == "" {
 		serverAddress = ":8080" // Default
 	}
 
-	allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
-	if allowedOrigins == "" {
+	allowedOrigins := r.get("ALLOWED_ORIGINS")
+	restOriginsSet := allowedOrigins != ""
+	if !restOriginsSet {
 		allowedOrigins = "*" // Default to allow all origins
 	}
 
-	// JWT Configuration
-	secretKey := os.Getenv("JWT_SECRET_KEY")
-	if secretKey == "" {
-		return nil, fmt.Errorf("JWT_SECRET_KEY environment variable not set")
+	// Default to the REST allowlist, but only one that was set: the implicit
+	// "*" would let any site open a WebSocket carrying a user's credentials,
+	// so with neither set browsers are refused
+	allowedWSOrigins := r.get("ALLOWED_WS_ORIGINS")
+	if allowedWSOrigins == "" && restOriginsSet {
+		allowedWSOrigins = allowedOrigins
 	}
 
-	accessTokenDuration := 15 * time.Minute    // Default 15 minutes
-	refreshTokenDuration := 7 * 24 * time.Hour // Default 7 days
+	logRedactedParams := []string{"token", "password", "refresh_token"}
+	if envParams := r.get("LOG_REDACTED_PARAMS"); envParams != "" {
+		logRedactedParams = strings.Split(envParams, ",")
+	}
 
-	if envDuration := os.Getenv("JWT_ACCESS_TOKEN_DURATION"); envDuration != "" {
-		duration, err := time.ParseDuration(envDuration)
-		if err == nil {
-			accessTokenDuration = duration
-		}
+	reservedUsernames := []string{"admin", "administrator", "root", "system", "moderator", "mod", "support", "staff", "server", "anonymous"}
+	if envNames := r.get("RESERVED_USERNAMES"); envNames != "" {
+		reservedUsernames = strings.Split(envNames, ",")
 	}
 
-	if envDuration := os.Getenv("JWT_REFRESH_TOKEN_DURATION"); envDuration != "" {
-		duration, err := time.ParseDuration(envDuration)
-		if err == nil {
-			refreshTokenDuration = duration
+	logLevel := r.get("LOG_LEVEL")
+	if logLevel == "" {
+		logLevel = "info" // Default
+	}
+
+	// Default to logging every request
+	logSampleRate := r.floatVar("LOG_SAMPLE_RATE", 1.0, func(rate float64) bool {
+		return rate >= 0 && rate <= 1
+	}, "must be between 0 and 1")
+
+	userCacheSize := r.intVar("USER_CACHE_SIZE", 1000, nonNegative[int], "must not be negative")
+	userCacheTTL := r.durationVar("USER_CACHE_TTL", time.Minute, positive[time.Duration], "must be positive")
+	dbQueryTimeout := r.durationVar("DB_QUERY_TIMEOUT", 5*time.Second, nonNegative[time.Duration], "must not be negative")
+	defaultRating := r.intVar("DEFAULT_RATING", 1200, positive[int], "must be positive")
+	ratingFloor := r.intVar("RATING_FLOOR", 100, nonNegative[int], "must not be negative")
+	if ratingFloor > defaultRating {
+		r.fail("RATING_FLOOR (%d) must not exceed DEFAULT_RATING (%d)", ratingFloor, defaultRating)
+	}
+	deflationBonus := r.intVar("RATING_DEFLATION_BONUS", 0, nonNegative[int], "must not be negative") // Default to no bonus
+	placementGames := r.intVar("PLACEMENT_GAMES", 5, nonNegative[int], "must not be negative")
+	placementKFactor := r.intVar("PLACEMENT_K_FACTOR", 64, positive[int], "must be positive")
+	timeOddsRatingGap := r.intVar("TIME_ODDS_RATING_GAP", 0, nonNegative[int], "must not be negative") // Default to no time odds
+	maxConcurrentGames := r.intVar("MAX_CONCURRENT_GAMES", 3, nonNegative[int], "must not be negative")
+	waitingTimeout := r.durationVar("WAITING_TIMEOUT", 2*time.Minute, nonNegative[time.Duration], "must not be negative")
+	disconnectGrace := r.durationVar("DISCONNECT_GRACE", 30*time.Second, nonNegative[time.Duration], "must not be negative")
+	abandonTimeout := r.durationVar("ABANDON_TIMEOUT", 2*time.Minute, positive[time.Duration], "must be positive")
+	idleGameTimeout := r.durationVar("IDLE_GAME_TIMEOUT", 10*time.Minute, nonNegative[time.Duration], "must not be negative")
+	idleGameGrace := r.durationVar("IDLE_GAME_GRACE", time.Minute, positive[time.Duration], "must be positive")
+	gameCleanupDelay := r.durationVar("GAME_CLEANUP_DELAY", 5*time.Minute, nonNegative[time.Duration], "must not be negative")
+	maxGameDuration := r.durationVar("MAX_GAME_DURATION", 8*time.Hour, nonNegative[time.Duration], "must not be negative")
+	addTimeAmount := r.durationVar("ADD_TIME_AMOUNT", 15*time.Second, nonNegative[time.Duration], "must not be negative")
+	addTimeCasualOnly := r.boolVar("ADD_TIME_CASUAL_ONLY", true)
+	abortPlies := r.intVar("ABORT_MOVE_THRESHOLD", 2, nonNegative[int], "must not be negative")
+	clockAuthority := r.oneOf("CLOCK_AUTHORITY", ClockServer, ClockClient)
+
+	authRateLimit := r.intVar("AUTH_RATE_LIMIT", 10, positive[int], "must be positive")
+	authRateBurst := r.intVar("AUTH_RATE_BURST", 5, positive[int], "must be positive")
+
+	ipAllowList := r.cidrList("IP_ALLOW_LIST")
+	ipDenyList := r.cidrList("IP_DENY_LIST")
+	trustedProxies := r.cidrList("TRUSTED_PROXIES")
+
+	wsHandshakeTimeout := r.durationVar("WS_HANDSHAKE_TIMEOUT", 10*time.Second, positive[time.Duration], "must be positive")
+	wsIntentTimeout := r.durationVar("WS_INTENT_TIMEOUT", 30*time.Second, nonNegative[time.Duration], "must not be negative")
+	wsStrictMessages := r.boolVar("WS_STRICT_MESSAGES", false)
+	maxConnections := r.intVar("WS_MAX_CONNECTIONS", 10000, nonNegative[int], "must not be negative")
+	guestSpectators := r.boolVar("WS_GUEST_SPECTATORS", true)
+	maxGameSpectators := r.intVar("MAX_SPECTATORS_PER_GAME", 200, nonNegative[int], "must not be negative")
+	maxSpectators := r.intVar("MAX_SPECTATORS", 2000, nonNegative[int], "must not be negative")
+	metricsEnabled := r.boolVar("METRICS_ENABLED", true)
+	maxRequestBodyBytes := r.intVar("MAX_REQUEST_BODY_BYTES", 1<<20, positive[int], "must be positive")
+	maxPageSize := r.intVar("MAX_PAGE_SIZE", 100, positive[int], "must be positive")
+
+	avatarDir := r.get("AVATAR_DIR")
+	if avatarDir == "" {
+		avatarDir = "avatars" // Default
+	}
+	avatarURLPrefix := r.get("AVATAR_URL_PREFIX")
+	if avatarURLPrefix == "" {
+		avatarURLPrefix = "/avatars" // Default
+	}
+	maxAvatarBytes := r.intVar("MAX_AVATAR_BYTES", 512<<10, positive[int], "must be positive")
+	if maxAvatarBytes > maxRequestBodyBytes {
+		// The upload would be cut off by the body limit before it's checked
+		r.fail("MAX_AVATAR_BYTES (%d) must not exceed MAX_REQUEST_BODY_BYTES (%d)", maxAvatarBytes, maxRequestBodyBytes)
+	}
+
+	// JWT Configuration
+	secretKey := r.required("JWT_SECRET_KEY")
+	minSecretLength := r.intVar("JWT_MIN_SECRET_LENGTH", DefaultMinSecretLength, positive[int], "must be positive")
+	if secretKey != "" {
+		if len(secretKey) < minSecretLength {
+			r.fail("JWT_SECRET_KEY must be at least %d bytes, got %d", minSecretLength, len(secretKey))
+		}
+		if isWeakSecret(secretKey) {
+			log.Println("Warning: JWT_SECRET_KEY looks like a placeholder or common value; generate a random key")
 		}
 	}
 
+	accessTokenDuration := r.durationVar("JWT_ACCESS_TOKEN_DURATION", 15*time.Minute, positive[time.Duration], "must be positive")
+	refreshTokenDuration := r.durationVar("JWT_REFRESH_TOKEN_DURATION", 7*24*time.Hour, positive[time.Duration], "must be positive")
+	allowQueryToken := r.boolVar("JWT_ALLOW_QUERY_TOKEN", true) // Default to allowing query tokens for development
+	refreshTokenDelivery := r.oneOf("JWT_REFRESH_TOKEN_DELIVERY", TokenDeliveryBody, TokenDeliveryCookie)
+	cookieSecure := r.boolVar("JWT_COOKIE_SECURE", true)
+	tokenPurgeInterval := r.durationVar("TOKEN_PURGE_INTERVAL", time.Hour, nonNegative[time.Duration], "must not be negative")
+	passwordPreset := r.oneOf("PASSWORD_HASH_PRESET", auth.PasswordPresetDefault, auth.PasswordPresetFast, auth.PasswordPresetStrong)
+
+	if len(r.problems) > 0 {
+		return nil, &ValidationError{Problems: r.problems}
+	}
+
 	return &Config{
-		DatabaseURL:    databaseURL,
-		ServerAddress:  serverAddress,
-		AllowedOrigins: allowedOrigins,
+		DatabaseURL:         databaseURL,
+		DatabaseReplicaURL:  r.get("DATABASE_REPLICA_URL"),
+		ServerAddress:       serverAddress,
+		AllowedOrigins:      allowedOrigins,
+		AllowedWSOrigins:    allowedWSOrigins,
+		LogRedactedParams:   logRedactedParams,
+		ReservedUsernames:   reservedUsernames,
+		LogLevel:            logLevel,
+		LogSampleRate:       logSampleRate,
+		UserCacheSize:       userCacheSize,
+		UserCacheTTL:        userCacheTTL,
+		DBQueryTimeout:      dbQueryTimeout,
+		DefaultRating:       defaultRating,
+		RatingFloor:         ratingFloor,
+		DeflationBonus:      deflationBonus,
+		PlacementGames:      placementGames,
+		PlacementKFactor:    placementKFactor,
+		TimeOddsRatingGap:   timeOddsRatingGap,
+		MaxConcurrentGames:  maxConcurrentGames,
+		WaitingTimeout:      waitingTimeout,
+		DisconnectGrace:     disconnectGrace,
+		AbandonTimeout:      abandonTimeout,
+		IdleGameTimeout:     idleGameTimeout,
+		IdleGameGrace:       idleGameGrace,
+		GameCleanupDelay:    gameCleanupDelay,
+		MaxGameDuration:     maxGameDuration,
+		AddTimeAmount:       addTimeAmount,
+		AddTimeCasualOnly:   addTimeCasualOnly,
+		AbortPlies:          abortPlies,
+		ClockAuthority:      clockAuthority,
+		AuthRateLimit:       authRateLimit,
+		AuthRateBurst:       authRateBurst,
+		IPAllowList:         ipAllowList,
+		IPDenyList:          ipDenyList,
+		TrustedProxies:      trustedProxies,
+		WSHandshakeTimeout:  wsHandshakeTimeout,
+		WSIntentTimeout:     wsIntentTimeout,
+		WSStrictMessages:    wsStrictMessages,
+		MaxConnections:      maxConnections,
+		MaxGameSpectators:   maxGameSpectators,
+		MaxSpectators:       maxSpectators,
+		MetricsEnabled:      metricsEnabled,
+		MaxRequestBodyBytes: int64(maxRequestBodyBytes),
+		PasswordPreset:      passwordPreset,
+		TokenPurgeInterval:  tokenPurgeInterval,
+		GuestSpectators:     guestSpectators,
+		MaxPageSize:         maxPageSize,
+		AvatarDir:           avatarDir,
+		AvatarURLPrefix:     avatarURLPrefix,
+		MaxAvatarBytes:      maxAvatarBytes,
 		JWT: JWTConfig{
 			SecretKey:            secretKey,
 			AccessTokenDuration:  accessTokenDuration,
 			RefreshTokenDuration: refreshTokenDuration,
+			AllowQueryToken:      allowQueryToken,
+			RefreshTokenDelivery: refreshTokenDelivery,
+			CookieSecure:         cookieSecure,
 		},
 	}, nil
 }
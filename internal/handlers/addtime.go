@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+)
+
+// A player may give their opponent time this often, after a short burst, so
+// add_time can't be used to flood the game with clock updates
+const (
+	addTimeRate  = rate.Limit(1.0 / 10) // Grants per second once the burst is used up
+	addTimeBurst = 3
+)
+
+// Why an add_time request was refused
+var (
+	errAddTimeRated    = errors.New("time can only be added in casual games")
+	errAddTimeTooOften = errors.New("you're adding time too often")
+)
+
+// handleAddTime gives the sender's opponent the configured courtesy time and
+// tells everyone in the game
+func (h *WebSocketHandler) handleAddTime(conn *websocket.Conn, gameID string) {
+	if h.config.AddTimeAmount <= 0 {
+		h.sendErrorCode(conn, "ADD_TIME_DISABLED", "Adding time is disabled on this server")
+		return
+	}
+
+	h.mu.Lock()
+	err := h.addTime(conn, gameID)
+	h.mu.Unlock()
+
+	// Coded errors look up the connection's protocol, which needs h.mu
+	switch {
+	case err == nil:
+	case errors.Is(err, errAddTimeRated):
+		h.sendErrorCode(conn, "ADD_TIME_RATED", err.Error())
+	case errors.Is(err, errAddTimeTooOften):
+		h.sendErrorCode(conn, "RATE_LIMITED", err.Error())
+	case errors.Is(err, services.ErrNoClock):
+		h.sendErrorCode(conn, "NO_CLOCK", err.Error())
+	default:
+		h.sendGameError(conn, err)
+	}
+}
+
+// addTime puts the courtesy time on the clock of the sender's opponent and
+// broadcasts the new time. Callers must hold h.mu.
+func (h *WebSocketHandler) addTime(conn *websocket.Conn, gameID string) error {
+	session, exists := h.sessions[gameID]
+	if !exists {
+		return services.ErrGameNotFound
+	}
+
+	var playerColor chess.Color
+	if conn == session.White.Conn {
+		playerColor = chess.White
+	} else if conn == session.Black.Conn {
+		playerColor = chess.Black
+	} else {
+		return errNotInGame
+	}
+
+	if h.config.AddTimeCasualOnly {
+		if state, err := h.gameService.GetGameState(gameID); err == nil && !state.Casual {
+			return errAddTimeRated
+		}
+	}
+
+	if session.addTime == nil {
+		session.addTime = make(map[chess.Color]*rate.Limiter)
+	}
+	limiter := session.addTime[playerColor]
+	if limiter == nil {
+		limiter = rate.NewLimiter(addTimeRate, addTimeBurst)
+		session.addTime[playerColor] = limiter
+	}
+	if !limiter.Allow() {
+		return errAddTimeTooOften
+	}
+
+	opponent := playerColor.Other()
+	seconds := h.config.AddTimeAmount.Seconds()
+	timeLeft, err := h.gameService.AddTime(gameID, opponent, seconds)
+	if err != nil {
+		return err
+	}
+	h.gameService.RecordEvent(gameID, services.EventAddTime, opponent, h.config.AddTimeAmount.String())
+
+	// The flag was set for the time the side to move had before
+	if session.CurrentTurn == opponent {
+		h.armFlag(session, gameID)
+	}
+
+	timeAddedMsg := struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Color    string  `json:"color"`   // Side that got the time
+			AddedBy  string  `json:"addedBy"` // Side that gave it
+			Seconds  float64 `json:"seconds"`
+			TimeLeft float64 `json:"timeLeft"`
+		} `json:"payload"`
+	}{Type: "timeAdded"}
+	timeAddedMsg.Payload.Color = opponent.String()
+	timeAddedMsg.Payload.AddedBy = playerColor.String()
+	timeAddedMsg.Payload.Seconds = seconds
+	timeAddedMsg.Payload.TimeLeft = timeLeft
+
+	h.broadcastToGame(session, timeAddedMsg)
+	return nil
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"chess-ws-go/internal/repositories"
+	"chess-ws-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAuditPageSize bounds the audit entries returned per request
+const maxAuditPageSize = 500
+
+// Moderator is the live side of admin interventions: it ends games and
+// drops users' connections
+type Moderator interface {
+	TerminateGame(ctx context.Context, gameID string) error
+	AbortGame(ctx context.Context, gameID string, reason string) error
+	DisconnectUser(userID string) int
+}
+
+// AdminHandler handles admin-only HTTP requests
+type AdminHandler struct {
+	auditLogger *services.AuditLogger
+	moderator   Moderator
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(auditLogger *services.AuditLogger, moderator Moderator) *AdminHandler {
+	return &AdminHandler{
+		auditLogger: auditLogger,
+		moderator:   moderator,
+	}
+}
+
+// ModerationRequest is the reason given for an admin intervention, kept in
+// the audit log
+type ModerationRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// TerminateGame ends a game without a result, for abusive or stuck games
+func (h *AdminHandler) TerminateGame(c *gin.Context) {
+	adminID := c.GetString("user_id") // From auth middleware
+	gameID := c.Param("id")
+
+	var req ModerationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.moderator.TerminateGame(c.Request.Context(), gameID); err != nil {
+		switch err {
+		case services.ErrGameNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		case services.ErrGameOver:
+			c.JSON(http.StatusConflict, gin.H{"error": "Game is already over"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to terminate game"})
+		}
+		return
+	}
+
+	h.auditLogger.Log(c.Request.Context(), adminID, services.AuditGameTerminated, gameID, req.Reason)
+	c.JSON(http.StatusOK, gin.H{"message": "Game terminated"})
+}
+
+// AbortGame ends a game that hasn't properly started, such as a wrong
+// pairing. Games past the abort threshold have to be terminated instead.
+func (h *AdminHandler) AbortGame(c *gin.Context) {
+	adminID := c.GetString("user_id") // From auth middleware
+	gameID := c.Param("id")
+
+	var req ModerationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.moderator.AbortGame(c.Request.Context(), gameID, req.Reason); err != nil {
+		switch err {
+		case services.ErrGameNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		case services.ErrGameOver:
+			c.JSON(http.StatusConflict, gin.H{"error": "Game is already over"})
+		case services.ErrGameStarted:
+			c.JSON(http.StatusConflict, gin.H{"error": "Game is too far along to abort"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to abort game"})
+		}
+		return
+	}
+
+	h.auditLogger.Log(c.Request.Context(), adminID, services.AuditGameAborted, gameID, req.Reason)
+	c.JSON(http.StatusOK, gin.H{"message": "Game aborted"})
+}
+
+// DisconnectUser closes all of a user's WebSocket connections
+func (h *AdminHandler) DisconnectUser(c *gin.Context) {
+	adminID := c.GetString("user_id") // From auth middleware
+	userID := c.Param("id")
+
+	var req ModerationRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	closed := h.moderator.DisconnectUser(userID)
+
+	h.auditLogger.Log(c.Request.Context(), adminID, services.AuditUserDisconnected, userID, req.Reason)
+	c.JSON(http.StatusOK, gin.H{"connections_closed": closed})
+}
+
+// GetAuditLog returns audit log entries filtered by actor, action, target and
+// time range (RFC3339 "since"/"until" query parameters)
+func (h *AdminHandler) GetAuditLog(c *gin.Context) {
+	limit := pageLimit(c, 50, maxAuditPageSize)
+
+	filter := repositories.AuditFilter{
+		ActorID:  c.Query("actor"),
+		Action:   c.Query("action"),
+		TargetID: c.Query("target"),
+		Limit:    limit,
+	}
+
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since timestamp"})
+			return
+		}
+		filter.Since = &t
+	}
+
+	if until := c.Query("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid until timestamp"})
+			return
+		}
+		filter.Until = &t
+	}
+
+	entries, err := h.auditLogger.Query(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+	})
+}
@@ -3,20 +3,27 @@ package handlers
 import (
 	"net/http"
 
+	"chess-ws-go/internal/auth"
+	"chess-ws-go/internal/config"
 	"chess-ws-go/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
+// refreshTokenCookie is the cookie carrying the refresh token in cookie delivery mode
+const refreshTokenCookie = "refresh_token"
+
 // AuthHandler handles authentication-related HTTP requests
 type AuthHandler struct {
 	authService *services.AuthService
+	jwtConfig   *config.JWTConfig
 }
 
 // NewAuthHandler creates a new authentication handler
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+func NewAuthHandler(authService *services.AuthService, jwtConfig *config.JWTConfig) *AuthHandler {
 	return &AuthHandler{
 		authService: authService,
+		jwtConfig:   jwtConfig,
 	}
 }
 
@@ -31,9 +38,11 @@ type RegisterRequest struct {
 type LoginRequest struct {
 	UsernameOrEmail string `json:"username_or_email" binding:"required"`
 	Password        string `json:"password" binding:"required"`
+	Device          string `json:"device"` // Optional session label, defaults to the user agent
 }
 
-// RefreshTokenRequest represents a token refresh request
+// RefreshTokenRequest represents a token refresh request.
+// In cookie delivery mode the token is read from the cookie instead.
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
@@ -41,8 +50,7 @@ type RefreshTokenRequest struct {
 // Register handles user registration
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -57,6 +65,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 			c.JSON(http.StatusConflict, gin.H{"error": "Username or email already exists"})
 			return
 		}
+		if err == services.ErrInvalidUsername || err == services.ErrReservedUsername {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
 		return
 	}
@@ -70,13 +82,20 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // Login handles user login
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
+	device := req.Device
+	if device == "" {
+		device = c.Request.UserAgent()
+	}
+	ctx := services.WithClientIP(c.Request.Context(), c.ClientIP())
+	ctx = services.WithDeviceLabel(ctx, device)
+	ctx = services.WithUserAgent(ctx, c.Request.UserAgent())
+
 	tokens, err := h.authService.Login(
-		c.Request.Context(),
+		ctx,
 		req.UsernameOrEmail,
 		req.Password,
 	)
@@ -97,30 +116,89 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"access_token":  tokens.AccessToken,
-		"refresh_token": tokens.RefreshToken,
-		"token_type":    "Bearer",
-	})
+	h.respondWithTokens(c, tokens)
 }
 
 // RefreshToken handles token refresh
 func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	var req RefreshTokenRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	refreshToken := ""
+	if h.jwtConfig.RefreshTokenDelivery == config.TokenDeliveryCookie {
+		refreshToken, _ = c.Cookie(refreshTokenCookie)
+	}
+
+	if refreshToken == "" {
+		var req RefreshTokenRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		refreshToken = req.RefreshToken
 	}
 
 	tokens, err := h.authService.RefreshToken(
 		c.Request.Context(),
-		req.RefreshToken,
+		refreshToken,
 	)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
 		return
 	}
 
+	h.respondWithTokens(c, tokens)
+}
+
+// ListSessions lists the authenticated user's active sessions
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	sessions, err := h.authService.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession signs the authenticated user out of one of their sessions
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	err := h.authService.RevokeSession(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		if err == services.ErrSessionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// respondWithTokens writes a token pair using the configured refresh token delivery
+func (h *AuthHandler) respondWithTokens(c *gin.Context, tokens *auth.TokenPair) {
+	if h.jwtConfig.RefreshTokenDelivery == config.TokenDeliveryCookie {
+		c.SetSameSite(http.SameSiteStrictMode)
+		c.SetCookie(
+			refreshTokenCookie,
+			tokens.RefreshToken,
+			int(h.jwtConfig.RefreshTokenDuration.Seconds()),
+			"/auth/refresh",
+			"",
+			h.jwtConfig.CookieSecure,
+			true, // HttpOnly
+		)
+
+		c.JSON(http.StatusOK, gin.H{
+			"access_token": tokens.AccessToken,
+			"token_type":   "Bearer",
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"access_token":  tokens.AccessToken,
 		"refresh_token": tokens.RefreshToken,
@@ -138,7 +216,11 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 
 	err := h.authService.VerifyEmail(c.Request.Context(), token)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+		if err == services.ErrInvalidToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
 		return
 	}
 
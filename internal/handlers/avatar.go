@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// avatarFormField is the multipart form field an avatar is uploaded in
+const avatarFormField = "avatar"
+
+// AvatarHandler handles profile image uploads
+type AvatarHandler struct {
+	avatarService *services.AvatarService
+}
+
+// NewAvatarHandler creates a new avatar handler
+func NewAvatarHandler(avatarService *services.AvatarService) *AvatarHandler {
+	return &AvatarHandler{
+		avatarService: avatarService,
+	}
+}
+
+// UploadAvatar replaces the authenticated user's avatar with the image sent
+// in the "avatar" field of a multipart form
+func (h *AvatarHandler) UploadAvatar(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	header, err := c.FormFile(avatarFormField)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing avatar file"})
+		return
+	}
+	if header.Size > int64(h.avatarService.MaxBytes()) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAvatarTooLarge.Error()})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read avatar file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, int64(h.avatarService.MaxBytes())+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read avatar file"})
+		return
+	}
+
+	user, err := h.avatarService.SetAvatar(c.Request.Context(), userID, data)
+	if err != nil {
+		h.avatarError(c, err, "Failed to update avatar")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Avatar updated successfully",
+		"user":    user,
+	})
+}
+
+// RemoveAvatar clears the authenticated user's avatar
+func (h *AvatarHandler) RemoveAvatar(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	user, err := h.avatarService.RemoveAvatar(c.Request.Context(), userID)
+	if err != nil {
+		h.avatarError(c, err, "Failed to remove avatar")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Avatar removed successfully",
+		"user":    user,
+	})
+}
+
+// avatarError responds to an avatar service error
+func (h *AvatarHandler) avatarError(c *gin.Context, err error, fallback string) {
+	switch err {
+	case services.ErrAvatarTooLarge:
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case services.ErrAvatarType:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case services.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case services.ErrUserConflict:
+		c.JSON(http.StatusConflict, gin.H{"error": "Profile was modified concurrently, please retry"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
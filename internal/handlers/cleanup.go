@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/services"
+)
+
+// armCleanup schedules dropping a finished game's session. Until then its
+// players can still chat and agree a rematch. Callers must hold h.mu.
+func (h *WebSocketHandler) armCleanup(session *GameSession, gameID string) {
+	if h.config.GameCleanupDelay <= 0 || session.cleanup != nil {
+		return
+	}
+	session.cleanup = time.AfterFunc(h.config.GameCleanupDelay, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.releaseGame(gameID)
+	})
+}
+
+// releaseGame drops a finished game's session and lets the game service
+// forget it. Anything asked about it afterwards is answered from storage.
+// Callers must hold h.mu.
+func (h *WebSocketHandler) releaseGame(gameID string) {
+	session, exists := h.sessions[gameID]
+	if !exists || !h.gameOver(gameID) {
+		return
+	}
+	if session.cleanup != nil {
+		session.cleanup.Stop()
+		session.cleanup = nil
+	}
+	h.releaseSpectators(gameID, session)
+	for _, player := range []*Player{session.White, session.Black} {
+		if player.Conn != nil {
+			h.messageService.DissociateConnection(player.Conn, gameID)
+		}
+	}
+	delete(h.sessions, gameID)
+	h.gameService.ReleaseGame(gameID)
+	logging.Debugf("Released finished game %s", gameID)
+}
+
+// enforceMaxDuration ends the games that went on past the maximum duration.
+// Players and spectators learn why from the game-over method. The game is
+// ended, and stored, before h.mu is taken, so the database round-trip
+// doesn't hold up every other game.
+func (h *WebSocketHandler) enforceMaxDuration(now time.Time) {
+	for _, gameID := range h.gameService.OverlongGames(now) {
+		if err := h.gameService.EndOverlongGame(gameID, context.Background(), h.getUserRepository()); err != nil {
+			continue
+		}
+		logging.Warnf("Game %s drawn by the server: still going after %s", gameID, h.config.MaxGameDuration)
+
+		h.mu.Lock()
+		if session, exists := h.sessions[gameID]; exists {
+			stopPlay(session)
+			h.announceGameOver(session, gameID)
+		} else {
+			// Nobody is connected to keep it around for
+			h.gameService.ReleaseGame(gameID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// announceForfeit tells everyone in a game that a tournament forfeited it
+// for want of a player, as the handler isn't the one ending it. Every other
+// game ends through the handler, which announces it there and then.
+func (h *WebSocketHandler) announceForfeit(end services.GameEnd) {
+	if end.Method != services.MethodForfeit {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[end.GameID]
+	if !exists {
+		// Nobody is connected to keep it around for
+		h.gameService.ReleaseGame(end.GameID)
+		return
+	}
+	stopPlay(session)
+	h.announceGameOver(session, end.GameID)
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"chess-ws-go/internal/logging"
+
+	"github.com/gorilla/websocket"
+)
+
+// closeWriteTimeout bounds how long sending a close frame may take
+const closeWriteTimeout = time.Second
+
+// closeReason is the code and text of a close frame the server sends when it
+// ends a connection, so clients can tell why they were disconnected
+type closeReason struct {
+	Code int
+	Text string
+}
+
+var (
+	closeShutdown        = closeReason{websocket.CloseGoingAway, "server shutting down"}
+	closeIdle            = closeReason{websocket.ClosePolicyViolation, "no request received in time"}
+	closeMalformedFrame  = closeReason{websocket.CloseProtocolError, "malformed frame"}
+	closeAdminDisconnect = closeReason{websocket.ClosePolicyViolation, "disconnected by an administrator"}
+)
+
+// closeReasonFor picks the close frame to answer a read error with. It
+// returns false when no frame should be sent: the client closed the
+// connection itself or the transport is already gone.
+func closeReasonFor(err error) (closeReason, bool) {
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return closeReason{}, false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return closeIdle, true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return closeReason{}, false
+	}
+
+	return closeMalformedFrame, true
+}
+
+// sendClose tells the client why the server is ending the connection. The
+// caller still closes the underlying connection.
+func sendClose(conn *websocket.Conn, reason closeReason) {
+	err := conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(reason.Code, reason.Text),
+		time.Now().Add(closeWriteTimeout))
+	if err != nil && !errors.Is(err, websocket.ErrCloseSent) {
+		logging.Debugf("Error sending close frame: %v", err)
+	}
+}
+
+// Shutdown sends every open connection a going-away close frame and closes
+// it. Meant to be registered with http.Server.RegisterOnShutdown, since the
+// server doesn't track hijacked connections itself.
+func (h *WebSocketHandler) Shutdown() {
+	h.mu.Lock()
+	conns := make([]*websocket.Conn, 0, len(h.connections))
+	for conn := range h.connections {
+		conns = append(conns, conn)
+	}
+	h.mu.Unlock()
+
+	logging.Infof("Closing %d WebSocket connections for shutdown", len(conns))
+	for _, conn := range conns {
+		sendClose(conn, closeShutdown)
+		conn.Close()
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportHandler handles personal data export requests
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// Export returns everything stored about the authenticated user as a JSON
+// download
+func (h *ExportHandler) Export(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	export, err := h.exportService.Export(c.Request.Context(), userID)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export user data"})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="user-data.json"`)
+	c.JSON(http.StatusOK, export)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FriendHandler handles friend-related HTTP requests
+type FriendHandler struct {
+	friendService *services.FriendService
+}
+
+// NewFriendHandler creates a new friend handler
+func NewFriendHandler(friendService *services.FriendService) *FriendHandler {
+	return &FriendHandler{
+		friendService: friendService,
+	}
+}
+
+// FriendRequest represents a request to add a friend
+type FriendRequest struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// ListFriends returns the authenticated user's friends with their online status
+func (h *FriendHandler) ListFriends(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	friends, err := h.friendService.ListFriends(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list friends"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"friends": friends,
+	})
+}
+
+// ListRequests returns pending friend requests sent to the authenticated user
+func (h *FriendHandler) ListRequests(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	requests, err := h.friendService.ListRequests(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list friend requests"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"requests": requests,
+	})
+}
+
+// SendRequest sends a friend request to another user
+func (h *FriendHandler) SendRequest(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	var req FriendRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := h.friendService.SendRequest(c.Request.Context(), userID, req.Username)
+	if err != nil {
+		switch err {
+		case services.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		case services.ErrCannotFriendSelf:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case services.ErrAlreadyFriends:
+			c.JSON(http.StatusConflict, gin.H{"error": "Friend request already sent or already friends"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send friend request"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Friend request sent",
+	})
+}
+
+// AcceptRequest accepts a pending friend request from the user in the path
+func (h *FriendHandler) AcceptRequest(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	err := h.friendService.AcceptRequest(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		if err == services.ErrFriendRequestGone {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Friend request not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept friend request"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Friend request accepted",
+	})
+}
+
+// RemoveFriend removes a friend or declines a pending request
+func (h *FriendHandler) RemoveFriend(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	err := h.friendService.RemoveFriend(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		if err == services.ErrFriendRequestGone {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Friend not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove friend"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Friend removed",
+	})
+}
@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+
+	"chess-ws-go/internal/auth"
+	"chess-ws-go/internal/repositories"
+	"chess-ws-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GameLobby is the live side of games created over HTTP: it enforces the
+// concurrent game cap and puts the offer to the opponent
+type GameLobby interface {
+	AtGameLimit(userID string, admin bool) bool
+	OfferGame(ctx context.Context, offer GameOffer) (string, error)
+}
+
+// Why a game offer couldn't be made
+var (
+	errOpponentUnavailable = errors.New("opponent is offline")
+	errChallengePending    = errors.New("challenge already pending")
+)
+
+// GameHandler handles game-related HTTP requests
+type GameHandler struct {
+	gameService     *services.GameService
+	analysisService *services.AnalysisService
+	userRepo        repositories.UserRepository
+	lobby           GameLobby
+}
+
+// NewGameHandler creates a new game handler
+func NewGameHandler(
+	gameService *services.GameService,
+	analysisService *services.AnalysisService,
+	userRepo repositories.UserRepository,
+	lobby GameLobby,
+) *GameHandler {
+	return &GameHandler{
+		gameService:     gameService,
+		analysisService: analysisService,
+		userRepo:        userRepo,
+		lobby:           lobby,
+	}
+}
+
+// AnalyzeMovesRequest represents a move list to step through
+type AnalyzeMovesRequest struct {
+	Moves []string `json:"moves" binding:"required"`
+}
+
+// Color preferences a player can state when starting a game
+const (
+	ColorWhite  = "white"
+	ColorBlack  = "black"
+	ColorRandom = "random"
+)
+
+// CreateGameRequest starts a game against a chosen opponent
+type CreateGameRequest struct {
+	Opponent    string               `json:"opponent" binding:"required"`
+	Color       string               `json:"color" binding:"omitempty,oneof=white black random"`
+	TimeControl services.TimeControl `json:"time_control"`
+	Casual      bool                 `json:"casual"`     // Leave ratings alone
+	OpenSeats   bool                 `json:"open_seats"` // Let spectators take a seat a player abandoned; casual games only
+	Private     bool                 `json:"private"`    // Only the players may view the game while it is live
+}
+
+// CreateGame challenges an opponent to a game. The opponent is asked over
+// the WebSocket and nothing is created unless they accept; then both players
+// are told and take their seats by sending a reconnect message for the game,
+// and the clock starts once both have.
+func (h *GameHandler) CreateGame(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+	username := c.GetString("username")
+	role, _ := c.Get("role")
+	admin := role == auth.RoleAdmin
+
+	var req CreateGameRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tc := req.TimeControl
+	if tc.Initial <= 0 && tc.DaysPerMove == 0 {
+		tc = services.DefaultTimeControl
+	} else if !tc.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time control"})
+		return
+	}
+	if req.OpenSeats && !req.Casual {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only casual games can have open seats"})
+		return
+	}
+
+	opponent, err := h.userRepo.GetByUsername(c.Request.Context(), req.Opponent)
+	if err != nil {
+		if err == repositories.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Opponent not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up opponent"})
+		return
+	}
+	if opponent.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can't play against yourself"})
+		return
+	}
+
+	if h.lobby.AtGameLimit(userID, admin) {
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already playing the maximum number of games"})
+		return
+	}
+	if h.lobby.AtGameLimit(opponent.ID, opponent.Role == auth.RoleAdmin) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Opponent is already playing the maximum number of games"})
+		return
+	}
+
+	challengeID, err := h.lobby.OfferGame(c.Request.Context(), GameOffer{
+		ChallengerID:   userID,
+		ChallengerName: username,
+		Admin:          admin,
+		Opponent:       opponent,
+		Color:          req.Color,
+		TimeControl:    tc,
+		Casual:         req.Casual,
+		OpenSeats:      req.OpenSeats,
+		Private:        req.Private,
+	})
+	switch err {
+	case nil:
+	case errOpponentUnavailable:
+		c.JSON(http.StatusConflict, gin.H{"error": "Opponent is offline"})
+		return
+	case errChallengePending:
+		c.JSON(http.StatusConflict, gin.H{"error": "Challenge already pending"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to challenge opponent"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"challenge_id": challengeID,
+		"opponent":     opponent.Username,
+		"time_control": tc,
+		"casual":       req.Casual,
+		"open_seats":   req.OpenSeats,
+		"private":      req.Private,
+		"expires_in":   int(challengeTimeout.Seconds()),
+	})
+}
+
+// playsBlack settles a color preference, drawing lots for "random" or none
+func playsBlack(preference string) bool {
+	switch preference {
+	case ColorWhite:
+		return false
+	case ColorBlack:
+		return true
+	default:
+		return rand.Intn(2) == 1
+	}
+}
+
+// GetGame returns the full state of a live or finished game
+func (h *GameHandler) GetGame(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	details, err := h.gameService.GetGameDetails(c.Request.Context(), c.Param("id"), userID, h.userRepo)
+	if err != nil {
+		switch err {
+		case services.ErrGameNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		case services.ErrGameForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"error": "This game is private"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load game"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"game": details,
+	})
+}
+
+// GetLiveGame returns a compact snapshot of a game for clients polling over
+// HTTP. The ETag changes with every ply and when the game ends, so pollers
+// can send If-None-Match and get 304 Not Modified while nothing happens.
+func (h *GameHandler) GetLiveGame(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	live, err := h.gameService.GetLiveGame(c.Param("id"), userID)
+	if err != nil {
+		switch err {
+		case services.ErrGameNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		case services.ErrGameForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"error": "This game is private"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load game"})
+		}
+		return
+	}
+
+	etag := fmt.Sprintf(`"%s-%d"`, live.ID, live.Ply)
+	if live.Outcome != "" {
+		etag = fmt.Sprintf(`"%s-%d-over"`, live.ID, live.Ply)
+	}
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"game": live,
+	})
+}
+
+// GetAnalysis returns the position after every ply of a finished game
+func (h *GameHandler) GetAnalysis(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	plies, err := h.analysisService.AnalyzeGame(c.Request.Context(), c.Param("id"), userID, h.userRepo)
+	if err != nil {
+		switch err {
+		case services.ErrGameNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		case services.ErrGameForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"error": "This game is private"})
+		case services.ErrGameInProgress:
+			c.JSON(http.StatusConflict, gin.H{"error": "Game is still in progress"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to analyze game"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"plies": plies,
+	})
+}
+
+// GetGameEvents returns the event log of a finished game: joins, moves,
+// chat, draw offers, disconnects and how it ended
+func (h *GameHandler) GetGameEvents(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+	role, _ := c.Get("role")
+	admin := role == auth.RoleAdmin
+
+	events, err := h.gameService.GameEvents(c.Request.Context(), c.Param("id"), userID, admin)
+	if err != nil {
+		switch err {
+		case services.ErrGameNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Game not found"})
+		case services.ErrGameForbidden:
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the game's players can see its events"})
+		case services.ErrGameInProgress:
+			c.JSON(http.StatusConflict, gin.H{"error": "Game is still in progress"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load game events"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+	})
+}
+
+// AnalyzeMoves returns the position after every ply of a submitted move list
+func (h *GameHandler) AnalyzeMoves(c *gin.Context) {
+	var req AnalyzeMovesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	plies, err := h.analysisService.AnalyzeMoves(c.Request.Context(), req.Moves)
+	if err != nil {
+		if err == services.ErrInvalidMoveList {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid move list"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to analyze moves"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"plies": plies,
+	})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/services"
+)
+
+// What happens to a game nobody moves in once the idle warning runs out
+const (
+	idleAbort   = "abort"   // No move was made; the game ends without a result
+	idleForfeit = "forfeit" // The side to move loses
+)
+
+// armIdleTimer restarts the countdown to warning the players of a game in
+// which nobody is moving. The window never ends before the side to move's
+// running clock would, so slow thinking in a long game isn't cut short.
+// Correspondence games are left to their move deadline. Callers must hold
+// h.mu.
+func (h *WebSocketHandler) armIdleTimer(session *GameSession, gameID string) {
+	stopIdleTimer(session)
+	if h.config.IdleGameTimeout <= 0 || h.correspondence(gameID) {
+		return
+	}
+
+	window := h.config.IdleGameTimeout
+	if timeLeft, err := h.gameService.TimeLeft(gameID, session.CurrentTurn); err == nil {
+		window = max(window, time.Duration(timeLeft*float64(time.Second)))
+	}
+
+	session.idleSeq++
+	seq := session.idleSeq
+	session.idleTimer = time.AfterFunc(window, func() {
+		h.warnIdle(gameID, seq)
+	})
+}
+
+func stopIdleTimer(session *GameSession) {
+	if session.idleTimer != nil {
+		session.idleTimer.Stop()
+		session.idleTimer = nil
+	}
+}
+
+// idleSession returns the session of an unfinished game whose idle timer is
+// still the one identified by seq. A move rearms the timer, which makes
+// callbacks already in flight stale. Callers must hold h.mu.
+func (h *WebSocketHandler) idleSession(gameID string, seq int) *GameSession {
+	session, exists := h.sessions[gameID]
+	if !exists || session.idleSeq != seq || h.gameOver(gameID) {
+		return nil
+	}
+	return session
+}
+
+// warnIdle tells both players the game will be ended unless someone moves
+// within the grace period
+func (h *WebSocketHandler) warnIdle(gameID string, seq int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := h.idleSession(gameID, seq)
+	if session == nil {
+		return
+	}
+
+	action := idleForfeit
+	if h.moveCount(gameID) == 0 {
+		action = idleAbort
+	}
+
+	session.idleTimer = time.AfterFunc(h.config.IdleGameGrace, func() {
+		h.endIdleGame(gameID, seq)
+	})
+
+	idleMsg := struct {
+		Type    string `json:"type"`
+		Payload struct {
+			GameID string  `json:"gameId"`
+			Action string  `json:"action"` // idleAbort or idleForfeit
+			Turn   string  `json:"turn"`   // Side that forfeits
+			EndsIn float64 `json:"endsIn"` // Seconds left to make a move
+		} `json:"payload"`
+	}{Type: "idleWarning"}
+	idleMsg.Payload.GameID = gameID
+	idleMsg.Payload.Action = action
+	idleMsg.Payload.Turn = session.CurrentTurn.String()
+	idleMsg.Payload.EndsIn = h.config.IdleGameGrace.Seconds()
+
+	h.broadcastToPlayers(session, idleMsg)
+}
+
+// endIdleGame ends a game nobody moved in despite the warning. A game that
+// never got a move is aborted without touching ratings; otherwise the side
+// to move forfeits as if they had abandoned it. Ending the game stores it,
+// so h.mu is only held to check the timer and to announce the result.
+func (h *WebSocketHandler) endIdleGame(gameID string, seq int) {
+	h.mu.Lock()
+	session := h.idleSession(gameID, seq)
+	if session == nil {
+		h.mu.Unlock()
+		return
+	}
+	session.idleTimer = nil
+	color := session.CurrentTurn
+	noMoves := h.moveCount(gameID) == 0
+	h.mu.Unlock()
+
+	ctx := context.Background()
+	if noMoves {
+		_ = h.AbortGame(ctx, gameID, services.AbortReasonNoShow)
+		return
+	}
+
+	if _, err := h.gameService.AbandonGame(gameID, color, ctx, h.getUserRepository()); err != nil {
+		return
+	}
+	logging.Infof("Game %s forfeited by %s after sitting idle", gameID, color.Name())
+	h.mu.Lock()
+	h.announceGameOver(session, gameID)
+	h.mu.Unlock()
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxLobbyGames caps how many games one list_games reply carries
+const maxLobbyGames = 50
+
+// lobbyGame is a game in progress as shown in the lobby
+type lobbyGame struct {
+	GameID      string                `json:"gameId"`
+	White       string                `json:"white"`
+	Black       string                `json:"black"`
+	WhiteRating int                   `json:"whiteRating"`
+	BlackRating int                   `json:"blackRating"`
+	TimeControl services.TimeControl  `json:"timeControl"`
+	Category    models.RatingCategory `json:"category"`
+	Ply         int                   `json:"ply"`
+	Joinable    bool                  `json:"joinable"`
+	Spectatable bool                  `json:"spectatable"`
+}
+
+// lobbySeek is a player waiting in matchmaking, who the next join is paired with
+type lobbySeek struct {
+	Username   string `json:"username"`
+	Rating     int    `json:"rating"`
+	Preference string `json:"preference"`
+	Joinable   bool   `json:"joinable"`
+}
+
+// handleListGames sends the public games in progress, optionally only those
+// of one speed category, along with the open matchmaking seek if any
+func (h *WebSocketHandler) handleListGames(ctx context.Context, conn *websocket.Conn, userID string, category string) {
+	filter := models.RatingCategory(category)
+	switch filter {
+	case "", models.RatingBullet, models.RatingBlitz, models.RatingRapid:
+	default:
+		h.sendInvalidMessage(conn, &invalidMessageError{Field: "payload.category", Reason: "must be bullet, blitz or rapid"})
+		return
+	}
+
+	listings := h.gameService.ListPublicGames(filter)
+	if len(listings) > maxLobbyGames {
+		listings = listings[:maxLobbyGames]
+	}
+
+	users := map[string]*models.User{}
+	lookup := func(id string) *models.User {
+		if user, ok := users[id]; ok {
+			return user
+		}
+		user, err := h.userRepo.GetByID(ctx, id)
+		if err != nil {
+			user = nil
+		}
+		users[id] = user
+		return user
+	}
+
+	games := make([]lobbyGame, 0, len(listings))
+	for _, listing := range listings {
+		game := lobbyGame{
+			GameID:      listing.ID,
+			TimeControl: listing.TimeControl,
+			Category:    listing.Category,
+			Ply:         listing.Ply,
+		}
+		if white := lookup(listing.WhiteID); white != nil {
+			game.White = white.Username
+			game.WhiteRating = white.Rating(listing.Category)
+		}
+		if black := lookup(listing.BlackID); black != nil {
+			game.Black = black.Username
+			game.BlackRating = black.Rating(listing.Category)
+		}
+		games = append(games, game)
+	}
+
+	seeks := []lobbySeek{}
+	h.mu.Lock()
+	for i := range games {
+		if session, ok := h.sessions[games[i].GameID]; ok {
+			games[i].Spectatable = h.spectatorsFull(session) == ""
+		}
+	}
+	if waiting := h.waitingPlayer; waiting != nil && waiting.UserID != userID {
+		seeks = append(seeks, lobbySeek{
+			Username:   waiting.Username,
+			Rating:     waiting.Rating,
+			Preference: waiting.Preference,
+			Joinable:   true,
+		})
+	}
+	h.mu.Unlock()
+
+	h.sendMessage(conn, struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Games []lobbyGame `json:"games"`
+			Seeks []lobbySeek `json:"seeks"`
+		} `json:"payload"`
+	}{
+		Type: "gameList",
+		Payload: struct {
+			Games []lobbyGame `json:"games"`
+			Seeks []lobbySeek `json:"seeks"`
+		}{Games: games, Seeks: seeks},
+	})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"chess-ws-go/internal/stats"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConnectionCounter reports how many WebSockets are open and the most that
+// may be, zero meaning no limit
+type ConnectionCounter interface {
+	ConnectionCount() (open int, limit int)
+}
+
+type MetricsHandler struct {
+	collector   *stats.Collector
+	connections ConnectionCounter
+}
+
+func NewMetricsHandler(collector *stats.Collector, connections ConnectionCounter) *MetricsHandler {
+	return &MetricsHandler{
+		collector:   collector,
+		connections: connections,
+	}
+}
+
+// Metrics serves server statistics in the Prometheus text format
+func (h *MetricsHandler) Metrics(c *gin.Context) {
+	current := h.collector.GetStats()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "chess_active_connections %d\n", current.ActiveConnections)
+	open, limit := h.connections.ConnectionCount()
+	fmt.Fprintf(&b, "chess_ws_connections_open %d\n", open)
+	fmt.Fprintf(&b, "chess_ws_connections_limit %d\n", limit)
+	fmt.Fprintf(&b, "chess_active_games %d\n", current.ActiveGames)
+	writeGauges(&b, "chess_games_in_progress", "category", current.Games.ByCategory)
+	writeGauges(&b, "chess_games_in_progress_by_stakes", "stakes", map[string]int{
+		"rated":  current.Games.Rated,
+		"casual": current.Games.Casual,
+	})
+	fmt.Fprintf(&b, "chess_requests_total %d\n", current.TotalRequests)
+	fmt.Fprintf(&b, "chess_uptime_seconds %g\n", time.Since(current.StartTime).Seconds())
+	writeSummary(&b, "chess_move_processing_seconds", h.collector.MoveProcessing())
+	writeSummary(&b, "chess_move_handling_seconds", h.collector.MoveHandling())
+	writeCounters(&b, "chess_ws_messages_total", "type", h.collector.Messages())
+	writeCounters(&b, "chess_ws_messages_rejected_total", "reason", h.collector.RejectedMessages())
+
+	c.String(http.StatusOK, b.String())
+}
+
+func writeSummary(b *strings.Builder, name string, s stats.SummarySnapshot) {
+	fmt.Fprintf(b, "%s{quantile=\"0.5\"} %g\n", name, s.P50.Seconds())
+	fmt.Fprintf(b, "%s{quantile=\"0.95\"} %g\n", name, s.P95.Seconds())
+	fmt.Fprintf(b, "%s_sum %g\n", name, s.Sum.Seconds())
+	fmt.Fprintf(b, "%s_count %d\n", name, s.Count)
+}
+
+func writeGauges(b *strings.Builder, name string, label string, values map[string]int) {
+	labels := make([]string, 0, len(values))
+	for value := range values {
+		labels = append(labels, value)
+	}
+	sort.Strings(labels)
+	for _, value := range labels {
+		fmt.Fprintf(b, "%s{%s=%q} %d\n", name, label, value, values[value])
+	}
+}
+
+func writeCounters(b *strings.Builder, name string, label string, counts map[string]uint64) {
+	labels := make([]string, 0, len(counts))
+	for value := range counts {
+		labels = append(labels, value)
+	}
+	sort.Strings(labels)
+	for _, value := range labels {
+		fmt.Fprintf(b, "%s{%s=%q} %d\n", name, label, value, counts[value])
+	}
+}
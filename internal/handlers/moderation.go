@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+
+	"chess-ws-go/internal/logging"
+)
+
+// TerminateGame ends a game on an administrator's say-so and tells everyone
+// in it. Pending forfeits and premoves go with it.
+func (h *WebSocketHandler) TerminateGame(ctx context.Context, gameID string) error {
+	// Ending the game stores it, which isn't done under h.mu
+	if err := h.gameService.TerminateGame(gameID, ctx, h.getUserRepository()); err != nil {
+		return err
+	}
+	logging.Infof("Game %s terminated by an administrator", gameID)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[gameID]
+	if !exists {
+		return nil
+	}
+	stopPlay(session)
+	h.announceGameOver(session, gameID)
+	return nil
+}
+
+// AbortGame ends a game that hasn't properly started, on an administrator's
+// say-so or because nobody moved. Its players and spectators get a
+// gameAborted message with the reason ahead of the game-over message, and
+// the game is left to be cleaned up. Aborting stores the game, so callers
+// must not hold h.mu.
+func (h *WebSocketHandler) AbortGame(ctx context.Context, gameID string, reason string) error {
+	if err := h.gameService.AbortGame(gameID, reason, ctx, h.getUserRepository()); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[gameID]
+	if !exists {
+		h.gameService.ReleaseGame(gameID)
+		return nil
+	}
+	stopPlay(session)
+
+	abortedMsg := struct {
+		Type    string `json:"type"`
+		Payload struct {
+			GameID string `json:"gameId"`
+			Reason string `json:"reason"`
+		} `json:"payload"`
+	}{Type: "gameAborted"}
+	abortedMsg.Payload.GameID = gameID
+	abortedMsg.Payload.Reason = reason
+
+	h.broadcastToGame(session, abortedMsg)
+	h.announceGameOver(session, gameID)
+	return nil
+}
+
+// stopPlay cancels the pending forfeits and premoves of a game the server
+// ended. Callers must hold h.mu.
+func stopPlay(session *GameSession) {
+	for color, a := range session.away {
+		a.timer.Stop()
+		delete(session.away, color)
+	}
+	session.premoves = nil
+}
+
+// DisconnectUser closes every WebSocket a user has open and returns how
+// many there were. Their games carry on as after any disconnect, so they
+// forfeit unless they come back in time.
+func (h *WebSocketHandler) DisconnectUser(userID string) int {
+	h.mu.Lock()
+	conns := h.connsOf(userID)
+	h.mu.Unlock()
+
+	// Each reader then fails and cleans up after its connection
+	for _, conn := range conns {
+		sendClose(conn, closeAdminDisconnect)
+		conn.Close()
+	}
+	if len(conns) > 0 {
+		logging.Infof("Disconnected %d WebSockets of user %s at an administrator's request", len(conns), userID)
+	}
+	return len(conns)
+}
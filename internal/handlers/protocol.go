@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Versions of the game WebSocket message protocol. Clients pick one with a
+// "chess.v<N>" subprotocol or a hello message; those that do neither are
+// assumed to predate negotiation and get ProtocolV1.
+const (
+	// ProtocolV1 is the original format; errors only carry a text payload
+	ProtocolV1 = 1
+	// ProtocolV2 adds error codes and the offending field to error messages
+	ProtocolV2 = 2
+
+	// CurrentProtocol is the newest version the server speaks
+	CurrentProtocol = ProtocolV2
+)
+
+const (
+	subprotocolPrefix = "chess.v"
+
+	// tokenSubprotocolPrefix marks the subprotocol browsers carry their
+	// access token in, as the auth middleware reads it
+	tokenSubprotocolPrefix = "token."
+)
+
+// supportedProtocols lists every version the server still speaks, oldest first
+var supportedProtocols = []int{ProtocolV1, ProtocolV2}
+
+func protocolSupported(version int) bool {
+	for _, v := range supportedProtocols {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func subprotocolName(version int) string {
+	return subprotocolPrefix + strconv.Itoa(version)
+}
+
+// negotiateProtocol picks the newest supported version out of the offered
+// subprotocols. It returns the highest version offered when none is
+// supported, and 0 when the client didn't ask for a version at all.
+func negotiateProtocol(subprotocols []string) (version int, ok bool) {
+	for _, name := range subprotocols {
+		digits, found := strings.CutPrefix(name, subprotocolPrefix)
+		if !found {
+			continue
+		}
+		offered, err := strconv.Atoi(digits)
+		if err != nil {
+			continue
+		}
+		if protocolSupported(offered) {
+			if !ok || offered > version {
+				version, ok = offered, true
+			}
+		} else if !ok && offered > version {
+			version = offered
+		}
+	}
+	return version, ok
+}
+
+// selectSubprotocol picks the subprotocol to accept out of those a client
+// offered: the newest supported chess version, or else the token subprotocol,
+// which implies ProtocolV1. Browsers fail a handshake whose response doesn't
+// echo one of their offers, so ok is false when nothing offered can be
+// accepted; requested is then the highest chess version asked for, if any.
+func selectSubprotocol(offered []string) (name string, version int, ok bool) {
+	version, ok = negotiateProtocol(offered)
+	if ok {
+		return subprotocolName(version), version, true
+	}
+	if version != 0 {
+		return "", version, false
+	}
+	for _, name := range offered {
+		if strings.HasPrefix(name, tokenSubprotocolPrefix) {
+			return name, ProtocolV1, true
+		}
+	}
+	if len(offered) > 0 {
+		return "", 0, false
+	}
+	return "", ProtocolV1, true
+}
+
+// rejectProtocols refuses an upgrade none of whose subprotocols the server
+// speaks, telling the client which ones it could have asked for
+func rejectProtocols(w http.ResponseWriter, offered []string, requested int) {
+	names := make([]string, len(supportedProtocols))
+	for i, v := range supportedProtocols {
+		names[i] = subprotocolName(v)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error        string   `json:"error"`
+		Offered      []string `json:"offered"`
+		Requested    int      `json:"requested,omitempty"`
+		Supported    []int    `json:"supported"`
+		Subprotocols []string `json:"subprotocols"`
+	}{
+		Error:        "unsupported protocol",
+		Offered:      offered,
+		Requested:    requested,
+		Supported:    supportedProtocols,
+		Subprotocols: names,
+	})
+}
+
+// protocolFor returns the protocol version negotiated on a connection. It
+// doesn't take h.mu, since errors are often sent with it held.
+func (h *WebSocketHandler) protocolFor(conn *websocket.Conn) int {
+	if version, ok := h.protocols.Load(conn); ok {
+		return version.(int)
+	}
+	return ProtocolV1
+}
+
+// handleHello switches a connection to the protocol version the client asks
+// for, leaving it unchanged when that version isn't supported
+func (h *WebSocketHandler) handleHello(conn *websocket.Conn, version int) {
+	if !protocolSupported(version) {
+		h.sendUnsupportedProtocol(conn, version)
+		return
+	}
+
+	h.mu.Lock()
+	if h.connections[conn] {
+		h.protocols.Store(conn, version)
+	}
+	h.mu.Unlock()
+
+	h.sendMessage(conn, struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Version   int   `json:"version"`
+			Supported []int `json:"supported"`
+		} `json:"payload"`
+	}{
+		Type: "hello",
+		Payload: struct {
+			Version   int   `json:"version"`
+			Supported []int `json:"supported"`
+		}{Version: version, Supported: supportedProtocols},
+	})
+}
+
+func (h *WebSocketHandler) sendUnsupportedProtocol(conn *websocket.Conn, requested int) {
+	h.sendMessage(conn, struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Requested int   `json:"requested"`
+			Supported []int `json:"supported"`
+		} `json:"payload"`
+	}{
+		Type: "unsupportedProtocol",
+		Payload: struct {
+			Requested int   `json:"requested"`
+			Supported []int `json:"supported"`
+		}{Requested: requested, Supported: supportedProtocols},
+	})
+}
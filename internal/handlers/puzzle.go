@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PuzzleHandler handles puzzle-related HTTP requests
+type PuzzleHandler struct {
+	puzzleService *services.PuzzleService
+}
+
+// NewPuzzleHandler creates a new puzzle handler
+func NewPuzzleHandler(puzzleService *services.PuzzleService) *PuzzleHandler {
+	return &PuzzleHandler{
+		puzzleService: puzzleService,
+	}
+}
+
+// PuzzleMoveRequest represents a move submitted for the current puzzle
+type PuzzleMoveRequest struct {
+	Move string `json:"move" binding:"required"`
+}
+
+// NextPuzzle starts a random puzzle near the user's puzzle rating
+func (h *PuzzleHandler) NextPuzzle(c *gin.Context) {
+	h.startPuzzle(c, "")
+}
+
+// GetPuzzle starts a specific puzzle
+func (h *PuzzleHandler) GetPuzzle(c *gin.Context) {
+	h.startPuzzle(c, c.Param("id"))
+}
+
+// startPuzzle starts a puzzle for the authenticated user and returns its position
+func (h *PuzzleHandler) startPuzzle(c *gin.Context, puzzleID string) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	puzzle, err := h.puzzleService.StartPuzzle(c.Request.Context(), userID, puzzleID)
+	if err != nil {
+		if err == services.ErrPuzzleNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Puzzle not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load puzzle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"puzzle": puzzle,
+	})
+}
+
+// SubmitMove checks a move for the user's current puzzle
+func (h *PuzzleHandler) SubmitMove(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	var req PuzzleMoveRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.puzzleService.SubmitMove(c.Request.Context(), userID, req.Move)
+	if err != nil {
+		switch err {
+		case services.ErrNoActivePuzzle:
+			c.JSON(http.StatusConflict, gin.H{"error": "No puzzle in progress"})
+		case services.ErrPuzzleIllegalMove:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Illegal move"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check move"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"result": result,
+	})
+}
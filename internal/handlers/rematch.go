@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+	"github.com/gorilla/websocket"
+)
+
+// rematchOffer is a finished game's player asking their opponent for another
+// game, rated or casual
+type rematchOffer struct {
+	by    chess.Color
+	conn  *websocket.Conn // Connection the offer came from; the rematch starts there
+	admin bool            // Whether the offerer is exempt from the game cap
+	rated bool
+}
+
+// Why a rematch request failed
+var (
+	errNotInGame       = errors.New("player not in this game")
+	errRematchTooEarly = errors.New("a rematch can only be offered once the game is over")
+	errNoRematchOffer  = errors.New("your opponent hasn't offered a rematch")
+	errRematchStakes   = errors.New("the offer is for other stakes; offer a rematch to propose yours")
+	errOpponentOffline = errors.New("your opponent is no longer online")
+)
+
+// sendRematchError reports why a rematch request failed
+func (h *WebSocketHandler) sendRematchError(conn *websocket.Conn, err error) {
+	switch {
+	case errors.Is(err, errRematchTooEarly):
+		h.sendErrorCode(conn, "GAME_IN_PROGRESS", err.Error())
+	case errors.Is(err, errRematchStakes):
+		h.sendErrorCode(conn, "REMATCH_STAKES_MISMATCH", err.Error())
+	case errors.Is(err, errNoRematchOffer), errors.Is(err, errOpponentOffline):
+		h.sendErrorCode(conn, "NO_REMATCH_OFFER", err.Error())
+	default:
+		h.sendGameError(conn, err)
+	}
+}
+
+// rematchPlayer returns the session of a finished game and the side a user
+// played in it. Callers must hold h.mu.
+func (h *WebSocketHandler) rematchPlayer(userID string, gameID string) (*GameSession, chess.Color, error) {
+	session, exists := h.sessions[gameID]
+	if !exists {
+		return nil, chess.NoColor, services.ErrGameNotFound
+	}
+
+	var color chess.Color
+	switch userID {
+	case session.White.UserID:
+		color = chess.White
+	case session.Black.UserID:
+		color = chess.Black
+	default:
+		return nil, chess.NoColor, errNotInGame
+	}
+
+	if !h.gameOver(gameID) {
+		return nil, chess.NoColor, errRematchTooEarly
+	}
+	return session, color, nil
+}
+
+// opponentOffer returns the rematch offer a player's opponent made. Callers
+// must hold h.mu.
+func opponentOffer(session *GameSession, color chess.Color) (*rematchOffer, error) {
+	if session.rematch == nil || session.rematch.by == color {
+		return nil, errNoRematchOffer
+	}
+	return session.rematch, nil
+}
+
+// handleRematchOffer offers the opponent of a finished game another one with
+// the colors swapped. Both players have to agree on whether it's rated, so
+// offering the same stakes the opponent already offered starts it, and
+// offering different stakes replaces their offer with a counter-offer.
+func (h *WebSocketHandler) handleRematchOffer(ctx context.Context, conn *websocket.Conn, userID string, gameID string, rated bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, color, err := h.rematchPlayer(userID, gameID)
+	if err != nil {
+		h.sendRematchError(conn, err)
+		return
+	}
+	if offer, err := opponentOffer(session, color); err == nil && offer.rated == rated {
+		h.startRematch(ctx, conn, gameID, session, color, offer)
+		return
+	}
+
+	session.rematch = &rematchOffer{by: color, conn: conn, admin: isAdmin(ctx), rated: rated}
+
+	opponent := session.Black
+	if color == chess.Black {
+		opponent = session.White
+	}
+	rematchMsg := struct {
+		Type    string `json:"type"`
+		Payload struct {
+			GameID    string `json:"gameId"`
+			OfferedBy string `json:"offeredBy"`
+			Rated     bool   `json:"rated"`
+		} `json:"payload"`
+	}{Type: "rematchOffer"}
+	rematchMsg.Payload.GameID = gameID
+	rematchMsg.Payload.OfferedBy = color.String()
+	rematchMsg.Payload.Rated = rated
+
+	h.broadcast(h.connsOf(opponent.UserID), rematchMsg)
+}
+
+// handleRematchResponse accepts or declines the opponent's rematch offer. An
+// acceptance names the stakes it agrees to, so a client that missed a
+// counter-offer can't start a rated game its player thought was casual.
+func (h *WebSocketHandler) handleRematchResponse(ctx context.Context, conn *websocket.Conn, userID string, gameID string, accept bool, rated bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, color, err := h.rematchPlayer(userID, gameID)
+	var offer *rematchOffer
+	if err == nil {
+		offer, err = opponentOffer(session, color)
+	}
+	if err != nil {
+		h.sendRematchError(conn, err)
+		return
+	}
+
+	if !accept {
+		session.rematch = nil
+		h.sendMessage(offer.conn, struct {
+			Type    string `json:"type"`
+			Payload struct {
+				GameID     string `json:"gameId"`
+				DeclinedBy string `json:"declinedBy"`
+			} `json:"payload"`
+		}{
+			Type: "rematchDeclined",
+			Payload: struct {
+				GameID     string `json:"gameId"`
+				DeclinedBy string `json:"declinedBy"`
+			}{
+				GameID:     gameID,
+				DeclinedBy: color.String(),
+			},
+		})
+		return
+	}
+
+	if offer.rated != rated {
+		h.sendRematchError(conn, errRematchStakes)
+		return
+	}
+	h.startRematch(ctx, conn, gameID, session, color, offer)
+}
+
+// startRematch starts the rematch of a finished game that the player on the
+// given side agreed to. Ratings are looked up afresh, since either player may
+// have played other games since, and a rated rematch is rated from them like
+// any other game. Time odds are worked out again from them too, as the colors
+// swap. Players at their game limit are told so directly. Callers must hold
+// h.mu.
+func (h *WebSocketHandler) startRematch(
+	ctx context.Context,
+	conn *websocket.Conn,
+	gameID string,
+	session *GameSession,
+	color chess.Color,
+	offer *rematchOffer,
+) {
+	previous := map[chess.Color]*Player{chess.White: session.White, chess.Black: session.Black}
+	offerer := &Player{
+		Conn:     offer.conn,
+		Username: previous[offer.by].Username,
+		UserID:   previous[offer.by].UserID,
+		Admin:    offer.admin,
+	}
+	accepter := &Player{
+		Conn:     conn,
+		Username: previous[color].Username,
+		UserID:   previous[color].UserID,
+		Admin:    isAdmin(ctx),
+	}
+	if !h.userConns[offerer.UserID][offerer.Conn] {
+		session.rematch = nil
+		h.sendRematchError(conn, errOpponentOffline)
+		return
+	}
+	if h.atGameLimit(accepter) {
+		h.sendGameLimitError(conn)
+		return
+	}
+	if h.atGameLimit(offerer) {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Your opponent is already playing too many games"})
+		h.sendGameLimitError(offerer.Conn)
+		return
+	}
+
+	state, err := h.gameService.GetGameState(gameID)
+	if err != nil {
+		h.sendGameError(conn, err)
+		return
+	}
+	lastWhiteRating, lastWhiteProvisional := h.standing(ctx, state.WhitePlayer)
+	lastBlackRating, lastBlackProvisional := h.standing(ctx, state.BlackPlayer)
+
+	// Colors swap: last game's black plays white. Casual games show no ratings.
+	white, black := accepter, offerer
+	if color == chess.White {
+		white, black = offerer, accepter
+	}
+	if offer.rated {
+		white.Rating, white.Provisional = lastBlackRating, lastBlackProvisional
+		black.Rating, black.Provisional = lastWhiteRating, lastWhiteProvisional
+	}
+	tc := state.TimeSettings.WithoutOdds()
+	if !lastWhiteProvisional && !lastBlackProvisional {
+		tc = services.WithRatingOdds(tc, lastBlackRating, lastWhiteRating, h.config.TimeOddsRatingGap)
+	}
+
+	session.rematch = nil
+	// A rematch of a private game is just as private
+	rematchID := h.startGame(white, black, tc, services.GameOptions{Casual: !offer.rated, Private: state.Private})
+	logging.Infof("Game %s rematched as %s game %s", gameID, stakes(offer.rated), rematchID)
+
+	rematchMsg := struct {
+		Type    string `json:"type"`
+		Payload struct {
+			GameID    string `json:"gameId"`
+			RematchID string `json:"rematchId"`
+			Rated     bool   `json:"rated"`
+		} `json:"payload"`
+	}{Type: "rematchStarted"}
+	rematchMsg.Payload.GameID = gameID
+	rematchMsg.Payload.RematchID = rematchID
+	rematchMsg.Payload.Rated = offer.rated
+
+	h.broadcast([]*websocket.Conn{white.Conn, black.Conn}, rematchMsg)
+
+	// The players have moved on, so the old game needn't wait out its delay
+	h.releaseGame(gameID)
+}
+
+// stakes names whether a game is rated
+func stakes(rated bool) string {
+	if rated {
+		return "rated"
+	}
+	return "casual"
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"chess-ws-go/internal/logging"
+
+	"github.com/corentings/chess/v2"
+	"github.com/gorilla/websocket"
+)
+
+// maxBufferedMessages bounds the recent messages kept for each seat of a
+// game. A player away for longer than that many updates gets a fresh state
+// snapshot instead of a replay.
+const maxBufferedMessages = 100
+
+// outbox holds the recent game messages sent to one seat, so a player whose
+// connection dropped can be sent exactly what they missed
+type outbox struct {
+	messages []bufferedMessage
+	dropped  int // Sequence number of the newest message no longer held
+}
+
+// bufferedMessage is an encoded message as it was sent, sequence number included
+type bufferedMessage struct {
+	seq  int
+	data []byte
+}
+
+// add buffers a message, discarding the oldest one if the outbox is full
+func (o *outbox) add(seq int, data []byte) {
+	if len(o.messages) >= maxBufferedMessages {
+		o.dropped = o.messages[0].seq
+		copy(o.messages, o.messages[1:])
+		o.messages = o.messages[:len(o.messages)-1]
+	}
+	o.messages = append(o.messages, bufferedMessage{seq: seq, data: data})
+}
+
+// since returns the messages sent after lastSeq. It reports false when some
+// of them have been discarded, as replaying the rest would leave a gap.
+func (o *outbox) since(lastSeq int) ([]bufferedMessage, bool) {
+	if lastSeq < o.dropped {
+		return nil, false
+	}
+	i := sort.Search(len(o.messages), func(i int) bool {
+		return o.messages[i].seq > lastSeq
+	})
+	return o.messages[i:], true
+}
+
+// withSeq adds a sequence number to an encoded message object
+func withSeq(data []byte, seq int) []byte {
+	numbered := []byte(`{"seq":` + strconv.Itoa(seq))
+	if len(data) > 2 {
+		numbered = append(numbered, ',')
+	}
+	return append(numbered, data[1:]...)
+}
+
+// deliver numbers a game message, buffers it for the given seats and sends
+// it to conns. Spectators get the number too but have nothing to replay.
+// The outbox lock is held while queuing so every connection receives the
+// game's messages in sequence order; queuing never waits on a peer.
+func (h *WebSocketHandler) deliver(session *GameSession, seats []*Player, conns []*websocket.Conn, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		logging.Warnf("Error encoding message: %v", err)
+		return
+	}
+
+	session.outMu.Lock()
+	defer session.outMu.Unlock()
+
+	session.outSeq++
+	data = withSeq(data, session.outSeq)
+	for _, player := range seats {
+		if player == nil {
+			continue
+		}
+		if session.outboxes == nil {
+			session.outboxes = make(map[chess.Color]*outbox)
+		}
+		box := session.outboxes[player.Color]
+		if box == nil {
+			box = &outbox{}
+			session.outboxes[player.Color] = box
+		}
+		box.add(session.outSeq, data)
+	}
+	h.writeAll(conns, data)
+}
+
+// lastSeq returns the sequence number of the newest message sent to a game
+func (s *GameSession) lastSeq() int {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	return s.outSeq
+}
+
+// resetOutbox empties a seat's outbox when it changes hands. Nothing sent
+// before can be replayed to the new player, who starts from a snapshot.
+func (s *GameSession) resetOutbox(color chess.Color) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	if s.outboxes != nil {
+		s.outboxes[color] = &outbox{dropped: s.outSeq}
+	}
+}
+
+// replayMissed resends a reconnecting player the messages sent after the
+// last one their client saw. It reports false when that can't be done
+// seamlessly, leaving the caller to send a full state snapshot.
+func (h *WebSocketHandler) replayMissed(conn *websocket.Conn, session *GameSession, color chess.Color, lastSeq *int) bool {
+	if lastSeq == nil {
+		return false
+	}
+
+	session.outMu.Lock()
+	defer session.outMu.Unlock()
+
+	// A number the game never reached came from somewhere else
+	if *lastSeq < 0 || *lastSeq > session.outSeq {
+		return false
+	}
+	box := session.outboxes[color]
+	if box == nil {
+		return true // Nothing has been sent to this seat
+	}
+	missed, complete := box.since(*lastSeq)
+	if !complete {
+		return false
+	}
+	for _, message := range missed {
+		h.writeAll([]*websocket.Conn{conn}, message.data)
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON decodes the JSON request body into req. It answers 413 when the
+// body is over the size limit and 400 when it's otherwise invalid, and
+// reports whether the handler should carry on.
+func bindJSON(c *gin.Context, req interface{}) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	return false
+}
+
+// pageLimit reads the "limit" query parameter. Missing, malformed or
+// non-positive values fall back to def, and large ones are clamped to max.
+func pageLimit(c *gin.Context, def int, max int) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 {
+		return def
+	}
+	return min(limit, max)
+}
+
+// pageNumber reads the 1-based "page" query parameter, treating anything
+// below 1 as the first page
+func pageNumber(c *gin.Context) int {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
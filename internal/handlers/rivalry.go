@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RivalryHandler handles head-to-head record requests
+type RivalryHandler struct {
+	rivalryService *services.RivalryService
+}
+
+// NewRivalryHandler creates a new rivalry handler
+func NewRivalryHandler(rivalryService *services.RivalryService) *RivalryHandler {
+	return &RivalryHandler{
+		rivalryService: rivalryService,
+	}
+}
+
+// HeadToHead returns one user's wins, losses and draws against another and
+// their most recent games together. Their private games are shown only to
+// the two of them.
+func (h *RivalryHandler) HeadToHead(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+	rivalry, err := h.rivalryService.HeadToHead(c.Request.Context(), userID, c.Param("username"), c.Param("opponent"))
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up head-to-head record"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rivalry)
+}
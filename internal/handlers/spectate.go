@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+	"github.com/gorilla/websocket"
+)
+
+// handleSpectate subscribes a connection to the moves, clocks and result of
+// a game it isn't playing in. Joins beyond the per-game or global spectator
+// cap are refused with a SPECTATOR_LIMIT error.
+func (h *WebSocketHandler) handleSpectate(conn *websocket.Conn, userID string, gameID string) {
+	// Private games are hidden from everyone but their players
+	if _, err := h.gameService.GetLiveGame(gameID, userID); err != nil {
+		if errors.Is(err, services.ErrGameForbidden) {
+			err = services.ErrGameNotFound
+		}
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: err.Error()})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[gameID]
+	if !exists || h.gameOver(gameID) {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Game is not in progress"})
+		return
+	}
+	if session.White.UserID == userID || session.Black.UserID == userID {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Players can't spectate their own game"})
+		return
+	}
+
+	if !session.spectators[conn] {
+		if reason := h.spectatorsFull(session); reason != "" {
+			h.sendErrorCode(conn, "SPECTATOR_LIMIT", reason)
+			return
+		}
+		if session.spectators == nil {
+			session.spectators = make(map[*websocket.Conn]bool)
+		}
+		session.spectators[conn] = true
+		h.spectatorCount++
+		h.messageService.AssociateConnection(conn, gameID)
+	}
+
+	h.sendMessage(conn, struct {
+		Type    string `json:"type"`
+		Payload struct {
+			GameID     string `json:"gameId"`
+			Spectators int    `json:"spectators"`
+		} `json:"payload"`
+	}{
+		Type: "spectating",
+		Payload: struct {
+			GameID     string `json:"gameId"`
+			Spectators int    `json:"spectators"`
+		}{GameID: gameID, Spectators: len(session.spectators)},
+	})
+	h.sendGameState(conn, session, gameID)
+}
+
+// handleUnspectate stops sending a game's updates to a spectator
+func (h *WebSocketHandler) handleUnspectate(conn *websocket.Conn, gameID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if session, exists := h.sessions[gameID]; exists {
+		h.removeSpectator(gameID, session, conn)
+	}
+}
+
+// spectatorsFull explains why a game can't take another spectator, or
+// returns an empty string when it can. Callers must hold h.mu.
+func (h *WebSocketHandler) spectatorsFull(session *GameSession) string {
+	if limit := h.config.MaxGameSpectators; limit > 0 && len(session.spectators) >= limit {
+		return "This game has reached its spectator limit"
+	}
+	if limit := h.config.MaxSpectators; limit > 0 && h.spectatorCount >= limit {
+		return "The server has reached its spectator limit"
+	}
+	return ""
+}
+
+// removeSpectator unsubscribes a connection from a game. Callers must hold h.mu.
+func (h *WebSocketHandler) removeSpectator(gameID string, session *GameSession, conn *websocket.Conn) {
+	if session.spectators[conn] {
+		delete(session.spectators, conn)
+		h.spectatorCount--
+		h.messageService.DissociateConnection(conn, gameID)
+	}
+}
+
+// releaseSpectators unsubscribes everyone watching a finished game, freeing
+// their places under the global cap. Callers must hold h.mu.
+func (h *WebSocketHandler) releaseSpectators(gameID string, session *GameSession) {
+	for conn := range session.spectators {
+		h.messageService.DissociateConnection(conn, gameID)
+	}
+	h.spectatorCount -= len(session.spectators)
+	session.spectators = nil
+}
+
+// spectatorConns returns the connections watching a game. Callers must hold h.mu.
+func spectatorConns(session *GameSession) []*websocket.Conn {
+	conns := make([]*websocket.Conn, 0, len(session.spectators))
+	for conn := range session.spectators {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// broadcastToGame sends a message to both players of a game and everyone
+// watching it. A crowded game still costs one encoding per update.
+func (h *WebSocketHandler) broadcastToGame(session *GameSession, message interface{}) {
+	h.deliver(session, []*Player{session.White, session.Black}, append(playerConns(session), spectatorConns(session)...), message)
+}
+
+// handleTakeSeat moves a spectator into the seat of a player who has been
+// gone longer than their disconnect grace, in casual games created with open
+// seats. The game carries on with the spectator playing that side. An empty
+// color takes whichever seat is vacant.
+func (h *WebSocketHandler) handleTakeSeat(conn *websocket.Conn, userID string, username string, gameID string, color string) {
+	if color != "" && color != ColorWhite && color != ColorBlack {
+		h.sendInvalidMessage(conn, &invalidMessageError{Field: "payload.color", Reason: "must be white or black"})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[gameID]
+	if !exists || !session.spectators[conn] {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Only spectators of a game can take a seat in it"})
+		return
+	}
+
+	seat, vacant := h.vacantSeat(session, color)
+	if !vacant {
+		h.sendErrorCode(conn, "SEAT_NOT_VACANT", "No seat is vacant in this game")
+		return
+	}
+	if h.atGameLimit(&Player{UserID: userID}) {
+		h.sendGameLimitError(conn)
+		return
+	}
+
+	if err := h.gameService.ReplacePlayer(gameID, seat, userID); err != nil {
+		if errors.Is(err, services.ErrSeatsClosed) {
+			h.sendErrorCode(conn, "SEATS_CLOSED", err.Error())
+			return
+		}
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: err.Error()})
+		return
+	}
+
+	// The seat changes hands with the absence that vacated it
+	session.away[seat].timer.Stop()
+	delete(session.away, seat)
+	delete(session.premoves, seat)
+	delete(session.graceUsed, seat)
+	session.resetOutbox(seat)
+	h.removeSpectator(gameID, session, conn)
+
+	player := &Player{Color: seat, Username: username, UserID: userID, Preference: seatName(seat)}
+	previous := session.White
+	if seat == chess.White {
+		session.White = player
+	} else {
+		previous = session.Black
+		session.Black = player
+	}
+	if previous.Conn != nil {
+		h.messageService.DissociateConnection(previous.Conn, gameID)
+	}
+	h.seatConnection(gameID, player, conn)
+	logging.Infof("%s took over %s's %s seat in game %s", username, previous.Username, seat.Name(), gameID)
+	h.gameService.RecordEvent(gameID, services.EventJoin, seat, "took over from "+previous.Username)
+
+	h.broadcastToGame(session, struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Color    string `json:"color"`
+			Previous string `json:"previous"`
+			Player   string `json:"player"`
+		} `json:"payload"`
+	}{
+		Type: "seatTaken",
+		Payload: struct {
+			Color    string `json:"color"`
+			Previous string `json:"previous"`
+			Player   string `json:"player"`
+		}{Color: seat.String(), Previous: previous.Username, Player: username},
+	})
+
+	// The clock only runs again once nobody is away
+	if len(session.away) == 0 {
+		h.gameService.ResumeClock(gameID)
+		h.armFlag(session, gameID)
+	}
+
+	h.sendGameState(conn, session, gameID)
+}
+
+// vacantSeat finds a seat whose player has been away past their disconnect
+// grace, limited to one color unless color is empty. Callers must hold h.mu.
+func (h *WebSocketHandler) vacantSeat(session *GameSession, color string) (chess.Color, bool) {
+	for _, seat := range []chess.Color{chess.White, chess.Black} {
+		if color != "" && color != seatName(seat) {
+			continue
+		}
+		if a := session.away[seat]; a != nil && time.Since(a.since) >= h.remainingGrace(session, seat) {
+			return seat, true
+		}
+	}
+	return chess.NoColor, false
+}
+
+// seatName returns how clients name a seat: ColorWhite or ColorBlack
+func seatName(seat chess.Color) string {
+	if seat == chess.White {
+		return ColorWhite
+	}
+	return ColorBlack
+}
+
+// sendGameFull tells a user that a game they asked to play in already has
+// both its players, and whether they may watch it instead by sending
+// spectate. Private games are hidden from them altogether. Callers must
+// hold h.mu.
+func (h *WebSocketHandler) sendGameFull(conn *websocket.Conn, userID string, gameID string, session *GameSession) {
+	if _, err := h.gameService.GetLiveGame(gameID, userID); err != nil {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Game not found"})
+		return
+	}
+	canSpectate := !h.gameOver(gameID) && h.spectatorsFull(session) == ""
+
+	h.sendMessage(conn, struct {
+		Type    string `json:"type"`
+		Payload struct {
+			GameID      string `json:"gameId"`
+			CanSpectate bool   `json:"canSpectate"`
+		} `json:"payload"`
+	}{
+		Type: "gameFull",
+		Payload: struct {
+			GameID      string `json:"gameId"`
+			CanSpectate bool   `json:"canSpectate"`
+		}{
+			GameID:      gameID,
+			CanSpectate: canSpectate,
+		},
+	})
+}
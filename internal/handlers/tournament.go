@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"net/http"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TournamentHandler handles tournament-related HTTP requests
+type TournamentHandler struct {
+	tournamentService *services.TournamentService
+}
+
+// NewTournamentHandler creates a new tournament handler
+func NewTournamentHandler(tournamentService *services.TournamentService) *TournamentHandler {
+	return &TournamentHandler{
+		tournamentService: tournamentService,
+	}
+}
+
+// CreateTournamentRequest represents a request to create a tournament
+type CreateTournamentRequest struct {
+	Name         string                `json:"name" binding:"required,max=100"`
+	Format       string                `json:"format" binding:"omitempty,oneof=swiss knockout"`
+	Rounds       int                   `json:"rounds" binding:"omitempty,min=1,max=20"` // Swiss only
+	SeedByRating bool                  `json:"seed_by_rating"`                          // Knockout only
+	TimeControl  *services.TimeControl `json:"time_control"`
+}
+
+// ReportResultRequest represents a game result reported for a tournament round.
+// An empty result is read from the finished game.
+type ReportResultRequest struct {
+	GameID string `json:"game_id" binding:"required"`
+	Result string `json:"result"`
+}
+
+// CreateTournament creates a new tournament owned by the authenticated user
+func (h *TournamentHandler) CreateTournament(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	var req CreateTournamentRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	tc := services.DefaultTimeControl
+	if req.TimeControl != nil {
+		if !req.TimeControl.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time control"})
+			return
+		}
+		tc = *req.TimeControl
+	}
+
+	if req.Format != services.FormatKnockout && req.Rounds == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Swiss tournaments need a number of rounds"})
+		return
+	}
+
+	tournament := h.tournamentService.CreateTournament(userID, services.TournamentOptions{
+		Name:         req.Name,
+		Format:       req.Format,
+		Rounds:       req.Rounds,
+		SeedByRating: req.SeedByRating,
+		TimeControl:  tc,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"tournament": tournament,
+	})
+}
+
+// GetTournament returns a tournament with its standings and pairings
+func (h *TournamentHandler) GetTournament(c *gin.Context) {
+	tournament, err := h.tournamentService.GetTournament(c.Param("id"))
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tournament": tournament,
+	})
+}
+
+// Register registers the authenticated user for a tournament
+func (h *TournamentHandler) Register(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	err := h.tournamentService.Register(c.Request.Context(), c.Param("id"), userID)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Registered for tournament"})
+}
+
+// StartRound pairs and starts the next round of a tournament
+func (h *TournamentHandler) StartRound(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	pairings, err := h.tournamentService.StartRound(c.Param("id"), userID)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pairings": pairings,
+	})
+}
+
+// StartBracket seeds a knockout tournament and starts its first round
+func (h *TournamentHandler) StartBracket(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	bracket, err := h.tournamentService.StartBracket(c.Param("id"), userID)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bracket": bracket,
+	})
+}
+
+// GetBracket returns the bracket of a knockout tournament for rendering
+func (h *TournamentHandler) GetBracket(c *gin.Context) {
+	tournament, err := h.tournamentService.GetTournament(c.Param("id"))
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+	if tournament.Format != services.FormatKnockout {
+		h.respondWithError(c, services.ErrWrongTournamentFormat)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    tournament.Status,
+		"players":   tournament.Players,
+		"bracket":   tournament.Bracket,
+		"winner_id": tournament.WinnerID,
+	})
+}
+
+// ReportResult records the result of a game in the current round
+func (h *TournamentHandler) ReportResult(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	var req ReportResultRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := h.tournamentService.ReportResult(c.Param("id"), userID, req.GameID, req.Result)
+	if err != nil {
+		h.respondWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Result recorded"})
+}
+
+// respondWithError maps tournament service errors to HTTP responses
+func (h *TournamentHandler) respondWithError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrTournamentNotFound, services.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case services.ErrTournamentForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case services.ErrTournamentStarted, services.ErrTournamentFinished, services.ErrAlreadyRegistered,
+		services.ErrRoundInProgress, services.ErrResultAlreadyPresent, services.ErrResultMismatch:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case services.ErrNotEnoughPlayers, services.ErrPairingNotFound, services.ErrInvalidResult,
+		services.ErrWrongTournamentFormat:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Tournament request failed"})
+	}
+}
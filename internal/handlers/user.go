@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"net/http"
-	"strconv"
 
 	"chess-ws-go/internal/services"
 
@@ -13,13 +12,15 @@ import (
 type UserHandler struct {
 	userService *services.UserService
 	authService *services.AuthService
+	maxPageSize int // Largest page of users a client may ask for
 }
 
 // NewUserHandler creates a new user handler
-func NewUserHandler(userService *services.UserService, authService *services.AuthService) *UserHandler {
+func NewUserHandler(userService *services.UserService, authService *services.AuthService, maxPageSize int) *UserHandler {
 	return &UserHandler{
 		userService: userService,
 		authService: authService,
+		maxPageSize: maxPageSize,
 	}
 }
 
@@ -40,6 +41,19 @@ type PasswordResetConfirmRequest struct {
 	Password string `json:"password" binding:"required,min=8"`
 }
 
+// NotificationPreferencesRequest represents a notification preferences
+// update; omitted preferences keep their current value
+type NotificationPreferencesRequest struct {
+	GameInvites    *bool `json:"game_invites,omitempty"`
+	GameSummaries  *bool `json:"game_summaries,omitempty"`
+	SecurityAlerts *bool `json:"security_alerts,omitempty"`
+}
+
+// EmailChangeConfirmRequest represents an email change confirmation request
+type EmailChangeConfirmRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
 // UpdateProfile handles user profile updates
 func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	userID := c.GetString("user_id") // From auth middleware
@@ -49,8 +63,7 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 	}
 
 	var req UpdateProfileRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -60,6 +73,14 @@ func (h *UserHandler) UpdateProfile(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 			return
 		}
+		if err == services.ErrUserConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "Profile was modified concurrently, please retry"})
+			return
+		}
+		if err == services.ErrEmailTaken {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already taken"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
 		return
 	}
@@ -77,7 +98,8 @@ func (h *UserHandler) DeleteAccount(c *gin.Context) {
 		return
 	}
 
-	err := h.userService.DeleteUser(c.Request.Context(), userID)
+	ctx := services.WithClientIP(c.Request.Context(), c.ClientIP())
+	err := h.userService.DeleteUser(ctx, userID)
 	if err != nil {
 		if err == services.ErrUserNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
@@ -92,13 +114,21 @@ func (h *UserHandler) DeleteAccount(c *gin.Context) {
 	})
 }
 
-// ListUsers handles user listing with pagination and search
+// ListUsers handles user listing with pagination and search.
+// By default it uses cursor pagination: pass the returned next_cursor as
+// "after" to fetch the following page. Supplying "page" switches to offset
+// pagination, which is kept for the admin UI.
 func (h *UserHandler) ListUsers(c *gin.Context) {
 	// Get query parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	page := pageNumber(c)
+	limit := pageLimit(c, 10, h.maxPageSize)
 	search := c.Query("search")
 
+	if _, offset := c.GetQuery("page"); !offset {
+		h.listUsersByCursor(c, c.Query("after"), limit, search)
+		return
+	}
+
 	users, total, err := h.userService.ListUsers(c.Request.Context(), page, limit, search)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
@@ -116,15 +146,38 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 	})
 }
 
+// listUsersByCursor responds with a keyset-paginated page of users
+func (h *UserHandler) listUsersByCursor(c *gin.Context, after string, limit int, search string) {
+	users, nextCursor, err := h.userService.ListUsersAfter(c.Request.Context(), after, limit, search)
+	if err != nil {
+		if err == services.ErrInvalidCursor {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		if err == services.ErrInvalidLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":       users,
+		"next_cursor": nextCursor,
+		"limit":       limit,
+	})
+}
+
 // RequestPasswordReset handles password reset requests
 func (h *UserHandler) RequestPasswordReset(c *gin.Context) {
 	var req PasswordResetRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	err := h.userService.RequestPasswordReset(c.Request.Context(), req.Email)
+	ctx := services.WithClientIP(c.Request.Context(), c.ClientIP())
+	err := h.userService.RequestPasswordReset(ctx, req.Email)
 	if err != nil {
 		// Always return success to prevent email enumeration
 		c.JSON(http.StatusOK, gin.H{
@@ -138,17 +191,109 @@ func (h *UserHandler) RequestPasswordReset(c *gin.Context) {
 	})
 }
 
+// GetNotificationPreferences returns which optional emails the user receives
+func (h *UserHandler) GetNotificationPreferences(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	prefs, err := h.userService.NotificationPreferences(c.Request.Context(), userID)
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": prefs,
+	})
+}
+
+// UpdateNotificationPreferences changes which optional emails the user receives
+func (h *UserHandler) UpdateNotificationPreferences(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+
+	var req NotificationPreferencesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	prefs, err := h.userService.UpdateNotificationPreferences(c.Request.Context(), userID, services.NotificationPreferenceUpdate{
+		GameInvites:    req.GameInvites,
+		GameSummaries:  req.GameSummaries,
+		SecurityAlerts: req.SecurityAlerts,
+	})
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if err == services.ErrUserConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "Profile was modified concurrently, please retry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": prefs,
+	})
+}
+
+// ConfirmEmailChange switches the user to the email address a profile
+// update asked for, once they've received the token sent there
+func (h *UserHandler) ConfirmEmailChange(c *gin.Context) {
+	userID := c.GetString("user_id") // From auth middleware
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req EmailChangeConfirmRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.userService.ConfirmEmailChange(c.Request.Context(), userID, req.Token)
+	if err != nil {
+		if err == services.ErrInvalidToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation token"})
+			return
+		}
+		if err == services.ErrEmailTaken {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already taken"})
+			return
+		}
+		if err == services.ErrUserConflict {
+			c.JSON(http.StatusConflict, gin.H{"error": "Profile was modified concurrently, please retry"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email changed successfully",
+		"user":    user,
+	})
+}
+
 // ConfirmPasswordReset handles password reset confirmation
 func (h *UserHandler) ConfirmPasswordReset(c *gin.Context) {
 	var req PasswordResetConfirmRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	err := h.userService.ConfirmPasswordReset(c.Request.Context(), req.Token, req.Password)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+		if err == services.ErrInvalidToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset token"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
 		return
 	}
 
@@ -3,50 +3,114 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"math/rand"
+	"net"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"chess-ws-go/internal/auth"
 	"chess-ws-go/internal/config"
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/middleware"
+	"chess-ws-go/internal/models"
 	"chess-ws-go/internal/repositories"
 	"chess-ws-go/internal/services"
 
 	"github.com/corentings/chess/v2"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-}
+// upgrader holds the upgrade settings shared by every connection; serve
+// adds the configured handshake timeout and origin check
+var upgrader = websocket.Upgrader{}
 
 // Types to manage player sessions and game state
 type Player struct {
-	Conn     *websocket.Conn
-	Color    chess.Color
-	Username string
-	UserID   string
+	Conn        *websocket.Conn
+	Color       chess.Color
+	Username    string
+	UserID      string
+	Rating      int    // Overall rating when the player joined matchmaking or a challenge (0 when unknown)
+	Provisional bool   // The rating is still being placed, so it says little about strength
+	Admin       bool   // Admins aren't subject to the concurrent game cap
+	Preference  string // Color asked for in matchmaking: ColorWhite, ColorBlack or ColorRandom
+}
+
+// challengeTimeout is how long a direct challenge stays open before it expires
+const challengeTimeout = 60 * time.Second
+
+// Challenge is a pending direct game invitation from one user to another
+type Challenge struct {
+	ID          string
+	Challenger  *Player // Conn is nil for challenges issued over HTTP
+	TargetID    string
+	TargetName  string
+	TimeControl services.TimeControl
+	Color       string // Challenger's color preference; WebSocket challengers always get white
+	Casual      bool
+	OpenSeats   bool
+	Private     bool // Only the players may view the game
+	timer       *time.Timer
+}
+
+// gameOptions returns the options the challenge's game is created with
+func (c *Challenge) gameOptions() services.GameOptions {
+	return services.GameOptions{Casual: c.Casual, OpenSeats: c.OpenSeats, Private: c.Private}
 }
 
 type GameSession struct {
 	White       *Player
 	Black       *Player
-	Game        *chess.Game
 	CurrentTurn chess.Color
+	premoves    map[chess.Color]string        // Move each player queued for their next turn
+	away        map[chess.Color]*absence      // Players who disconnected and haven't returned
+	graceUsed   map[chess.Color]time.Duration // Clock freeze each player has used up
+	flagTimer   *time.Timer                   // Ends the game when the side to move runs out of server time
+	idleTimer   *time.Timer                   // Warns about, then ends, a game nobody moves in
+	idleSeq     int                           // Identifies the current idle timer, so stale ones do nothing
+	cleanup     *time.Timer                   // Drops the session once the game has been over for a while
+	spectators  map[*websocket.Conn]bool      // Connections watching the game
+	rematch     *rematchOffer                 // Pending offer of another game, once this one is over
+	addTime     map[chess.Color]*rate.Limiter // How often each player may give the other time
+
+	outMu    sync.Mutex
+	outSeq   int                     // Sequence number of the newest message sent to the game
+	outboxes map[chess.Color]*outbox // Recent messages to each seat, replayed on reconnect
+}
+
+// absence tracks a player who disconnected from a game in progress
+type absence struct {
+	since time.Time
+	timer *time.Timer // Fires the abandonment forfeit
 }
 
 type WebSocketHandler struct {
-	sessions       map[string]*GameSession // gameID -> GameSession
-	connections    map[*websocket.Conn]bool
-	waitingPlayer  *Player // Player waiting for opponent
+	sessions       map[string]*GameSession             // gameID -> GameSession
+	connections    map[*websocket.Conn]bool            // Open connections
+	protocols      sync.Map                            // *websocket.Conn -> negotiated protocol version, read without h.mu
+	writers        sync.Map                            // *websocket.Conn -> *connWriter, read without h.mu
+	userConns      map[string]map[*websocket.Conn]bool // userID -> open connections
+	challenges     map[string]*Challenge               // challengeID -> pending challenge
+	waitingPlayer  *Player                             // Player waiting for opponent
+	waitTimer      *time.Timer                         // Gives up on finding the waiting player an opponent
+	spectatorCount int                                 // Spectators across all sessions
+	openConns      atomic.Int64                        // Connections admitted, including those still upgrading
 	mu             sync.Mutex
 	messageService *services.MessageService
 	gameService    *services.GameService
 	userRepo       repositories.UserRepository
 	config         *config.Config
+	observeMove    func(time.Duration)           // Receives move handling latencies; nil when metrics are off
+	countMessage   func(string)                  // Receives the type of each message handled; nil when metrics are off
+	countRejected  func(string)                  // Receives why a message was rejected; nil when metrics are off
+	notifier       *services.NotificationService // Emails users the challenges they receive; nil sends none
 }
 
 func NewWebSocketHandler(
@@ -55,14 +119,18 @@ func NewWebSocketHandler(
 	userRepo repositories.UserRepository,
 	config *config.Config,
 ) *WebSocketHandler {
-	return &WebSocketHandler{
+	h := &WebSocketHandler{
 		sessions:       make(map[string]*GameSession),
 		connections:    make(map[*websocket.Conn]bool),
+		userConns:      make(map[string]map[*websocket.Conn]bool),
+		challenges:     make(map[string]*Challenge),
 		messageService: messageService,
 		gameService:    gameService,
 		userRepo:       userRepo,
 		config:         config,
 	}
+	gameService.OnGameOver(h.announceForfeit)
+	return h
 }
 
 func (h *WebSocketHandler) UpgradeHandler(w http.ResponseWriter, r *http.Request) {
@@ -72,120 +140,656 @@ func (h *WebSocketHandler) UpgradeHandler(w http.ResponseWriter, r *http.Request
 
 	// Validate authentication
 	if userID == "" || username == "" {
-		log.Println("Authentication required for WebSocket connection")
+		logging.Warnf("Authentication required for WebSocket connection")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Set WebSocket protocol for token authentication if needed
+	h.serve(w, r, userID, username)
+}
+
+// SpectateHandler upgrades an unauthenticated connection that may only
+// watch public games. Everything else it sends is refused with an
+// AUTH_REQUIRED error.
+func (h *WebSocketHandler) SpectateHandler(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, "", guestName)
+}
+
+// checkOrigin accepts upgrades from the configured WebSocket origins, which
+// may differ from those allowed to call the REST API; with none configured
+// every browser origin is refused. Requests without an Origin
+// header come from native clients rather than browsers, which always send
+// one, so no other site can be making them on a user's behalf, and they're
+// let through whatever the allowlist.
+func (h *WebSocketHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || middleware.OriginAllowed(h.config.AllowedWSOrigins, origin)
+}
+
+// serverFullRetryAfter is how long clients refused for want of a connection
+// slot are told to wait before trying again
+const serverFullRetryAfter = 30 * time.Second
+
+// admit takes one of the server's connection slots, reporting false when
+// they're all in use
+func (h *WebSocketHandler) admit() bool {
+	limit := int64(h.config.MaxConnections)
+	for {
+		open := h.openConns.Load()
+		if limit > 0 && open >= limit {
+			return false
+		}
+		if h.openConns.CompareAndSwap(open, open+1) {
+			return true
+		}
+	}
+}
+
+// ConnectionCount returns how many WebSockets are open and how many may be,
+// zero meaning there's no limit
+func (h *WebSocketHandler) ConnectionCount() (int, int) {
+	return int(h.openConns.Load()), h.config.MaxConnections
+}
+
+// serve upgrades a connection and reads its messages until it closes. An
+// empty userID makes it an anonymous spectator. A full server refuses the
+// upgrade outright, leaving the games already being played unaffected.
+func (h *WebSocketHandler) serve(w http.ResponseWriter, r *http.Request, userID string, username string) {
+	// Agree on a message protocol version before taking a slot, refusing
+	// clients that only offer subprotocols the server doesn't speak
+	offered := websocket.Subprotocols(r)
+	subprotocol, version, ok := selectSubprotocol(offered)
+	if !ok {
+		logging.Infof("Refusing WebSocket for %s: unsupported subprotocols %v", username, offered)
+		rejectProtocols(w, offered, version)
+		return
+	}
+
+	if !h.admit() {
+		logging.Debugf("Refusing WebSocket for %s: server full at %d connections", username, h.config.MaxConnections)
+		w.Header().Set("Retry-After", strconv.Itoa(int(serverFullRetryAfter.Seconds())))
+		http.Error(w, "Server is full, try again later", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.openConns.Add(-1)
+
+	// Echo the accepted subprotocol, as browsers require
 	upgradeHeaders := http.Header{}
+	if subprotocol != "" {
+		upgradeHeaders.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
 
 	// Upgrade the connection
-	conn, err := upgrader.Upgrade(w, r, upgradeHeaders)
+	wsUpgrader := upgrader
+	wsUpgrader.HandshakeTimeout = h.config.WSHandshakeTimeout
+	wsUpgrader.CheckOrigin = h.checkOrigin
+	conn, err := wsUpgrader.Upgrade(w, r, upgradeHeaders)
 	if err != nil {
-		log.Println("Upgrade error:", err)
+		logging.Warnf("Upgrade error: %v", err)
 		return
 	}
 	defer conn.Close()
+	writer := h.startWriter(conn)
+	defer writer.close()
+
+	// Reap connections that never send a request
+	if h.config.WSIntentTimeout > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(h.config.WSIntentTimeout))
+	}
+
+	logging.Infof("User %s (ID: %s) connected via WebSocket", username, userID)
 
-	log.Printf("User %s (ID: %s) connected via WebSocket", username, userID)
+	h.registerConnection(conn, userID, version)
+	defer func() {
+		h.unregisterConnection(conn, userID)
+		logging.Infof("User %s (ID: %s) disconnected", username, userID)
+	}()
+
+	// Run the reader with authenticated user info until the connection closes.
+	// The request context stays live for as long as the handler runs.
+	h.authenticatedReader(r.Context(), conn, userID, username)
+}
+
+// ObserveMoves registers a callback that receives how long each move took
+// to handle, including time spent waiting for the handler lock. It must be
+// called before the handler starts serving connections.
+func (h *WebSocketHandler) ObserveMoves(observe func(time.Duration)) {
+	h.observeMove = observe
+}
+
+// CountMessages registers callbacks that receive the type of every message
+// handled and the reason for every message rejected. It must be called
+// before the handler starts serving connections.
+func (h *WebSocketHandler) CountMessages(count func(messageType string), reject func(reason string)) {
+	h.countMessage = count
+	h.countRejected = reject
+}
 
+// registerConnection records an open connection for the given user
+func (h *WebSocketHandler) registerConnection(conn *websocket.Conn, userID string, version int) {
 	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	h.connections[conn] = true
-	h.mu.Unlock()
+	h.protocols.Store(conn, version)
+	h.messageService.AddConnection(conn)
+	if userID == "" {
+		return // Anonymous spectators aren't anyone to look up
+	}
+	if h.userConns[userID] == nil {
+		h.userConns[userID] = make(map[*websocket.Conn]bool)
+	}
+	h.userConns[userID][conn] = true
+}
 
-	defer func() {
-		h.mu.Lock()
-		delete(h.connections, conn)
+// unregisterConnection forgets a closed connection and drops any challenges it issued
+func (h *WebSocketHandler) unregisterConnection(conn *websocket.Conn, userID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.connections, conn)
+	h.protocols.Delete(conn)
+	h.messageService.RemoveConnection(conn)
+	if conns, ok := h.userConns[userID]; ok {
+		delete(conns, conn)
+		if len(conns) == 0 {
+			delete(h.userConns, userID)
+		}
+	}
+
+	if h.waitingPlayer != nil && h.waitingPlayer.Conn == conn {
+		h.dequeueWaiting()
+	}
+
+	for id, challenge := range h.challenges {
+		if challenge.Challenger.Conn != nil && challenge.Challenger.Conn == conn {
+			challenge.timer.Stop()
+			delete(h.challenges, id)
+		}
+	}
+
+	for gameID, session := range h.sessions {
+		h.removeSpectator(gameID, session, conn)
+		if h.gameOver(gameID) {
+			continue
+		}
+		if session.White.Conn == conn {
+			h.gameService.RecordEvent(gameID, services.EventDisconnect, chess.White, "")
+			h.startAbsence(gameID, session, chess.White)
+		} else if session.Black.Conn == conn {
+			h.gameService.RecordEvent(gameID, services.EventDisconnect, chess.Black, "")
+			h.startAbsence(gameID, session, chess.Black)
+		}
+	}
+}
+
+// startAbsence freezes a disconnected player's clock for what's left of their
+// grace and arms the abandonment forfeit. The freeze is capped per game so
+// disconnecting can't be used to stall the opponent. Callers must hold h.mu.
+func (h *WebSocketHandler) startAbsence(gameID string, session *GameSession, color chess.Color) {
+	// Correspondence players come and go; only their move deadline counts
+	if h.correspondence(gameID) {
+		return
+	}
+	if session.away == nil {
+		session.away = make(map[chess.Color]*absence)
+	}
+	if session.away[color] != nil {
+		return
+	}
+
+	session.away[color] = &absence{
+		since: time.Now(),
+		timer: time.AfterFunc(h.config.AbandonTimeout, func() {
+			h.abandonGame(gameID, color)
+		}),
+	}
+
+	h.gameService.PauseClock(gameID)
+	stopFlag(session)
+
+	grace := h.remainingGrace(session, color)
+	logging.Infof("Paused %s clock in game %s for up to %s after disconnect", color.Name(), gameID, grace)
+
+	opponent := session.Black
+	if color == chess.Black {
+		opponent = session.White
+	}
+	h.sendOpponentDisconnected(session, opponent, color, session.away[color])
+	h.sendToPlayer(session, opponent, struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Color     string  `json:"color"`
+			Grace     float64 `json:"grace"`     // Seconds the clock stays frozen
+			AbandonIn float64 `json:"abandonIn"` // Seconds until the game is forfeited
+		} `json:"payload"`
+	}{
+		Type: "clockPaused",
+		Payload: struct {
+			Color     string  `json:"color"`
+			Grace     float64 `json:"grace"`
+			AbandonIn float64 `json:"abandonIn"`
+		}{
+			Color:     color.String(),
+			Grace:     grace.Seconds(),
+			AbandonIn: h.config.AbandonTimeout.Seconds(),
+		},
+	})
+}
+
+// endAbsence resumes the clock of a player who reconnected. Time away beyond
+// their remaining grace is charged to their clock if it was their turn.
+// Callers must hold h.mu.
+func (h *WebSocketHandler) endAbsence(ctx context.Context, gameID string, session *GameSession, color chess.Color) {
+	a := session.away[color]
+	if a == nil {
+		return
+	}
+	a.timer.Stop()
+	delete(session.away, color)
+
+	away := time.Since(a.since)
+	frozen := min(away, h.remainingGrace(session, color))
+	if session.graceUsed == nil {
+		session.graceUsed = make(map[chess.Color]time.Duration)
+	}
+	session.graceUsed[color] += frozen
+
+	charged := time.Duration(0)
+	flagged := false
+	if session.CurrentTurn == color && away > frozen {
+		charged = away - frozen
+		if state, err := h.gameService.GetGameState(gameID); err == nil {
+			timeLeft := state.TimeControl.WhiteTimeLeft
+			if color == chess.Black {
+				timeLeft = state.TimeControl.BlackTimeLeft
+			}
+			timeLeft -= charged.Seconds()
+			_ = h.gameService.UpdateTime(gameID, color, timeLeft)
+			flagged = timeLeft <= 0
+		}
+	}
+
+	logging.Infof("Resumed %s clock in game %s after %s away (%s frozen)", color.Name(), gameID, away.Round(time.Millisecond), frozen.Round(time.Millisecond))
+
+	opponent := session.Black
+	if color == chess.Black {
+		opponent = session.White
+	}
+	h.sendToPlayer(session, opponent, struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Color string  `json:"color"`
+			Away  float64 `json:"away"` // Seconds the player was gone
+		} `json:"payload"`
+	}{
+		Type: "opponentReconnected",
+		Payload: struct {
+			Color string  `json:"color"`
+			Away  float64 `json:"away"`
+		}{Color: color.String(), Away: away.Seconds()},
+	})
+
+	resumeMsg := struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Color   string  `json:"color"`
+			Frozen  float64 `json:"frozen"`  // Seconds the clock was frozen
+			Charged float64 `json:"charged"` // Seconds taken off the clock
+		} `json:"payload"`
+	}{
+		Type: "clockResumed",
+		Payload: struct {
+			Color   string  `json:"color"`
+			Frozen  float64 `json:"frozen"`
+			Charged float64 `json:"charged"`
+		}{
+			Color:   color.String(),
+			Frozen:  frozen.Seconds(),
+			Charged: charged.Seconds(),
+		},
+	}
+	h.broadcastToPlayers(session, resumeMsg)
+
+	// The clock only runs again once nobody is away
+	if len(session.away) == 0 {
+		h.gameService.ResumeClock(gameID)
+		h.armFlag(session, gameID)
+	}
+
+	if flagged {
+		h.handleTimeout(ctx, session, gameID, color)
+	}
+}
+
+// sendOpponentDisconnected tells a player their opponent is gone and how
+// long they have left to come back before forfeiting
+func (h *WebSocketHandler) sendOpponentDisconnected(session *GameSession, to *Player, color chess.Color, a *absence) {
+	reconnectIn := max(h.config.AbandonTimeout-time.Since(a.since), 0)
+	h.sendToPlayer(session, to, struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Color       string  `json:"color"`
+			ReconnectIn float64 `json:"reconnectIn"` // Seconds until the game is forfeited
+		} `json:"payload"`
+	}{
+		Type: "opponentDisconnected",
+		Payload: struct {
+			Color       string  `json:"color"`
+			ReconnectIn float64 `json:"reconnectIn"`
+		}{Color: color.String(), ReconnectIn: reconnectIn.Seconds()},
+	})
+}
+
+// remainingGrace returns how much clock freeze a player has left in a game
+func (h *WebSocketHandler) remainingGrace(session *GameSession, color chess.Color) time.Duration {
+	return max(h.config.DisconnectGrace-session.graceUsed[color], 0)
+}
+
+// abandonGame forfeits the game of a player who didn't reconnect in time
+func (h *WebSocketHandler) abandonGame(gameID string, color chess.Color) {
+	h.mu.Lock()
+	session, exists := h.sessions[gameID]
+	if !exists || session.away[color] == nil {
 		h.mu.Unlock()
-		log.Printf("User %s (ID: %s) disconnected", username, userID)
-	}()
+		return
+	}
+	delete(session.away, color)
+	h.mu.Unlock()
+
+	if _, err := h.gameService.AbandonGame(gameID, color, context.Background(), h.getUserRepository()); err != nil {
+		return
+	}
+
+	logging.Infof("Game %s forfeited by %s after abandonment", gameID, color.Name())
+	h.mu.Lock()
+	h.announceGameOver(session, gameID)
+	h.mu.Unlock()
+}
+
+// isOnline reports whether a user has at least one open connection.
+// Callers must hold h.mu.
+func (h *WebSocketHandler) isOnline(userID string) bool {
+	return len(h.userConns[userID]) > 0
+}
+
+// IsOnline reports whether a user currently has an open WebSocket connection
+func (h *WebSocketHandler) IsOnline(userID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.isOnline(userID)
+}
+
+// IsInGame reports whether a user is seated in a game that hasn't finished
+func (h *WebSocketHandler) IsInGame(userID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	// Start the reader goroutine with authenticated user info
-	go h.authenticatedReader(conn, userID, username)
+	for gameID, session := range h.sessions {
+		if session.White.UserID != userID && session.Black.UserID != userID {
+			continue
+		}
+		if !h.gameOver(gameID) {
+			return true
+		}
+	}
+	return false
 }
 
 // authenticatedReader is a new method that handles messages with user authentication
-func (h *WebSocketHandler) authenticatedReader(conn *websocket.Conn, userID string, username string) {
+func (h *WebSocketHandler) authenticatedReader(ctx context.Context, conn *websocket.Conn, userID string, username string) {
 	defer conn.Close()
+
+	// Connections must say what they're here for before the intent deadline
+	// set on connect; pings alone don't count
+	active := false
+	unknownTypeReplies := rate.NewLimiter(unknownTypeReplyRate, unknownTypeReplyBurst)
+
 	for {
 		messageType, p, err := conn.ReadMessage()
 		if err != nil {
-			log.Println("Read error:", err)
+			var netErr net.Error
+			if !active && errors.As(err, &netErr) && netErr.Timeout() {
+				logging.Infof("Closing idle WebSocket for user %s: no request within %s", username, h.config.WSIntentTimeout)
+			} else {
+				logging.Debugf("Read error: %v", err)
+			}
+			if reason, ok := closeReasonFor(err); ok {
+				sendClose(conn, reason)
+			}
 			break
 		}
 
 		if messageType != websocket.TextMessage {
+			h.countRejection(rejectNotText)
 			continue
 		}
 
-		var message struct {
-			Type    string `json:"type"`
-			Payload struct {
-				Move     string  `json:"move"`
-				GameID   string  `json:"gameId"`
-				Accept   bool    `json:"accept"`
-				TimeLeft float64 `json:"timeLeft"`
-				Message  string  `json:"message"`
-			} `json:"payload"`
+		message, err := decodeMessage(p, h.config.WSStrictMessages)
+		if err != nil {
+			logging.Debugf("Rejected message from user %s: %v", username, err)
+			h.countRejection(rejectionReason(err))
+			var unknown *unknownTypeError
+			if !errors.As(err, &unknown) {
+				h.sendInvalidMessage(conn, err)
+			} else if unknownTypeReplies.Allow() {
+				h.sendUnknownType(conn, unknown.Type)
+			}
+			continue
+		}
+		if h.countMessage != nil {
+			h.countMessage(message.Type)
 		}
 
-		if err := json.Unmarshal(p, &message); err != nil {
-			log.Println("Error unmarshaling message:", err)
+		if userID == "" && !guestMessages[message.Type] {
+			h.sendErrorCode(conn, "AUTH_REQUIRED", "Sign in to do more than watch games")
 			continue
 		}
 
+		if !active && message.Type != "ping" && message.Type != "hello" {
+			active = true
+			_ = conn.SetReadDeadline(time.Time{})
+		}
+
 		// Use the authenticated username instead of relying on the message
 		switch message.Type {
 		case "join":
-			h.handleJoinGame(conn, username, userID)
+			h.handleJoinGame(ctx, conn, username, userID, message.Payload.Color, message.Payload.GameID)
 		case "move":
-			err := h.handleMove(conn, message.Payload.Move, message.Payload.GameID)
+			err := h.handleMove(ctx, conn, message.Payload.Move, message.Payload.GameID)
+			if err != nil {
+				h.sendGameError(conn, err)
+			}
+		case "get_state":
+			h.handleGetState(conn, userID, message.Payload.GameID)
+		case "list_games":
+			h.handleListGames(ctx, conn, userID, message.Payload.Category)
+		case "spectate":
+			h.handleSpectate(conn, userID, message.Payload.GameID)
+		case "unspectate":
+			h.handleUnspectate(conn, message.Payload.GameID)
+		case "take_seat":
+			h.handleTakeSeat(conn, userID, username, message.Payload.GameID, message.Payload.Color)
+		case "get_board_ascii":
+			h.handleGetBoard(conn, userID, message.Payload.GameID)
+		case "premove":
+			err := h.handlePremove(ctx, conn, message.Payload.Move, message.Payload.GameID)
 			if err != nil {
-				h.sendMessage(conn, struct {
-					Type    string `json:"type"`
-					Payload string `json:"payload"`
-				}{Type: "error", Payload: err.Error()})
+				h.sendGameError(conn, err)
 			}
 		case "resign":
-			h.handleResign(conn, message.Payload.GameID)
+			h.handleResign(ctx, conn, message.Payload.GameID)
 		case "draw_offer":
 			h.handleDrawOffer(conn, message.Payload.GameID)
 		case "draw_response":
-			h.handleDrawResponse(conn, message.Payload.GameID, message.Payload.Accept)
+			h.handleDrawResponse(ctx, conn, message.Payload.GameID, message.Payload.Accept)
 		case "time_update":
-			h.handleTimeUpdate(conn, message.Payload.GameID, message.Payload.TimeLeft)
+			h.handleTimeUpdate(ctx, conn, message.Payload.GameID, message.Payload.TimeLeft)
 		case "chat":
 			h.handleChat(conn, message.Payload.GameID, message.Payload.Message, username)
 		case "reconnect":
-			h.handleReconnect(conn, message.Payload.GameID, username, userID)
+			h.handleReconnect(ctx, conn, message.Payload.GameID, username, userID, message.Payload.LastSeq)
+		case "challenge":
+			h.handleChallenge(ctx, conn, userID, username, message.Payload.Target, message.Payload.TimeControl, message.Payload.Private)
+		case "challenge_response":
+			h.handleChallengeResponse(ctx, conn, userID, username, message.Payload.ChallengeID, message.Payload.Accept)
+		case "add_time":
+			h.handleAddTime(conn, message.Payload.GameID)
+		case "rematch_offer":
+			h.handleRematchOffer(ctx, conn, userID, message.Payload.GameID, message.Payload.Rated)
+		case "rematch_response":
+			h.handleRematchResponse(ctx, conn, userID, message.Payload.GameID, message.Payload.Accept, message.Payload.Rated)
 		case "ping":
 			h.handlePing(conn)
-		default:
-			log.Println("Unknown message type:", message.Type)
+		case "hello":
+			h.handleHello(conn, message.Payload.Version)
 		}
 	}
 }
 
-func generateGameID() string {
-	return uuid.New().String()
+func (h *WebSocketHandler) countRejection(reason string) {
+	if h.countRejected != nil {
+		h.countRejected(reason)
+	}
+}
+
+// sendInvalidMessage tells the client why its message was rejected
+func (h *WebSocketHandler) sendInvalidMessage(conn *websocket.Conn, err error) {
+	var field string
+	var invalid *invalidMessageError
+	if errors.As(err, &invalid) {
+		field = invalid.Field
+	}
+	h.sendError(conn, "INVALID_MESSAGE", field, err.Error())
+}
+
+// sendUnknownType tells the client the server doesn't understand a message
+// type, echoing the type it received
+func (h *WebSocketHandler) sendUnknownType(conn *websocket.Conn, messageType string) {
+	text := (&unknownTypeError{Type: messageType}).Error()
+	if h.protocolFor(conn) < ProtocolV2 {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: text})
+		return
+	}
+
+	h.sendMessage(conn, struct {
+		Type        string `json:"type"`
+		Code        string `json:"code"`
+		Field       string `json:"field"`
+		MessageType string `json:"messageType"`
+		Payload     string `json:"payload"`
+	}{Type: "error", Code: "UNKNOWN_MESSAGE_TYPE", Field: "type", MessageType: messageType, Payload: text})
+}
+
+// sendErrorCode sends an error carrying a machine-readable code
+func (h *WebSocketHandler) sendErrorCode(conn *websocket.Conn, code string, message string) {
+	h.sendError(conn, code, "", message)
+}
+
+// sendGameError reports a failed game action. Actions on a game that has
+// already ended get their own code, as a client may lose the race with a
+// mate, a flag or the opponent's resignation through no fault of its own.
+func (h *WebSocketHandler) sendGameError(conn *websocket.Conn, err error) {
+	if errors.Is(err, services.ErrGameOver) {
+		h.sendErrorCode(conn, "GAME_ALREADY_OVER", err.Error())
+		return
+	}
+	h.sendMessage(conn, struct {
+		Type    string `json:"type"`
+		Payload string `json:"payload"`
+	}{Type: "error", Payload: err.Error()})
+}
+
+// sendError sends an error with a code and, optionally, the offending field.
+// Codes only exist from ProtocolV2 on; older clients get the text alone.
+func (h *WebSocketHandler) sendError(conn *websocket.Conn, code string, field string, message string) {
+	if h.protocolFor(conn) < ProtocolV2 {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: message})
+		return
+	}
+
+	h.sendMessage(conn, struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Field   string `json:"field,omitempty"`
+		Payload string `json:"payload"`
+	}{Type: "error", Code: code, Field: field, Payload: message})
+}
+
+// sendToPlayer sends a message to one player of a game. Players who aren't
+// connected get it when they reconnect.
+func (h *WebSocketHandler) sendToPlayer(session *GameSession, player *Player, message interface{}) {
+	if player == nil {
+		return
+	}
+	var conns []*websocket.Conn
+	if player.Conn != nil {
+		conns = append(conns, player.Conn)
+	}
+	h.deliver(session, []*Player{player}, conns, message)
+}
+
+// broadcastToPlayers sends a message to both players of a game
+func (h *WebSocketHandler) broadcastToPlayers(session *GameSession, message interface{}) {
+	h.deliver(session, []*Player{session.White, session.Black}, playerConns(session), message)
+}
+
+// connsOf returns a user's open connections. Callers must hold h.mu.
+func (h *WebSocketHandler) connsOf(userID string) []*websocket.Conn {
+	conns := make([]*websocket.Conn, 0, len(h.userConns[userID]))
+	for conn := range h.userConns[userID] {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// playerConns returns the connections of a game's connected players
+func playerConns(session *GameSession) []*websocket.Conn {
+	conns := make([]*websocket.Conn, 0, 2)
+	for _, player := range []*Player{session.White, session.Black} {
+		if player != nil && player.Conn != nil {
+			conns = append(conns, player.Conn)
+		}
+	}
+	return conns
 }
 
 func (h *WebSocketHandler) sendMessage(conn *websocket.Conn, message interface{}) {
-	w, err := conn.NextWriter(websocket.TextMessage)
+	data, err := json.Marshal(message)
 	if err != nil {
-		log.Println("Error getting next writer:", err)
+		logging.Warnf("Error encoding message: %v", err)
+		return
+	}
+	h.writeMessage(conn, data)
+}
+
+// broadcast sends the same message to several connections. It is encoded
+// once and the resulting bytes are queued for each connection in turn.
+func (h *WebSocketHandler) broadcast(conns []*websocket.Conn, message interface{}) {
+	if len(conns) == 0 {
 		return
 	}
-	defer w.Close()
 
-	err = json.NewEncoder(w).Encode(message)
+	data, err := json.Marshal(message)
 	if err != nil {
-		log.Println("Error encoding message:", err)
+		logging.Warnf("Error encoding message: %v", err)
+		return
 	}
+	h.writeAll(conns, data)
 }
 
-func (h *WebSocketHandler) handleMove(conn *websocket.Conn, moveStr string, gameID string) error {
+func (h *WebSocketHandler) handleMove(ctx context.Context, conn *websocket.Conn, moveStr string, gameID string) error {
+	if h.observeMove != nil {
+		start := time.Now()
+		defer func() { h.observeMove(time.Since(start)) }()
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -204,150 +808,585 @@ func (h *WebSocketHandler) handleMove(conn *websocket.Conn, moveStr string, game
 		return fmt.Errorf("player not in this game")
 	}
 
+	if h.gameOver(gameID) {
+		return services.ErrGameOver
+	}
+
 	// Check if it's player's turn
 	if playerColor != session.CurrentTurn {
 		return fmt.Errorf("not your turn")
 	}
 
-	// Create context for database operations
-	ctx := context.Background()
-
-	// Get user repository from the application context
-	userRepo := h.getUserRepository()
+	// A normal move replaces any queued premove
+	delete(session.premoves, playerColor)
 
-	// Make the move using the game service
-	err := h.gameService.MakeMove(gameID, moveStr, ctx, userRepo)
-	if err != nil {
-		return fmt.Errorf("invalid move: %w", err)
+	if err := h.applyMove(ctx, session, gameID, moveStr); err != nil {
+		return err
 	}
 
-	// Update session state
-	session.CurrentTurn = chess.Color(1 - int(session.CurrentTurn))
+	h.playPremove(ctx, session, gameID)
+	return nil
+}
 
-	// Broadcast the move to both players
-	moveMsg := struct {
-		Type    string `json:"type"`
-		Payload struct {
-			Move     string `json:"move"`
-			Position string `json:"position"`
-			Turn     string `json:"turn"`
-		} `json:"payload"`
-	}{
-		Type: "move",
-		Payload: struct {
-			Move     string `json:"move"`
-			Position string `json:"position"`
-			Turn     string `json:"turn"`
-		}{
-			Move:     moveStr,
-			Position: session.Game.Position().String(),
-			Turn:     session.CurrentTurn.String(),
-		},
+// handlePremove queues a move to be played as soon as it is the player's
+// turn. An empty move clears the queued premove. If it's already the
+// player's turn, the move is played straight away.
+func (h *WebSocketHandler) handlePremove(ctx context.Context, conn *websocket.Conn, moveStr string, gameID string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[gameID]
+	if !exists {
+		return fmt.Errorf("game session not found")
 	}
 
-	h.sendMessage(session.White.Conn, moveMsg)
-	h.sendMessage(session.Black.Conn, moveMsg)
+	var playerColor chess.Color
+	if conn == session.White.Conn {
+		playerColor = chess.White
+	} else if conn == session.Black.Conn {
+		playerColor = chess.Black
+	} else {
+		return fmt.Errorf("player not in this game")
+	}
 
-	// Check for game over
-	if session.Game.Outcome() != chess.NoOutcome {
-		h.handleGameOver(session)
+	if h.gameOver(gameID) {
+		return services.ErrGameOver
 	}
 
-	return nil
-}
+	if moveStr == "" {
+		delete(session.premoves, playerColor)
+		return nil
+	}
 
-func (h *WebSocketHandler) handleGameOver(session *GameSession) {
-	// Get game outcome and method
-	outcome := session.Game.Outcome()
-	method := session.Game.Method()
+	// The opponent's move may have arrived first; play it as a normal move
+	if playerColor == session.CurrentTurn {
+		if err := h.applyMove(ctx, session, gameID, moveStr); err != nil {
+			return err
+		}
+		h.playPremove(ctx, session, gameID)
+		return nil
+	}
 
-	// Prepare game over message
-	gameOverMsg := struct {
+	if session.premoves == nil {
+		session.premoves = make(map[chess.Color]string)
+	}
+	session.premoves[playerColor] = moveStr
+
+	h.sendMessage(conn, struct {
+		Type    string `json:"type"`
+		Payload string `json:"payload"`
+	}{Type: "premoveSet", Payload: moveStr})
+
+	return nil
+}
+
+// playPremove plays the premove queued by the player whose turn it now is.
+// An illegal premove is dropped silently. Caller must hold h.mu.
+func (h *WebSocketHandler) playPremove(ctx context.Context, session *GameSession, gameID string) {
+	moveStr, queued := session.premoves[session.CurrentTurn]
+	if !queued {
+		return
+	}
+	delete(session.premoves, session.CurrentTurn)
+
+	if err := h.applyMove(ctx, session, gameID, moveStr); err != nil {
+		logging.Debugf("Dropped premove %s in game %s: %v", moveStr, gameID, err)
+	}
+}
+
+// applyMove makes a move for the side to play, broadcasts it and handles the
+// end of the game. Caller must hold h.mu.
+func (h *WebSocketHandler) applyMove(ctx context.Context, session *GameSession, gameID string, moveStr string) error {
+	// Get user repository from the application context
+	userRepo := h.getUserRepository()
+
+	// Make the move using the game service
+	san, err := h.gameService.MakeMove(gameID, moveStr, ctx, userRepo)
+	if errors.Is(err, services.ErrClockExpired) {
+		h.handleTimeout(ctx, session, gameID, session.CurrentTurn)
+		return fmt.Errorf("your time ran out")
+	}
+	if err != nil {
+		return fmt.Errorf("invalid move: %w", err)
+	}
+
+	// Update session state
+	mover := session.CurrentTurn
+	session.CurrentTurn = session.CurrentTurn.Other()
+
+	// Broadcast the move to both players, as the game recorded it rather
+	// than as it was sent, so everyone sees e.g. e8=N for an underpromotion
+	game, err := h.gameService.GetGame(gameID)
+	if err != nil {
+		return err
+	}
+	counters, _ := h.gameService.DrawCounters(gameID)
+	moveMsg := struct {
 		Type    string `json:"type"`
 		Payload struct {
-			Outcome string `json:"outcome"`
-			Method  string `json:"method"`
-			Winner  string `json:"winner"`
+			Move      string `json:"move"`
+			Promotion string `json:"promotion,omitempty"` // Piece a pawn was promoted to: q, r, b or n
+			Position  string `json:"position"`
+			Turn      string `json:"turn"`
+			services.DrawCounters
 		} `json:"payload"`
 	}{
-		Type: "gameOver",
+		Type: "move",
 		Payload: struct {
-			Outcome string `json:"outcome"`
-			Method  string `json:"method"`
-			Winner  string `json:"winner"`
+			Move      string `json:"move"`
+			Promotion string `json:"promotion,omitempty"` // Piece a pawn was promoted to: q, r, b or n
+			Position  string `json:"position"`
+			Turn      string `json:"turn"`
+			services.DrawCounters
 		}{
-			Outcome: outcome.String(),
-			Method:  method.String(),
-			Winner:  determineWinner(outcome),
+			Move:         san,
+			Promotion:    game.Promotion(),
+			Position:     game.FEN,
+			Turn:         session.CurrentTurn.String(),
+			DrawCounters: counters,
 		},
 	}
 
-	// Send game over message to both players
-	if session.White != nil && session.White.Conn != nil {
-		h.sendMessage(session.White.Conn, gameOverMsg)
+	h.broadcastToGame(session, moveMsg)
+
+	// Check for game over, including automatic draws (stalemate, insufficient material)
+	if isOver, _, _, _ := h.gameService.IsGameOver(gameID); isOver {
+		session.premoves = nil
+		h.announceGameOver(session, gameID)
+	} else {
+		if h.serverClock(gameID) {
+			h.broadcastTimeLeft(session, gameID, mover)
+			h.armFlag(session, gameID)
+		}
+		h.armIdleTimer(session, gameID)
+	}
+
+	return nil
+}
+
+// announceGameOver tells everyone in a game how it ended, as the game
+// service recorded it. That may differ from what the chess library says:
+// a game left early is aborted, with no winner. Callers must hold h.mu.
+func (h *WebSocketHandler) announceGameOver(session *GameSession, gameID string) {
+	outcome, method, err := h.gameService.Result(gameID)
+	if err != nil {
+		return
+	}
+
+	game, err := h.gameService.GetGame(gameID)
+	if err != nil {
+		return
 	}
-	if session.Black != nil && session.Black.Conn != nil {
-		h.sendMessage(session.Black.Conn, gameOverMsg)
+
+	winner := determineWinner(game.Outcome)
+	if outcome == services.OutcomeNone {
+		winner = ""
 	}
+	h.broadcastGameOver(session, game, outcome, method, winner)
+	h.releaseSpectators(gameID, session)
+	h.armCleanup(session, gameID)
 }
 
-func (h *WebSocketHandler) handleJoinGame(conn *websocket.Conn, username string, userID string) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+// handleJoinGame queues a player for matchmaking, or with a gameID, seats
+// them in that game. A player already seated there takes their seat back as
+// if reconnecting rather than being queued; anyone else finds it full. A
+// player who walked out of a game in progress is sent back to it rather than
+// queued, as their opponent is waiting on them; one still at the board may
+// queue for another game, up to the concurrent game cap.
+func (h *WebSocketHandler) handleJoinGame(ctx context.Context, conn *websocket.Conn, username string, userID string, preference string, gameID string) {
+	if gameID == "" {
+		h.mu.Lock()
+		gameID = h.gameAwayFrom(userID)
+		h.mu.Unlock()
+	}
+	if gameID != "" {
+		h.handleReconnect(ctx, conn, gameID, username, userID, nil)
+		return
+	}
+
+	switch preference {
+	case "":
+		preference = ColorRandom
+	case ColorWhite, ColorBlack, ColorRandom:
+	default:
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Color must be white, black or random"})
+		return
+	}
 
 	newPlayer := &Player{
-		Conn:     conn,
-		Username: username,
-		UserID:   userID,
+		Conn:       conn,
+		Username:   username,
+		UserID:     userID,
+		Admin:      isAdmin(ctx),
+		Preference: preference,
+	}
+
+	if h.atGameLimit(newPlayer) {
+		h.sendGameLimitError(conn)
+		return
 	}
 
+	// Look the rating up before locking; it's shown at game start and
+	// decides time odds
+	newPlayer.Rating, newPlayer.Provisional = h.standing(ctx, userID)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	if h.waitingPlayer == nil {
 		// First player joins and waits
-		h.waitingPlayer = newPlayer
-		h.waitingPlayer.Color = chess.White
-		h.sendMessage(conn, struct {
-			Type    string `json:"type"`
-			Payload string `json:"payload"`
-		}{
-			Type:    "waiting",
-			Payload: "Waiting for opponent...",
-		})
+		h.handleWaiting(newPlayer)
+	} else if h.waitingPlayer.UserID == userID {
+		// Already queued, maybe from another connection; the newest request
+		// replaces the old one so nobody is matched against themselves
+		h.dequeueWaiting()
+		h.handleWaiting(newPlayer)
+	} else if h.atGameLimit(h.waitingPlayer) {
+		// The waiting player started other games meanwhile; take their place
+		h.sendGameLimitError(h.waitingPlayer.Conn)
+		h.dequeueWaiting()
+		h.handleWaiting(newPlayer)
 	} else {
 		// Second player joins, start the game
-		gameID := generateGameID()
-		newPlayer.Color = chess.Black
+		white, black := seatPlayers(h.waitingPlayer, newPlayer)
+		tc := services.DefaultTimeControl
+		if !white.Provisional && !black.Provisional {
+			// A rating still being placed is too unreliable to handicap anyone by
+			tc = services.WithRatingOdds(tc, white.Rating, black.Rating, h.config.TimeOddsRatingGap)
+		}
+		gameID := h.startGame(white, black, tc, services.GameOptions{})
+		logging.Infof("Seated %s as white (asked for %s) and %s as black (asked for %s) in game %s",
+			white.Username, white.Preference, black.Username, black.Preference, gameID)
+		h.dequeueWaiting()
+	}
+}
 
-		session := &GameSession{
-			White:       h.waitingPlayer,
-			Black:       newPlayer,
-			Game:        chess.NewGame(),
-			CurrentTurn: chess.White,
+// gameAwayFrom returns a game in progress a user disconnected from and hasn't
+// returned to, or "" if there's none. Callers must hold h.mu.
+func (h *WebSocketHandler) gameAwayFrom(userID string) string {
+	for gameID, session := range h.sessions {
+		for _, player := range []*Player{session.White, session.Black} {
+			if player.UserID == userID && session.away[player.Color] != nil && !h.gameOver(gameID) {
+				return gameID
+			}
 		}
+	}
+	return ""
+}
+
+// dequeueWaiting takes the waiting player out of matchmaking. Callers must hold h.mu.
+func (h *WebSocketHandler) dequeueWaiting() {
+	if h.waitTimer != nil {
+		h.waitTimer.Stop()
+		h.waitTimer = nil
+	}
+	h.waitingPlayer = nil
+}
+
+// expireWaiting dequeues a player nobody was matched with in time
+func (h *WebSocketHandler) expireWaiting(player *Player) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// The player was matched or left just as the timer fired
+	if h.waitingPlayer != player {
+		return
+	}
+	h.dequeueWaiting()
+
+	logging.Infof("No opponent found for %s within %s", player.Username, h.config.WaitingTimeout)
+	h.sendMessage(player.Conn, struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Waited float64 `json:"waited"` // Seconds spent waiting
+		} `json:"payload"`
+	}{
+		Type: "noOpponentFound",
+		Payload: struct {
+			Waited float64 `json:"waited"`
+		}{Waited: h.config.WaitingTimeout.Seconds()},
+	})
+}
+
+// seatPlayers decides who of two matched players gets white. A preference
+// is honored unless both players asked for the same color, in which case,
+// like when neither cares, the colors are drawn at random.
+func seatPlayers(a, b *Player) (white *Player, black *Player) {
+	switch {
+	case a.Preference == ColorWhite && b.Preference != ColorWhite,
+		b.Preference == ColorBlack && a.Preference != ColorBlack:
+		return a, b
+	case b.Preference == ColorWhite && a.Preference != ColorWhite,
+		a.Preference == ColorBlack && b.Preference != ColorBlack:
+		return b, a
+	}
+	if rand.Intn(2) == 0 {
+		return a, b
+	}
+	return b, a
+}
+
+// handleWaiting queues a player for the next opponent. Callers must hold h.mu.
+func (h *WebSocketHandler) handleWaiting(player *Player) {
+	h.waitingPlayer = player
+	if timeout := h.config.WaitingTimeout; timeout > 0 {
+		h.waitTimer = time.AfterFunc(timeout, func() {
+			h.expireWaiting(player)
+		})
+	}
+	h.sendMessage(player.Conn, struct {
+		Type    string `json:"type"`
+		Payload string `json:"payload"`
+	}{
+		Type:    "waiting",
+		Payload: "Waiting for opponent...",
+	})
+}
+
+// isAdmin reports whether the connection was authenticated as an admin
+func isAdmin(ctx context.Context) bool {
+	role, _ := ctx.Value("role").(auth.Role)
+	return role == auth.RoleAdmin
+}
+
+// atGameLimit reports whether a player already plays the maximum number of
+// unfinished games allowed
+func (h *WebSocketHandler) atGameLimit(player *Player) bool {
+	if player.Admin || h.config.MaxConcurrentGames <= 0 {
+		return false
+	}
+	return h.gameService.ActiveGamesFor(player.UserID) >= h.config.MaxConcurrentGames
+}
+
+// AtGameLimit reports whether a user already plays the maximum number of
+// unfinished games allowed
+func (h *WebSocketHandler) AtGameLimit(userID string, admin bool) bool {
+	return h.atGameLimit(&Player{UserID: userID, Admin: admin})
+}
+
+// GameOffer is a game proposed over HTTP. Like a WebSocket challenge it
+// waits for the opponent to accept before any game is created.
+type GameOffer struct {
+	ChallengerID   string
+	ChallengerName string
+	Admin          bool
+	Opponent       *models.User
+	Color          string
+	TimeControl    services.TimeControl
+	Casual         bool
+	OpenSeats      bool
+	Private        bool
+}
+
+// OfferGame sends a challenge on behalf of a user who may not be connected
+// and returns its ID. Once accepted the game is created and both players
+// take their seats by sending a reconnect message for it.
+func (h *WebSocketHandler) OfferGame(ctx context.Context, offer GameOffer) (string, error) {
+	rating, provisional := h.standing(ctx, offer.ChallengerID)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.isOnline(offer.Opponent.ID) {
+		return "", errOpponentUnavailable
+	}
+	if h.challengePending(offer.ChallengerID, offer.Opponent.ID) {
+		return "", errChallengePending
+	}
 
-		h.sessions[gameID] = session
-		h.waitingPlayer = nil
+	challenge := &Challenge{
+		ID: uuid.New().String(),
+		Challenger: &Player{
+			Username:    offer.ChallengerName,
+			UserID:      offer.ChallengerID,
+			Rating:      rating,
+			Provisional: provisional,
+			Admin:       offer.Admin,
+		},
+		TargetID:    offer.Opponent.ID,
+		TargetName:  offer.Opponent.Username,
+		TimeControl: offer.TimeControl,
+		Color:       offer.Color,
+		Casual:      offer.Casual,
+		OpenSeats:   offer.OpenSeats,
+		Private:     offer.Private,
+	}
+	h.issueChallenge(challenge)
+	return challenge.ID, nil
+}
 
-		// Notify both players that game has started
-		gameStartMsg := struct {
+// notifyGameCreated tells both players of a game created outside the
+// WebSocket flow that it's waiting for them. Callers must hold h.mu.
+func (h *WebSocketHandler) notifyGameCreated(gameID string, whiteID string, blackID string) {
+	for _, seat := range []struct {
+		userID string
+		color  chess.Color
+	}{{whiteID, chess.White}, {blackID, chess.Black}} {
+		msg := struct {
 			Type    string `json:"type"`
 			Payload struct {
-				GameID   string `json:"gameId"`
-				Color    string `json:"color"`
-				Opponent string `json:"opponent"`
+				GameID string `json:"gameId"`
+				Color  string `json:"color"`
 			} `json:"payload"`
-		}{Type: "gameStart"}
+		}{Type: "gameCreated"}
+		msg.Payload.GameID = gameID
+		msg.Payload.Color = seat.color.Name()
+
+		h.broadcast(h.connsOf(seat.userID), msg)
+	}
+}
+
+// attachGame builds a session for a game that was created without one, such
+// as over HTTP or by a tournament, so its players can take their seats. The
+// players come from seatsOf, called before taking h.mu. Callers must hold
+// h.mu.
+func (h *WebSocketHandler) attachGame(gameID string, white, black *Player) *GameSession {
+	if h.gameOver(gameID) {
+		return nil
+	}
+	state, err := h.gameService.GetGameState(gameID)
+	if err != nil || state.WhitePlayer != white.UserID || state.BlackPlayer != black.UserID {
+		return nil
+	}
+
+	session := &GameSession{
+		White:       white,
+		Black:       black,
+		CurrentTurn: state.CurrentTurn,
+	}
+	h.sessions[gameID] = session
+	h.armIdleTimer(session, gameID)
+	return session
+}
+
+// seatsOf looks up the players of a game that has no session yet, for
+// attachGame, or returns nils if there's no such game. Lookups may reach
+// the database, so call it before taking h.mu.
+func (h *WebSocketHandler) seatsOf(ctx context.Context, gameID string) (white, black *Player) {
+	state, err := h.gameService.GetGameState(gameID)
+	if err != nil {
+		return nil, nil
+	}
+
+	seat := func(userID string, color chess.Color) *Player {
+		player := &Player{UserID: userID, Color: color}
+		if user, err := h.userRepo.GetByID(ctx, userID); err == nil {
+			player.Username = user.Username
+			player.Admin = user.Role == auth.RoleAdmin
+		}
+		return player
+	}
+	return seat(state.WhitePlayer, chess.White), seat(state.BlackPlayer, chess.Black)
+}
+
+// sendGameLimitError tells a player they can't start another game yet
+func (h *WebSocketHandler) sendGameLimitError(conn *websocket.Conn) {
+	h.sendMessage(conn, struct {
+		Type    string `json:"type"`
+		Payload string `json:"payload"`
+	}{Type: "error", Payload: fmt.Sprintf("You can't play more than %d games at once", h.config.MaxConcurrentGames)})
+}
+
+// startGame creates a game for two players, registers its session and notifies
+// both sides. Callers must hold h.mu.
+func (h *WebSocketHandler) startGame(white, black *Player, tc services.TimeControl, opts services.GameOptions) string {
+	gameID := h.gameService.CreateGameWithOptions(white.UserID, black.UserID, tc, opts)
+
+	white.Color = chess.White
+	black.Color = chess.Black
+
+	session := &GameSession{
+		White:       white,
+		Black:       black,
+		CurrentTurn: chess.White,
+	}
+	h.sessions[gameID] = session
+	for _, player := range []*Player{white, black} {
+		if player.Conn != nil {
+			h.messageService.AssociateConnection(player.Conn, gameID)
+		}
+	}
+	h.armIdleTimer(session, gameID)
+	h.gameService.RecordEvent(gameID, services.EventJoin, chess.White, white.Username)
+	h.gameService.RecordEvent(gameID, services.EventJoin, chess.Black, black.Username)
+
+	// Notify both players that game has started
+	gameStartMsg := struct {
+		Type    string `json:"type"`
+		Payload struct {
+			GameID              string               `json:"gameId"`
+			Color               string               `json:"color"`
+			Opponent            string               `json:"opponent"`
+			Rating              int                  `json:"rating,omitempty"`         // Omitted when unrated
+			OpponentRating      int                  `json:"opponentRating,omitempty"` // Omitted when unrated
+			Provisional         bool                 `json:"provisional,omitempty"`    // The rating is still being placed
+			OpponentProvisional bool                 `json:"opponentProvisional,omitempty"`
+			TimeControl         services.TimeControl `json:"timeControl"`
+		} `json:"payload"`
+	}{Type: "gameStart"}
+
+	// Notify white player
+	gameStartMsg.Payload.GameID = gameID
+	gameStartMsg.Payload.TimeControl = tc
+	gameStartMsg.Payload.Color = "white"
+	gameStartMsg.Payload.Opponent = black.Username
+	gameStartMsg.Payload.Rating = white.Rating
+	gameStartMsg.Payload.OpponentRating = black.Rating
+	gameStartMsg.Payload.Provisional = white.Provisional
+	gameStartMsg.Payload.OpponentProvisional = black.Provisional
+	h.sendMessage(white.Conn, gameStartMsg)
+
+	// Notify black player
+	gameStartMsg.Payload.Color = "black"
+	gameStartMsg.Payload.Opponent = white.Username
+	gameStartMsg.Payload.Rating = black.Rating
+	gameStartMsg.Payload.OpponentRating = white.Rating
+	gameStartMsg.Payload.Provisional = black.Provisional
+	gameStartMsg.Payload.OpponentProvisional = white.Provisional
+	h.sendMessage(black.Conn, gameStartMsg)
+
+	h.armFlag(session, gameID)
+
+	return gameID
+}
+
+// gameOver reports whether a game has ended. Its state lives in the game
+// service and changes under that lock, so it's asked there rather than read
+// from the session; h.mu may be held, as it's always taken first. A game
+// the service no longer holds counts as over.
+func (h *WebSocketHandler) gameOver(gameID string) bool {
+	over, _, _, err := h.gameService.IsGameOver(gameID)
+	return err != nil || over
+}
+
+// moveCount returns how many half-moves a game has had, or 0 if the game
+// service no longer holds it. Like gameOver, it's safe under h.mu.
+func (h *WebSocketHandler) moveCount(gameID string) int {
+	plies, _ := h.gameService.MoveCount(gameID)
+	return plies
+}
 
-		// Notify white player
-		gameStartMsg.Payload.GameID = gameID
-		gameStartMsg.Payload.Color = "white"
-		gameStartMsg.Payload.Opponent = newPlayer.Username
-		h.sendMessage(session.White.Conn, gameStartMsg)
+// ratingOf returns a user's overall rating, or 0 when it can't be looked up.
+// Lookups go through the rating cache but may still reach the database, so
+// call it before taking h.mu.
+func (h *WebSocketHandler) ratingOf(ctx context.Context, userID string) int {
+	rating, _ := h.standing(ctx, userID)
+	return rating
+}
 
-		// Notify black player
-		gameStartMsg.Payload.Color = "black"
-		gameStartMsg.Payload.Opponent = session.White.Username
-		h.sendMessage(session.Black.Conn, gameStartMsg)
+// standing returns a user's overall rating, or 0 when it can't be looked
+// up, and whether it's still provisional. Like ratingOf, call it before
+// taking h.mu.
+func (h *WebSocketHandler) standing(ctx context.Context, userID string) (int, bool) {
+	user, err := h.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		logging.Debugf("No rating for user %s: %v", userID, err)
+		return 0, false
 	}
+	return user.EloRating, user.Provisional()
 }
 
 // Helper function to determine the winner
@@ -365,10 +1404,13 @@ func determineWinner(outcome chess.Outcome) string {
 }
 
 // handleResign handles a player resigning from a game
-func (h *WebSocketHandler) handleResign(conn *websocket.Conn, gameID string) {
+func (h *WebSocketHandler) handleResign(ctx context.Context, conn *websocket.Conn, gameID string) {
+	// Seats can change hands, so they're read under the lock, which is held
+	// until everyone has been told
 	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	session, exists := h.sessions[gameID]
-	h.mu.Unlock()
 
 	if !exists {
 		h.sendMessage(conn, struct {
@@ -392,56 +1434,30 @@ func (h *WebSocketHandler) handleResign(conn *websocket.Conn, gameID string) {
 		return
 	}
 
-	// Create context for database operations
-	ctx := context.Background()
-
 	// Get user repository from the application context
 	userRepo := h.getUserRepository()
 
 	// Use game service to handle resignation
 	err := h.gameService.ResignGame(gameID, playerColor, ctx, userRepo)
 	if err != nil {
-		h.sendMessage(conn, struct {
-			Type    string `json:"type"`
-			Payload string `json:"payload"`
-		}{Type: "error", Payload: err.Error()})
+		h.sendGameError(conn, err)
 		return
 	}
 
 	// Check game over and notify players
-	isOver, outcome, method, _ := h.gameService.IsGameOver(gameID)
-	if isOver {
-		gameOverMsg := struct {
-			Type    string `json:"type"`
-			Payload struct {
-				Outcome string `json:"outcome"`
-				Method  string `json:"method"`
-				Winner  string `json:"winner"`
-			} `json:"payload"`
-		}{
-			Type: "gameOver",
-			Payload: struct {
-				Outcome string `json:"outcome"`
-				Method  string `json:"method"`
-				Winner  string `json:"winner"`
-			}{
-				Outcome: outcome.String(),
-				Method:  method.String(),
-				Winner:  determineWinner(outcome),
-			},
-		}
-
-		// Send game over message to both players
-		h.sendMessage(session.White.Conn, gameOverMsg)
-		h.sendMessage(session.Black.Conn, gameOverMsg)
+	if isOver, _, _, _ := h.gameService.IsGameOver(gameID); isOver {
+		h.announceGameOver(session, gameID)
 	}
 }
 
 // handleDrawOffer handles a player offering a draw
 func (h *WebSocketHandler) handleDrawOffer(conn *websocket.Conn, gameID string) {
+	// Seats can change hands, so they're read under the lock, which is held
+	// until everyone has been told
 	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	session, exists := h.sessions[gameID]
-	h.mu.Unlock()
 
 	if !exists {
 		h.sendMessage(conn, struct {
@@ -469,14 +1485,12 @@ func (h *WebSocketHandler) handleDrawOffer(conn *websocket.Conn, gameID string)
 	}
 
 	// Use game service to handle draw offer
-	err := h.gameService.OfferDraw(gameID)
+	err := h.gameService.OfferDraw(gameID, playerColor)
 	if err != nil {
-		h.sendMessage(conn, struct {
-			Type    string `json:"type"`
-			Payload string `json:"payload"`
-		}{Type: "error", Payload: err.Error()})
+		h.sendGameError(conn, err)
 		return
 	}
+	h.gameService.RecordEvent(gameID, services.EventDrawOffer, playerColor, "")
 
 	// Notify opponent of draw offer
 	drawOfferMsg := struct {
@@ -493,14 +1507,17 @@ func (h *WebSocketHandler) handleDrawOffer(conn *websocket.Conn, gameID string)
 		},
 	}
 
-	h.sendMessage(opponent.Conn, drawOfferMsg)
+	h.sendToPlayer(session, opponent, drawOfferMsg)
 }
 
 // handleDrawResponse handles a player's response to a draw offer
-func (h *WebSocketHandler) handleDrawResponse(conn *websocket.Conn, gameID string, accept bool) {
+func (h *WebSocketHandler) handleDrawResponse(ctx context.Context, conn *websocket.Conn, gameID string, accept bool) {
+	// Seats can change hands, so they're read under the lock, which is held
+	// until everyone has been told
 	h.mu.Lock()
+	defer h.mu.Unlock()
+
 	session, exists := h.sessions[gameID]
-	h.mu.Unlock()
 
 	if !exists {
 		h.sendMessage(conn, struct {
@@ -528,19 +1545,13 @@ func (h *WebSocketHandler) handleDrawResponse(conn *websocket.Conn, gameID strin
 	}
 
 	if accept {
-		// Create context for database operations
-		ctx := context.Background()
-
 		// Get user repository from the application context
 		userRepo := h.getUserRepository()
 
 		// Accept draw
-		err := h.gameService.AcceptDraw(gameID, ctx, userRepo)
+		err := h.gameService.AcceptDraw(gameID, playerColor, ctx, userRepo)
 		if err != nil {
-			h.sendMessage(conn, struct {
-				Type    string `json:"type"`
-				Payload string `json:"payload"`
-			}{Type: "error", Payload: err.Error()})
+			h.sendGameError(conn, err)
 			return
 		}
 
@@ -559,42 +1570,18 @@ func (h *WebSocketHandler) handleDrawResponse(conn *websocket.Conn, gameID strin
 			},
 		}
 
-		h.sendMessage(session.White.Conn, drawAcceptedMsg)
-		h.sendMessage(session.Black.Conn, drawAcceptedMsg)
+		h.broadcastToPlayers(session, drawAcceptedMsg)
 
 		// Send game over message
-		gameOverMsg := struct {
-			Type    string `json:"type"`
-			Payload struct {
-				Outcome string `json:"outcome"`
-				Method  string `json:"method"`
-				Winner  string `json:"winner"`
-			} `json:"payload"`
-		}{
-			Type: "gameOver",
-			Payload: struct {
-				Outcome string `json:"outcome"`
-				Method  string `json:"method"`
-				Winner  string `json:"winner"`
-			}{
-				Outcome: "Draw",
-				Method:  "Agreement",
-				Winner:  "draw",
-			},
-		}
-
-		h.sendMessage(session.White.Conn, gameOverMsg)
-		h.sendMessage(session.Black.Conn, gameOverMsg)
+		h.announceGameOver(session, gameID)
 	} else {
 		// Decline draw
-		err := h.gameService.DeclineDraw(gameID)
+		err := h.gameService.DeclineDraw(gameID, playerColor)
 		if err != nil {
-			h.sendMessage(conn, struct {
-				Type    string `json:"type"`
-				Payload string `json:"payload"`
-			}{Type: "error", Payload: err.Error()})
+			h.sendGameError(conn, err)
 			return
 		}
+		h.gameService.RecordEvent(gameID, services.EventDrawDeclined, playerColor, "")
 
 		// Notify opponent
 		drawDeclinedMsg := struct {
@@ -611,16 +1598,17 @@ func (h *WebSocketHandler) handleDrawResponse(conn *websocket.Conn, gameID strin
 			},
 		}
 
-		h.sendMessage(opponent.Conn, drawDeclinedMsg)
+		h.sendToPlayer(session, opponent, drawDeclinedMsg)
 	}
 }
 
 // handleTimeUpdate handles updating a player's remaining time
-func (h *WebSocketHandler) handleTimeUpdate(conn *websocket.Conn, gameID string, timeLeft float64) {
+func (h *WebSocketHandler) handleTimeUpdate(ctx context.Context, conn *websocket.Conn, gameID string, timeLeft float64) {
+	// Seats can change hands, so they're read under the lock
 	h.mu.Lock()
-	session, exists := h.sessions[gameID]
-	h.mu.Unlock()
+	defer h.mu.Unlock()
 
+	session, exists := h.sessions[gameID]
 	if !exists {
 		h.sendMessage(conn, struct {
 			Type    string `json:"type"`
@@ -643,13 +1631,16 @@ func (h *WebSocketHandler) handleTimeUpdate(conn *websocket.Conn, gameID string,
 		return
 	}
 
+	// Reports can't be trusted when the server keeps time
+	if h.serverClock(gameID) || h.correspondence(gameID) {
+		logging.Debugf("Ignoring %s time report in server-clocked game %s", playerColor.Name(), gameID)
+		return
+	}
+
 	// Update time in game service
 	err := h.gameService.UpdateTime(gameID, playerColor, timeLeft)
 	if err != nil {
-		h.sendMessage(conn, struct {
-			Type    string `json:"type"`
-			Payload string `json:"payload"`
-		}{Type: "error", Payload: err.Error()})
+		h.sendGameError(conn, err)
 		return
 	}
 
@@ -671,16 +1662,250 @@ func (h *WebSocketHandler) handleTimeUpdate(conn *websocket.Conn, gameID string,
 		},
 	}
 
-	h.sendMessage(session.White.Conn, timeUpdateMsg)
-	h.sendMessage(session.Black.Conn, timeUpdateMsg)
+	h.broadcastToGame(session, timeUpdateMsg)
+
+	// A clock reaching zero ends the game
+	if timeLeft <= 0 {
+		h.handleTimeout(ctx, session, gameID, playerColor)
+	}
+}
+
+// serverClock reports whether the server keeps time in a game
+func (h *WebSocketHandler) serverClock(gameID string) bool {
+	state, err := h.gameService.GetGameState(gameID)
+	return err == nil && state.ServerClock
 }
 
-// handleChat handles a chat message from a player
-func (h *WebSocketHandler) handleChat(conn *websocket.Conn, gameID string, message string, username string) {
+// correspondence reports whether a game runs on per-move deadlines instead of a clock
+func (h *WebSocketHandler) correspondence(gameID string) bool {
+	state, err := h.gameService.GetGameState(gameID)
+	return err == nil && state.TimeSettings.Correspondence()
+}
+
+// broadcastTimeLeft tells both players what a side has left on its server clock
+func (h *WebSocketHandler) broadcastTimeLeft(session *GameSession, gameID string, color chess.Color) {
+	timeLeft, err := h.gameService.TimeLeft(gameID, color)
+	if err != nil {
+		return
+	}
+
+	timeUpdateMsg := struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Color    string  `json:"color"`
+			TimeLeft float64 `json:"timeLeft"`
+		} `json:"payload"`
+	}{
+		Type: "timeUpdate",
+		Payload: struct {
+			Color    string  `json:"color"`
+			TimeLeft float64 `json:"timeLeft"`
+		}{
+			Color:    color.String(),
+			TimeLeft: timeLeft,
+		},
+	}
+
+	h.broadcastToGame(session, timeUpdateMsg)
+}
+
+// armFlag schedules a check for when the side to move would run out of
+// server time. Nothing is scheduled while the clock is paused for a
+// disconnect. Callers must hold h.mu.
+func (h *WebSocketHandler) armFlag(session *GameSession, gameID string) {
+	stopFlag(session)
+	if !h.serverClock(gameID) || len(session.away) > 0 {
+		return
+	}
+
+	timeLeft, err := h.gameService.TimeLeft(gameID, session.CurrentTurn)
+	if err != nil {
+		return
+	}
+	session.flagTimer = time.AfterFunc(time.Duration(max(timeLeft, 0)*float64(time.Second)), func() {
+		h.checkFlag(gameID)
+	})
+}
+
+func stopFlag(session *GameSession) {
+	if session.flagTimer != nil {
+		session.flagTimer.Stop()
+		session.flagTimer = nil
+	}
+}
+
+// checkFlag ends the game on time if the side to move has run out, and
+// otherwise waits for the rest of their time
+func (h *WebSocketHandler) checkFlag(gameID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[gameID]
+	if !exists || len(session.away) > 0 {
+		return
+	}
+	if isOver, _, _, _ := h.gameService.IsGameOver(gameID); isOver {
+		return
+	}
+
+	timeLeft, err := h.gameService.TimeLeft(gameID, session.CurrentTurn)
+	if err != nil {
+		return
+	}
+	if timeLeft > 0 {
+		h.armFlag(session, gameID)
+		return
+	}
+	h.handleTimeout(context.Background(), session, gameID, session.CurrentTurn)
+}
+
+// deadlineSweepInterval is how often correspondence move deadlines are checked
+const deadlineSweepInterval = time.Minute
+
+// StartDeadlineSweeper sweeps the games every deadlineSweepInterval
+func (h *WebSocketHandler) StartDeadlineSweeper() {
+	ticker := time.NewTicker(deadlineSweepInterval)
+	go func() {
+		for now := range ticker.C {
+			h.Sweep(now)
+		}
+	}()
+}
+
+// Sweep times out correspondence games whose side to move let the deadline
+// pass as of now, whether or not anyone is connected, and draws games that
+// ran past the maximum duration
+func (h *WebSocketHandler) Sweep(now time.Time) {
+	h.enforceDeadlines(now)
+	h.enforceMaxDuration(now)
+}
+
+// enforceDeadlines ends correspondence games on time. Like enforceMaxDuration
+// it ends each game before taking h.mu, which it needs only to announce it.
+func (h *WebSocketHandler) enforceDeadlines(now time.Time) {
+	for _, overdue := range h.gameService.OverdueMoves(now) {
+		logging.Infof("%s missed the move deadline in correspondence game %s", overdue.Color.Name(), overdue.GameID)
+
+		_, _, err := h.gameService.HandleTimeout(overdue.GameID, overdue.Color, context.Background(), h.getUserRepository())
+		if err != nil {
+			continue
+		}
+		h.mu.Lock()
+		if session, exists := h.sessions[overdue.GameID]; exists {
+			h.announceGameOver(session, overdue.GameID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// handleTimeout ends a game on time and notifies both players. A game that
+// ended some other way first is left as it is. Callers must hold h.mu.
+func (h *WebSocketHandler) handleTimeout(ctx context.Context, session *GameSession, gameID string, color chess.Color) {
+	if _, _, err := h.gameService.HandleTimeout(gameID, color, ctx, h.getUserRepository()); err != nil {
+		return
+	}
+
+	h.announceGameOver(session, gameID)
+}
+
+// broadcastGameOver tells both players and any spectators how a game ended,
+// along with the final position and the PGN so clients can offer analysis
+// straight away
+func (h *WebSocketHandler) broadcastGameOver(session *GameSession, game *services.GameView, outcome string, method string, winner string) {
+	stopFlag(session)
+	stopIdleTimer(session)
+
+	gameOverMsg := struct {
+		Type    string `json:"type"`
+		Payload struct {
+			Outcome string `json:"outcome"`
+			Method  string `json:"method"`
+			Winner  string `json:"winner"`
+			FEN     string `json:"fen"`
+			PGN     string `json:"pgn"`
+		} `json:"payload"`
+	}{
+		Type: "gameOver",
+		Payload: struct {
+			Outcome string `json:"outcome"`
+			Method  string `json:"method"`
+			Winner  string `json:"winner"`
+			FEN     string `json:"fen"`
+			PGN     string `json:"pgn"`
+		}{
+			Outcome: outcome,
+			Method:  method,
+			Winner:  winner,
+			FEN:     game.FEN,
+			PGN:     game.PGN,
+		},
+	}
+
+	h.broadcastToGame(session, gameOverMsg)
+}
+
+// handleGetBoard sends the board of a game rendered as text to a participant
+// or spectator
+func (h *WebSocketHandler) handleGetBoard(conn *websocket.Conn, userID string, gameID string) {
+	// Only the players and the game's spectators, guests included, may look
 	h.mu.Lock()
 	session, exists := h.sessions[gameID]
+	allowed := exists && (session.spectators[conn] ||
+		userID != "" && (session.White.UserID == userID || session.Black.UserID == userID))
 	h.mu.Unlock()
 
+	if !exists {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Game not found"})
+		return
+	}
+	if !allowed {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Not playing or watching this game"})
+		return
+	}
+
+	drawing, err := h.messageService.DrawBoard(gameID, userID)
+	if err != nil {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: err.Error()})
+		return
+	}
+
+	h.sendMessage(conn, struct {
+		Type    string `json:"type"`
+		Payload struct {
+			GameID  string `json:"gameId"`
+			ASCII   string `json:"ascii"`
+			Unicode string `json:"unicode"`
+		} `json:"payload"`
+	}{
+		Type: "boardAscii",
+		Payload: struct {
+			GameID  string `json:"gameId"`
+			ASCII   string `json:"ascii"`
+			Unicode string `json:"unicode"`
+		}{
+			GameID:  gameID,
+			ASCII:   drawing.ASCII,
+			Unicode: drawing.Unicode,
+		},
+	})
+}
+
+// handleChat handles a chat message from a player
+func (h *WebSocketHandler) handleChat(conn *websocket.Conn, gameID string, message string, username string) {
+	// Seats can change hands, so they're read under the lock
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[gameID]
 	if !exists {
 		h.sendMessage(conn, struct {
 			Type    string `json:"type"`
@@ -698,6 +1923,13 @@ func (h *WebSocketHandler) handleChat(conn *websocket.Conn, gameID string, messa
 		}{Type: "error", Payload: err.Error()})
 		return
 	}
+	senderColor := chess.NoColor
+	if session.White.Conn == conn {
+		senderColor = chess.White
+	} else if session.Black.Conn == conn {
+		senderColor = chess.Black
+	}
+	h.gameService.RecordEvent(gameID, services.EventChat, senderColor, username+": "+message)
 
 	// Broadcast chat message to both players
 	chatMsg := struct {
@@ -717,17 +1949,32 @@ func (h *WebSocketHandler) handleChat(conn *websocket.Conn, gameID string, messa
 		},
 	}
 
-	h.sendMessage(session.White.Conn, chatMsg)
-	h.sendMessage(session.Black.Conn, chatMsg)
+	h.broadcastToPlayers(session, chatMsg)
 }
 
 // handleReconnect handles a player reconnecting to a game
-func (h *WebSocketHandler) handleReconnect(conn *websocket.Conn, gameID string, username string, userID string) {
+//
+// A client that says which message it saw last is sent the ones it missed,
+// provided they're all still buffered; otherwise it gets a state snapshot.
+func (h *WebSocketHandler) handleReconnect(ctx context.Context, conn *websocket.Conn, gameID string, username string, userID string, lastSeq *int) {
+	// A game created without a session needs its players looked up first,
+	// since that may reach the database
+	h.mu.Lock()
+	_, exists := h.sessions[gameID]
+	h.mu.Unlock()
+	var white, black *Player
+	if !exists {
+		white, black = h.seatsOf(ctx, gameID)
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	session, exists := h.sessions[gameID]
-	if !exists {
+	if !exists && white != nil {
+		session = h.attachGame(gameID, white, black)
+	}
+	if session == nil {
 		h.sendMessage(conn, struct {
 			Type    string `json:"type"`
 			Payload string `json:"payload"`
@@ -735,14 +1982,74 @@ func (h *WebSocketHandler) handleReconnect(conn *websocket.Conn, gameID string,
 		return
 	}
 
-	// Check if username matches either player
-	if session.White.Username == username {
-		// Update white player's connection
-		session.White.Conn = conn
-	} else if session.Black.Username == username {
-		// Update black player's connection
-		session.Black.Conn = conn
-	} else {
+	var returning *Player
+	switch userID {
+	case session.White.UserID:
+		returning = session.White
+	case session.Black.UserID:
+		returning = session.Black
+	default:
+		h.sendGameFull(conn, userID, gameID, session)
+		return
+	}
+	h.recordArrival(gameID, returning, username)
+	h.seatConnection(gameID, returning, conn)
+
+	// Catch the player up before sending anything their return sets off, so
+	// replayed messages arrive in sequence
+	if !h.replayMissed(conn, session, returning.Color, lastSeq) {
+		h.sendGameState(conn, session, gameID)
+	}
+	h.endAbsence(ctx, gameID, session, returning.Color)
+
+	// The returning player may find their opponent gone in turn
+	for color, a := range session.away {
+		h.sendOpponentDisconnected(session, returning, color, a)
+	}
+
+	// Games created without a session start once both players are seated
+	if session.White.Conn != nil && session.Black.Conn != nil && len(session.away) == 0 {
+		h.gameService.ResumeClock(gameID)
+		h.armFlag(session, gameID)
+	}
+}
+
+// seatConnection points a player's seat at a connection, taking the game
+// off the connection it used before. Callers must hold h.mu.
+func (h *WebSocketHandler) seatConnection(gameID string, player *Player, conn *websocket.Conn) {
+	if player.Conn != nil && player.Conn != conn {
+		h.messageService.DissociateConnection(player.Conn, gameID)
+	}
+	player.Conn = conn
+	h.messageService.AssociateConnection(conn, gameID)
+}
+
+// recordArrival logs a player connecting to a game: the first time counts
+// as joining it, later times as reconnecting
+func (h *WebSocketHandler) recordArrival(gameID string, player *Player, username string) {
+	if player.Conn == nil {
+		h.gameService.RecordEvent(gameID, services.EventJoin, player.Color, username)
+		return
+	}
+	h.gameService.RecordEvent(gameID, services.EventReconnect, player.Color, "")
+}
+
+// handleGetState sends a game's current state to one of its players
+func (h *WebSocketHandler) handleGetState(conn *websocket.Conn, userID string, gameID string) {
+	// Seats can change hands, so membership is checked under the lock
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session, exists := h.sessions[gameID]
+
+	if !exists {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Game not found"})
+		return
+	}
+	if session.White.UserID != userID && session.Black.UserID != userID {
 		h.sendMessage(conn, struct {
 			Type    string `json:"type"`
 			Payload string `json:"payload"`
@@ -750,8 +2057,16 @@ func (h *WebSocketHandler) handleReconnect(conn *websocket.Conn, gameID string,
 		return
 	}
 
-	// Get current game state
-	game, err := h.gameService.GetGame(gameID)
+	h.sendGameState(conn, session, gameID)
+}
+
+// sendGameState sends the position, clocks and draw claim counters of a game.
+// It carries the sequence number of the newest message already reflected in
+// it, which is read first so a message racing the snapshot is never skipped.
+func (h *WebSocketHandler) sendGameState(conn *websocket.Conn, session *GameSession, gameID string) {
+	seq := session.lastSeq()
+
+	snapshot, err := h.gameService.Snapshot(gameID)
 	if err != nil {
 		h.sendMessage(conn, struct {
 			Type    string `json:"type"`
@@ -760,46 +2075,275 @@ func (h *WebSocketHandler) handleReconnect(conn *websocket.Conn, gameID string,
 		return
 	}
 
-	gameState, err := h.gameService.GetGameState(gameID)
+	h.sendMessage(conn, struct {
+		Type    string                 `json:"type"`
+		Seq     int                    `json:"seq"`
+		Payload *services.GameSnapshot `json:"payload"`
+	}{Type: "gameState", Seq: seq, Payload: snapshot})
+}
+
+// handleChallenge sends a direct game invitation to another user's connections
+func (h *WebSocketHandler) handleChallenge(ctx context.Context, conn *websocket.Conn, userID string, username string, targetName string, tc services.TimeControl, private bool) {
+	if targetName == "" || targetName == username {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Invalid challenge target"})
+		return
+	}
+
+	target, err := h.userRepo.GetByUsername(ctx, targetName)
 	if err != nil {
 		h.sendMessage(conn, struct {
 			Type    string `json:"type"`
 			Payload string `json:"payload"`
-		}{Type: "error", Payload: err.Error()})
+		}{Type: "error", Payload: "User not found"})
+		return
+	}
+
+	if tc.Initial <= 0 && tc.DaysPerMove == 0 {
+		tc = services.DefaultTimeControl
+	} else if !tc.Valid() {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Invalid time control"})
+		return
+	}
+	rating, provisional := h.standing(ctx, userID)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.isOnline(target.ID) {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "User is offline"})
+		return
+	}
+
+	// Only one open challenge per challenger/target pair
+	if h.challengePending(userID, target.ID) {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Challenge already pending"})
+		return
+	}
+
+	challenger := &Player{
+		Conn:        conn,
+		Username:    username,
+		UserID:      userID,
+		Rating:      rating,
+		Provisional: provisional,
+		Admin:       isAdmin(ctx),
+	}
+	if h.atGameLimit(challenger) {
+		h.sendGameLimitError(conn)
 		return
 	}
 
-	// Send current game state to reconnected player
-	gameStateMsg := struct {
+	h.issueChallenge(&Challenge{
+		ID:          uuid.New().String(),
+		Challenger:  challenger,
+		TargetID:    target.ID,
+		TargetName:  target.Username,
+		TimeControl: tc,
+		Color:       ColorWhite,
+		Private:     private,
+	})
+}
+
+// challengePending reports whether a user already has an open challenge to
+// another. Callers must hold h.mu.
+func (h *WebSocketHandler) challengePending(challengerID string, targetID string) bool {
+	for _, c := range h.challenges {
+		if c.Challenger.UserID == challengerID && c.TargetID == targetID {
+			return true
+		}
+	}
+	return false
+}
+
+// issueChallenge registers a challenge, arms its expiry and tells both
+// users about it. Callers must hold h.mu.
+func (h *WebSocketHandler) issueChallenge(challenge *Challenge) {
+	challenge.timer = time.AfterFunc(challengeTimeout, func() {
+		h.expireChallenge(challenge.ID)
+	})
+	h.challenges[challenge.ID] = challenge
+
+	challengeMsg := struct {
 		Type    string `json:"type"`
 		Payload struct {
-			Position    string  `json:"position"`
-			Turn        string  `json:"turn"`
-			WhitePlayer string  `json:"whitePlayer"`
-			BlackPlayer string  `json:"blackPlayer"`
-			WhiteTime   float64 `json:"whiteTime"`
-			BlackTime   float64 `json:"blackTime"`
+			ChallengeID string               `json:"challengeId"`
+			Challenger  string               `json:"challenger"`
+			Target      string               `json:"target"`
+			TimeControl services.TimeControl `json:"timeControl"`
+			Casual      bool                 `json:"casual,omitempty"`
+			Private     bool                 `json:"private,omitempty"`
 		} `json:"payload"`
-	}{
-		Type: "gameState",
-		Payload: struct {
-			Position    string  `json:"position"`
-			Turn        string  `json:"turn"`
-			WhitePlayer string  `json:"whitePlayer"`
-			BlackPlayer string  `json:"blackPlayer"`
-			WhiteTime   float64 `json:"whiteTime"`
-			BlackTime   float64 `json:"blackTime"`
+	}{Type: "challengeReceived"}
+	challengeMsg.Payload.ChallengeID = challenge.ID
+	challengeMsg.Payload.Challenger = challenge.Challenger.Username
+	challengeMsg.Payload.Target = challenge.TargetName
+	challengeMsg.Payload.TimeControl = challenge.TimeControl
+	challengeMsg.Payload.Casual = challenge.Casual
+	challengeMsg.Payload.Private = challenge.Private
+
+	h.broadcast(h.connsOf(challenge.TargetID), challengeMsg)
+
+	// Confirm to the challenger
+	challengeMsg.Type = "challengeSent"
+	h.broadcast(h.challengerConns(challenge), challengeMsg)
+
+	h.emailInvite(challenge)
+}
+
+// SetNotifier makes challenges email an invite to targets who haven't turned
+// those off. It must be called before the handler starts serving connections.
+func (h *WebSocketHandler) SetNotifier(notifier *services.NotificationService) {
+	h.notifier = notifier
+}
+
+// emailInvite emails the target of a challenge about it. Sending happens in
+// the background, as callers hold h.mu.
+func (h *WebSocketHandler) emailInvite(challenge *Challenge) {
+	if h.notifier == nil {
+		return
+	}
+	subject := fmt.Sprintf("%s challenged you to a game", challenge.Challenger.Username)
+	body := fmt.Sprintf("%s challenged you to a game. Sign in within %s to accept it.",
+		challenge.Challenger.Username, challengeTimeout)
+	go func() {
+		if _, err := h.notifier.Notify(context.Background(), challenge.TargetID, services.NotifyGameInvite, subject, body); err != nil {
+			logging.Warnf("Failed to email challenge %s to user %s: %v", challenge.ID, challenge.TargetID, err)
+		}
+	}()
+}
+
+// challengerConns returns where to reach the issuer of a challenge: the
+// connection it came from or, for one issued over HTTP, all of theirs.
+// Callers must hold h.mu.
+func (h *WebSocketHandler) challengerConns(challenge *Challenge) []*websocket.Conn {
+	if challenge.Challenger.Conn != nil {
+		return []*websocket.Conn{challenge.Challenger.Conn}
+	}
+	return h.connsOf(challenge.Challenger.UserID)
+}
+
+// handleChallengeResponse accepts or declines a pending challenge
+func (h *WebSocketHandler) handleChallengeResponse(ctx context.Context, conn *websocket.Conn, userID string, username string, challengeID string, accept bool) {
+	rating, provisional := h.standing(ctx, userID)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	challenge, exists := h.challenges[challengeID]
+	if !exists || challenge.TargetID != userID {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Challenge not found"})
+		return
+	}
+
+	challenge.timer.Stop()
+	delete(h.challenges, challengeID)
+
+	if !accept {
+		h.broadcast(h.challengerConns(challenge), struct {
+			Type    string `json:"type"`
+			Payload struct {
+				ChallengeID string `json:"challengeId"`
+				DeclinedBy  string `json:"declinedBy"`
+			} `json:"payload"`
 		}{
-			Position:    game.Position().String(),
-			Turn:        game.Position().Turn().String(),
-			WhitePlayer: gameState.WhitePlayer,
-			BlackPlayer: gameState.BlackPlayer,
-			WhiteTime:   gameState.TimeControl.WhiteTimeLeft,
-			BlackTime:   gameState.TimeControl.BlackTimeLeft,
-		},
+			Type: "challengeDeclined",
+			Payload: struct {
+				ChallengeID string `json:"challengeId"`
+				DeclinedBy  string `json:"declinedBy"`
+			}{
+				ChallengeID: challengeID,
+				DeclinedBy:  username,
+			},
+		})
+		return
 	}
 
-	h.sendMessage(conn, gameStateMsg)
+	if challenge.Challenger.Conn != nil && !h.userConns[challenge.Challenger.UserID][challenge.Challenger.Conn] {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Challenger is no longer online"})
+		return
+	}
+
+	opponent := &Player{
+		Conn:        conn,
+		Username:    username,
+		UserID:      userID,
+		Rating:      rating,
+		Provisional: provisional,
+		Admin:       isAdmin(ctx),
+	}
+	if h.atGameLimit(opponent) {
+		h.sendGameLimitError(conn)
+		return
+	}
+	if h.atGameLimit(challenge.Challenger) {
+		h.sendMessage(conn, struct {
+			Type    string `json:"type"`
+			Payload string `json:"payload"`
+		}{Type: "error", Payload: "Challenger is already playing too many games"})
+		for _, c := range h.challengerConns(challenge) {
+			h.sendGameLimitError(c)
+		}
+		return
+	}
+	if challenge.Challenger.Conn == nil {
+		h.startOfferedGame(challenge, opponent)
+		return
+	}
+	h.startGame(challenge.Challenger, opponent, challenge.TimeControl, challenge.gameOptions())
+}
+
+// startOfferedGame creates the game of an accepted HTTP challenge. Nobody is
+// seated yet, so the clock waits until both players have reconnected to it.
+// Callers must hold h.mu.
+func (h *WebSocketHandler) startOfferedGame(challenge *Challenge, opponent *Player) {
+	white, black := challenge.Challenger, opponent
+	if playsBlack(challenge.Color) {
+		white, black = black, white
+	}
+
+	gameID := h.gameService.CreateGameWithOptions(white.UserID, black.UserID, challenge.TimeControl, challenge.gameOptions())
+	h.gameService.PauseClock(gameID)
+	h.notifyGameCreated(gameID, white.UserID, black.UserID)
+}
+
+// expireChallenge removes an unanswered challenge and notifies both users
+func (h *WebSocketHandler) expireChallenge(challengeID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	challenge, exists := h.challenges[challengeID]
+	if !exists {
+		return
+	}
+	delete(h.challenges, challengeID)
+
+	expiredMsg := struct {
+		Type    string `json:"type"`
+		Payload struct {
+			ChallengeID string `json:"challengeId"`
+		} `json:"payload"`
+	}{Type: "challengeExpired"}
+	expiredMsg.Payload.ChallengeID = challengeID
+
+	h.broadcast(append(h.connsOf(challenge.TargetID), h.challengerConns(challenge)...), expiredMsg)
 }
 
 // handlePing responds to ping messages to keep the connection alive
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"chess-ws-go/internal/logging"
+
+	"github.com/gorilla/websocket"
+)
+
+// writeTimeout bounds how long writing one message may take. A peer that
+// has stopped reading for this long is taken to be gone.
+const writeTimeout = 10 * time.Second
+
+// sendQueueSize is how many messages may wait to be written to one
+// connection. A peer that falls this far behind isn't keeping up, and is
+// dropped rather than buffered for without bound.
+const sendQueueSize = 256
+
+// connWriter writes a connection's messages from a goroutine of its own, so
+// queuing a message never waits on the peer and no socket is ever written
+// while h.mu or a game's outbox lock is held. Messages are written in the
+// order they were queued. The queue is never closed, since those queuing
+// can't know when the connection goes; stop ends the writer instead.
+type connWriter struct {
+	h     *WebSocketHandler
+	conn  *websocket.Conn
+	queue chan []byte
+	stop  chan struct{}
+}
+
+// startWriter starts writing a newly upgraded connection's messages. Messages
+// reach a connection from its own reader as well as from other players'
+// goroutines, so its writer is registered in h.writers to be looked up rather
+// than passed around. serve removes it once the connection is closed.
+func (h *WebSocketHandler) startWriter(conn *websocket.Conn) *connWriter {
+	w := &connWriter{
+		h:     h,
+		conn:  conn,
+		queue: make(chan []byte, sendQueueSize),
+		stop:  make(chan struct{}),
+	}
+	h.writers.Store(conn, w)
+	go w.run()
+	return w
+}
+
+// close stops the writer once the connection is done with. Messages still
+// queued are dropped, as the peer is gone.
+func (w *connWriter) close() {
+	w.h.writers.Delete(w.conn)
+	close(w.stop)
+}
+
+func (w *connWriter) run() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		case data := <-w.queue:
+			_ = w.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := w.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				w.h.dropConnection(w.conn, err)
+			}
+		}
+	}
+}
+
+// writeMessage queues an encoded message for a connection. Every message the
+// handler sends goes through here. It never blocks: a peer too far behind to
+// take another message is dropped.
+func (h *WebSocketHandler) writeMessage(conn *websocket.Conn, data []byte) {
+	value, ok := h.writers.Load(conn)
+	if !ok {
+		logging.Debugf("Skipping message to closed connection")
+		return
+	}
+	select {
+	case value.(*connWriter).queue <- data:
+	default:
+		h.dropConnection(conn, errSendQueueFull)
+	}
+}
+
+// writeAll queues an encoded message for each connection in turn
+func (h *WebSocketHandler) writeAll(conns []*websocket.Conn, data []byte) {
+	for _, conn := range conns {
+		h.writeMessage(conn, data)
+	}
+}
+
+var errSendQueueFull = errors.New("too many messages waiting to be sent")
+
+// dropConnection closes a connection that a message couldn't be written or
+// queued to, as its peer is gone or stuck. Its reader then fails at once and
+// cleans up after it, starting the abandonment clock of any game it was
+// playing, instead of the connection lingering until a read times out.
+func (h *WebSocketHandler) dropConnection(conn *websocket.Conn, err error) {
+	// Only the first failure gets to close the connection and report it
+	if conn.Close() != nil {
+		logging.Debugf("Skipping message to dropped connection: %v", err)
+		return
+	}
+	logWriteError(err)
+}
+
+func logWriteError(err error) {
+	// The peer disconnected; the reader cleans up after them
+	if errors.Is(err, websocket.ErrCloseSent) || errors.Is(err, net.ErrClosed) {
+		logging.Debugf("Skipping message to closed connection: %v", err)
+		return
+	}
+	logging.Warnf("Dropping connection after failed write: %v", err)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"chess-ws-go/internal/services"
+
+	"golang.org/x/time/rate"
+)
+
+// wsMessage is a request read from a client WebSocket
+type wsMessage struct {
+	Type    string    `json:"type"`
+	Payload wsPayload `json:"payload"`
+}
+
+type wsPayload struct {
+	Move        string               `json:"move"`
+	GameID      string               `json:"gameId"`
+	Accept      bool                 `json:"accept"`
+	TimeLeft    float64              `json:"timeLeft"`
+	Message     string               `json:"message"`
+	Target      string               `json:"target"`
+	ChallengeID string               `json:"challengeId"`
+	TimeControl services.TimeControl `json:"timeControl"`
+	Version     int                  `json:"version"`
+	Color       string               `json:"color"`
+	Category    string               `json:"category"`
+	Rated       bool                 `json:"rated"`   // Stakes of a rematch
+	Private     bool                 `json:"private"` // Hide a challenge's game from spectators
+	LastSeq     *int                 `json:"lastSeq"` // Newest message a reconnecting client saw; absent for a snapshot
+}
+
+// requiredFields lists the payload fields each message type has to carry.
+// Types missing from the map are rejected as unknown.
+var requiredFields = map[string][]string{
+	"join":               nil,
+	"ping":               nil,
+	"hello":              {"version"},
+	"move":               {"gameId", "move"},
+	"premove":            {"gameId"},
+	"get_state":          {"gameId"},
+	"list_games":         nil,
+	"spectate":           {"gameId"},
+	"unspectate":         {"gameId"},
+	"take_seat":          {"gameId"},
+	"get_board_ascii":    {"gameId"},
+	"resign":             {"gameId"},
+	"draw_offer":         {"gameId"},
+	"draw_response":      {"gameId", "accept"},
+	"time_update":        {"gameId", "timeLeft"},
+	"add_time":           {"gameId"},
+	"chat":               {"gameId", "message"},
+	"reconnect":          {"gameId"},
+	"challenge":          {"target"},
+	"challenge_response": {"challengeId", "accept"},
+	"rematch_offer":      {"gameId", "rated"},
+	"rematch_response":   {"gameId", "accept", "rated"},
+}
+
+// guestName is how anonymous spectators appear in logs
+const guestName = "guest"
+
+// guestMessages lists the message types anonymous spectators may send.
+// None of them changes a game.
+var guestMessages = map[string]bool{
+	"ping":            true,
+	"hello":           true,
+	"spectate":        true,
+	"unspectate":      true,
+	"get_board_ascii": true,
+}
+
+// Reasons a client message is rejected, as reported to metrics
+const (
+	rejectUnknownType = "unknown_type" // Message type the protocol doesn't define
+	rejectInvalid     = "invalid"      // Malformed JSON or a bad or missing payload field
+	rejectNotText     = "not_text"     // Binary frames carry no messages
+)
+
+// rejectionReason classifies an error returned by decodeMessage
+func rejectionReason(err error) string {
+	var unknown *unknownTypeError
+	if errors.As(err, &unknown) {
+		return rejectUnknownType
+	}
+	return rejectInvalid
+}
+
+// Replies to unknown message types are rate limited per connection, so a
+// client probing for message types can't make the server do the talking
+const (
+	unknownTypeReplyRate  = rate.Limit(1) // Replies per second once the burst is used up
+	unknownTypeReplyBurst = 10
+)
+
+// unknownTypeError is returned for a message whose type the protocol doesn't define
+type unknownTypeError struct {
+	Type string
+}
+
+func (e *unknownTypeError) Error() string {
+	return fmt.Sprintf("unknown message type %q", e.Type)
+}
+
+// invalidMessageError describes why a client message was rejected
+type invalidMessageError struct {
+	Field  string // Path of the offending field, empty when the whole message is bad
+	Reason string
+}
+
+func (e *invalidMessageError) Error() string {
+	if e.Field == "" {
+		return "invalid message: " + e.Reason
+	}
+	return fmt.Sprintf("invalid message: %s %s", e.Field, e.Reason)
+}
+
+// decodeMessage parses and validates a client message. Strict mode also
+// rejects fields the protocol doesn't define.
+func decodeMessage(data []byte, strict bool) (wsMessage, error) {
+	var message wsMessage
+
+	var envelope struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := unmarshal(data, &envelope, strict); err != nil {
+		return message, describeDecodeError("", err)
+	}
+
+	if envelope.Type == "" {
+		return message, &invalidMessageError{Field: "type", Reason: "is required"}
+	}
+	required, known := requiredFields[envelope.Type]
+	if !known {
+		return message, &unknownTypeError{Type: envelope.Type}
+	}
+	message.Type = envelope.Type
+
+	present := map[string]json.RawMessage{}
+	if len(envelope.Payload) > 0 && !bytes.Equal(envelope.Payload, []byte("null")) {
+		if err := json.Unmarshal(envelope.Payload, &present); err != nil {
+			return message, &invalidMessageError{Field: "payload", Reason: "must be an object"}
+		}
+		if err := unmarshal(envelope.Payload, &message.Payload, strict); err != nil {
+			return message, describeDecodeError("payload", err)
+		}
+	}
+
+	for _, field := range required {
+		if value, ok := present[field]; !ok || bytes.Equal(value, []byte("null")) {
+			return message, &invalidMessageError{Field: "payload." + field, Reason: "is required"}
+		}
+	}
+
+	return message, nil
+}
+
+func unmarshal(data []byte, v interface{}, strict bool) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(v)
+}
+
+// describeDecodeError turns a JSON decoding error into an invalidMessageError
+// naming the field at fault where it can be told
+func describeDecodeError(prefix string, err error) error {
+	qualify := func(field string) string {
+		if prefix == "" {
+			return field
+		}
+		return prefix + "." + field
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		field := prefix
+		if typeErr.Field != "" {
+			field = qualify(typeErr.Field)
+		}
+		return &invalidMessageError{Field: field, Reason: "must be " + jsonKind(typeErr.Type.Kind())}
+	}
+
+	// The decoder reports unknown fields only through its message
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &invalidMessageError{Field: qualify(strings.Trim(field, `"`)), Reason: "is not a known field"}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &invalidMessageError{Reason: fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)}
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return &invalidMessageError{Reason: "malformed JSON: unexpected end of input"}
+	}
+	return &invalidMessageError{Field: prefix, Reason: err.Error()}
+}
+
+// jsonKind names a Go kind the way a client author would think of it
+func jsonKind(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,107 @@
+// Package logging provides leveled logging on top of the standard log
+// package, with optional sampling for high-volume request logs.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the minimum severity of messages that are emitted
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var (
+	currentLevel atomic.Int32
+	sampleRate   atomic.Uint64 // Sample rate scaled to [0, sampleScale]
+)
+
+const sampleScale = 1_000_000
+
+func init() {
+	currentLevel.Store(int32(LevelInfo))
+	sampleRate.Store(sampleScale)
+}
+
+// ParseLevel converts a level name (debug, info, warn, error) into a Level
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level %q", name)
+	}
+}
+
+// SetLevel sets the minimum level for debug and info messages.
+// Warnings and errors are always emitted.
+func SetLevel(level Level) {
+	currentLevel.Store(int32(level))
+}
+
+// SetSampleRate sets the fraction (0 to 1) of sampled messages that are kept
+func SetSampleRate(rate float64) {
+	if rate < 0 {
+		rate = 0
+	}
+	if rate > 1 {
+		rate = 1
+	}
+	sampleRate.Store(uint64(rate * sampleScale))
+}
+
+// Sampled reports whether a high-volume message should be logged this time
+func Sampled() bool {
+	rate := sampleRate.Load()
+	if rate >= sampleScale {
+		return true
+	}
+	return uint64(rand.Intn(sampleScale)) < rate
+}
+
+// Enabled reports whether messages at the given level are emitted
+func Enabled(level Level) bool {
+	return level >= LevelWarn || level >= Level(currentLevel.Load())
+}
+
+// Debugf logs a debug message
+func Debugf(format string, args ...interface{}) {
+	logf(LevelDebug, "DEBUG", format, args...)
+}
+
+// Infof logs an informational message
+func Infof(format string, args ...interface{}) {
+	logf(LevelInfo, "INFO", format, args...)
+}
+
+// Warnf logs a warning, regardless of the configured level
+func Warnf(format string, args ...interface{}) {
+	logf(LevelWarn, "WARN", format, args...)
+}
+
+// Errorf logs an error, regardless of the configured level
+func Errorf(format string, args ...interface{}) {
+	logf(LevelError, "ERROR", format, args...)
+}
+
+func logf(level Level, prefix string, format string, args ...interface{}) {
+	if !Enabled(level) {
+		return
+	}
+	log.Printf("["+prefix+"] "+format, args...)
+}
@@ -41,23 +41,36 @@ func (rl *AuthRateLimiter) getLimiter(ip string) *rate.Limiter {
 	return limiter
 }
 
-// AuthMiddleware creates a middleware for authentication
-func AuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
+// AuthMiddleware creates a middleware for authentication. Requests from
+// addresses on the filter's deny list are rejected before anything else and
+// allowlisted addresses aren't rate limited; ipFilter may be nil.
+func AuthMiddleware(cfg *config.JWTConfig, ipFilter *IPFilter) gin.HandlerFunc {
 	jwtMaker := auth.NewJWTMaker(cfg.SecretKey)
 	rateLimiter := NewAuthRateLimiter(rate.Every(1*time.Minute), 5) // 5 attempts per minute
 
 	return func(c *gin.Context) {
-		// Rate limiting check
-		limiter := rateLimiter.getLimiter(c.ClientIP())
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "too many failed authentication attempts",
+		clientIP := c.ClientIP()
+		if ipFilter.Denied(clientIP) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
 			})
 			c.Abort()
 			return
 		}
 
-		token := extractToken(c)
+		// Rate limiting check, skipped for allowlisted internal tools
+		if !ipFilter.Allowed(clientIP) {
+			if ok, retryAfter := allow(rateLimiter.getLimiter(clientIP)); !ok {
+				setRetryAfter(c, retryAfter)
+				c.JSON(http.StatusTooManyRequests, gin.H{
+					"error": "too many failed authentication attempts",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		token := extractToken(c, cfg.AllowQueryToken)
 		if token == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "no authorization token provided",
@@ -90,8 +103,9 @@ func AuthMiddleware(cfg *config.JWTConfig) gin.HandlerFunc {
 	}
 }
 
-// extractToken extracts the JWT token from various sources
-func extractToken(c *gin.Context) string {
+// extractToken extracts the JWT token from various sources.
+// The query parameter source is only consulted when allowQuery is set.
+func extractToken(c *gin.Context, allowQuery bool) string {
 	// 1. Try Authorization header
 	authHeader := c.GetHeader("Authorization")
 	if authHeader != "" {
@@ -115,9 +129,11 @@ func extractToken(c *gin.Context) string {
 	}
 
 	// 3. Try query parameter (less secure, but sometimes necessary for WebSocket)
-	token := c.Query("token")
-	if token != "" {
-		return token
+	if allowQuery {
+		token := c.Query("token")
+		if token != "" {
+			return token
+		}
 	}
 
 	return ""
@@ -5,25 +5,24 @@ import (
 	"strings"
 )
 
+// OriginAllowed reports whether an origin is in a comma-separated allowlist,
+// where * allows every origin
+func OriginAllowed(allowedOrigins string, origin string) bool {
+	for _, allowedOrigin := range strings.Split(allowedOrigins, ",") {
+		if allowedOrigin == "*" || allowedOrigin == origin {
+			return true
+		}
+	}
+	return false
+}
+
 // CorsMiddleware creates a middleware that handles CORS
 func CorsMiddleware(allowedOrigins string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 
-			// Split the allowed origins string into a slice
-			origins := strings.Split(allowedOrigins, ",")
-
-			// Check if the request origin is allowed
-			allowed := false
-			for _, allowedOrigin := range origins {
-				if allowedOrigin == "*" || allowedOrigin == origin {
-					allowed = true
-					break
-				}
-			}
-
-			if allowed {
+			if OriginAllowed(allowedOrigins, origin) {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
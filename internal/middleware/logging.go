@@ -1,9 +1,11 @@
 package middleware
 
 import (
+	"chess-ws-go/internal/logging"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
@@ -36,20 +38,50 @@ func (rw *responseWriter) Write(buf []byte) (int, error) {
 	return n, err
 }
 
-// LoggingMiddleware wraps an http.Handler and logs request information
-func LoggingMiddleware(next http.Handler) http.Handler {
+// redactQuery replaces the values of sensitive query parameters with REDACTED,
+// keeping the rest of the query string intact
+func redactQuery(rawQuery string, redacted map[string]bool) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil {
+			key = name
+		}
+		if redacted[strings.ToLower(key)] {
+			pairs[i] = key + "=REDACTED"
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// LoggingMiddleware creates a middleware that logs request information.
+// Values of the given query parameters are redacted, and headers such as
+// Authorization are never logged. Forwarded client addresses are only
+// believed from trusted proxies.
+func LoggingMiddleware(redactedParams []string, trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	redacted := make(map[string]bool, len(redactedParams))
+	for _, param := range redactedParams {
+		redacted[strings.ToLower(strings.TrimSpace(param))] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return loggingHandler(next, redacted, trustedProxies)
+	}
+}
+
+// loggingHandler wraps an http.Handler and logs request information
+func loggingHandler(next http.Handler, redacted map[string]bool, trustedProxies []*net.IPNet) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		wrapped := wrapResponseWriter(w)
 
-		// Get real IP considering forwarded headers
-		realIP := r.Header.Get("X-Real-IP")
-		if realIP == "" {
-			realIP = r.Header.Get("X-Forwarded-For")
-			if realIP == "" {
-				realIP = r.RemoteAddr
-			}
-		}
+		// Get real IP considering forwarded headers from trusted proxies
+		realIP := RealIP(r, trustedProxies)
 
 		// Process request
 		next.ServeHTTP(wrapped, r)
@@ -63,6 +95,12 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 			userAgent = userAgent[:100] + "..."
 		}
 
+		// Include the query string with sensitive values scrubbed
+		path := r.URL.Path
+		if query := redactQuery(r.URL.RawQuery, redacted); query != "" {
+			path += "?" + query
+		}
+
 		// Log entry
 		logEntry := fmt.Sprintf(
 			"[%s] %s - %s \"%s %s %s\" %d %d \"%s\" \"%s\" (took: %v)",
@@ -70,7 +108,7 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 			realIP,
 			r.Host,
 			r.Method,
-			r.URL.Path,
+			path,
 			r.Proto,
 			wrapped.status,
 			wrapped.size,
@@ -79,6 +117,11 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 			duration,
 		)
 
-		log.Println(logEntry)
+		// Server errors are always logged; other requests honour level and sampling
+		if wrapped.status >= http.StatusInternalServerError {
+			logging.Errorf("%s", logEntry)
+		} else if logging.Enabled(logging.LevelInfo) && logging.Sampled() {
+			logging.Infof("%s", logEntry)
+		}
 	})
 }
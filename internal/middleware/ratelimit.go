@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit limits each authenticated user, or client IP for anonymous
+// requests, to r requests per second with bursts of up to b
+func RateLimit(r rate.Limit, b int) gin.HandlerFunc {
+	rateLimiter := NewAuthRateLimiter(r, b)
+
+	return func(c *gin.Context) {
+		key := c.GetString("user_id")
+		if key == "" {
+			key = c.ClientIP()
+		}
+
+		if ok, retryAfter := allow(rateLimiter.getLimiter(key)); !ok {
+			setRetryAfter(c, retryAfter)
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "too many requests",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// allow takes a token from the limiter if one is available. Otherwise it
+// returns how long until one will be, without consuming anything, so
+// rejected requests don't push the window further out.
+func allow(limiter *rate.Limiter) (bool, time.Duration) {
+	reservation := limiter.Reserve()
+	if !reservation.OK() {
+		return false, 0
+	}
+
+	delay := reservation.Delay()
+	if delay > 0 {
+		reservation.Cancel()
+		return false, delay
+	}
+	return true, 0
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, rounded up
+func setRetryAfter(c *gin.Context, delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+}
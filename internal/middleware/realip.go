@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RealIP returns the client address of a request. Forwarding headers are
+// only honored when the immediate peer is one of the trusted proxies, so
+// clients connecting directly can't spoof their address. X-Forwarded-For is
+// read from the right, skipping trusted proxies, since only the hops added
+// by our own proxies can be believed.
+func RealIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+
+	if !contains(trustedProxies, peer) {
+		return peer
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if i == 0 || !contains(trustedProxies, hop) {
+				return hop
+			}
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+
+	return peer
+}
+
+// TrustProxies makes gin's ClientIP, used for rate limiting and auditing,
+// follow the same trusted proxy rules as RealIP
+func TrustProxies(router *gin.Engine, trustedProxies []*net.IPNet) error {
+	proxies := make([]string, len(trustedProxies))
+	for i, proxy := range trustedProxies {
+		proxies[i] = proxy.String()
+	}
+	return router.SetTrustedProxies(proxies)
+}
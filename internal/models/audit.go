@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// AuditEntry records a security-sensitive action
+type AuditEntry struct {
+	ID        string    `json:"id" db:"id"`
+	ActorID   string    `json:"actor_id" db:"actor_id"`
+	Action    string    `json:"action" db:"action"`
+	TargetID  string    `json:"target_id" db:"target_id"`
+	IP        string    `json:"ip" db:"ip"`
+	Details   string    `json:"details" db:"details"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// FriendshipStatus represents the state of a friendship between two users
+type FriendshipStatus string
+
+const (
+	FriendshipPending  FriendshipStatus = "pending"
+	FriendshipAccepted FriendshipStatus = "accepted"
+)
+
+// Friendship represents a friend request or an established friendship
+type Friendship struct {
+	RequesterID string           `json:"requester_id" db:"requester_id"`
+	AddresseeID string           `json:"addressee_id" db:"addressee_id"`
+	Status      FriendshipStatus `json:"status" db:"status"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// Friend represents the other user in a friendship
+type Friend struct {
+	UserID      string    `json:"user_id" db:"user_id"`
+	Username    string    `json:"username" db:"username"`
+	DisplayName string    `json:"display_name" db:"display_name"`
+	EloRating   int       `json:"elo_rating" db:"elo_rating"`
+	Since       time.Time `json:"since" db:"since"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// GameRecord represents a finished game persisted to the database
+type GameRecord struct {
+	ID             string    `json:"id" db:"id"`
+	WhiteID        string    `json:"white_id" db:"white_id"`
+	BlackID        string    `json:"black_id" db:"black_id"`
+	WhiteRating    int       `json:"white_rating" db:"white_rating"`
+	BlackRating    int       `json:"black_rating" db:"black_rating"`
+	InitialTime    float64   `json:"initial_time" db:"initial_time"`
+	Increment      float64   `json:"increment" db:"increment"`
+	BlackInitial   *float64  `json:"black_initial,omitempty" db:"black_initial_time"` // Set for games played at time odds
+	BlackIncrement *float64  `json:"black_increment,omitempty" db:"black_increment"`
+	FEN            string    `json:"fen" db:"fen"`
+	PGN            string    `json:"pgn" db:"pgn"`
+	Outcome        string    `json:"outcome" db:"outcome"`
+	Method         string    `json:"method" db:"method"`
+	IsPrivate      bool      `json:"is_private" db:"is_private"`
+	Events         string    `json:"-" db:"events"` // JSON event log; served separately to players and admins
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	EndedAt        time.Time `json:"ended_at" db:"ended_at"`
+}
+
+// CorrespondenceGame is a correspondence game in progress, persisted after
+// every move so it survives a server restart
+type CorrespondenceGame struct {
+	ID           string    `json:"id" db:"id"`
+	WhiteID      string    `json:"white_id" db:"white_id"`
+	BlackID      string    `json:"black_id" db:"black_id"`
+	DaysPerMove  int       `json:"days_per_move" db:"days_per_move"`
+	PGN          string    `json:"pgn" db:"pgn"`
+	MoveDeadline time.Time `json:"move_deadline" db:"move_deadline"`
+	IsPrivate    bool      `json:"is_private" db:"is_private"`
+	Casual       bool      `json:"casual" db:"casual"`
+	Events       string    `json:"-" db:"events"` // JSON event log
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// HeadToHead is one user's record against another in finished games
+type HeadToHead struct {
+	Wins   int `json:"wins" db:"wins"`
+	Losses int `json:"losses" db:"losses"`
+	Draws  int `json:"draws" db:"draws"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Puzzle is a tactics puzzle. FEN is the position with the solver to move,
+// and Solution is the expected line in standard algebraic notation separated
+// by spaces, alternating the solver's moves and the opponent's replies.
+type Puzzle struct {
+	ID        string    `json:"id" db:"id"`
+	FEN       string    `json:"fen" db:"fen"`
+	Solution  string    `json:"-" db:"solution"`
+	Rating    int       `json:"rating" db:"rating"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -14,13 +14,38 @@ type User struct {
 	Role         auth.Role `json:"role" db:"role"`
 	DisplayName  string    `json:"display_name" db:"display_name"`
 
+	// Profile image. AvatarKey locates the stored files so they can be
+	// removed when the avatar is replaced.
+	AvatarURL          string `json:"avatar_url,omitempty" db:"avatar_url"`
+	AvatarThumbnailURL string `json:"avatar_thumbnail_url,omitempty" db:"avatar_thumbnail_url"`
+	AvatarKey          string `json:"-" db:"avatar_key"`
+
 	// Account status
-	IsVerified         bool   `json:"is_verified" db:"is_verified"`
-	VerificationToken  string `json:"-" db:"verification_token"`
-	PasswordResetToken string `json:"-" db:"password_reset_token"`
+	IsVerified                  bool       `json:"is_verified" db:"is_verified"`
+	VerificationToken           string     `json:"-" db:"verification_token"`
+	VerificationTokenExpiresAt  *time.Time `json:"-" db:"verification_token_expires_at"`
+	PasswordResetToken          string     `json:"-" db:"password_reset_token"`
+	PasswordResetTokenExpiresAt *time.Time `json:"-" db:"password_reset_token_expires_at"`
+
+	// Email change awaiting confirmation; Email stays in use until then
+	PendingEmail              string     `json:"pending_email,omitempty" db:"pending_email"`
+	EmailChangeToken          string     `json:"-" db:"email_change_token"`
+	EmailChangeTokenExpiresAt *time.Time `json:"-" db:"email_change_token_expires_at"`
+
+	// Chess stats. EloRating is the overall rating across all games; the
+	// category ratings only move with games of that speed.
+	EloRating    int `json:"elo_rating" db:"elo_rating"`
+	BulletRating int `json:"bullet_rating" db:"bullet_rating"`
+	BlitzRating  int `json:"blitz_rating" db:"blitz_rating"`
+	RapidRating  int `json:"rapid_rating" db:"rapid_rating"`
+	PuzzleRating int `json:"puzzle_rating" db:"puzzle_rating"`
+
+	// Rated games left before the user's ratings are established. Until
+	// then they move faster and leave opponents' ratings alone.
+	PlacementGamesRemaining int `json:"placement_games_remaining" db:"placement_games_remaining"`
 
-	// Chess stats
-	EloRating int `json:"elo_rating" db:"elo_rating"`
+	// Optional emails the user has chosen to receive
+	NotificationPreferences `json:"notifications"`
 
 	// Security
 	FailedLoginAttempts int        `json:"-" db:"failed_login_attempts"`
@@ -29,6 +54,98 @@ type User struct {
 	// Timestamps
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// Version is bumped on every update for optimistic locking
+	Version int `json:"-" db:"version"`
+}
+
+// NotificationPreferences records which optional emails a user receives.
+// Emails needed to use the account, such as verification and password reset
+// links, are always sent.
+type NotificationPreferences struct {
+	GameInvites    bool `json:"game_invites" db:"notify_game_invites"`
+	GameSummaries  bool `json:"game_summaries" db:"notify_game_summaries"`
+	SecurityAlerts bool `json:"security_alerts" db:"notify_security_alerts"`
+}
+
+// DefaultNotificationPreferences are the preferences of new users: they
+// hear about invites and suspicious sign-ins, but not about every game
+// they finish
+var DefaultNotificationPreferences = NotificationPreferences{
+	GameInvites:    true,
+	GameSummaries:  false,
+	SecurityAlerts: true,
+}
+
+// DefaultRating is the starting rating of new users in every category
+var DefaultRating = 1200
+
+// RatingFloor is the lowest a rating can fall through losses. A rating
+// already below it, say because the floor was raised, doesn't fall further.
+var RatingFloor = 100
+
+// DeflationBonus is added to a win by a player rated below DefaultRating, to
+// offset the points that drain out of the pool as players leave
+var DeflationBonus = 0
+
+// PlacementGames is how many rated games new users play before their
+// ratings are established
+var PlacementGames = 5
+
+// PlacementKFactor is the Elo K-factor of players still in placement, so
+// their ratings find their level in a few games
+var PlacementKFactor = 64
+
+// RatingCategory groups games by speed so each speed is rated separately
+type RatingCategory string
+
+const (
+	RatingBullet RatingCategory = "bullet"
+	RatingBlitz  RatingCategory = "blitz"
+	RatingRapid  RatingCategory = "rapid"
+)
+
+// RatingCategoryFor classifies a time control by its estimated duration
+// per player (initial time plus 40 moves of increment, in seconds)
+func RatingCategoryFor(initial, increment float64) RatingCategory {
+	estimated := initial + 40*increment
+	switch {
+	case estimated < 180:
+		return RatingBullet
+	case estimated < 480:
+		return RatingBlitz
+	default:
+		return RatingRapid
+	}
+}
+
+// Rating returns the user's rating in the given category
+func (u *User) Rating(category RatingCategory) int {
+	switch category {
+	case RatingBullet:
+		return u.BulletRating
+	case RatingBlitz:
+		return u.BlitzRating
+	default:
+		return u.RapidRating
+	}
+}
+
+// SetRating sets the user's rating in the given category
+func (u *User) SetRating(category RatingCategory, rating int) {
+	switch category {
+	case RatingBullet:
+		u.BulletRating = rating
+	case RatingBlitz:
+		u.BlitzRating = rating
+	default:
+		u.RapidRating = rating
+	}
+}
+
+// Provisional reports whether the user is still playing placement games
+func (u *User) Provisional() bool {
+	return u.PlacementGamesRemaining > 0
 }
 
 // UserPermission represents a permission assigned to a user
@@ -42,6 +159,9 @@ type RefreshToken struct {
 	ID        string    `db:"id"`
 	UserID    string    `db:"user_id"`
 	Token     string    `db:"token"`
+	Label     string    `db:"label"`      // Device the session was started from
+	IPAddress string    `db:"ip_address"` // Client IP at login
+	UserAgent string    `db:"user_agent"` // Client user agent at login
 	ExpiresAt time.Time `db:"expires_at"`
 	CreatedAt time.Time `db:"created_at"`
 }
@@ -50,16 +170,23 @@ type RefreshToken struct {
 func NewUser(id, username, email, passwordHash string) *User {
 	now := time.Now()
 	return &User{
-		ID:                  id,
-		Username:            username,
-		Email:               email,
-		PasswordHash:        passwordHash,
-		Role:                auth.RolePlayer, // Default role
-		DisplayName:         username,        // Default to username
-		IsVerified:          false,           // Requires verification
-		EloRating:           1200,            // Default ELO rating
-		FailedLoginAttempts: 0,
-		CreatedAt:           now,
-		UpdatedAt:           now,
+		ID:                      id,
+		Username:                username,
+		Email:                   email,
+		PasswordHash:            passwordHash,
+		Role:                    auth.RolePlayer, // Default role
+		DisplayName:             username,        // Default to username
+		IsVerified:              false,           // Requires verification
+		EloRating:               DefaultRating,   // Default ELO rating
+		BulletRating:            DefaultRating,
+		BlitzRating:             DefaultRating,
+		RapidRating:             DefaultRating,
+		PuzzleRating:            DefaultRating,
+		PlacementGamesRemaining: PlacementGames,
+		FailedLoginAttempts:     0,
+		CreatedAt:               now,
+		UpdatedAt:               now,
+
+		NotificationPreferences: DefaultNotificationPreferences,
 	}
 }
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"chess-ws-go/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// AuditFilter narrows down audit log queries. Empty fields are ignored.
+type AuditFilter struct {
+	ActorID  string
+	Action   string
+	TargetID string
+	Since    *time.Time
+	Until    *time.Time
+	Limit    int
+}
+
+// AuditRepository defines the interface for audit log data access
+type AuditRepository interface {
+	Create(ctx context.Context, entry *models.AuditEntry) error
+	List(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error)
+}
+
+// SQLAuditRepository implements AuditRepository using SQL database
+type SQLAuditRepository struct {
+	db *sqlx.DB
+}
+
+// NewSQLAuditRepository creates a new SQL-based audit repository
+func NewSQLAuditRepository(db *sqlx.DB) AuditRepository {
+	return &SQLAuditRepository{db: db}
+}
+
+// Create adds a new entry to the audit log
+func (r *SQLAuditRepository) Create(ctx context.Context, entry *models.AuditEntry) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// Generate UUID if not provided
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+
+	// Set created timestamp if not provided
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO audit_log (id, actor_id, action, target_id, ip, details, created_at)
+		VALUES (:id, :actor_id, :action, :target_id, :ip, :details, :created_at)
+	`
+
+	_, err := r.db.NamedExecContext(ctx, query, entry)
+	return err
+}
+
+// List retrieves audit entries matching the filter, newest first
+func (r *SQLAuditRepository) List(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	entries := []*models.AuditEntry{}
+
+	conditions := []string{}
+	args := []interface{}{}
+
+	addCondition := func(clause string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filter.ActorID != "" {
+		addCondition("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		addCondition("action = $%d", filter.Action)
+	}
+	if filter.TargetID != "" {
+		addCondition("target_id = $%d", filter.TargetID)
+	}
+	if filter.Since != nil {
+		addCondition("created_at >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		addCondition("created_at < $%d", *filter.Until)
+	}
+
+	query := `
+		SELECT id, COALESCE(actor_id, '') AS actor_id, action,
+			COALESCE(target_id, '') AS target_id, COALESCE(ip, '') AS ip,
+			COALESCE(details, '') AS details, created_at
+		FROM audit_log
+	`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC LIMIT $%d`, len(args))
+
+	err := r.db.SelectContext(ctx, &entries, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"chess-ws-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CorrespondenceRepository defines the interface for access to correspondence
+// games in progress
+type CorrespondenceRepository interface {
+	Save(ctx context.Context, game *models.CorrespondenceGame) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]*models.CorrespondenceGame, error)
+}
+
+// SQLCorrespondenceRepository implements CorrespondenceRepository using SQL database
+type SQLCorrespondenceRepository struct {
+	db *sqlx.DB
+}
+
+// NewSQLCorrespondenceRepository creates a new SQL-based correspondence game repository
+func NewSQLCorrespondenceRepository(db *sqlx.DB) CorrespondenceRepository {
+	return &SQLCorrespondenceRepository{db: db}
+}
+
+// Save stores a correspondence game, replacing what was stored for it before
+func (r *SQLCorrespondenceRepository) Save(ctx context.Context, game *models.CorrespondenceGame) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	game.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO correspondence_games (
+			id, white_id, black_id, days_per_move, pgn, move_deadline,
+			is_private, casual, events, created_at, updated_at
+		) VALUES (
+			:id, :white_id, :black_id, :days_per_move, :pgn, :move_deadline,
+			:is_private, :casual, :events, :created_at, :updated_at
+		)
+		ON CONFLICT (id) DO UPDATE SET
+			white_id = EXCLUDED.white_id,
+			black_id = EXCLUDED.black_id,
+			pgn = EXCLUDED.pgn,
+			move_deadline = EXCLUDED.move_deadline,
+			is_private = EXCLUDED.is_private,
+			casual = EXCLUDED.casual,
+			events = EXCLUDED.events,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.db.NamedExecContext(ctx, query, game)
+	return err
+}
+
+// Delete removes a correspondence game, once it's over. Deleting a game that
+// isn't stored is not an error.
+func (r *SQLCorrespondenceRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM correspondence_games WHERE id = $1`, id)
+	return err
+}
+
+// List retrieves every stored correspondence game
+func (r *SQLCorrespondenceRepository) List(ctx context.Context) ([]*models.CorrespondenceGame, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	games := []*models.CorrespondenceGame{}
+
+	query := `
+		SELECT * FROM correspondence_games
+		ORDER BY created_at
+	`
+
+	err := r.db.SelectContext(ctx, &games, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return games, nil
+}
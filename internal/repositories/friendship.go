@@ -0,0 +1,202 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"chess-ws-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrFriendshipNotFound = errors.New("friendship not found")
+	ErrFriendshipExists   = errors.New("friendship already exists")
+)
+
+// FriendshipRepository defines the interface for friendship data access
+type FriendshipRepository interface {
+	CreateRequest(ctx context.Context, requesterID string, addresseeID string) error
+	Get(ctx context.Context, userID string, otherID string) (*models.Friendship, error)
+	Accept(ctx context.Context, requesterID string, addresseeID string) error
+	Delete(ctx context.Context, userID string, otherID string) error
+	ListFriends(ctx context.Context, userID string) ([]*models.Friend, error)
+	ListPendingRequests(ctx context.Context, userID string) ([]*models.Friend, error)
+}
+
+// SQLFriendshipRepository implements FriendshipRepository using SQL database
+type SQLFriendshipRepository struct {
+	db *sqlx.DB
+}
+
+// NewSQLFriendshipRepository creates a new SQL-based friendship repository
+func NewSQLFriendshipRepository(db *sqlx.DB) FriendshipRepository {
+	return &SQLFriendshipRepository{db: db}
+}
+
+// CreateRequest records a pending friend request from requester to addressee
+func (r *SQLFriendshipRepository) CreateRequest(ctx context.Context, requesterID string, addresseeID string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	now := time.Now()
+
+	query := `
+		INSERT INTO friendships (requester_id, addressee_id, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		ON CONFLICT (requester_id, addressee_id) DO NOTHING
+	`
+
+	result, err := r.db.ExecContext(ctx, query, requesterID, addresseeID, models.FriendshipPending, now)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrFriendshipExists
+	}
+
+	return nil
+}
+
+// Get retrieves the friendship between two users in either direction
+func (r *SQLFriendshipRepository) Get(ctx context.Context, userID string, otherID string) (*models.Friendship, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var friendship models.Friendship
+
+	query := `
+		SELECT * FROM friendships
+		WHERE (requester_id = $1 AND addressee_id = $2)
+		   OR (requester_id = $2 AND addressee_id = $1)
+	`
+
+	err := r.db.GetContext(ctx, &friendship, query, userID, otherID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrFriendshipNotFound
+		}
+		return nil, err
+	}
+
+	return &friendship, nil
+}
+
+// Accept marks a pending friend request as accepted
+func (r *SQLFriendshipRepository) Accept(ctx context.Context, requesterID string, addresseeID string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE friendships SET
+			status = $3,
+			updated_at = $4
+		WHERE requester_id = $1 AND addressee_id = $2 AND status = $5
+	`
+
+	result, err := r.db.ExecContext(
+		ctx,
+		query,
+		requesterID,
+		addresseeID,
+		models.FriendshipAccepted,
+		time.Now(),
+		models.FriendshipPending,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrFriendshipNotFound
+	}
+
+	return nil
+}
+
+// Delete removes the friendship between two users in either direction
+func (r *SQLFriendshipRepository) Delete(ctx context.Context, userID string, otherID string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		DELETE FROM friendships
+		WHERE (requester_id = $1 AND addressee_id = $2)
+		   OR (requester_id = $2 AND addressee_id = $1)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, otherID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrFriendshipNotFound
+	}
+
+	return nil
+}
+
+// ListFriends retrieves all accepted friends of a user
+func (r *SQLFriendshipRepository) ListFriends(ctx context.Context, userID string) ([]*models.Friend, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	friends := []*models.Friend{}
+
+	query := `
+		SELECT u.id AS user_id, u.username, u.display_name, u.elo_rating, f.updated_at AS since
+		FROM friendships f
+		JOIN users u ON u.id = CASE WHEN f.requester_id = $1 THEN f.addressee_id ELSE f.requester_id END
+		WHERE (f.requester_id = $1 OR f.addressee_id = $1) AND f.status = $2
+		ORDER BY u.username
+	`
+
+	err := r.db.SelectContext(ctx, &friends, query, userID, models.FriendshipAccepted)
+	if err != nil {
+		return nil, err
+	}
+
+	return friends, nil
+}
+
+// ListPendingRequests retrieves the users who sent a user a friend request that is still pending
+func (r *SQLFriendshipRepository) ListPendingRequests(ctx context.Context, userID string) ([]*models.Friend, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	requests := []*models.Friend{}
+
+	query := `
+		SELECT u.id AS user_id, u.username, u.display_name, u.elo_rating, f.created_at AS since
+		FROM friendships f
+		JOIN users u ON u.id = f.requester_id
+		WHERE f.addressee_id = $1 AND f.status = $2
+		ORDER BY f.created_at
+	`
+
+	err := r.db.SelectContext(ctx, &requests, query, userID, models.FriendshipPending)
+	if err != nil {
+		return nil, err
+	}
+
+	return requests, nil
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"chess-ws-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrGameNotFound = errors.New("game not found")
+)
+
+// GameRepository defines the interface for finished game data access
+type GameRepository interface {
+	Create(ctx context.Context, game *models.GameRecord) error
+	GetByID(ctx context.Context, id string) (*models.GameRecord, error)
+	ListByPlayer(ctx context.Context, userID string, limit int) ([]*models.GameRecord, error)
+	ListBetween(ctx context.Context, userID string, opponentID string, includePrivate bool, limit int) ([]*models.GameRecord, error)
+	CountBetween(ctx context.Context, userID string, opponentID string, includePrivate bool) (*models.HeadToHead, error)
+}
+
+// SQLGameRepository implements GameRepository using SQL database
+type SQLGameRepository struct {
+	db *sqlx.DB
+}
+
+// NewSQLGameRepository creates a new SQL-based game repository
+func NewSQLGameRepository(db *sqlx.DB) GameRepository {
+	return &SQLGameRepository{db: db}
+}
+
+// Create stores a finished game
+func (r *SQLGameRepository) Create(ctx context.Context, game *models.GameRecord) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO games (
+			id, white_id, black_id, white_rating, black_rating,
+			initial_time, increment, black_initial_time, black_increment,
+			fen, pgn, outcome, method, is_private, events, created_at, ended_at
+		) VALUES (
+			:id, :white_id, :black_id, :white_rating, :black_rating,
+			:initial_time, :increment, :black_initial_time, :black_increment,
+			:fen, :pgn, :outcome, :method, :is_private, :events, :created_at, :ended_at
+		)
+	`
+
+	_, err := r.db.NamedExecContext(ctx, query, game)
+	return err
+}
+
+// GetByID retrieves a finished game by ID
+func (r *SQLGameRepository) GetByID(ctx context.Context, id string) (*models.GameRecord, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var game models.GameRecord
+
+	query := `
+		SELECT * FROM games
+		WHERE id = $1
+	`
+
+	err := r.db.GetContext(ctx, &game, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrGameNotFound
+		}
+		return nil, err
+	}
+
+	return &game, nil
+}
+
+// ListByPlayer retrieves the finished games a user played either side of,
+// most recently ended first
+func (r *SQLGameRepository) ListByPlayer(ctx context.Context, userID string, limit int) ([]*models.GameRecord, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	games := []*models.GameRecord{}
+
+	query := `
+		SELECT * FROM games
+		WHERE white_id = $1 OR black_id = $1
+		ORDER BY ended_at DESC
+		LIMIT $2
+	`
+
+	err := r.db.SelectContext(ctx, &games, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return games, nil
+}
+
+// ListBetween retrieves the finished games two users played against each
+// other, most recently ended first. Private games are left out unless
+// includePrivate is set, which only a request by one of the two may do.
+func (r *SQLGameRepository) ListBetween(ctx context.Context, userID string, opponentID string, includePrivate bool, limit int) ([]*models.GameRecord, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	games := []*models.GameRecord{}
+
+	query := `
+		SELECT * FROM games
+		WHERE ((white_id = $1 AND black_id = $2) OR (white_id = $2 AND black_id = $1))
+			AND (is_private = FALSE OR $3)
+		ORDER BY ended_at DESC
+		LIMIT $4
+	`
+
+	err := r.db.SelectContext(ctx, &games, query, userID, opponentID, includePrivate, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return games, nil
+}
+
+// CountBetween tallies the results of the finished games two users played
+// against each other, from the first user's side. Games without a result,
+// such as aborted ones, aren't counted, nor are private games unless
+// includePrivate is set.
+func (r *SQLGameRepository) CountBetween(ctx context.Context, userID string, opponentID string, includePrivate bool) (*models.HeadToHead, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var record models.HeadToHead
+
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE (white_id = $1 AND outcome = '1-0') OR (black_id = $1 AND outcome = '0-1')) AS wins,
+			COUNT(*) FILTER (WHERE (white_id = $1 AND outcome = '0-1') OR (black_id = $1 AND outcome = '1-0')) AS losses,
+			COUNT(*) FILTER (WHERE outcome = '1/2-1/2') AS draws
+		FROM games
+		WHERE ((white_id = $1 AND black_id = $2) OR (white_id = $2 AND black_id = $1))
+			AND (is_private = FALSE OR $3)
+	`
+
+	err := r.db.GetContext(ctx, &record, query, userID, opponentID, includePrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
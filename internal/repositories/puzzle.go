@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"chess-ws-go/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	ErrPuzzleNotFound = errors.New("puzzle not found")
+)
+
+// PuzzleRepository defines the interface for puzzle data access
+type PuzzleRepository interface {
+	GetByID(ctx context.Context, id string) (*models.Puzzle, error)
+	GetRandom(ctx context.Context, minRating int, maxRating int) (*models.Puzzle, error)
+}
+
+// SQLPuzzleRepository implements PuzzleRepository using SQL database
+type SQLPuzzleRepository struct {
+	db *sqlx.DB
+}
+
+// NewSQLPuzzleRepository creates a new SQL-based puzzle repository
+func NewSQLPuzzleRepository(db *sqlx.DB) PuzzleRepository {
+	return &SQLPuzzleRepository{db: db}
+}
+
+// GetByID retrieves a puzzle by ID
+func (r *SQLPuzzleRepository) GetByID(ctx context.Context, id string) (*models.Puzzle, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var puzzle models.Puzzle
+
+	query := `
+		SELECT * FROM puzzles
+		WHERE id = $1
+	`
+
+	err := r.db.GetContext(ctx, &puzzle, query, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPuzzleNotFound
+		}
+		return nil, err
+	}
+
+	return &puzzle, nil
+}
+
+// GetRandom retrieves a random puzzle rated within [minRating, maxRating]
+func (r *SQLPuzzleRepository) GetRandom(ctx context.Context, minRating int, maxRating int) (*models.Puzzle, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var puzzle models.Puzzle
+
+	query := `
+		SELECT * FROM puzzles
+		WHERE rating BETWEEN $1 AND $2
+		ORDER BY random()
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &puzzle, query, minRating, maxRating)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPuzzleNotFound
+		}
+		return nil, err
+	}
+
+	return &puzzle, nil
+}
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// defaultQueryTimeout bounds queries whose context has no deadline
+const defaultQueryTimeout = 5 * time.Second
+
+var queryTimeout atomic.Int64
+
+func init() {
+	queryTimeout.Store(int64(defaultQueryTimeout))
+}
+
+// SetQueryTimeout sets the deadline applied to queries whose context has none.
+// A non-positive value disables it.
+func SetQueryTimeout(timeout time.Duration) {
+	queryTimeout.Store(int64(timeout))
+}
+
+// withQueryTimeout derives a context with the configured query timeout unless
+// ctx already carries a deadline, so a stalled database can't hang callers
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := time.Duration(queryTimeout.Load())
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
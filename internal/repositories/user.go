@@ -3,28 +3,96 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"chess-ws-go/internal/models"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 var (
-	ErrUserNotFound      = errors.New("user not found")
-	ErrUserAlreadyExists = errors.New("user already exists")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrUserAlreadyExists    = errors.New("user already exists")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrUserConflict         = errors.New("user was modified concurrently")
+	ErrTokenNotFound        = errors.New("token not found or expired")
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
 )
 
+// uniqueViolation is the Postgres error code for a unique constraint violation
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a Postgres unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// UserCursor marks a position in the user listing, which is ordered by
+// (created_at, id). Listing "after" a cursor returns the users that sort
+// strictly after it, so pages stay stable under concurrent inserts.
+type UserCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Encode returns the opaque string form of the cursor handed to clients:
+// base64url (unpadded) of "<created_at as RFC3339Nano>|<user id>".
+func (c UserCursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeUserCursor parses a cursor produced by UserCursor.Encode
+func DecodeUserCursor(s string) (*UserCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &UserCursor{CreatedAt: createdAt, ID: parts[1]}, nil
+}
+
+// UserListOptions controls how users are listed. When After is set, keyset
+// pagination is used and Offset is ignored.
+type UserListOptions struct {
+	Search string
+	Limit  int
+	Offset int
+	After  *UserCursor
+}
+
 // UserRepository defines the interface for user data access
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	GetByID(ctx context.Context, id string) (*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByVerificationToken(ctx context.Context, token string) (*models.User, error)
+	GetByPasswordResetToken(ctx context.Context, token string) (*models.User, error)
+	GetByEmailChangeToken(ctx context.Context, token string) (*models.User, error)
+	ClearExpiredUserTokens(ctx context.Context) (int64, error)
 	Update(ctx context.Context, user *models.User) error
+	UpdateRatingsTx(ctx context.Context, whiteID string, blackID string, apply func(white, black *models.User) error) error
 	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, opts UserListOptions) ([]*models.User, error)
+	Count(ctx context.Context, search string) (int, error)
 
 	// Permission methods
 	AddPermission(ctx context.Context, userID string, permission string) error
@@ -34,22 +102,62 @@ type UserRepository interface {
 	// Token methods
 	SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error
 	GetRefreshToken(ctx context.Context, tokenID string) (*models.RefreshToken, error)
+	GetRefreshTokenByToken(ctx context.Context, token string) (*models.RefreshToken, error)
+	ListRefreshTokens(ctx context.Context, userID string) ([]*models.RefreshToken, error)
 	DeleteRefreshToken(ctx context.Context, tokenID string) error
+	DeleteUserRefreshToken(ctx context.Context, userID string, tokenID string) error
 	DeleteUserRefreshTokens(ctx context.Context, userID string) error
+	DeleteExpiredTokens(ctx context.Context) (int64, error)
 }
 
 // SQLUserRepository implements UserRepository using SQL database
 type SQLUserRepository struct {
-	db *sqlx.DB
+	db     *sqlx.DB // Primary, used for writes, transactions and token lookups
+	reader *sqlx.DB // Read replica for profile and listing reads (may be the primary)
 }
 
 // NewSQLUserRepository creates a new SQL-based user repository
 func NewSQLUserRepository(db *sqlx.DB) UserRepository {
-	return &SQLUserRepository{db: db}
+	return NewSQLUserRepositoryWithReplica(db, nil)
+}
+
+// NewSQLUserRepositoryWithReplica creates a SQL-based user repository that
+// sends plain reads to replica. A nil replica falls back to the primary.
+func NewSQLUserRepositoryWithReplica(db *sqlx.DB, replica *sqlx.DB) UserRepository {
+	if replica == nil {
+		replica = db
+	}
+	return &SQLUserRepository{db: db, reader: replica}
+}
+
+type readPrimaryKey struct{}
+
+// ReadPrimary marks ctx so that user lookups made with it go to the primary
+// and skip any cache. Reads that feed an Update need it: a lagging replica
+// hands back a stale version, which the update then rejects as a conflict.
+func ReadPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPrimaryKey{}, true)
+}
+
+// readsPrimary reports whether ctx was marked by ReadPrimary
+func readsPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(readPrimaryKey{}).(bool)
+	return primary
+}
+
+// readerFor returns the database a plain read made with ctx goes to
+func (r *SQLUserRepository) readerFor(ctx context.Context) *sqlx.DB {
+	if readsPrimary(ctx) {
+		return r.db
+	}
+	return r.reader
 }
 
 // Create adds a new user to the database
 func (r *SQLUserRepository) Create(ctx context.Context, user *models.User) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// Generate UUID if not provided
 	if user.ID == "" {
 		user.ID = uuid.New().String()
@@ -63,21 +171,31 @@ func (r *SQLUserRepository) Create(ctx context.Context, user *models.User) error
 	query := `
 		INSERT INTO users (
 			id, username, email, password_hash, role, display_name, 
-			is_verified, verification_token, elo_rating, 
+			is_verified, verification_token, verification_token_expires_at, elo_rating, 
+			bullet_rating, blitz_rating, rapid_rating, puzzle_rating, placement_games_remaining,
+			notify_game_invites, notify_game_summaries, notify_security_alerts,
 			failed_login_attempts, created_at, updated_at
 		) VALUES (
 			:id, :username, :email, :password_hash, :role, :display_name, 
-			:is_verified, :verification_token, :elo_rating, 
+			:is_verified, :verification_token, :verification_token_expires_at, :elo_rating, 
+			:bullet_rating, :blitz_rating, :rapid_rating, :puzzle_rating, :placement_games_remaining,
+			:notify_game_invites, :notify_game_summaries, :notify_security_alerts,
 			:failed_login_attempts, :created_at, :updated_at
 		)
 	`
 
 	_, err := r.db.NamedExecContext(ctx, query, user)
+	if isUniqueViolation(err) {
+		return ErrUserAlreadyExists
+	}
 	return err
 }
 
 // GetByID retrieves a user by ID
 func (r *SQLUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var user models.User
 
 	query := `
@@ -85,7 +203,7 @@ func (r *SQLUserRepository) GetByID(ctx context.Context, id string) (*models.Use
 		WHERE id = $1
 	`
 
-	err := r.db.GetContext(ctx, &user, query, id)
+	err := r.readerFor(ctx).GetContext(ctx, &user, query, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrUserNotFound
@@ -96,16 +214,19 @@ func (r *SQLUserRepository) GetByID(ctx context.Context, id string) (*models.Use
 	return &user, nil
 }
 
-// GetByUsername retrieves a user by username
+// GetByUsername retrieves a user by username, ignoring case
 func (r *SQLUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var user models.User
 
 	query := `
 		SELECT * FROM users 
-		WHERE username = $1
+		WHERE LOWER(username) = LOWER($1)
 	`
 
-	err := r.db.GetContext(ctx, &user, query, username)
+	err := r.readerFor(ctx).GetContext(ctx, &user, query, username)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrUserNotFound
@@ -116,16 +237,19 @@ func (r *SQLUserRepository) GetByUsername(ctx context.Context, username string)
 	return &user, nil
 }
 
-// GetByEmail retrieves a user by email
+// GetByEmail retrieves a user by email, ignoring case
 func (r *SQLUserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var user models.User
 
 	query := `
 		SELECT * FROM users 
-		WHERE email = $1
+		WHERE LOWER(email) = LOWER($1)
 	`
 
-	err := r.db.GetContext(ctx, &user, query, email)
+	err := r.readerFor(ctx).GetContext(ctx, &user, query, email)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrUserNotFound
@@ -136,8 +260,105 @@ func (r *SQLUserRepository) GetByEmail(ctx context.Context, email string) (*mode
 	return &user, nil
 }
 
-// Update updates an existing user
+// GetByVerificationToken retrieves the user holding an unexpired email
+// verification token
+func (r *SQLUserRepository) GetByVerificationToken(ctx context.Context, token string) (*models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT * FROM users
+		WHERE verification_token = $1 AND verification_token_expires_at > $2
+	`
+
+	return r.getByToken(ctx, query, token)
+}
+
+// GetByPasswordResetToken retrieves the user holding an unexpired password
+// reset token
+func (r *SQLUserRepository) GetByPasswordResetToken(ctx context.Context, token string) (*models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT * FROM users
+		WHERE password_reset_token = $1 AND password_reset_token_expires_at > $2
+	`
+
+	return r.getByToken(ctx, query, token)
+}
+
+// GetByEmailChangeToken retrieves the user holding an unexpired email change
+// confirmation token
+func (r *SQLUserRepository) GetByEmailChangeToken(ctx context.Context, token string) (*models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT * FROM users
+		WHERE email_change_token = $1 AND email_change_token_expires_at > $2
+	`
+
+	return r.getByToken(ctx, query, token)
+}
+
+// getByToken runs a token lookup query, treating unknown and expired tokens
+// alike. Lookups stay on the primary since a replica may not have seen a
+// freshly issued token yet.
+func (r *SQLUserRepository) getByToken(ctx context.Context, query string, token string) (*models.User, error) {
+	if token == "" {
+		return nil, ErrTokenNotFound
+	}
+
+	var user models.User
+	err := r.db.GetContext(ctx, &user, query, token, time.Now())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ClearExpiredUserTokens burns every email verification, password reset and
+// email change token past its expiry, dropping an unconfirmed pending email
+// with its token. It returns how many users were touched. Their version is
+// bumped so a concurrent update can't bring an expired token back.
+func (r *SQLUserRepository) ClearExpiredUserTokens(ctx context.Context) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE users SET
+			verification_token = CASE WHEN verification_token_expires_at < $1 THEN '' ELSE verification_token END,
+			verification_token_expires_at = CASE WHEN verification_token_expires_at < $1 THEN NULL ELSE verification_token_expires_at END,
+			password_reset_token = CASE WHEN password_reset_token_expires_at < $1 THEN '' ELSE password_reset_token END,
+			password_reset_token_expires_at = CASE WHEN password_reset_token_expires_at < $1 THEN NULL ELSE password_reset_token_expires_at END,
+			pending_email = CASE WHEN email_change_token_expires_at < $1 THEN '' ELSE pending_email END,
+			email_change_token = CASE WHEN email_change_token_expires_at < $1 THEN '' ELSE email_change_token END,
+			email_change_token_expires_at = CASE WHEN email_change_token_expires_at < $1 THEN NULL ELSE email_change_token_expires_at END,
+			version = version + 1
+		WHERE verification_token_expires_at < $1
+			OR password_reset_token_expires_at < $1
+			OR email_change_token_expires_at < $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Update updates an existing user. The write only succeeds if the stored
+// version still matches user.Version; otherwise ErrUserConflict is returned
+// and the caller should reload the user and retry.
 func (r *SQLUserRepository) Update(ctx context.Context, user *models.User) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	user.UpdatedAt = time.Now()
 
 	query := `
@@ -147,17 +368,38 @@ func (r *SQLUserRepository) Update(ctx context.Context, user *models.User) error
 			password_hash = :password_hash,
 			role = :role,
 			display_name = :display_name,
+			avatar_url = :avatar_url,
+			avatar_thumbnail_url = :avatar_thumbnail_url,
+			avatar_key = :avatar_key,
 			is_verified = :is_verified,
 			verification_token = :verification_token,
+			verification_token_expires_at = :verification_token_expires_at,
+			password_reset_token = :password_reset_token,
+			password_reset_token_expires_at = :password_reset_token_expires_at,
+			pending_email = :pending_email,
+			email_change_token = :email_change_token,
+			email_change_token_expires_at = :email_change_token_expires_at,
 			elo_rating = :elo_rating,
+			bullet_rating = :bullet_rating,
+			blitz_rating = :blitz_rating,
+			rapid_rating = :rapid_rating,
+			puzzle_rating = :puzzle_rating,
+			placement_games_remaining = :placement_games_remaining,
+			notify_game_invites = :notify_game_invites,
+			notify_game_summaries = :notify_game_summaries,
+			notify_security_alerts = :notify_security_alerts,
 			failed_login_attempts = :failed_login_attempts,
 			last_login_at = :last_login_at,
-			updated_at = :updated_at
-		WHERE id = :id
+			updated_at = :updated_at,
+			version = version + 1
+		WHERE id = :id AND version = :version
 	`
 
 	result, err := r.db.NamedExecContext(ctx, query, user)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrUserAlreadyExists
+		}
 		return err
 	}
 
@@ -167,14 +409,101 @@ func (r *SQLUserRepository) Update(ctx context.Context, user *models.User) error
 	}
 
 	if rowsAffected == 0 {
+		// Distinguish a missing user from a stale version
+		var exists bool
+		err := r.db.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, user.ID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrUserNotFound
+		}
+		return ErrUserConflict
+	}
+
+	user.Version++
+	return nil
+}
+
+// UpdateRatingsTx loads both players inside a single transaction, lets apply
+// adjust their ratings and writes both back. The rows are locked for the
+// duration, and any error rolls the whole update back so the two ratings
+// change together or not at all.
+func (r *SQLUserRepository) UpdateRatingsTx(
+	ctx context.Context,
+	whiteID string,
+	blackID string,
+	apply func(white, black *models.User) error,
+) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock in a consistent order so concurrent updates can't deadlock
+	users := []*models.User{}
+	query := `SELECT * FROM users WHERE id IN ($1, $2) ORDER BY id FOR UPDATE`
+	if err := tx.SelectContext(ctx, &users, query, whiteID, blackID); err != nil {
+		return err
+	}
+
+	var white, black *models.User
+	for _, user := range users {
+		switch user.ID {
+		case whiteID:
+			white = user
+		case blackID:
+			black = user
+		}
+	}
+	if white == nil || black == nil {
 		return ErrUserNotFound
 	}
 
+	if err := apply(white, black); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	update := `
+		UPDATE users SET
+			elo_rating = :elo_rating,
+			bullet_rating = :bullet_rating,
+			blitz_rating = :blitz_rating,
+			rapid_rating = :rapid_rating,
+			placement_games_remaining = :placement_games_remaining,
+			updated_at = :updated_at,
+			version = version + 1
+		WHERE id = :id
+	`
+	for _, user := range []*models.User{white, black} {
+		row := *user
+		row.UpdatedAt = now
+		if _, err := tx.NamedExecContext(ctx, update, &row); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, user := range []*models.User{white, black} {
+		user.UpdatedAt = now
+		user.Version++
+	}
 	return nil
 }
 
 // Delete removes a user by ID
 func (r *SQLUserRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `DELETE FROM users WHERE id = $1`
 
 	result, err := r.db.ExecContext(ctx, query, id)
@@ -194,8 +523,76 @@ func (r *SQLUserRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// List retrieves users ordered by creation time, optionally filtered by a
+// search term matched against username and display name
+func (r *SQLUserRepository) List(ctx context.Context, opts UserListOptions) ([]*models.User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	users := []*models.User{}
+
+	conditions := []string{}
+	args := []interface{}{}
+
+	if opts.Search != "" {
+		args = append(args, "%"+opts.Search+"%")
+		conditions = append(conditions, fmt.Sprintf("(username ILIKE $%d OR display_name ILIKE $%d)", len(args), len(args)))
+	}
+
+	if opts.After != nil {
+		args = append(args, opts.After.CreatedAt, opts.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) > ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	query := `SELECT * FROM users`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, opts.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at, id LIMIT $%d`, len(args))
+
+	if opts.After == nil && opts.Offset > 0 {
+		args = append(args, opts.Offset)
+		query += fmt.Sprintf(` OFFSET $%d`, len(args))
+	}
+
+	err := r.reader.SelectContext(ctx, &users, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Count returns the number of users matching an optional search term
+func (r *SQLUserRepository) Count(ctx context.Context, search string) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var count int
+
+	query := `SELECT COUNT(*) FROM users`
+	args := []interface{}{}
+
+	if search != "" {
+		query += ` WHERE username ILIKE $1 OR display_name ILIKE $1`
+		args = append(args, "%"+search+"%")
+	}
+
+	err := r.reader.GetContext(ctx, &count, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 // AddPermission adds a permission to a user
 func (r *SQLUserRepository) AddPermission(ctx context.Context, userID string, permission string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		INSERT INTO user_permissions (user_id, permission)
 		VALUES ($1, $2)
@@ -208,6 +605,9 @@ func (r *SQLUserRepository) AddPermission(ctx context.Context, userID string, pe
 
 // RemovePermission removes a permission from a user
 func (r *SQLUserRepository) RemovePermission(ctx context.Context, userID string, permission string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		DELETE FROM user_permissions
 		WHERE user_id = $1 AND permission = $2
@@ -219,6 +619,9 @@ func (r *SQLUserRepository) RemovePermission(ctx context.Context, userID string,
 
 // GetPermissions retrieves all permissions for a user
 func (r *SQLUserRepository) GetPermissions(ctx context.Context, userID string) ([]string, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var permissions []string
 
 	query := `
@@ -226,7 +629,7 @@ func (r *SQLUserRepository) GetPermissions(ctx context.Context, userID string) (
 		WHERE user_id = $1
 	`
 
-	err := r.db.SelectContext(ctx, &permissions, query, userID)
+	err := r.reader.SelectContext(ctx, &permissions, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -236,6 +639,9 @@ func (r *SQLUserRepository) GetPermissions(ctx context.Context, userID string) (
 
 // SaveRefreshToken saves a refresh token to the database
 func (r *SQLUserRepository) SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	// Generate UUID if not provided
 	if token.ID == "" {
 		token.ID = uuid.New().String()
@@ -247,8 +653,8 @@ func (r *SQLUserRepository) SaveRefreshToken(ctx context.Context, token *models.
 	}
 
 	query := `
-		INSERT INTO refresh_tokens (id, user_id, token, expires_at, created_at)
-		VALUES (:id, :user_id, :token, :expires_at, :created_at)
+		INSERT INTO refresh_tokens (id, user_id, token, label, ip_address, user_agent, expires_at, created_at)
+		VALUES (:id, :user_id, :token, :label, :ip_address, :user_agent, :expires_at, :created_at)
 	`
 
 	_, err := r.db.NamedExecContext(ctx, query, token)
@@ -257,6 +663,9 @@ func (r *SQLUserRepository) SaveRefreshToken(ctx context.Context, token *models.
 
 // GetRefreshToken retrieves a refresh token by ID
 func (r *SQLUserRepository) GetRefreshToken(ctx context.Context, tokenID string) (*models.RefreshToken, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	var token models.RefreshToken
 
 	query := `
@@ -267,7 +676,7 @@ func (r *SQLUserRepository) GetRefreshToken(ctx context.Context, tokenID string)
 	err := r.db.GetContext(ctx, &token, query, tokenID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("refresh token not found")
+			return nil, ErrRefreshTokenNotFound
 		}
 		return nil, err
 	}
@@ -275,18 +684,107 @@ func (r *SQLUserRepository) GetRefreshToken(ctx context.Context, tokenID string)
 	return &token, nil
 }
 
+// GetRefreshTokenByToken retrieves a refresh token by its value. Revoked
+// tokens are deleted, so they aren't found.
+func (r *SQLUserRepository) GetRefreshTokenByToken(ctx context.Context, token string) (*models.RefreshToken, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var refreshToken models.RefreshToken
+
+	query := `
+		SELECT * FROM refresh_tokens
+		WHERE token = $1
+	`
+
+	err := r.db.GetContext(ctx, &refreshToken, query, token)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &refreshToken, nil
+}
+
+// ListRefreshTokens retrieves a user's unexpired refresh tokens, newest first
+func (r *SQLUserRepository) ListRefreshTokens(ctx context.Context, userID string) ([]*models.RefreshToken, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	var tokens []*models.RefreshToken
+
+	query := `
+		SELECT * FROM refresh_tokens
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY created_at DESC
+	`
+
+	err := r.db.SelectContext(ctx, &tokens, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
 // DeleteRefreshToken deletes a refresh token by ID
 func (r *SQLUserRepository) DeleteRefreshToken(ctx context.Context, tokenID string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `DELETE FROM refresh_tokens WHERE id = $1`
 
 	_, err := r.db.ExecContext(ctx, query, tokenID)
 	return err
 }
 
+// DeleteUserRefreshToken deletes one of a user's refresh tokens by ID
+func (r *SQLUserRepository) DeleteUserRefreshToken(ctx context.Context, userID string, tokenID string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM refresh_tokens WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, tokenID, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrRefreshTokenNotFound
+	}
+
+	return nil
+}
+
 // DeleteUserRefreshTokens deletes all refresh tokens for a user
 func (r *SQLUserRepository) DeleteUserRefreshTokens(ctx context.Context, userID string) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `DELETE FROM refresh_tokens WHERE user_id = $1`
 
 	_, err := r.db.ExecContext(ctx, query, userID)
 	return err
 }
+
+// DeleteExpiredTokens deletes every refresh token past its expiry and
+// returns how many there were
+func (r *SQLUserRepository) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM refresh_tokens WHERE expires_at < NOW()`
+
+	result, err := r.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"chess-ws-go/internal/models"
+)
+
+// CachedUserRepository wraps a UserRepository with a small LRU cache of
+// users by ID, for hot rating reads during matchmaking and game-over
+// handling. Writes always go through to the wrapped repository and evict
+// the cached entry, so every writer must share the one cached repository.
+type CachedUserRepository struct {
+	UserRepository
+	size    int
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Front is most recently used
+}
+
+type cachedUser struct {
+	user      models.User
+	expiresAt time.Time
+}
+
+// NewCachedUserRepository creates a caching wrapper around repo holding at
+// most size users for ttl each. A non-positive size or ttl disables caching.
+func NewCachedUserRepository(repo UserRepository, size int, ttl time.Duration) UserRepository {
+	if size <= 0 || ttl <= 0 {
+		return repo
+	}
+
+	return &CachedUserRepository{
+		UserRepository: repo,
+		size:           size,
+		ttl:            ttl,
+		entries:        make(map[string]*list.Element),
+		order:          list.New(),
+	}
+}
+
+// GetByID returns a user from the cache, loading it on a miss. Lookups
+// marked with ReadPrimary always load, refreshing the cached copy.
+func (r *CachedUserRepository) GetByID(ctx context.Context, id string) (*models.User, error) {
+	if !readsPrimary(ctx) {
+		if user, ok := r.get(id); ok {
+			return user, nil
+		}
+	}
+
+	user, err := r.UserRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.put(user)
+	return user, nil
+}
+
+// Update writes the user through to the wrapped repository and evicts it
+func (r *CachedUserRepository) Update(ctx context.Context, user *models.User) error {
+	err := r.UserRepository.Update(ctx, user)
+	r.evict(user.ID)
+	return err
+}
+
+// UpdateRatingsTx updates both ratings in the wrapped repository and evicts
+// both players
+func (r *CachedUserRepository) UpdateRatingsTx(
+	ctx context.Context,
+	whiteID string,
+	blackID string,
+	apply func(white, black *models.User) error,
+) error {
+	err := r.UserRepository.UpdateRatingsTx(ctx, whiteID, blackID, apply)
+	r.evict(whiteID)
+	r.evict(blackID)
+	return err
+}
+
+// Delete removes the user from the wrapped repository and evicts it
+func (r *CachedUserRepository) Delete(ctx context.Context, id string) error {
+	err := r.UserRepository.Delete(ctx, id)
+	r.evict(id)
+	return err
+}
+
+// ClearExpiredUserTokens clears lapsed tokens in the wrapped repository
+// and, since it can touch any user, empties the cache if it changed any
+func (r *CachedUserRepository) ClearExpiredUserTokens(ctx context.Context) (int64, error) {
+	cleared, err := r.UserRepository.ClearExpiredUserTokens(ctx)
+	if cleared > 0 {
+		r.mu.Lock()
+		r.entries = make(map[string]*list.Element)
+		r.order.Init()
+		r.mu.Unlock()
+	}
+	return cleared, err
+}
+
+// get returns a copy of a cached, unexpired user
+func (r *CachedUserRepository) get(id string) (*models.User, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, ok := r.entries[id]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cachedUser)
+	if time.Now().After(entry.expiresAt) {
+		r.order.Remove(elem)
+		delete(r.entries, id)
+		return nil, false
+	}
+
+	r.order.MoveToFront(elem)
+	user := entry.user
+	return &user, true
+}
+
+// put caches a copy of the user, evicting the least recently used entry if full
+func (r *CachedUserRepository) put(user *models.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := &cachedUser{user: *user, expiresAt: time.Now().Add(r.ttl)}
+
+	if elem, ok := r.entries[user.ID]; ok {
+		elem.Value = entry
+		r.order.MoveToFront(elem)
+		return
+	}
+
+	r.entries[user.ID] = r.order.PushFront(entry)
+
+	if r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*cachedUser).user.ID)
+	}
+}
+
+// evict drops a user from the cache
+func (r *CachedUserRepository) evict(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if elem, ok := r.entries[id]; ok {
+		r.order.Remove(elem)
+		delete(r.entries, id)
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"chess-ws-go/internal/repositories"
+
+	"github.com/corentings/chess/v2"
+)
+
+var (
+	ErrInvalidMoveList = errors.New("invalid move list")
+	ErrGameInProgress  = errors.New("game is still in progress")
+)
+
+// maxAnalysisPlies bounds the length of a move list accepted for analysis
+const maxAnalysisPlies = 1000
+
+// Evaluation is an engine's assessment of a position, from white's side
+type Evaluation struct {
+	Centipawns *int   `json:"centipawns,omitempty"`
+	MateIn     *int   `json:"mate_in,omitempty"`
+	BestMove   string `json:"best_move,omitempty"`
+}
+
+// Evaluator evaluates positions given as FEN. A UCI engine can be plugged
+// in by implementing this; a nil evaluation means "no opinion".
+type Evaluator interface {
+	Evaluate(ctx context.Context, fen string) (*Evaluation, error)
+}
+
+// NoopEvaluator is the default evaluator and never evaluates anything
+type NoopEvaluator struct{}
+
+// Evaluate returns no evaluation
+func (NoopEvaluator) Evaluate(ctx context.Context, fen string) (*Evaluation, error) {
+	return nil, nil
+}
+
+// AnalysisPly is the position after a ply. Ply 0 is the starting position.
+type AnalysisPly struct {
+	Ply        int         `json:"ply"`
+	Move       string      `json:"move,omitempty"`
+	FEN        string      `json:"fen"`
+	Evaluation *Evaluation `json:"evaluation,omitempty"`
+}
+
+// AnalysisService reconstructs the positions of a game so clients can step
+// through it
+type AnalysisService struct {
+	gameService *GameService
+	evaluator   Evaluator
+}
+
+// NewAnalysisService creates a new analysis service. A nil evaluator falls
+// back to NoopEvaluator.
+func NewAnalysisService(gameService *GameService, evaluator Evaluator) *AnalysisService {
+	if evaluator == nil {
+		evaluator = NoopEvaluator{}
+	}
+
+	return &AnalysisService{
+		gameService: gameService,
+		evaluator:   evaluator,
+	}
+}
+
+// AnalyzeMoves replays moves given in standard algebraic notation from the
+// starting position and returns every position along the way
+func (s *AnalysisService) AnalyzeMoves(ctx context.Context, moves []string) ([]AnalysisPly, error) {
+	if len(moves) > maxAnalysisPlies {
+		return nil, ErrInvalidMoveList
+	}
+
+	game := chess.NewGame()
+	plies := make([]AnalysisPly, 0, len(moves)+1)
+	plies = append(plies, AnalysisPly{Ply: 0, FEN: game.FEN()})
+
+	for i, move := range moves {
+		if err := game.PushMove(move, nil); err != nil {
+			return nil, ErrInvalidMoveList
+		}
+		plies = append(plies, AnalysisPly{Ply: i + 1, Move: move, FEN: game.FEN()})
+	}
+
+	for i := range plies {
+		evaluation, err := s.evaluator.Evaluate(ctx, plies[i].FEN)
+		if err != nil {
+			return nil, err
+		}
+		plies[i].Evaluation = evaluation
+	}
+
+	return plies, nil
+}
+
+// AnalyzeGame returns every position of a finished game. Live games can't be
+// analyzed so the feature can't be used to cheat.
+func (s *AnalysisService) AnalyzeGame(
+	ctx context.Context,
+	gameID string,
+	viewerID string,
+	userRepo repositories.UserRepository,
+) ([]AnalysisPly, error) {
+	details, err := s.gameService.GetGameDetails(ctx, gameID, viewerID, userRepo)
+	if err != nil {
+		return nil, err
+	}
+	if details.Live {
+		return nil, ErrGameInProgress
+	}
+
+	return s.AnalyzeMoves(ctx, details.Moves)
+}
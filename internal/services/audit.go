@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+)
+
+// Audited actions
+const (
+	AuditLogin                  = "login"
+	AuditLoginNewDevice         = "login_new_device"
+	AuditLoginFailed            = "login_failed"
+	AuditPasswordResetRequested = "password_reset_requested"
+	AuditPasswordChanged        = "password_changed"
+	AuditEmailChanged           = "email_changed"
+	AuditRoleChanged            = "role_changed"
+	AuditUserBanned             = "user_banned"
+	AuditAccountDeleted         = "account_deleted"
+	AuditGameTerminated         = "game_terminated"
+	AuditGameAborted            = "game_aborted"
+	AuditUserDisconnected       = "user_disconnected"
+)
+
+type contextKey string
+
+const clientIPKey contextKey = "client_ip"
+
+// WithClientIP returns a context carrying the client IP recorded in audit entries
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+// ClientIPFromContext returns the client IP stored by WithClientIP, if any
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+const userAgentKey contextKey = "user_agent"
+
+// WithUserAgent returns a context carrying the client user agent recorded
+// with new sessions
+func WithUserAgent(ctx context.Context, userAgent string) context.Context {
+	return context.WithValue(ctx, userAgentKey, userAgent)
+}
+
+// UserAgentFromContext returns the user agent stored by WithUserAgent, if any
+func UserAgentFromContext(ctx context.Context) string {
+	userAgent, _ := ctx.Value(userAgentKey).(string)
+	return userAgent
+}
+
+// AuditLogger records security-sensitive actions to the audit log
+type AuditLogger struct {
+	auditRepo repositories.AuditRepository
+}
+
+// NewAuditLogger creates a new audit logger
+func NewAuditLogger(auditRepo repositories.AuditRepository) *AuditLogger {
+	return &AuditLogger{
+		auditRepo: auditRepo,
+	}
+}
+
+// Log records an action. Failures are written to the server log rather than
+// returned, so auditing never breaks the action being audited.
+func (l *AuditLogger) Log(ctx context.Context, actorID string, action string, targetID string, details string) {
+	if l == nil || l.auditRepo == nil {
+		return
+	}
+
+	entry := &models.AuditEntry{
+		ActorID:   actorID,
+		Action:    action,
+		TargetID:  targetID,
+		IP:        ClientIPFromContext(ctx),
+		Details:   details,
+		CreatedAt: time.Now(),
+	}
+
+	if err := l.auditRepo.Create(ctx, entry); err != nil {
+		logging.Errorf("Failed to write audit log entry (action: %s, actor: %s, target: %s): %v",
+			action, actorID, targetID, err)
+	}
+}
+
+// Query returns audit entries matching the filter
+func (l *AuditLogger) Query(ctx context.Context, filter repositories.AuditFilter) ([]*models.AuditEntry, error) {
+	return l.auditRepo.List(ctx, filter)
+}
@@ -3,10 +3,15 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
+	"unicode"
 
 	"chess-ws-go/internal/auth"
 	"chess-ws-go/internal/config"
+	"chess-ws-go/internal/logging"
 	"chess-ws-go/internal/models"
 	"chess-ws-go/internal/repositories"
 
@@ -17,27 +22,175 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserExists         = errors.New("user already exists")
 	ErrUserNotVerified    = errors.New("user not verified")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrInvalidUsername    = errors.New("username may only contain letters, digits, '.', '_' and '-', and must start and end with a letter or digit")
+	ErrReservedUsername   = errors.New("username is reserved")
 )
 
+// usernamePattern allows separators only between letters and digits
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+
+// Lifetimes of the single-use tokens sent by email
+const (
+	verificationTokenTTL  = 24 * time.Hour
+	passwordResetTokenTTL = time.Hour
+	emailChangeTokenTTL   = 24 * time.Hour
+)
+
+// maxDeviceLabelLength bounds the device label stored with a session
+const maxDeviceLabelLength = 200
+
+const deviceLabelKey contextKey = "device_label"
+
+// WithDeviceLabel returns a context carrying a label for the device a
+// session is started from, such as a client-chosen name or its user agent
+func WithDeviceLabel(ctx context.Context, label string) context.Context {
+	if len(label) > maxDeviceLabelLength {
+		label = label[:maxDeviceLabelLength]
+	}
+	return context.WithValue(ctx, deviceLabelKey, label)
+}
+
+// deviceLabelFromContext returns the label stored by WithDeviceLabel, if any
+func deviceLabelFromContext(ctx context.Context) string {
+	label, _ := ctx.Value(deviceLabelKey).(string)
+	return label
+}
+
+// Session is a signed-in device, backed by a refresh token
+type Session struct {
+	ID        string    `json:"id"`
+	Label     string    `json:"label"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 // AuthService handles authentication operations
 type AuthService struct {
-	userRepo  repositories.UserRepository
-	jwtMaker  *auth.JWTMaker
-	jwtConfig *config.JWTConfig
+	userRepo          repositories.UserRepository
+	jwtMaker          *auth.JWTMaker
+	jwtConfig         *config.JWTConfig
+	auditLogger       *AuditLogger
+	notifier          *NotificationService
+	sender            EmailSender
+	reservedUsernames map[string]bool // Reserved names, folded by reservedKey
 }
 
-// NewAuthService creates a new authentication service
+// NewAuthService creates a new authentication service. Emails are only
+// logged until SetEmailSender is given a real sender.
 func NewAuthService(
 	userRepo repositories.UserRepository,
 	jwtConfig *config.JWTConfig,
+	auditLogger *AuditLogger,
 ) *AuthService {
 	return &AuthService{
-		userRepo:  userRepo,
-		jwtMaker:  auth.NewJWTMaker(jwtConfig.SecretKey),
-		jwtConfig: jwtConfig,
+		userRepo:    userRepo,
+		jwtMaker:    auth.NewJWTMaker(jwtConfig.SecretKey),
+		jwtConfig:   jwtConfig,
+		auditLogger: auditLogger,
+		sender:      LogEmailSender{},
+	}
+}
+
+// SetEmailSender sets how account emails, such as verification links, are
+// delivered
+func (s *AuthService) SetEmailSender(sender EmailSender) {
+	s.sender = sender
+}
+
+// SetReservedUsernames replaces the names nobody may register. Matching
+// ignores case and separators, so reserving "admin" also blocks "Ad_min".
+func (s *AuthService) SetReservedUsernames(names []string) {
+	s.reservedUsernames = make(map[string]bool, len(names))
+	for _, name := range names {
+		if key := reservedKey(name); key != "" {
+			s.reservedUsernames[key] = true
+		}
+	}
+}
+
+// SetNotifier makes logins from new devices email a security alert to users
+// who haven't turned those off
+func (s *AuthService) SetNotifier(notifier *NotificationService) {
+	s.notifier = notifier
+}
+
+// StartTokenPurger periodically deletes expired refresh tokens and clears
+// expired verification, password reset and email change tokens from users.
+// None of them can be used anyway; this keeps them from piling up.
+func (s *AuthService) StartTokenPurger(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			s.purgeExpiredTokens(context.Background())
+			s.purgeExpiredUserTokens(context.Background())
+		}
+	}()
+}
+
+func (s *AuthService) purgeExpiredTokens(ctx context.Context) {
+	purged, err := s.userRepo.DeleteExpiredTokens(ctx)
+	if err != nil {
+		logging.Warnf("Failed to purge expired refresh tokens: %v", err)
+		return
+	}
+	if purged > 0 {
+		logging.Infof("Purged %d expired refresh tokens", purged)
 	}
 }
 
+func (s *AuthService) purgeExpiredUserTokens(ctx context.Context) {
+	cleared, err := s.userRepo.ClearExpiredUserTokens(ctx)
+	if err != nil {
+		logging.Warnf("Failed to clear expired user tokens: %v", err)
+		return
+	}
+	if cleared > 0 {
+		logging.Infof("Cleared expired verification, reset or email change tokens from %d users", cleared)
+	}
+}
+
+// alertNewDevice emails a user about a login from a device they haven't
+// used before. Sending happens in the background so a slow mail provider
+// doesn't hold up the login.
+func (s *AuthService) alertNewDevice(ctx context.Context, user *models.User, token *models.RefreshToken) {
+	if s.notifier == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	body := fmt.Sprintf("Your account was signed in from a new device (IP address %s, %s) at %s. If this wasn't you, change your password and revoke the session.",
+		token.IPAddress, token.UserAgent, token.CreatedAt.UTC().Format(time.RFC1123))
+	go func() {
+		if _, err := s.notifier.Notify(ctx, user.ID, NotifySecurityAlert, "New sign-in to your account", body); err != nil {
+			logging.Warnf("Failed to send new device alert to user %s: %v", user.ID, err)
+		}
+	}()
+}
+
+// reservedKey folds a username for comparison against reserved names
+func reservedKey(username string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '_' || r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, username)
+}
+
+// validateUsername checks a new username's format and that it isn't reserved
+func (s *AuthService) validateUsername(username string) error {
+	if !usernamePattern.MatchString(username) {
+		return ErrInvalidUsername
+	}
+	if s.reservedUsernames[reservedKey(username)] {
+		return ErrReservedUsername
+	}
+	return nil
+}
+
 // RegisterUser registers a new user
 func (s *AuthService) RegisterUser(
 	ctx context.Context,
@@ -45,7 +198,14 @@ func (s *AuthService) RegisterUser(
 	email string,
 	password string,
 ) (*models.User, error) {
-	// Check if user already exists
+	if err := s.validateUsername(username); err != nil {
+		return nil, err
+	}
+	email = normalizeEmail(email)
+
+	// Check if user already exists. This is only a fast path; two concurrent
+	// registrations can both get past it, so the unique constraints checked
+	// by Create are what actually decide.
 	_, err := s.userRepo.GetByUsername(ctx, username)
 	if err == nil {
 		return nil, ErrUserExists
@@ -76,15 +236,25 @@ func (s *AuthService) RegisterUser(
 	)
 
 	// Generate verification token
+	expiresAt := time.Now().Add(verificationTokenTTL)
 	user.VerificationToken = uuid.New().String()
+	user.VerificationTokenExpiresAt = &expiresAt
 
 	// Save user
 	err = s.userRepo.Create(ctx, user)
 	if err != nil {
+		if err == repositories.ErrUserAlreadyExists {
+			return nil, ErrUserExists
+		}
 		return nil, err
 	}
 
-	// TODO: Send verification email
+	sendInBackground(ctx, s.sender, Email{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Welcome, %s! Verify your email address with this token: %s\n\nIt expires in %s.",
+			user.Username, user.VerificationToken, expiresIn(verificationTokenTTL)),
+	})
 
 	return user, nil
 }
@@ -95,17 +265,20 @@ func (s *AuthService) Login(
 	usernameOrEmail string,
 	password string,
 ) (*auth.TokenPair, error) {
-	// Find user by username or email
+	// Find user by username or email. Login updates the user either way, so
+	// the read has to be current.
 	var user *models.User
 	var err error
+	ctx = repositories.ReadPrimary(ctx)
 
 	// Try username first
 	user, err = s.userRepo.GetByUsername(ctx, usernameOrEmail)
 	if err != nil {
 		if err == repositories.ErrUserNotFound {
 			// Try email
-			user, err = s.userRepo.GetByEmail(ctx, usernameOrEmail)
+			user, err = s.userRepo.GetByEmail(ctx, normalizeEmail(usernameOrEmail))
 			if err != nil {
+				s.auditLogger.Log(ctx, "", AuditLoginFailed, usernameOrEmail, "unknown user")
 				return nil, ErrInvalidCredentials
 			}
 		} else {
@@ -128,6 +301,7 @@ func (s *AuthService) Login(
 		// Increment failed login attempts
 		user.FailedLoginAttempts++
 		_ = s.userRepo.Update(ctx, user)
+		s.auditLogger.Log(ctx, user.ID, AuditLoginFailed, user.ID, "wrong password")
 		return nil, ErrInvalidCredentials
 	}
 
@@ -155,7 +329,7 @@ func (s *AuthService) Login(
 		user.Username,
 		user.Role,
 		authPermissions,
-		time.Duration(s.jwtConfig.AccessTokenDuration)*time.Minute,
+		s.jwtConfig.AccessTokenDuration,
 	)
 	if err != nil {
 		return nil, err
@@ -167,7 +341,7 @@ func (s *AuthService) Login(
 		user.Username,
 		user.Role,
 		nil, // No permissions in refresh token
-		time.Duration(s.jwtConfig.RefreshTokenDuration)*time.Hour,
+		s.jwtConfig.RefreshTokenDuration,
 	)
 	if err != nil {
 		return nil, err
@@ -178,21 +352,50 @@ func (s *AuthService) Login(
 		ID:        uuid.New().String(),
 		UserID:    user.ID,
 		Token:     refreshToken,
-		ExpiresAt: time.Now().Add(time.Duration(s.jwtConfig.RefreshTokenDuration) * time.Hour),
+		Label:     deviceLabelFromContext(ctx),
+		IPAddress: ClientIPFromContext(ctx),
+		UserAgent: UserAgentFromContext(ctx),
+		ExpiresAt: time.Now().Add(s.jwtConfig.RefreshTokenDuration),
 		CreatedAt: time.Now(),
 	}
 
+	// Flag logins from a client none of the user's sessions has used
+	newDevice := s.isNewDevice(ctx, user.ID, refreshTokenModel)
+
 	err = s.userRepo.SaveRefreshToken(ctx, refreshTokenModel)
 	if err != nil {
 		return nil, err
 	}
 
+	s.auditLogger.Log(ctx, user.ID, AuditLogin, user.ID, "")
+	if newDevice {
+		s.auditLogger.Log(ctx, user.ID, AuditLoginNewDevice, user.ID, refreshTokenModel.UserAgent)
+		s.alertNewDevice(ctx, user, refreshTokenModel)
+	}
+
 	return &auth.TokenPair{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 	}, nil
 }
 
+// isNewDevice reports whether a login comes from an IP address or user agent
+// that none of the user's active sessions started from. A user's first
+// session isn't flagged.
+func (s *AuthService) isNewDevice(ctx context.Context, userID string, token *models.RefreshToken) bool {
+	sessions, err := s.userRepo.ListRefreshTokens(ctx, userID)
+	if err != nil || len(sessions) == 0 {
+		return false
+	}
+
+	knownIP, knownAgent := false, false
+	for _, session := range sessions {
+		knownIP = knownIP || session.IPAddress == token.IPAddress
+		knownAgent = knownAgent || session.UserAgent == token.UserAgent
+	}
+	return !knownIP || !knownAgent
+}
+
 // RefreshToken refreshes an access token using a refresh token
 func (s *AuthService) RefreshToken(
 	ctx context.Context,
@@ -204,6 +407,27 @@ func (s *AuthService) RefreshToken(
 		return nil, err
 	}
 
+	// Revoked sessions have had their token deleted
+	stored, err := s.userRepo.GetRefreshTokenByToken(ctx, refreshToken)
+	if err != nil {
+		if err == repositories.ErrRefreshTokenNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if stored.UserID != claims.UserID {
+		return nil, ErrInvalidToken
+	}
+
+	// Claim the token before issuing new ones: of two refreshes racing with
+	// the same token, only the one whose delete removes it carries on
+	if err := s.userRepo.DeleteUserRefreshToken(ctx, stored.UserID, stored.ID); err != nil {
+		if err == repositories.ErrRefreshTokenNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
 	// Get user
 	user, err := s.userRepo.GetByID(ctx, claims.UserID)
 	if err != nil {
@@ -228,7 +452,7 @@ func (s *AuthService) RefreshToken(
 		user.Username,
 		user.Role,
 		authPermissions,
-		time.Duration(s.jwtConfig.AccessTokenDuration)*time.Minute,
+		s.jwtConfig.AccessTokenDuration,
 	)
 	if err != nil {
 		return nil, err
@@ -240,7 +464,7 @@ func (s *AuthService) RefreshToken(
 		user.Username,
 		user.Role,
 		nil, // No permissions in refresh token
-		time.Duration(s.jwtConfig.RefreshTokenDuration)*time.Hour,
+		s.jwtConfig.RefreshTokenDuration,
 	)
 	if err != nil {
 		return nil, err
@@ -251,10 +475,14 @@ func (s *AuthService) RefreshToken(
 		ID:        uuid.New().String(),
 		UserID:    user.ID,
 		Token:     newRefreshToken,
-		ExpiresAt: time.Now().Add(time.Duration(s.jwtConfig.RefreshTokenDuration) * time.Hour),
+		Label:     stored.Label,
+		IPAddress: stored.IPAddress,
+		UserAgent: stored.UserAgent,
+		ExpiresAt: time.Now().Add(s.jwtConfig.RefreshTokenDuration),
 		CreatedAt: time.Now(),
 	}
 
+	// The session carries on with the new token only
 	err = s.userRepo.SaveRefreshToken(ctx, refreshTokenModel)
 	if err != nil {
 		return nil, err
@@ -266,32 +494,55 @@ func (s *AuthService) RefreshToken(
 	}, nil
 }
 
+// ListSessions returns a user's active sessions, newest first
+func (s *AuthService) ListSessions(ctx context.Context, userID string) ([]Session, error) {
+	tokens, err := s.userRepo.ListRefreshTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, len(tokens))
+	for i, token := range tokens {
+		sessions[i] = Session{
+			ID:        token.ID,
+			Label:     token.Label,
+			IPAddress: token.IPAddress,
+			UserAgent: token.UserAgent,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+		}
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession deletes one of a user's sessions so it can no longer refresh.
+// Access tokens already issued stay valid until they expire.
+func (s *AuthService) RevokeSession(ctx context.Context, userID string, sessionID string) error {
+	err := s.userRepo.DeleteUserRefreshToken(ctx, userID, sessionID)
+	if err == repositories.ErrRefreshTokenNotFound {
+		return ErrSessionNotFound
+	}
+	return err
+}
+
 // VerifyEmail verifies a user's email
 func (s *AuthService) VerifyEmail(
 	ctx context.Context,
 	token string,
 ) error {
-	// TODO: Implement a proper method to find user by verification token
-	// For now, this is a placeholder implementation
-
-	// In a real implementation, we would add a method to the repository:
-	// user, err := s.userRepo.GetByVerificationToken(ctx, token)
-
-	// For now, we'll return an error indicating this needs to be implemented
-	return errors.New("verification token lookup not implemented")
-
-	// Once implemented, the code would look like:
-	/*
-		user, err := s.userRepo.GetByVerificationToken(ctx, token)
-		if err != nil {
-			return err
+	user, err := s.userRepo.GetByVerificationToken(ctx, token)
+	if err != nil {
+		if err == repositories.ErrTokenNotFound {
+			return ErrInvalidToken
 		}
+		return err
+	}
 
-		// Mark user as verified
-		user.IsVerified = true
-		user.VerificationToken = ""
+	// Mark user as verified and burn the token
+	user.IsVerified = true
+	user.VerificationToken = ""
+	user.VerificationTokenExpiresAt = nil
 
-		// Update user
-		return s.userRepo.Update(ctx, user)
-	*/
+	return s.userRepo.Update(ctx, user)
 }
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // Register decoders for the allowed upload types
+	_ "image/jpeg"
+	"image/png"
+	"net/http"
+
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+	"chess-ws-go/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAvatarTooLarge = errors.New("avatar image too large")
+	ErrAvatarType     = errors.New("avatar must be a PNG, JPEG or GIF image")
+)
+
+// allowedAvatarTypes are the MIME types accepted for avatar uploads, as
+// sniffed from the content rather than trusted from the client
+var allowedAvatarTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+}
+
+const (
+	// maxAvatarDimension bounds the width and height of an upload, so a small
+	// file can't decode into a huge bitmap
+	maxAvatarDimension = 4096
+	avatarSize         = 256 // Side of the stored avatar, in pixels
+	avatarThumbSize    = 64  // Side of the thumbnail shown in lists and games
+)
+
+// AvatarService stores profile images. Uploads are cropped to a square and
+// re-encoded as PNG at a fixed size along with a thumbnail, which also drops
+// any metadata (such as photo locations) the original carried.
+type AvatarService struct {
+	userRepo repositories.UserRepository
+	store    storage.Store
+	maxBytes int
+}
+
+// NewAvatarService creates a new avatar service accepting uploads of up to
+// maxBytes
+func NewAvatarService(userRepo repositories.UserRepository, store storage.Store, maxBytes int) *AvatarService {
+	return &AvatarService{
+		userRepo: userRepo,
+		store:    store,
+		maxBytes: maxBytes,
+	}
+}
+
+// MaxBytes returns the largest upload accepted
+func (s *AvatarService) MaxBytes() int {
+	return s.maxBytes
+}
+
+// SetAvatar replaces a user's avatar with the uploaded image
+func (s *AvatarService) SetAvatar(ctx context.Context, userID string, data []byte) (*models.User, error) {
+	if len(data) > s.maxBytes {
+		return nil, ErrAvatarTooLarge
+	}
+	if !allowedAvatarTypes[http.DetectContentType(data)] {
+		return nil, ErrAvatarType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarType
+	}
+	if config.Width > maxAvatarDimension || config.Height > maxAvatarDimension {
+		return nil, ErrAvatarTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarType
+	}
+
+	full, err := encodePNG(scaleSquare(img, avatarSize))
+	if err != nil {
+		return nil, err
+	}
+	thumb, err := encodePNG(scaleSquare(img, avatarThumbSize))
+	if err != nil {
+		return nil, err
+	}
+
+	// A fresh key per upload means clients and caches never see a stale
+	// image under a URL they already know
+	key := userID + "/" + uuid.New().String()
+	if err := s.store.Put(ctx, avatarFile(key), "image/png", full); err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, avatarThumbFile(key), "image/png", thumb); err != nil {
+		s.deleteFiles(ctx, key)
+		return nil, err
+	}
+
+	user, oldKey, err := s.updateUser(ctx, userID, key)
+	if err != nil {
+		s.deleteFiles(ctx, key)
+		return nil, err
+	}
+	if oldKey != "" {
+		s.deleteFiles(ctx, oldKey)
+	}
+	return user, nil
+}
+
+// RemoveAvatar clears a user's avatar
+func (s *AvatarService) RemoveAvatar(ctx context.Context, userID string) (*models.User, error) {
+	user, oldKey, err := s.updateUser(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	if oldKey != "" {
+		s.deleteFiles(ctx, oldKey)
+	}
+	return user, nil
+}
+
+// updateUser points a user's profile at the avatar stored under key, or at
+// none if key is empty, and returns the key it replaced
+func (s *AvatarService) updateUser(ctx context.Context, userID, key string) (*models.User, string, error) {
+	ctx = repositories.ReadPrimary(ctx)
+	for attempt := 0; ; attempt++ {
+		user, err := s.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			if err == repositories.ErrUserNotFound {
+				return nil, "", ErrUserNotFound
+			}
+			return nil, "", err
+		}
+
+		oldKey := user.AvatarKey
+		user.AvatarKey = key
+		user.AvatarURL = ""
+		user.AvatarThumbnailURL = ""
+		if key != "" {
+			user.AvatarURL = s.store.URL(avatarFile(key))
+			user.AvatarThumbnailURL = s.store.URL(avatarThumbFile(key))
+		}
+
+		err = s.userRepo.Update(ctx, user)
+		if err == repositories.ErrUserConflict && attempt < maxUpdateRetries {
+			// Someone else updated the user in the meantime; reload and reapply
+			continue
+		}
+		if err != nil {
+			if err == repositories.ErrUserConflict {
+				return nil, "", ErrUserConflict
+			}
+			return nil, "", err
+		}
+		return user, oldKey, nil
+	}
+}
+
+// deleteFiles removes the images stored under an avatar key. Failures only
+// leave orphaned files behind, so they're logged rather than returned.
+func (s *AvatarService) deleteFiles(ctx context.Context, key string) {
+	for _, file := range []string{avatarFile(key), avatarThumbFile(key)} {
+		if err := s.store.Delete(ctx, file); err != nil {
+			logging.Warnf("Failed to delete avatar file %s: %v", file, err)
+		}
+	}
+}
+
+func avatarFile(key string) string      { return key + ".png" }
+func avatarThumbFile(key string) string { return key + "_thumb.png" }
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleSquare crops the centre square of an image and scales it to size
+// pixels a side. Each output pixel averages the source pixels it covers,
+// which keeps downscaled avatars from looking jagged.
+func scaleSquare(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	originX := bounds.Min.X + (bounds.Dx()-side)/2
+	originY := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0 := originY + y*side/size
+		y1 := max(originY+(y+1)*side/size, y0+1)
+		for x := 0; x < size; x++ {
+			x0 := originX + x*side/size
+			x1 := max(originX+(x+1)*side/size, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+// defaultNoShowTimeout is how long a bracket game may go without the
+// expected first moves before the absent player forfeits
+const defaultNoShowTimeout = 5 * time.Minute
+
+// How a bracket match was decided, besides the game's own end method
+const (
+	MatchBye      = "bye"
+	MatchForfeit  = "forfeit"
+	MatchReported = "reported"
+)
+
+// BracketMatch is one match in a single-elimination bracket. Winners of
+// slots 2n and 2n+1 meet in slot n of the next round.
+type BracketMatch struct {
+	Round    int    `json:"round"`
+	Slot     int    `json:"slot"`
+	WhiteID  string `json:"white_id,omitempty"`
+	BlackID  string `json:"black_id,omitempty"`
+	GameID   string `json:"game_id,omitempty"`
+	WinnerID string `json:"winner_id,omitempty"`
+	Method   string `json:"method,omitempty"`
+}
+
+// StartBracket seeds the registered players into a single-elimination
+// bracket and creates the first round's games. Top seeds receive byes when
+// the player count isn't a power of two.
+func (s *TournamentService) StartBracket(tournamentID string, userID string) ([][]*BracketMatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, exists := s.tournaments[tournamentID]
+	if !exists {
+		return nil, ErrTournamentNotFound
+	}
+	if t.CreatorID != userID {
+		return nil, ErrTournamentForbidden
+	}
+	if t.Format != FormatKnockout {
+		return nil, ErrWrongTournamentFormat
+	}
+	if t.Status != TournamentRegistering {
+		return nil, ErrTournamentStarted
+	}
+	if len(t.Players) < 2 {
+		return nil, ErrNotEnoughPlayers
+	}
+
+	// Seed by rating, or randomly
+	seeded := make([]*TournamentPlayer, len(t.Players))
+	copy(seeded, t.Players)
+	if t.SeedByRating {
+		sort.SliceStable(seeded, func(i, j int) bool {
+			return seeded[i].Rating > seeded[j].Rating
+		})
+	} else {
+		rand.Shuffle(len(seeded), func(i, j int) {
+			seeded[i], seeded[j] = seeded[j], seeded[i]
+		})
+	}
+	for i, p := range seeded {
+		p.Seed = i + 1
+	}
+
+	// Size the bracket to the next power of two and lay out the rounds
+	size := 1
+	rounds := 0
+	for size < len(seeded) {
+		size *= 2
+		rounds++
+	}
+	t.Rounds = rounds
+	t.Bracket = make([][]*BracketMatch, rounds)
+	for r := 0; r < rounds; r++ {
+		matches := size >> (r + 1)
+		t.Bracket[r] = make([]*BracketMatch, matches)
+		for slot := range t.Bracket[r] {
+			t.Bracket[r][slot] = &BracketMatch{Round: r, Slot: slot}
+		}
+	}
+
+	// Fill the first round so seeds 1 and 2 can only meet in the final
+	order := seedOrder(size)
+	t.Status = TournamentRunning
+	for slot, m := range t.Bracket[0] {
+		high, low := order[2*slot], order[2*slot+1]
+		m.WhiteID = seeded[high-1].UserID
+		if low <= len(seeded) {
+			m.BlackID = seeded[low-1].UserID
+		}
+	}
+	for _, m := range t.Bracket[0] {
+		if m.BlackID == "" {
+			s.advance(t, m, m.WhiteID, MatchBye)
+		} else {
+			s.startMatch(t, m)
+		}
+	}
+
+	return t.clone().Bracket, nil
+}
+
+// handleGameOver advances the bracket containing a game that just ended
+func (s *TournamentService) handleGameOver(end GameEnd) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tournaments {
+		if t.Format != FormatKnockout || t.Status != TournamentRunning {
+			continue
+		}
+		if m := t.bracketMatch(end.GameID); m != nil && m.WinnerID == "" {
+			s.stopNoShowTimer(end.GameID)
+			if end.Method == MethodAdminTerminated {
+				// Left for the organizer to settle with a reported result
+				return
+			}
+			if end.Method == MethodAborted {
+				// A player who quit early forfeits, and if nobody moved
+				// before the game timed out, white never showed. Any other
+				// abort, such as an early agreed draw or one by an admin,
+				// is left for the organizer like a terminated game.
+				switch {
+				case end.Outcome != chess.Draw:
+					s.advance(t, m, t.matchWinner(m, end.Outcome), MatchForfeit)
+				case end.NoShow && end.Plies == 0:
+					s.advance(t, m, m.BlackID, MatchForfeit)
+				}
+				return
+			}
+			s.advance(t, m, t.matchWinner(m, end.Outcome), end.Method)
+			return
+		}
+	}
+}
+
+// reportBracketResult records a bracket result reported by hand, e.g. for a
+// game played elsewhere. Callers must hold s.mu.
+func (s *TournamentService) reportBracketResult(t *Tournament, userID string, gameID string, outcome chess.Outcome) error {
+	m := t.bracketMatch(gameID)
+	if m == nil {
+		return ErrPairingNotFound
+	}
+	if userID != t.CreatorID {
+		return ErrTournamentForbidden
+	}
+	if m.WinnerID != "" {
+		return ErrResultAlreadyPresent
+	}
+
+	s.advance(t, m, t.matchWinner(m, outcome), MatchReported)
+	return nil
+}
+
+// checkNoShow forfeits a bracket game that hasn't got going: no move means
+// white never showed up, a single move means black didn't. The game is
+// checked and ended in one step under s.mu, so the match can't be decided
+// some other way in between.
+func (s *TournamentService) checkNoShow(tournamentID string, gameID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.noShowTimers, gameID)
+	t, exists := s.tournaments[tournamentID]
+	if !exists {
+		return
+	}
+	m := t.bracketMatch(gameID)
+	if m == nil || m.WinnerID != "" {
+		return
+	}
+
+	// The hook for the game ending finds the match decided
+	absent, err := s.gameService.ForfeitNoShow(gameID, context.Background(), s.userRepo)
+	if err != nil {
+		return
+	}
+	winner := m.BlackID
+	if absent == chess.Black {
+		winner = m.WhiteID
+	}
+	s.advance(t, m, winner, MatchForfeit)
+}
+
+// startMatch creates the game for a match whose players are both known and
+// arms the no-show check. Callers must hold s.mu.
+func (s *TournamentService) startMatch(t *Tournament, m *BracketMatch) {
+	m.GameID = s.gameService.CreateGameWithTimeControl(m.WhiteID, m.BlackID, t.TimeControl)
+
+	tournamentID, gameID := t.ID, m.GameID
+	s.noShowTimers[gameID] = time.AfterFunc(s.noShowTimeout, func() {
+		s.checkNoShow(tournamentID, gameID)
+	})
+}
+
+// stopNoShowTimer disarms the no-show check of a game that ended or whose
+// match was decided. Callers must hold s.mu.
+func (s *TournamentService) stopNoShowTimer(gameID string) {
+	if timer, ok := s.noShowTimers[gameID]; ok {
+		timer.Stop()
+		delete(s.noShowTimers, gameID)
+	}
+}
+
+// advance records a match winner and moves them into the next round, which
+// starts as soon as both its players are known. Callers must hold s.mu.
+func (s *TournamentService) advance(t *Tournament, m *BracketMatch, winnerID string, method string) {
+	m.WinnerID = winnerID
+	m.Method = method
+	s.stopNoShowTimer(m.GameID)
+
+	if m.Round == len(t.Bracket)-1 {
+		t.WinnerID = winnerID
+		t.Status = TournamentFinished
+		return
+	}
+
+	next := t.Bracket[m.Round+1][m.Slot/2]
+	if m.Slot%2 == 0 {
+		next.WhiteID = winnerID
+	} else {
+		next.BlackID = winnerID
+	}
+
+	if next.WhiteID != "" && next.BlackID != "" {
+		s.startMatch(t, next)
+	}
+}
+
+// matchWinner returns the winner of a match given its game outcome. Knockout
+// matches need a winner, so a draw goes to the higher seed.
+func (t *Tournament) matchWinner(m *BracketMatch, outcome chess.Outcome) string {
+	switch outcome {
+	case chess.WhiteWon:
+		return m.WhiteID
+	case chess.BlackWon:
+		return m.BlackID
+	}
+
+	if t.player(m.BlackID).Seed < t.player(m.WhiteID).Seed {
+		return m.BlackID
+	}
+	return m.WhiteID
+}
+
+// bracketMatch returns the bracket match played in the given game, if any
+func (t *Tournament) bracketMatch(gameID string) *BracketMatch {
+	for _, round := range t.Bracket {
+		for _, m := range round {
+			if m.GameID != "" && m.GameID == gameID {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// seedOrder returns the seeds of a bracket of the given size in slot order,
+// arranged so that higher seeds meet as late as possible
+// (e.g. 1 8 4 5 2 7 3 6 for eight players)
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		n := len(order)*2 + 1
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, n-seed)
+		}
+		order = next
+	}
+	return order
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/corentings/chess/v2"
+)
+
+var (
+	// ErrClockExpired is returned for a move made after the mover's clock ran out
+	ErrClockExpired = errors.New("clock expired")
+	// ErrNoClock is returned when adjusting the clock of a correspondence game
+	ErrNoClock = errors.New("correspondence games have no clock")
+)
+
+// SetServerClock chooses who keeps time in games created from now on. With
+// server clocks, moves are timed on arrival and client time reports are
+// ignored; otherwise clients report their own remaining time.
+func (s *GameService) SetServerClock(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serverClock = enabled
+}
+
+// TimeLeft returns a side's remaining time in seconds. For server clocks
+// this includes the time the side to move has spent on the current turn.
+func (s *GameService) TimeLeft(gameID string, color chess.Color) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, exists := s.gameStates[gameID]
+	if !exists {
+		return 0, fmt.Errorf("game state not found")
+	}
+	return state.timeLeftAt(color, time.Now()), nil
+}
+
+// AddTime puts extra seconds on a side's clock and returns what it then has
+// left. The stored time is adjusted, so a running server clock keeps
+// charging the current turn from when it started.
+func (s *GameService) AddTime(gameID string, color chess.Color, seconds float64) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		return 0, ErrGameNotFound
+	}
+	if game.Outcome() != chess.NoOutcome {
+		return 0, ErrGameOver
+	}
+	if state.TimeSettings.Correspondence() {
+		return 0, ErrNoClock
+	}
+
+	now := time.Now()
+	stored := state.TimeControl.WhiteTimeLeft
+	if color == chess.Black {
+		stored = state.TimeControl.BlackTimeLeft
+	}
+	state.setTimeLeft(color, stored+seconds)
+	return state.timeLeftAt(color, now), nil
+}
+
+// PauseClock stops a server clock, e.g. while a player is disconnected.
+// Time spent on the turn so far is charged first.
+func (s *GameService) PauseClock(gameID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, exists := s.gameStates[gameID]
+	if !exists || !state.ServerClock || state.clockPaused {
+		return
+	}
+	state.chargeTurn(time.Now())
+	state.clockPaused = true
+}
+
+// ResumeClock restarts a paused server clock
+func (s *GameService) ResumeClock(gameID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, exists := s.gameStates[gameID]
+	if !exists || !state.clockPaused {
+		return
+	}
+	state.clockPaused = false
+	state.turnStarted = time.Now()
+}
+
+// timeLeftAt returns a side's remaining time as of now
+func (gs *GameState) timeLeftAt(color chess.Color, now time.Time) float64 {
+	timeLeft := gs.TimeControl.WhiteTimeLeft
+	if color == chess.Black {
+		timeLeft = gs.TimeControl.BlackTimeLeft
+	}
+	if gs.ServerClock && !gs.clockPaused && color == gs.CurrentTurn {
+		timeLeft -= now.Sub(gs.turnStarted).Seconds()
+	}
+	return timeLeft
+}
+
+// chargeTurn takes the time spent on the current turn off the clock of the
+// side to move and restarts the turn at now. It returns what that side has left.
+func (gs *GameState) chargeTurn(now time.Time) float64 {
+	timeLeft := gs.timeLeftAt(gs.CurrentTurn, now)
+	gs.setTimeLeft(gs.CurrentTurn, timeLeft)
+	gs.turnStarted = now
+	return timeLeft
+}
+
+func (gs *GameState) setTimeLeft(color chess.Color, timeLeft float64) {
+	if color == chess.White {
+		gs.TimeControl.WhiteTimeLeft = timeLeft
+	} else {
+		gs.TimeControl.BlackTimeLeft = timeLeft
+	}
+}
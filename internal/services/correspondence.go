@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+
+	"github.com/corentings/chess/v2"
+)
+
+// MaxDaysPerMove is the longest a correspondence game may give a side to move
+const MaxDaysPerMove = 14
+
+// Correspondence reports whether the time control gives each side a number
+// of days per move instead of a running clock. Such games don't need both
+// players online: moves are made whenever the side to move connects, and
+// only the per-move deadline is enforced.
+func (tc TimeControl) Correspondence() bool {
+	return tc.DaysPerMove > 0
+}
+
+// moveWindow is how long a correspondence game gives the side to move
+func (tc TimeControl) moveWindow() time.Duration {
+	return time.Duration(tc.DaysPerMove) * 24 * time.Hour
+}
+
+// Category returns the rating category games with this time control count
+// towards. There is no separate correspondence rating; those games are
+// rated alongside rapid ones.
+func (tc TimeControl) Category() models.RatingCategory {
+	if tc.Correspondence() {
+		return models.RatingRapid
+	}
+	return models.RatingCategoryFor(tc.Initial, tc.Increment)
+}
+
+// OverdueMove identifies a correspondence game whose side to move has let
+// its deadline pass
+type OverdueMove struct {
+	GameID string
+	Color  chess.Color
+}
+
+// OverdueMoves returns the unfinished correspondence games whose move
+// deadline is before now
+func (s *GameService) OverdueMoves(now time.Time) []OverdueMove {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var overdue []OverdueMove
+	for gameID, game := range s.games {
+		state := s.gameStates[gameID]
+		if state == nil || !state.TimeSettings.Correspondence() || game.Outcome() != chess.NoOutcome {
+			continue
+		}
+		if now.After(state.MoveDeadline) {
+			overdue = append(overdue, OverdueMove{GameID: gameID, Color: state.CurrentTurn})
+		}
+	}
+	return overdue
+}
+
+// SetCorrespondenceRepository stores correspondence games in repo as they're
+// played, so RestoreCorrespondenceGames can bring them back after a restart.
+// It must be called before the service creates any games.
+func (s *GameService) SetCorrespondenceRepository(repo repositories.CorrespondenceRepository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.correspondenceRepo = repo
+}
+
+// correspondenceSnapshot captures a correspondence game for storage. It
+// returns nil for other games, or when there's nowhere to store them.
+// Callers must hold s.mu.
+func (s *GameService) correspondenceSnapshot(gameID string, game *chess.Game, state *GameState) *models.CorrespondenceGame {
+	if s.correspondenceRepo == nil || !state.TimeSettings.Correspondence() {
+		return nil
+	}
+	return &models.CorrespondenceGame{
+		ID:           gameID,
+		WhiteID:      state.WhitePlayer,
+		BlackID:      state.BlackPlayer,
+		DaysPerMove:  state.TimeSettings.DaysPerMove,
+		PGN:          game.String(),
+		MoveDeadline: state.MoveDeadline,
+		IsPrivate:    state.Private,
+		Casual:       state.Casual,
+		Events:       encodeEvents(gameID, state.Events),
+		CreatedAt:    state.CreatedAt,
+	}
+}
+
+// saveCorrespondence stores a snapshot taken by correspondenceSnapshot. It
+// does nothing for a nil snapshot. Callers must not hold s.mu.
+func (s *GameService) saveCorrespondence(snapshot *models.CorrespondenceGame) {
+	if snapshot == nil {
+		return
+	}
+	if err := s.correspondenceRepo.Save(context.Background(), snapshot); err != nil {
+		logging.Errorf("Failed to save correspondence game %s: %v", snapshot.ID, err)
+	}
+}
+
+// deleteCorrespondence removes a finished correspondence game's stored copy.
+// Callers must not hold s.mu.
+func (s *GameService) deleteCorrespondence(gameID string) {
+	if err := s.correspondenceRepo.Delete(context.Background(), gameID); err != nil {
+		logging.Errorf("Failed to delete finished correspondence game %s: %v", gameID, err)
+	}
+}
+
+// RestoreCorrespondenceGames loads the correspondence games stored when the
+// server last ran and returns how many it restored. Their move deadlines
+// kept running while it was down, so any that lapsed are timed out by the
+// next deadline sweep.
+func (s *GameService) RestoreCorrespondenceGames(ctx context.Context) (int, error) {
+	if s.correspondenceRepo == nil {
+		return 0, nil
+	}
+	stored, err := s.correspondenceRepo.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	restored := 0
+	for _, record := range stored {
+		if _, exists := s.games[record.ID]; exists {
+			continue
+		}
+		tc := TimeControl{DaysPerMove: record.DaysPerMove}
+		game, err := replayPGN(tc, record.PGN)
+		if err != nil {
+			logging.Warnf("Skipping correspondence game %s with unreadable PGN: %v", record.ID, err)
+			continue
+		}
+
+		var events GameEventLog
+		if record.Events != "" {
+			if err := json.Unmarshal([]byte(record.Events), &events); err != nil {
+				logging.Warnf("Dropping unreadable event log of correspondence game %s: %v", record.ID, err)
+			}
+		}
+
+		s.games[record.ID] = game
+		s.gameStates[record.ID] = &GameState{
+			WhitePlayer:  record.WhiteID,
+			BlackPlayer:  record.BlackID,
+			CurrentTurn:  game.Position().Turn(),
+			TimeSettings: tc,
+			ChatHistory:  []ChatMessage{},
+			turnStarted:  time.Now(),
+			Private:      record.IsPrivate,
+			Casual:       record.Casual,
+			MoveDeadline: record.MoveDeadline,
+			CreatedAt:    record.CreatedAt,
+			Events:       events,
+		}
+		restored++
+	}
+	return restored, nil
+}
+
+// replayPGN rebuilds a game in progress from its PGN. The moves are played
+// out on a new game since a game read from PGN has no outcome at all, rather
+// than none yet, and so would pass for finished.
+func replayPGN(tc TimeControl, pgn string) (*chess.Game, error) {
+	read, err := chess.PGN(strings.NewReader(pgn))
+	if err != nil {
+		return nil, err
+	}
+
+	game := newGame(tc)
+	for _, move := range moveHistory(chess.NewGame(read)) {
+		if err := game.PushMove(normalizePromotion(move), nil); err != nil {
+			return nil, err
+		}
+	}
+	return game, nil
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"chess-ws-go/internal/repositories"
+
+	"github.com/corentings/chess/v2"
+)
+
+// SetMaxDuration caps how long a game may go on, whatever its clocks say.
+// Games still unfinished after d are drawn by the server. Correspondence
+// games are exempt, as their move deadlines already keep them moving. Zero
+// removes the cap.
+func (s *GameService) SetMaxDuration(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxDuration = d
+}
+
+// OverlongGames returns the unfinished games that started more than the
+// maximum duration before now
+func (s *GameService) OverlongGames(now time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxDuration <= 0 {
+		return nil
+	}
+
+	var overlong []string
+	for gameID, game := range s.games {
+		state := s.gameStates[gameID]
+		if state == nil || state.TimeSettings.Correspondence() || game.Outcome() != chess.NoOutcome {
+			continue
+		}
+		if now.Sub(state.CreatedAt) > s.maxDuration {
+			overlong = append(overlong, gameID)
+		}
+	}
+	return overlong
+}
+
+// EndOverlongGame draws a game that ran past the maximum duration. Like any
+// early ending, it's aborted instead if barely any moves were made.
+func (s *GameService) EndOverlongGame(gameID string, ctx context.Context, userRepo repositories.UserRepository) error {
+	s.mu.Lock()
+	var finished *finishedGame
+	defer func() {
+		s.mu.Unlock()
+		s.persistFinished(finished)
+	}()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		return ErrGameNotFound
+	}
+	if game.Outcome() != chess.NoOutcome {
+		return ErrGameOver
+	}
+
+	// The chess library has no such method, so it's recorded as agreed drawn
+	if err := game.Draw(chess.DrawOffer); err != nil {
+		return err
+	}
+	finished = s.finishGame(ctx, gameID, game, state, userRepo, MethodMaxDuration)
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/repositories"
+
+	"github.com/corentings/chess/v2"
+)
+
+// Kinds of entries in a game's event log
+const (
+	EventJoin         = "join"
+	EventMove         = "move"
+	EventChat         = "chat"
+	EventDrawOffer    = "draw_offer"
+	EventDrawDeclined = "draw_declined"
+	EventResign       = "resign"
+	EventAddTime      = "add_time"
+	EventDisconnect   = "disconnect"
+	EventReconnect    = "reconnect"
+	EventGameOver     = "game_over"
+)
+
+// maxGameEvents bounds a game's event log. Once it's full the oldest
+// entries make way, so a marathon game can't grow it without limit.
+const maxGameEvents = 500
+
+// GameEvent is something that happened in a game, kept to settle disputes
+// and replay more than the moves
+type GameEvent struct {
+	Seq    int       `json:"seq"` // Position in the game's log, counting dropped entries
+	Type   string    `json:"type"`
+	Color  string    `json:"color,omitempty"` // Player the event concerns
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// GameEventLog is the retained part of a game's event log
+type GameEventLog struct {
+	Events  []GameEvent `json:"events"`
+	Dropped int         `json:"dropped"` // Oldest entries discarded to bound the log
+}
+
+// record appends an event, discarding the oldest one if the log is full
+func (l *GameEventLog) record(kind string, color chess.Color, detail string) {
+	event := GameEvent{
+		Seq:    l.Dropped + len(l.Events) + 1,
+		Type:   kind,
+		Detail: detail,
+		At:     time.Now(),
+	}
+	if color != chess.NoColor {
+		event.Color = color.Name()
+	}
+
+	if len(l.Events) >= maxGameEvents {
+		copy(l.Events, l.Events[1:])
+		l.Events[len(l.Events)-1] = event
+		l.Dropped++
+		return
+	}
+	l.Events = append(l.Events, event)
+}
+
+// clone returns a copy of the log that shares nothing with it
+func (l GameEventLog) clone() GameEventLog {
+	l.Events = append([]GameEvent(nil), l.Events...)
+	return l
+}
+
+// RecordEvent adds an event that happened outside the game service, such as
+// a player disconnecting, to a game's log. Finished games' logs are closed.
+func (s *GameService) RecordEvent(gameID string, kind string, color chess.Color, detail string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil || game.Outcome() != chess.NoOutcome {
+		return
+	}
+	state.Events.record(kind, color, detail)
+}
+
+// GameEvents returns a finished game's event log. Only its players may see
+// it, and only once it's over, so it can't be used to follow an opponent's
+// connection trouble mid-game; admins may look at any game at any time.
+func (s *GameService) GameEvents(ctx context.Context, gameID string, viewerID string, admin bool) (*GameEventLog, error) {
+	s.mu.Lock()
+	game, live := s.games[gameID]
+	state := s.gameStates[gameID]
+	if live && state != nil {
+		defer s.mu.Unlock()
+		if !admin && viewerID != state.WhitePlayer && viewerID != state.BlackPlayer {
+			return nil, ErrGameForbidden
+		}
+		if !admin && game.Outcome() == chess.NoOutcome {
+			return nil, ErrGameInProgress
+		}
+		events := state.Events.clone()
+		return &events, nil
+	}
+	s.mu.Unlock()
+
+	if s.gameRepo == nil {
+		return nil, ErrGameNotFound
+	}
+
+	record, err := s.gameRepo.GetByID(ctx, gameID)
+	if err != nil {
+		if err == repositories.ErrGameNotFound {
+			return nil, ErrGameNotFound
+		}
+		return nil, err
+	}
+	if !admin && viewerID != record.WhiteID && viewerID != record.BlackID {
+		return nil, ErrGameForbidden
+	}
+
+	events := &GameEventLog{Events: []GameEvent{}}
+	if record.Events != "" {
+		if err := json.Unmarshal([]byte(record.Events), events); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// encodeEvents serializes a game's event log for storage with the finished
+// game. A log that can't be encoded is left out rather than losing the game.
+func encodeEvents(gameID string, events GameEventLog) string {
+	data, err := json.Marshal(events)
+	if err != nil {
+		logging.Warnf("Failed to encode event log of game %s: %v", gameID, err)
+		return ""
+	}
+	return string(data)
+}
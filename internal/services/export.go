@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+
+	"github.com/corentings/chess/v2"
+)
+
+// maxExportRows caps each list in a data export. It's far above what a
+// real account accumulates, and only stops a runaway query.
+const maxExportRows = 10000
+
+// UserExport is everything stored about a user, as handed to them on
+// request. The profile's JSON form already omits password hashes and
+// tokens.
+type UserExport struct {
+	ExportedAt   time.Time            `json:"exported_at"`
+	Profile      *models.User         `json:"profile"`
+	Games        []*models.GameRecord `json:"games"`
+	ChatMessages []SentChatMessage    `json:"chat_messages"`
+	AuditEntries []*models.AuditEntry `json:"audit_entries"`
+}
+
+// SentChatMessage is a chat message a user sent in a game
+type SentChatMessage struct {
+	GameID  string `json:"game_id"`
+	Message string `json:"message"`
+}
+
+// ExportService assembles a user's data from the subsystems that hold it
+type ExportService struct {
+	userRepo    repositories.UserRepository
+	gameRepo    repositories.GameRepository
+	gameService *GameService
+	auditLogger *AuditLogger
+}
+
+// NewExportService creates a new export service
+func NewExportService(
+	userRepo repositories.UserRepository,
+	gameRepo repositories.GameRepository,
+	gameService *GameService,
+	auditLogger *AuditLogger,
+) *ExportService {
+	return &ExportService{
+		userRepo:    userRepo,
+		gameRepo:    gameRepo,
+		gameService: gameService,
+		auditLogger: auditLogger,
+	}
+}
+
+// Export gathers a user's profile, finished games, chat messages and audit
+// entries. Other people's chat messages are left out, even from the user's
+// own games, and so are the addresses others acted on the account from.
+func (s *ExportService) Export(ctx context.Context, userID string) (*UserExport, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if err == repositories.ErrUserNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	games, err := s.gameRepo.ListByPlayer(ctx, userID, maxExportRows)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.auditEntries(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserExport{
+		ExportedAt:   time.Now(),
+		Profile:      user,
+		Games:        games,
+		ChatMessages: s.chatMessages(user, games),
+		AuditEntries: entries,
+	}, nil
+}
+
+// chatMessages returns what a user said in their finished games, as their
+// event logs recorded it, and in games still held in memory
+func (s *ExportService) chatMessages(user *models.User, games []*models.GameRecord) []SentChatMessage {
+	messages := []SentChatMessage{}
+	persisted := make(map[string]bool, len(games))
+	for _, game := range games {
+		persisted[game.ID] = true
+		messages = append(messages, chatMessagesIn(game, user)...)
+	}
+
+	// Games that ended recently are both stored and still in memory
+	for _, message := range s.gameService.ChatMessagesBy(user.Username) {
+		if !persisted[message.GameID] {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// chatMessagesIn returns the chat messages a user sent in a finished game.
+// Entries are matched on the user's side as well as their name, so what
+// someone else said from a seat they took over stays out.
+func chatMessagesIn(game *models.GameRecord, user *models.User) []SentChatMessage {
+	if game.Events == "" {
+		return nil
+	}
+	var events GameEventLog
+	if err := json.Unmarshal([]byte(game.Events), &events); err != nil {
+		logging.Warnf("Skipping unreadable event log of game %s in export: %v", game.ID, err)
+		return nil
+	}
+
+	var color string
+	switch user.ID {
+	case game.WhiteID:
+		color = chess.White.Name()
+	case game.BlackID:
+		color = chess.Black.Name()
+	}
+
+	var messages []SentChatMessage
+	for _, event := range events.Events {
+		if event.Type != EventChat || event.Color != color {
+			continue
+		}
+		// Logged as "username: message"
+		sender, message, ok := strings.Cut(event.Detail, ": ")
+		if ok && sender == user.Username {
+			messages = append(messages, SentChatMessage{GameID: game.ID, Message: message})
+		}
+	}
+	return messages
+}
+
+// auditEntries returns the audit entries a user performed or was the
+// target of, newest first
+func (s *ExportService) auditEntries(ctx context.Context, userID string) ([]*models.AuditEntry, error) {
+	performed, err := s.auditLogger.Query(ctx, repositories.AuditFilter{ActorID: userID, Limit: maxExportRows})
+	if err != nil {
+		return nil, err
+	}
+	received, err := s.auditLogger.Query(ctx, repositories.AuditFilter{TargetID: userID, Limit: maxExportRows})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := performed
+	for _, entry := range received {
+		if entry.ActorID == userID {
+			continue // Already listed as performed
+		}
+		// The address belongs to whoever acted, such as an admin
+		redacted := *entry
+		redacted.IP = ""
+		entries = append(entries, &redacted)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+	return entries, nil
+}
+
+// ChatMessagesBy returns the chat messages a user has sent in games still
+// held in memory. What was said in finished games is also kept in their
+// stored event logs.
+func (s *GameService) ChatMessagesBy(username string) []SentChatMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := []SentChatMessage{}
+	for gameID, state := range s.gameStates {
+		for _, chat := range state.ChatHistory {
+			if chat.Sender == username {
+				messages = append(messages, SentChatMessage{GameID: gameID, Message: chat.Message})
+			}
+		}
+	}
+	return messages
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+)
+
+var (
+	ErrCannotFriendSelf  = errors.New("cannot add yourself as a friend")
+	ErrAlreadyFriends    = errors.New("friendship already exists")
+	ErrFriendRequestGone = errors.New("friend request not found")
+)
+
+// Presence statuses reported for friends
+const (
+	PresenceOffline = "offline"
+	PresenceOnline  = "online"
+	PresenceInGame  = "in_game"
+)
+
+// PresenceProvider reports whether users are currently connected or playing
+type PresenceProvider interface {
+	IsOnline(userID string) bool
+	IsInGame(userID string) bool
+}
+
+// FriendPresence is a friend together with their current online status
+type FriendPresence struct {
+	*models.Friend
+	Status string `json:"status"`
+}
+
+// FriendService handles friend requests and friend lists
+type FriendService struct {
+	friendRepo repositories.FriendshipRepository
+	userRepo   repositories.UserRepository
+	presence   PresenceProvider
+}
+
+// NewFriendService creates a new friend service
+func NewFriendService(
+	friendRepo repositories.FriendshipRepository,
+	userRepo repositories.UserRepository,
+	presence PresenceProvider,
+) *FriendService {
+	return &FriendService{
+		friendRepo: friendRepo,
+		userRepo:   userRepo,
+		presence:   presence,
+	}
+}
+
+// SendRequest sends a friend request to the user with the given username.
+// If that user already asked to be friends, the request is accepted instead.
+func (s *FriendService) SendRequest(ctx context.Context, userID string, username string) error {
+	target, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		if err == repositories.ErrUserNotFound {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	if target.ID == userID {
+		return ErrCannotFriendSelf
+	}
+
+	existing, err := s.friendRepo.Get(ctx, userID, target.ID)
+	if err == nil {
+		// The other user asked first, so this request completes the friendship
+		if existing.Status == models.FriendshipPending && existing.RequesterID == target.ID {
+			return s.friendRepo.Accept(ctx, target.ID, userID)
+		}
+		return ErrAlreadyFriends
+	} else if err != repositories.ErrFriendshipNotFound {
+		return err
+	}
+
+	err = s.friendRepo.CreateRequest(ctx, userID, target.ID)
+	if err == repositories.ErrFriendshipExists {
+		return ErrAlreadyFriends
+	}
+	return err
+}
+
+// AcceptRequest accepts a pending friend request sent by requesterID
+func (s *FriendService) AcceptRequest(ctx context.Context, userID string, requesterID string) error {
+	err := s.friendRepo.Accept(ctx, requesterID, userID)
+	if err == repositories.ErrFriendshipNotFound {
+		return ErrFriendRequestGone
+	}
+	return err
+}
+
+// RemoveFriend removes a friend, or declines/cancels a pending request
+func (s *FriendService) RemoveFriend(ctx context.Context, userID string, otherID string) error {
+	err := s.friendRepo.Delete(ctx, userID, otherID)
+	if err == repositories.ErrFriendshipNotFound {
+		return ErrFriendRequestGone
+	}
+	return err
+}
+
+// ListFriends returns a user's friends along with their current presence
+func (s *FriendService) ListFriends(ctx context.Context, userID string) ([]FriendPresence, error) {
+	friends, err := s.friendRepo.ListFriends(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]FriendPresence, len(friends))
+	for i, friend := range friends {
+		result[i] = FriendPresence{
+			Friend: friend,
+			Status: s.presenceOf(friend.UserID),
+		}
+	}
+
+	return result, nil
+}
+
+// ListRequests returns the pending friend requests sent to a user
+func (s *FriendService) ListRequests(ctx context.Context, userID string) ([]*models.Friend, error) {
+	return s.friendRepo.ListPendingRequests(ctx, userID)
+}
+
+// presenceOf resolves the presence status of a single user
+func (s *FriendService) presenceOf(userID string) string {
+	if s.presence == nil {
+		return PresenceOffline
+	}
+	if s.presence.IsInGame(userID) {
+		return PresenceInGame
+	}
+	if s.presence.IsOnline(userID) {
+		return PresenceOnline
+	}
+	return PresenceOffline
+}
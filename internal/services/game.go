@@ -2,34 +2,259 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/models"
 	"chess-ws-go/internal/repositories"
+	"chess-ws-go/internal/stats"
 
 	"github.com/corentings/chess/v2"
 	"github.com/google/uuid"
 )
 
+var (
+	ErrGameNotFound  = errors.New("game not found")
+	ErrGameForbidden = errors.New("not allowed to view this game")
+	ErrGameOver      = errors.New("game is already over")
+	ErrGameStarted   = errors.New("game has already started")
+
+	ErrNoDrawOffer    = errors.New("no draw was offered")
+	ErrOwnDrawOffer   = errors.New("you can't answer your own draw offer")
+	ErrDrawOfferStale = errors.New("the draw offer lapsed when a move was made")
+)
+
 // GameService handles chess game logic
 type GameService struct {
-	games      map[string]*chess.Game
-	gameStates map[string]*GameState
-	mu         sync.Mutex
+	games              map[string]*chess.Game
+	gameStates         map[string]*GameState
+	gameRepo           repositories.GameRepository
+	gameOverHooks      []GameOverHook
+	correspondenceRepo repositories.CorrespondenceRepository // Correspondence games in progress; nil keeps them in memory only
+	serverClock        bool                                  // New games are timed by the server
+	abortPlies         int                                   // Games left before this many plies are aborted rather than lost
+	maxDuration        time.Duration                         // Longest a clocked game may last before it's drawn; 0 for no limit
+	moveObserver       func(time.Duration)                   // Receives MakeMove latencies; nil when metrics are off
+	notifier           *NotificationService                  // Emails players a summary of stored games; nil sends none
+	mu                 sync.Mutex
+}
+
+// GameEnd describes a game that just ended
+type GameEnd struct {
+	GameID  string
+	Outcome chess.Outcome
+	Method  string
+	Plies   int  // Half-moves played
+	NoShow  bool // Aborted because nobody made the first move in time
+}
+
+// GameOverHook is called, on its own goroutine, whenever a game ends
+type GameOverHook func(end GameEnd)
+
+// TimeControl describes the clock settings a game is started with, in seconds.
+// Initial and Increment apply to both sides unless black's are set, which
+// gives a time-odds game. Correspondence games set DaysPerMove instead.
+type TimeControl struct {
+	Initial        float64  `json:"initial"`
+	Increment      float64  `json:"increment"`
+	BlackInitial   *float64 `json:"black_initial,omitempty"`
+	BlackIncrement *float64 `json:"black_increment,omitempty"`
+	DaysPerMove    int      `json:"days_per_move,omitempty"`
+}
+
+// InitialFor returns the starting clock of the given side
+func (tc TimeControl) InitialFor(color chess.Color) float64 {
+	if color == chess.Black && tc.BlackInitial != nil {
+		return *tc.BlackInitial
+	}
+	return tc.Initial
+}
+
+// IncrementFor returns the per-move increment of the given side
+func (tc TimeControl) IncrementFor(color chess.Color) float64 {
+	if color == chess.Black && tc.BlackIncrement != nil {
+		return *tc.BlackIncrement
+	}
+	return tc.Increment
+}
+
+// HasOdds reports whether the two sides start with different clocks
+func (tc TimeControl) HasOdds() bool {
+	return tc.InitialFor(chess.White) != tc.InitialFor(chess.Black) ||
+		tc.IncrementFor(chess.White) != tc.IncrementFor(chess.Black)
+}
+
+// Valid reports whether both sides get some time and no negative increment.
+// Correspondence games must have no clock and at most MaxDaysPerMove days.
+func (tc TimeControl) Valid() bool {
+	if tc.DaysPerMove != 0 {
+		return tc.DaysPerMove > 0 && tc.DaysPerMove <= MaxDaysPerMove &&
+			tc.Initial == 0 && tc.Increment == 0 && !tc.HasOdds()
+	}
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		if tc.InitialFor(color) <= 0 || tc.IncrementFor(color) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// pgnTag formats one side's clock settings as a PGN TimeControl value
+func (tc TimeControl) pgnTag(color chess.Color) string {
+	if tc.Correspondence() {
+		return fmt.Sprintf("1/%d", int(tc.moveWindow().Seconds()))
+	}
+	return fmt.Sprintf("%g+%g", tc.InitialFor(color), tc.IncrementFor(color))
+}
+
+// WithRatingOdds gives the stronger player a berserk clock (half the time,
+// no increment) when the rating difference is at least gap. A gap of zero
+// or less disables time odds.
+func WithRatingOdds(tc TimeControl, whiteRating, blackRating, gap int) TimeControl {
+	if gap <= 0 {
+		return tc
+	}
+
+	diff := whiteRating - blackRating
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff < gap {
+		return tc
+	}
+
+	berserkInitial, noIncrement := tc.Initial/2, 0.0
+	blackInitial, blackIncrement := tc.InitialFor(chess.Black), tc.IncrementFor(chess.Black)
+	if whiteRating > blackRating {
+		tc.Initial, tc.Increment = berserkInitial, noIncrement
+	} else {
+		blackInitial, blackIncrement = berserkInitial, noIncrement
+	}
+	tc.BlackInitial, tc.BlackIncrement = &blackInitial, &blackIncrement
+
+	return tc
+}
+
+// WithoutOdds returns the time control both sides would have had without
+// odds: the clock of the side that wasn't handicapped, for both
+func (tc TimeControl) WithoutOdds() TimeControl {
+	if !tc.HasOdds() {
+		return tc
+	}
+	if tc.InitialFor(chess.Black) > tc.Initial {
+		tc.Initial, tc.Increment = tc.InitialFor(chess.Black), tc.IncrementFor(chess.Black)
+	}
+	tc.BlackInitial, tc.BlackIncrement = nil, nil
+	return tc
+}
+
+// DefaultTimeControl is used when a game is created without explicit clock settings
+var DefaultTimeControl = TimeControl{
+	Initial:   600, // 10 minutes in seconds
+	Increment: 0,
 }
 
 // GameState represents the current state of a chess game
 type GameState struct {
-	WhitePlayer string
-	BlackPlayer string
-	CurrentTurn chess.Color
-	DrawOffered bool
-	TimeControl struct {
+	WhitePlayer  string
+	BlackPlayer  string
+	CurrentTurn  chess.Color
+	DrawOffered  bool
+	TimeSettings TimeControl
+	TimeControl  struct {
 		WhiteTimeLeft float64
 		BlackTimeLeft float64
 	}
-	ChatHistory []ChatMessage
+	ChatHistory  []ChatMessage
+	ServerClock  bool      // The server times moves; client time reports are ignored
+	turnStarted  time.Time // When the side to move's server clock started running
+	clockPaused  bool      // The server clock is stopped, e.g. for a disconnect
+	TimedOut     bool      // Game ended because a player's clock ran out
+	EndMethod    string    // How the game ended, set once it is over
+	Private      bool      // Only the players may view the game while it is live
+	Casual       bool      // Ratings are left alone when the game ends
+	MoveDeadline time.Time // Correspondence games: when the side to move loses on time
+	OpenSeats    bool      // Spectators may take over a seat its player abandoned; casual games only
+	noShow       bool      // Aborted for want of a first move
+	CreatedAt    time.Time
+
+	// The pending draw offer, if DrawOffered. Any move withdraws it.
+	DrawOfferedBy chess.Color
+	DrawOfferPly  int // Plies played when the offer was made
+
+	// What happened in the game, for replay and disputes
+	Events GameEventLog
+}
+
+// clone returns a deep copy of the state
+func (gs *GameState) clone() *GameState {
+	c := *gs
+	c.ChatHistory = append([]ChatMessage(nil), gs.ChatHistory...)
+	c.Events = gs.Events.clone()
+	return &c
+}
+
+// GameDetails is the full state of a live or finished game
+type GameDetails struct {
+	ID            string      `json:"id"`
+	Live          bool        `json:"live"`
+	Private       bool        `json:"private"`
+	FEN           string      `json:"fen"`
+	PGN           string      `json:"pgn"`
+	Moves         []string    `json:"moves"`
+	Turn          string      `json:"turn,omitempty"`
+	WhitePlayer   string      `json:"white_player"`
+	BlackPlayer   string      `json:"black_player"`
+	WhiteRating   int         `json:"white_rating"`
+	BlackRating   int         `json:"black_rating"`
+	TimeControl   TimeControl `json:"time_control"`
+	WhiteTimeLeft float64     `json:"white_time_left"`
+	BlackTimeLeft float64     `json:"black_time_left"`
+	Outcome       string      `json:"outcome,omitempty"`
+	Method        string      `json:"method,omitempty"`
+}
+
+// LiveGame is a compact snapshot of a game for clients polling over HTTP
+type LiveGame struct {
+	ID            string  `json:"id"`
+	FEN           string  `json:"fen"`
+	Ply           int     `json:"ply"`
+	Turn          string  `json:"turn,omitempty"`
+	LastMove      string  `json:"last_move,omitempty"`
+	WhiteTimeLeft float64 `json:"white_time_left"`
+	BlackTimeLeft float64 `json:"black_time_left"`
+	Outcome       string  `json:"outcome,omitempty"`
+	Method        string  `json:"method,omitempty"`
+}
+
+// GameSnapshot is the full state of a game as WebSocket clients see it when
+// they join, reconnect or ask for it
+type GameSnapshot struct {
+	Position     string     `json:"position"` // FEN
+	Turn         string     `json:"turn"`     // "w" or "b"
+	WhitePlayer  string     `json:"whitePlayer"`
+	BlackPlayer  string     `json:"blackPlayer"`
+	WhiteTime    float64    `json:"whiteTime"`              // Seconds left
+	BlackTime    float64    `json:"blackTime"`              // Seconds left
+	MoveDeadline *time.Time `json:"moveDeadline,omitempty"` // Correspondence games only
+	Outcome      string     `json:"outcome,omitempty"`      // Set once the game is over
+	Method       string     `json:"method,omitempty"`       // Set once the game is over
+	DrawCounters
+}
+
+// DrawCounters describe how close a game's current position is to a
+// claimable draw
+type DrawCounters struct {
+	HalfmoveClock int  `json:"halfmoveClock"` // Plies since the last capture or pawn move
+	Repetitions   int  `json:"repetitions"`   // Times the current position has occurred
+	CanClaim      bool `json:"canClaim"`      // A fifty-move or threefold repetition draw can be claimed
 }
 
 // ChatMessage represents a chat message in a game
@@ -38,41 +263,158 @@ type ChatMessage struct {
 	Message string
 }
 
-// NewGameService creates a new game service
-func NewGameService() *GameService {
+// NewGameService creates a new game service. Finished games are persisted
+// to gameRepo when it is non-nil.
+func NewGameService(gameRepo repositories.GameRepository) *GameService {
 	return &GameService{
 		games:      make(map[string]*chess.Game),
 		gameStates: make(map[string]*GameState),
+		gameRepo:   gameRepo,
 	}
 }
 
 // CreateGame creates a new chess game and returns its ID
 func (s *GameService) CreateGame(whitePlayer, blackPlayer string) string {
+	return s.CreateGameWithTimeControl(whitePlayer, blackPlayer, DefaultTimeControl)
+}
+
+// GameOptions sets how a new game is played and who may watch it. Given at
+// creation, a game is never briefly public or rated before they apply.
+type GameOptions struct {
+	Casual    bool // Ratings are left alone when the game ends
+	OpenSeats bool // Spectators may take over a seat its player abandoned; casual games only
+	Private   bool // Only the players may view the game while it is live
+}
+
+// CreateGameWithTimeControl creates a new chess game using the given clock settings
+func (s *GameService) CreateGameWithTimeControl(whitePlayer, blackPlayer string, tc TimeControl) string {
+	return s.CreateGameWithOptions(whitePlayer, blackPlayer, tc, GameOptions{})
+}
+
+// CreateGameWithOptions creates a new chess game using the given clock
+// settings and options
+func (s *GameService) CreateGameWithOptions(whitePlayer, blackPlayer string, tc TimeControl, opts GameOptions) string {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	gameID := uuid.New().String()
-	s.games[gameID] = chess.NewGame()
+	game := newGame(tc)
+	now := time.Now()
+	s.games[gameID] = game
 	s.gameStates[gameID] = &GameState{
-		WhitePlayer: whitePlayer,
-		BlackPlayer: blackPlayer,
-		CurrentTurn: chess.White,
-		DrawOffered: false,
+		WhitePlayer:  whitePlayer,
+		BlackPlayer:  blackPlayer,
+		CurrentTurn:  chess.White,
+		DrawOffered:  false,
+		TimeSettings: tc,
 		TimeControl: struct {
 			WhiteTimeLeft float64
 			BlackTimeLeft float64
 		}{
-			WhiteTimeLeft: 600, // 10 minutes in seconds
-			BlackTimeLeft: 600,
+			WhiteTimeLeft: tc.InitialFor(chess.White),
+			BlackTimeLeft: tc.InitialFor(chess.Black),
 		},
 		ChatHistory: []ChatMessage{},
+		ServerClock: s.serverClock && !tc.Correspondence(),
+		Private:     opts.Private,
+		Casual:      opts.Casual,
+		OpenSeats:   opts.Casual && opts.OpenSeats,
+		turnStarted: now,
+		CreatedAt:   now,
 	}
+	if tc.Correspondence() {
+		s.gameStates[gameID].MoveDeadline = now.Add(tc.moveWindow())
+	}
+	snapshot := s.correspondenceSnapshot(gameID, game, s.gameStates[gameID])
+	s.mu.Unlock()
 
+	s.saveCorrespondence(snapshot)
 	return gameID
 }
 
-// GetGame returns a chess game by ID
-func (s *GameService) GetGame(gameID string) (*chess.Game, error) {
+// newGame starts a game from the initial position with its time control
+// recorded in the PGN tags
+func newGame(tc TimeControl) *chess.Game {
+	game := chess.NewGame()
+	if tc.HasOdds() {
+		game.AddTagPair("WhiteTimeControl", tc.pgnTag(chess.White))
+		game.AddTagPair("BlackTimeControl", tc.pgnTag(chess.Black))
+	} else {
+		game.AddTagPair("TimeControl", tc.pgnTag(chess.White))
+	}
+	return game
+}
+
+// SetPrivate hides a game from everyone but its players, in the lobby and
+// from spectators, while it is live
+func (s *GameService) SetPrivate(gameID string) error {
+	s.mu.Lock()
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		s.mu.Unlock()
+		return ErrGameNotFound
+	}
+	state.Private = true
+	snapshot := s.correspondenceSnapshot(gameID, game, state)
+	s.mu.Unlock()
+
+	s.saveCorrespondence(snapshot)
+	return nil
+}
+
+// SetMoveObserver registers a callback that receives how long each MakeMove
+// call took. It must be called before the service starts handling moves.
+func (s *GameService) SetMoveObserver(observe func(time.Duration)) {
+	s.moveObserver = observe
+}
+
+// OnGameOver registers a hook to run whenever a game ends
+func (s *GameService) OnGameOver(hook GameOverHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gameOverHooks = append(s.gameOverHooks, hook)
+}
+
+// MoveCount returns the number of half-moves played in a game
+func (s *GameService) MoveCount(gameID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, exists := s.games[gameID]
+	if !exists {
+		return 0, fmt.Errorf("game not found")
+	}
+	return len(game.Moves()), nil
+}
+
+// GameView is a snapshot of a game's position and result. The live game
+// changes under the service lock, so callers get a copy taken under it,
+// which they can read freely while moves continue.
+type GameView struct {
+	FEN     string
+	Turn    chess.Color
+	Outcome chess.Outcome
+	Method  chess.Method
+	Moves   []string // In UCI notation, e.g. e7e8n for an underpromotion
+	PGN     string
+}
+
+// Over reports whether the game had ended when the snapshot was taken
+func (v *GameView) Over() bool {
+	return v.Outcome != chess.NoOutcome
+}
+
+// Promotion returns the piece the last move promoted a pawn to, or an empty
+// string if it wasn't a promotion
+func (v *GameView) Promotion() string {
+	if n := len(v.Moves); n > 0 && len(v.Moves[n-1]) == 5 {
+		return v.Moves[n-1][4:]
+	}
+	return ""
+}
+
+// GetGame returns a snapshot of a chess game by ID
+func (s *GameService) GetGame(gameID string) (*GameView, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -80,10 +422,24 @@ func (s *GameService) GetGame(gameID string) (*chess.Game, error) {
 	if !exists {
 		return nil, fmt.Errorf("game not found")
 	}
-	return game, nil
+
+	moves := game.Moves()
+	view := &GameView{
+		FEN:     game.FEN(),
+		Turn:    game.Position().Turn(),
+		Outcome: game.Outcome(),
+		Method:  game.Method(),
+		Moves:   make([]string, len(moves)),
+		PGN:     pgnOf(game, s.gameStates[gameID]),
+	}
+	for i, move := range moves {
+		view.Moves[i] = move.String()
+	}
+	return view, nil
 }
 
-// GetGameState returns the state of a game by ID
+// GetGameState returns a copy of the state of a game by ID. The copy is
+// taken under the lock, so callers can read it freely while moves continue.
 func (s *GameService) GetGameState(gameID string) (*GameState, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -92,55 +448,192 @@ func (s *GameService) GetGameState(gameID string) (*GameState, error) {
 	if !exists {
 		return nil, fmt.Errorf("game state not found")
 	}
-	return state, nil
+	return state.clone(), nil
 }
 
-// MakeMove makes a move in a chess game
-func (s *GameService) MakeMove(gameID, moveStr string, ctx context.Context, userRepo repositories.UserRepository) error {
+// GetLiveGame returns a snapshot of a game held in memory. Spectators may
+// read it unless the game is private.
+func (s *GameService) GetLiveGame(gameID string, viewerID string) (*LiveGame, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		return nil, ErrGameNotFound
+	}
+	if state.Private && viewerID != state.WhitePlayer && viewerID != state.BlackPlayer {
+		return nil, ErrGameForbidden
+	}
+
+	live := &LiveGame{
+		ID:            gameID,
+		FEN:           game.FEN(),
+		Ply:           len(game.Moves()),
+		WhiteTimeLeft: state.TimeControl.WhiteTimeLeft,
+		BlackTimeLeft: state.TimeControl.BlackTimeLeft,
+	}
+	if history := moveHistory(game); len(history) > 0 {
+		live.LastMove = history[len(history)-1]
+	}
+	if game.Outcome() == chess.NoOutcome {
+		live.Turn = game.Position().Turn().Name()
+	} else {
+		live.Outcome = resultOf(game, state)
+		live.Method = state.EndMethod
+	}
+
+	return live, nil
+}
+
+// Snapshot returns the position, players, clocks and draw counters of a
+// game, read together so they describe a single moment
+func (s *GameService) Snapshot(gameID string) (*GameSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		return nil, ErrGameNotFound
+	}
+
+	snapshot := &GameSnapshot{
+		Position:     game.Position().String(),
+		Turn:         game.Position().Turn().String(),
+		WhitePlayer:  state.WhitePlayer,
+		BlackPlayer:  state.BlackPlayer,
+		WhiteTime:    state.TimeControl.WhiteTimeLeft,
+		BlackTime:    state.TimeControl.BlackTimeLeft,
+		DrawCounters: drawCounters(game),
+	}
+	if state.TimeSettings.Correspondence() {
+		deadline := state.MoveDeadline
+		snapshot.MoveDeadline = &deadline
+	}
+	if game.Outcome() != chess.NoOutcome {
+		snapshot.Outcome = resultOf(game, state)
+		snapshot.Method = state.EndMethod
+	}
+
+	return snapshot, nil
+}
+
+// GetBoard returns the current board of a game for a viewer. Anyone may
+// view a game unless it is private.
+func (s *GameService) GetBoard(gameID string, viewerID string) (*chess.Board, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		return nil, ErrGameNotFound
+	}
+	if state.Private && viewerID != state.WhitePlayer && viewerID != state.BlackPlayer {
+		return nil, ErrGameForbidden
+	}
+
+	return game.Position().Board(), nil
+}
+
+// MakeMove makes a move in a chess game and returns it in standard algebraic
+// notation, which is how it's recorded whatever notation it was sent in
+func (s *GameService) MakeMove(gameID, moveStr string, ctx context.Context, userRepo repositories.UserRepository) (string, error) {
+	// Timed from before the lock so contention shows up in the latency
+	if observe := s.moveObserver; observe != nil {
+		start := time.Now()
+		defer func() { observe(time.Since(start)) }()
+	}
+
+	s.mu.Lock()
+	var finished *finishedGame
+	var snapshot *models.CorrespondenceGame
+	defer func() {
+		s.mu.Unlock()
+		s.persistFinished(finished)
+		s.saveCorrespondence(snapshot)
+	}()
+
 	game, exists := s.games[gameID]
 	if !exists {
-		return fmt.Errorf("game not found")
+		return "", fmt.Errorf("game not found")
 	}
 
 	state, exists := s.gameStates[gameID]
 	if !exists {
-		return fmt.Errorf("game state not found")
+		return "", fmt.Errorf("game state not found")
+	}
+
+	// Late moves, e.g. sent just after checkmate, must not touch a finished game
+	if game.Outcome() != chess.NoOutcome {
+		return "", ErrGameOver
 	}
 
 	// Verify it's the correct player's turn
 	if game.Position().Turn() != state.CurrentTurn {
-		return fmt.Errorf("not your turn")
+		return "", fmt.Errorf("not your turn")
+	}
+
+	// Server clocks charge the mover for the turn before anything else
+	now := time.Now()
+	if state.ServerClock && !state.clockPaused && state.chargeTurn(now) <= 0 {
+		return "", ErrClockExpired
+	}
+	if state.TimeSettings.Correspondence() && now.After(state.MoveDeadline) {
+		return "", ErrClockExpired
 	}
 
 	// Make the move
-	err := game.PushMove(moveStr, nil)
+	err := game.PushMove(normalizePromotion(moveStr), nil)
 	if err != nil {
-		return fmt.Errorf("invalid move: %w", err)
+		return "", fmt.Errorf("invalid move: %w", err)
+	}
+	san := lastMoveSAN(game)
+	state.Events.record(EventMove, state.CurrentTurn, san)
+
+	if state.ServerClock {
+		mover := state.CurrentTurn
+		state.setTimeLeft(mover, state.timeLeftAt(mover, now)+state.TimeSettings.IncrementFor(mover))
+		state.turnStarted = now
+	}
+	if state.TimeSettings.Correspondence() {
+		state.MoveDeadline = now.Add(state.TimeSettings.moveWindow())
 	}
 
 	// Update turn
-	state.CurrentTurn = chess.Color(1 - int(state.CurrentTurn))
+	state.CurrentTurn = state.CurrentTurn.Other()
 
-	// Reset draw offer after a move
+	// A move withdraws any pending draw offer, made by either side. This
+	// happens under s.mu with the move itself, so a draw can't be accepted
+	// in a position the offer wasn't made in.
 	state.DrawOffered = false
 
-	// Check if the game is over after this move
-	isOver, _, _, _ := s.IsGameOver(gameID)
-	if isOver && ctx != nil && userRepo != nil {
-		// Update ELO ratings if game is over
-		_ = s.UpdatePlayerRatings(ctx, gameID, state.WhitePlayer, state.BlackPlayer, userRepo)
+	// Check if the move ended the game. The chess library detects checkmate,
+	// stalemate and automatic draws such as insufficient material itself.
+	outcome := game.Outcome()
+	if outcome != chess.NoOutcome {
+		if game.Method() == chess.InsufficientMaterial {
+			logging.Infof("Game %s drawn automatically by insufficient material", gameID)
+		}
+
+		// Update ELO ratings and persist the finished game
+		finished = s.finishGame(ctx, gameID, game, state, userRepo, MethodName(game.Method()))
+	} else {
+		snapshot = s.correspondenceSnapshot(gameID, game, state)
 	}
 
-	return nil
+	return san, nil
 }
 
 // ResignGame handles a player resigning
 func (s *GameService) ResignGame(gameID string, color chess.Color, ctx context.Context, userRepo repositories.UserRepository) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	var finished *finishedGame
+	defer func() {
+		s.mu.Unlock()
+		s.persistFinished(finished)
+	}()
 
 	game, exists := s.games[gameID]
 	if !exists {
@@ -152,39 +645,193 @@ func (s *GameService) ResignGame(gameID string, color chess.Color, ctx context.C
 		return fmt.Errorf("game state not found")
 	}
 
+	// A game is finished once; resigning after a mate or a flag changes nothing
+	if game.Outcome() != chess.NoOutcome {
+		return ErrGameOver
+	}
+
 	// Set the game as resigned
+	state.Events.record(EventResign, color, "")
 	if color == chess.White {
 		game.Resign(chess.White)
 	} else {
 		game.Resign(chess.Black)
 	}
 
-	// Update ELO ratings if context and repo are provided
-	if ctx != nil && userRepo != nil {
-		_ = s.UpdatePlayerRatings(ctx, gameID, state.WhitePlayer, state.BlackPlayer, userRepo)
-	}
+	// Update ELO ratings and persist the finished game
+	finished = s.finishGame(ctx, gameID, game, state, userRepo, MethodName(game.Method()))
 
 	return nil
 }
 
-// OfferDraw offers a draw in a game
-func (s *GameService) OfferDraw(gameID string) error {
+// AbandonGame forfeits a game on behalf of a player who disconnected and
+// never came back. It returns the outcome.
+func (s *GameService) AbandonGame(gameID string, color chess.Color, ctx context.Context, userRepo repositories.UserRepository) (chess.Outcome, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	var finished *finishedGame
+	defer func() {
+		s.mu.Unlock()
+		s.persistFinished(finished)
+	}()
+
+	game, exists := s.games[gameID]
+	if !exists {
+		return chess.NoOutcome, fmt.Errorf("game not found")
+	}
 
 	state, exists := s.gameStates[gameID]
 	if !exists {
+		return chess.NoOutcome, fmt.Errorf("game state not found")
+	}
+
+	if game.Outcome() != chess.NoOutcome {
+		return chess.NoOutcome, ErrGameOver
+	}
+
+	game.Resign(color)
+	finished = s.finishGame(ctx, gameID, game, state, userRepo, MethodAbandoned)
+
+	return game.Outcome(), nil
+}
+
+// ForfeitNoShow ends a game that hasn't got going, forfeiting it for the
+// player who didn't show: white if nothing was played, black if only white
+// moved. Checking and ending happen under one lock, so a move arriving in
+// between can't be forfeited. It returns the absent side, or ErrGameStarted
+// once both have moved. The game is stored as a forfeit, however few moves
+// it had, and isn't rated.
+func (s *GameService) ForfeitNoShow(gameID string, ctx context.Context, userRepo repositories.UserRepository) (chess.Color, error) {
+	s.mu.Lock()
+	var finished *finishedGame
+	defer func() {
+		s.mu.Unlock()
+		s.persistFinished(finished)
+	}()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		return chess.NoColor, ErrGameNotFound
+	}
+	if game.Outcome() != chess.NoOutcome {
+		return chess.NoColor, ErrGameOver
+	}
+
+	absent := chess.White
+	switch len(game.Moves()) {
+	case 0:
+	case 1:
+		absent = chess.Black
+	default:
+		return chess.NoColor, ErrGameStarted
+	}
+
+	// The chess library has no forfeits; it's recorded as a resignation
+	game.Resign(absent)
+	finished = s.finishGame(ctx, gameID, game, state, userRepo, MethodForfeit)
+
+	return absent, nil
+}
+
+// AbortReasonNoShow is the reason to abort a game with when nobody made the
+// first move in time. Knockout tournaments forfeit such games against white.
+const AbortReasonNoShow = "no move made"
+
+// AbortGame ends a game that hasn't properly started, such as a bad pairing
+// or one whose players never moved. Aborted games have no result and leave
+// ratings alone. Once the abort threshold is reached the game counts, so
+// ErrGameStarted is returned; games without a move can always be aborted.
+func (s *GameService) AbortGame(gameID string, reason string, ctx context.Context, userRepo repositories.UserRepository) error {
+	s.mu.Lock()
+	var finished *finishedGame
+	defer func() {
+		s.mu.Unlock()
+		s.persistFinished(finished)
+	}()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		return ErrGameNotFound
+	}
+
+	if game.Outcome() != chess.NoOutcome {
+		return ErrGameOver
+	}
+	if len(game.Moves()) >= max(s.abortPlies, 1) {
+		return ErrGameStarted
+	}
+
+	// The chess library has no way to end a game without a result, so it's
+	// recorded as agreed drawn and reported as aborted
+	if err := game.Draw(chess.DrawOffer); err != nil {
+		return err
+	}
+	state.noShow = reason == AbortReasonNoShow && len(game.Moves()) == 0
+	finished = s.finishGame(ctx, gameID, game, state, userRepo, MethodAborted)
+	logging.Infof("Game %s aborted: %s", gameID, reason)
+
+	return nil
+}
+
+// TerminateGame ends a game on an administrator's say-so, whatever its
+// position. Like an aborted game it has no result and leaves ratings alone.
+func (s *GameService) TerminateGame(gameID string, ctx context.Context, userRepo repositories.UserRepository) error {
+	s.mu.Lock()
+	var finished *finishedGame
+	defer func() {
+		s.mu.Unlock()
+		s.persistFinished(finished)
+	}()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		return ErrGameNotFound
+	}
+	if game.Outcome() != chess.NoOutcome {
+		return ErrGameOver
+	}
+
+	// Recorded as agreed drawn for the chess library, as with aborted games
+	if err := game.Draw(chess.DrawOffer); err != nil {
+		return err
+	}
+	finished = s.finishGame(ctx, gameID, game, state, userRepo, MethodAdminTerminated)
+
+	return nil
+}
+
+// OfferDraw offers a draw in a game on behalf of a player. The offer stands
+// for the current position only: the next move withdraws it.
+func (s *GameService) OfferDraw(gameID string, color chess.Color) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
 		return fmt.Errorf("game state not found")
 	}
+	if game.Outcome() != chess.NoOutcome {
+		return ErrGameOver
+	}
 
 	state.DrawOffered = true
+	state.DrawOfferedBy = color
+	state.DrawOfferPly = len(game.Moves())
 	return nil
 }
 
-// AcceptDraw accepts a draw offer
-func (s *GameService) AcceptDraw(gameID string, ctx context.Context, userRepo repositories.UserRepository) error {
+// AcceptDraw accepts the opponent's draw offer on behalf of a player. It
+// fails if no offer is pending, including one a move has since withdrawn.
+func (s *GameService) AcceptDraw(gameID string, color chess.Color, ctx context.Context, userRepo repositories.UserRepository) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	var finished *finishedGame
+	defer func() {
+		s.mu.Unlock()
+		s.persistFinished(finished)
+	}()
 
 	game, exists := s.games[gameID]
 	if !exists {
@@ -196,52 +843,198 @@ func (s *GameService) AcceptDraw(gameID string, ctx context.Context, userRepo re
 		return fmt.Errorf("game state not found")
 	}
 
+	if game.Outcome() != chess.NoOutcome {
+		return ErrGameOver
+	}
 	if !state.DrawOffered {
-		return fmt.Errorf("no draw was offered")
+		return ErrNoDrawOffer
+	}
+	if state.DrawOfferedBy == color {
+		return ErrOwnDrawOffer
+	}
+	if len(game.Moves()) != state.DrawOfferPly {
+		state.DrawOffered = false
+		return ErrDrawOfferStale
 	}
 
 	// Set the game as drawn by agreement
 	game.Draw(chess.DrawOffer)
 
-	// Update ELO ratings if context and repo are provided
-	if ctx != nil && userRepo != nil {
-		_ = s.UpdatePlayerRatings(ctx, gameID, state.WhitePlayer, state.BlackPlayer, userRepo)
-	}
+	// Update ELO ratings and persist the finished game
+	finished = s.finishGame(ctx, gameID, game, state, userRepo, MethodName(game.Method()))
 
 	return nil
 }
 
-// DeclineDraw declines a draw offer
-func (s *GameService) DeclineDraw(gameID string) error {
+// ReleaseGame forgets a finished game, which was stored when it ended.
+// Games still in progress are kept.
+func (s *GameService) ReleaseGame(gameID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	state, exists := s.gameStates[gameID]
-	if !exists {
+	game, exists := s.games[gameID]
+	if !exists || game.Outcome() == chess.NoOutcome {
+		return
+	}
+	delete(s.games, gameID)
+	delete(s.gameStates, gameID)
+}
+
+// DeclineDraw declines a draw offer on behalf of a player. Only the side the
+// draw was offered to can decline it; the offer lapses by itself with the
+// next move.
+func (s *GameService) DeclineDraw(gameID string, color chess.Color) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
 		return fmt.Errorf("game state not found")
 	}
+	if game.Outcome() != chess.NoOutcome {
+		return ErrGameOver
+	}
+	if !state.DrawOffered {
+		return ErrNoDrawOffer
+	}
+	if state.DrawOfferedBy == color {
+		return ErrOwnDrawOffer
+	}
 
 	state.DrawOffered = false
 	return nil
 }
 
+// DrawCounters returns the fifty-move and repetition counts of a game's
+// current position. They're derived from the move list, so they always
+// match the position clients are shown.
+func (s *GameService) DrawCounters(gameID string) (DrawCounters, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, exists := s.games[gameID]
+	if !exists {
+		return DrawCounters{}, ErrGameNotFound
+	}
+	return drawCounters(game), nil
+}
+
+func drawCounters(game *chess.Game) DrawCounters {
+	counters := DrawCounters{
+		HalfmoveClock: game.Position().HalfMoveClock(),
+		Repetitions:   repetitions(game),
+	}
+	counters.CanClaim = counters.HalfmoveClock >= 100 || counters.Repetitions >= 3
+	return counters
+}
+
+// repetitions counts how often a game's current position has occurred,
+// comparing what the repetition rule does: piece placement, side to move,
+// castling rights and en passant square
+func repetitions(game *chess.Game) int {
+	current := game.Position()
+	count := 0
+	for _, pos := range game.Positions() {
+		if pos.Board().String() == current.Board().String() &&
+			pos.Turn() == current.Turn() &&
+			pos.CastleRights() == current.CastleRights() &&
+			pos.EnPassantSquare() == current.EnPassantSquare() {
+			count++
+		}
+	}
+	return count
+}
+
 // UpdateTime updates the remaining time for a player
 func (s *GameService) UpdateTime(gameID string, color chess.Color, timeLeft float64) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		return fmt.Errorf("game state not found")
+	}
+	if game.Outcome() != chess.NoOutcome {
+		return ErrGameOver
+	}
+
+	state.setTimeLeft(color, timeLeft)
+
+	return nil
+}
+
+// HandleTimeout ends a game because the given color's clock ran out. The
+// opponent wins, unless they don't have the material to ever checkmate, in
+// which case the game is drawn. It returns the outcome and the method name.
+func (s *GameService) HandleTimeout(gameID string, color chess.Color, ctx context.Context, userRepo repositories.UserRepository) (chess.Outcome, string, error) {
+	s.mu.Lock()
+	var finished *finishedGame
+	defer func() {
+		s.mu.Unlock()
+		s.persistFinished(finished)
+	}()
+
+	game, exists := s.games[gameID]
+	if !exists {
+		return chess.NoOutcome, "", fmt.Errorf("game not found")
+	}
+
 	state, exists := s.gameStates[gameID]
 	if !exists {
-		return fmt.Errorf("game state not found")
+		return chess.NoOutcome, "", fmt.Errorf("game state not found")
 	}
 
-	if color == chess.White {
-		state.TimeControl.WhiteTimeLeft = timeLeft
+	if game.Outcome() != chess.NoOutcome {
+		return chess.NoOutcome, "", ErrGameOver
+	}
+
+	method := MethodTimeout
+	if hasMatingMaterial(game.Position().Board(), color.Other()) {
+		// The chess library has no timeout method, so record it as a resignation
+		game.Resign(color)
 	} else {
-		state.TimeControl.BlackTimeLeft = timeLeft
+		method = MethodTimeoutVsInsufficientMaterial
+		_ = game.Draw(chess.DrawOffer)
 	}
+	state.TimedOut = true
 
-	return nil
+	// Update ELO ratings and persist the finished game
+	finished = s.finishGame(ctx, gameID, game, state, userRepo, method)
+
+	return game.Outcome(), method, nil
+}
+
+// hasMatingMaterial reports whether the given color could still checkmate.
+// A lone king, or a king with a single bishop or knight, cannot; nor can
+// bishops that all stand on squares of one color, which never attack the
+// other color. Anything more (any pawn, rook, queen, or two minor pieces
+// otherwise) is treated as enough.
+func hasMatingMaterial(board *chess.Board, color chess.Color) bool {
+	knights := 0
+	bishopColors := make(map[bool]bool) // Keyed by whether the square is light
+	for square, piece := range board.SquareMap() {
+		if piece.Color() != color {
+			continue
+		}
+		switch piece.Type() {
+		case chess.King:
+			continue
+		case chess.Knight:
+			knights++
+		case chess.Bishop:
+			bishopColors[lightSquare(square)] = true
+		default:
+			return true
+		}
+	}
+	return knights+len(bishopColors) >= 2
+}
+
+// lightSquare reports whether a square is a light one; a1 is dark
+func lightSquare(square chess.Square) bool {
+	return (int(square.File())+int(square.Rank()))%2 == 1
 }
 
 // AddChatMessage adds a chat message to the game
@@ -283,12 +1076,108 @@ func (s *GameService) GetActiveGamesCount() int {
 	return len(s.games)
 }
 
-// CalculateEloChange calculates the ELO rating change based on game outcome
-func calculateEloChange(playerRating, opponentRating int, outcome float64) int {
-	// K-factor determines the maximum possible adjustment
-	// Higher for newer players, lower for established players
-	kFactor := 32
+// GameCounts counts the games in memory for statistics, in progress ones
+// broken down by speed and stakes; correspondence games are counted apart.
+// The lock is held only while counting, and the counts share nothing with
+// the service, so they're safe to log and serve without it.
+func (s *GameService) GameCounts() stats.GameCounts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	counts := stats.GameCounts{
+		Total:      len(s.games),
+		ByCategory: make(map[string]int),
+	}
+	for gameID, game := range s.games {
+		state := s.gameStates[gameID]
+		if state == nil || game.Outcome() != chess.NoOutcome {
+			continue
+		}
+		counts.InProgress++
+
+		category := string(state.TimeSettings.Category())
+		if state.TimeSettings.Correspondence() {
+			category = "correspondence"
+		}
+		counts.ByCategory[category]++
+
+		if state.Casual {
+			counts.Casual++
+		} else {
+			counts.Rated++
+		}
+	}
+	return counts
+}
+
+// GameListing is a public game in progress, as shown in the lobby
+type GameListing struct {
+	ID          string
+	WhiteID     string
+	BlackID     string
+	TimeControl TimeControl
+	Category    models.RatingCategory
+	Ply         int
+	CreatedAt   time.Time
+}
+
+// ListPublicGames returns the unfinished games that aren't private, newest
+// first. An empty category lists games of every speed.
+func (s *GameService) ListPublicGames(category models.RatingCategory) []GameListing {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	listings := []GameListing{}
+	for gameID, game := range s.games {
+		state := s.gameStates[gameID]
+		if state == nil || state.Private || game.Outcome() != chess.NoOutcome {
+			continue
+		}
+		gameCategory := state.TimeSettings.Category()
+		if category != "" && gameCategory != category {
+			continue
+		}
+		listings = append(listings, GameListing{
+			ID:          gameID,
+			WhiteID:     state.WhitePlayer,
+			BlackID:     state.BlackPlayer,
+			TimeControl: state.TimeSettings,
+			Category:    gameCategory,
+			Ply:         len(game.Moves()),
+			CreatedAt:   state.CreatedAt,
+		})
+	}
+
+	sort.Slice(listings, func(i, j int) bool {
+		return listings[i].CreatedAt.After(listings[j].CreatedAt)
+	})
+	return listings
+}
+
+// ActiveGamesFor returns the number of unfinished games a user is playing in
+func (s *GameService) ActiveGamesFor(userID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for gameID, game := range s.games {
+		if game.Outcome() != chess.NoOutcome {
+			continue
+		}
+		state := s.gameStates[gameID]
+		if state != nil && (state.WhitePlayer == userID || state.BlackPlayer == userID) {
+			count++
+		}
+	}
+	return count
+}
+
+// establishedKFactor is the Elo K-factor of players past their placement
+// games. The K-factor is the most a rating can move in one game.
+const establishedKFactor = 32
+
+// CalculateEloChange calculates the ELO rating change based on game outcome
+func calculateEloChange(playerRating, opponentRating int, outcome float64, kFactor int) int {
 	// Expected score based on ELO difference
 	expectedScore := 1.0 / (1.0 + math.Pow(10, float64(opponentRating-playerRating)/400.0))
 
@@ -299,6 +1188,47 @@ func calculateEloChange(playerRating, opponentRating int, outcome float64) int {
 	return change
 }
 
+// ratedResult returns a player's new rating after a result. Wins by players
+// below the starting rating get the deflation bonus, and losses stop at the
+// rating floor; above the floor the Elo change is applied unaltered. A zero
+// K-factor leaves the rating as it was.
+func ratedResult(playerRating, opponentRating int, outcome float64, kFactor int) int {
+	if kFactor == 0 {
+		return playerRating
+	}
+	change := calculateEloChange(playerRating, opponentRating, outcome, kFactor)
+	if outcome == 1.0 && playerRating < models.DefaultRating {
+		change += models.DeflationBonus
+	}
+
+	return floorRating(playerRating, playerRating+change)
+}
+
+// kFactor returns the K-factor a player's ratings move with against an
+// opponent. Players in placement move fast to find their level, while
+// established players don't move at all against them, so a new account
+// can't be used to hand out or soak up points before its rating means
+// anything.
+func kFactor(player, opponent *models.User) int {
+	switch {
+	case player.Provisional():
+		return models.PlacementKFactor
+	case opponent.Provisional():
+		return 0
+	default:
+		return establishedKFactor
+	}
+}
+
+// floorRating stops a rating that went down at the rating floor, or where
+// it was if it already stood below it
+func floorRating(before, after int) int {
+	if after < before && after < models.RatingFloor {
+		return min(before, models.RatingFloor)
+	}
+	return after
+}
+
 // UpdatePlayerRatings updates the ELO ratings of both players after a game
 func (s *GameService) UpdatePlayerRatings(
 	ctx context.Context,
@@ -317,20 +1247,175 @@ func (s *GameService) UpdatePlayerRatings(
 		return fmt.Errorf("game is not over yet")
 	}
 
-	// Get player ratings
-	whiteUser, err := userRepo.GetByID(ctx, whiteUserID)
-	if err != nil {
-		return err
+	s.mu.Lock()
+	tc := DefaultTimeControl
+	if state, exists := s.gameStates[gameID]; exists {
+		if state.Casual || voided(state.EndMethod) {
+			s.mu.Unlock()
+			return nil
+		}
+		tc = state.TimeSettings
 	}
+	s.mu.Unlock()
 
-	blackUser, err := userRepo.GetByID(ctx, blackUserID)
-	if err != nil {
-		return err
+	category := tc.Category()
+	_, _, err = updateRatingsForOutcome(ctx, outcome, category, whiteUserID, blackUserID, userRepo)
+	return err
+}
+
+// finishedGame is what remains to be done for a game that just ended once
+// s.mu is released: rating the result and storing the game
+type finishedGame struct {
+	gameID         string
+	correspondence bool // The game's stored copy in progress is to be deleted
+	ctx            context.Context
+	userRepo       repositories.UserRepository
+	outcome        chess.Outcome
+	category       models.RatingCategory
+	rated          bool
+	record         *models.GameRecord // nil when the game isn't to be rated or stored
+}
+
+// finishGame records the end of a game and gathers what's needed to rate and
+// persist it, which the caller does with persistFinished after releasing
+// s.mu. It returns nil when there's nothing to persist or delete. Callers
+// must hold s.mu.
+func (s *GameService) finishGame(
+	ctx context.Context,
+	gameID string,
+	game *chess.Game,
+	state *GameState,
+	userRepo repositories.UserRepository,
+	method string,
+) *finishedGame {
+	if len(game.Moves()) < s.abortPlies && abortable(method) {
+		method = MethodAborted
+	}
+	state.EndMethod = method
+	state.Events.record(EventGameOver, chess.NoColor, resultOf(game, state)+" "+method)
+
+	// Hooks run asynchronously since we're holding s.mu
+	end := GameEnd{
+		GameID:  gameID,
+		Outcome: game.Outcome(),
+		Method:  method,
+		Plies:   len(game.Moves()),
+		NoShow:  state.noShow,
+	}
+	for _, hook := range s.gameOverHooks {
+		go hook(end)
 	}
 
-	whiteRating := whiteUser.EloRating
-	blackRating := blackUser.EloRating
+	finished := &finishedGame{
+		gameID:         gameID,
+		correspondence: s.correspondenceRepo != nil && state.TimeSettings.Correspondence(),
+	}
+	if ctx == nil || userRepo == nil {
+		if !finished.correspondence {
+			return nil
+		}
+		return finished
+	}
+
+	finished.ctx = ctx
+	finished.userRepo = userRepo
+	finished.outcome = game.Outcome()
+	finished.category = state.TimeSettings.Category()
+	finished.rated = !state.Casual && !voided(method) && method != MethodForfeit // Forfeited games weren't played
+	finished.record = &models.GameRecord{
+		ID:             gameID,
+		WhiteID:        state.WhitePlayer,
+		BlackID:        state.BlackPlayer,
+		InitialTime:    state.TimeSettings.Initial,
+		Increment:      state.TimeSettings.Increment,
+		BlackInitial:   state.TimeSettings.BlackInitial,
+		BlackIncrement: state.TimeSettings.BlackIncrement,
+		FEN:            game.FEN(),
+		PGN:            pgnOf(game, state),
+		Outcome:        resultOf(game, state),
+		Method:         method,
+		IsPrivate:      state.Private,
+		Events:         encodeEvents(gameID, state.Events),
+		CreatedAt:      state.CreatedAt,
+		EndedAt:        time.Now(),
+	}
+	return finished
+}
+
+// persistFinished applies the rating changes for a game finishGame ended and
+// stores it. It does nothing for a nil game. Callers must not hold s.mu.
+func (s *GameService) persistFinished(finished *finishedGame) {
+	if finished == nil {
+		return
+	}
+	// The copy stored while it was in progress goes once it's stored finished
+	if finished.correspondence {
+		defer s.deleteCorrespondence(finished.gameID)
+	}
+	record := finished.record
+	if record == nil {
+		return
+	}
+
+	if finished.rated {
+		var err error
+		record.WhiteRating, record.BlackRating, err = updateRatingsForOutcome(
+			finished.ctx, finished.outcome, finished.category, record.WhiteID, record.BlackID, finished.userRepo)
+		if err != nil {
+			logging.Warnf("Failed to update ratings for game %s: %v", record.ID, err)
+		}
+	}
 
+	if s.gameRepo == nil {
+		return
+	}
+
+	if err := s.gameRepo.Create(finished.ctx, record); err != nil {
+		logging.Errorf("Failed to persist game %s: %v", record.ID, err)
+		return
+	}
+	s.sendSummaries(finished.ctx, record)
+}
+
+// SetNotifier makes stored games email a summary to each player who hasn't
+// turned those off
+func (s *GameService) SetNotifier(notifier *NotificationService) {
+	s.notifier = notifier
+}
+
+// sendSummaries emails both players the result of a stored game. Sending
+// happens in the background so a slow mail provider doesn't hold up the
+// move or resignation that ended the game.
+func (s *GameService) sendSummaries(ctx context.Context, record *models.GameRecord) {
+	if s.notifier == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	subject := fmt.Sprintf("Game over: %s", record.Outcome)
+	ratings := map[string]int{record.WhiteID: record.WhiteRating, record.BlackID: record.BlackRating}
+	for _, userID := range []string{record.WhiteID, record.BlackID} {
+		body := fmt.Sprintf("Your game %s ended %s by %s.", record.ID, record.Outcome, record.Method)
+		if rating := ratings[userID]; rating != 0 {
+			body += fmt.Sprintf(" Your rating is now %d.", rating)
+		}
+		go func() {
+			if _, err := s.notifier.Notify(ctx, userID, NotifyGameSummary, subject, body); err != nil {
+				logging.Warnf("Failed to send game %s summary to user %s: %v", record.ID, userID, err)
+			}
+		}()
+	}
+}
+
+// updateRatingsForOutcome applies the ELO changes for a finished game to the
+// overall and category ratings and returns the new category ratings
+func updateRatingsForOutcome(
+	ctx context.Context,
+	outcome chess.Outcome,
+	category models.RatingCategory,
+	whiteUserID string,
+	blackUserID string,
+	userRepo repositories.UserRepository,
+) (int, int, error) {
 	// Determine outcome values for ELO calculation
 	var whiteOutcome, blackOutcome float64
 
@@ -345,27 +1430,172 @@ func (s *GameService) UpdatePlayerRatings(
 		whiteOutcome = 0.5
 		blackOutcome = 0.5
 	default:
-		return fmt.Errorf("invalid game outcome")
+		return 0, 0, fmt.Errorf("invalid game outcome")
 	}
 
-	// Calculate rating changes
-	whiteRatingChange := calculateEloChange(whiteRating, blackRating, whiteOutcome)
-	blackRatingChange := calculateEloChange(blackRating, whiteRating, blackOutcome)
+	var whiteRating, blackRating int
+
+	// Read and write both ratings in one transaction so they change together
+	err := userRepo.UpdateRatingsTx(ctx, whiteUserID, blackUserID, func(whiteUser, blackUser *models.User) error {
+		// Both sides are rated from the ratings before the game
+		whiteK, blackK := kFactor(whiteUser, blackUser), kFactor(blackUser, whiteUser)
+		whiteElo, blackElo := whiteUser.EloRating, blackUser.EloRating
+		whiteUser.EloRating = ratedResult(whiteElo, blackElo, whiteOutcome, whiteK)
+		blackUser.EloRating = ratedResult(blackElo, whiteElo, blackOutcome, blackK)
+
+		whiteCategory := whiteUser.Rating(category)
+		blackCategory := blackUser.Rating(category)
+		whiteUser.SetRating(category, ratedResult(whiteCategory, blackCategory, whiteOutcome, whiteK))
+		blackUser.SetRating(category, ratedResult(blackCategory, whiteCategory, blackOutcome, blackK))
+
+		whiteRating = whiteUser.Rating(category)
+		blackRating = blackUser.Rating(category)
+
+		// Every rated game counts towards placement, whatever the result
+		for _, user := range []*models.User{whiteUser, blackUser} {
+			if user.Provisional() {
+				user.PlacementGamesRemaining--
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
 
-	// Update ratings
-	whiteUser.EloRating += whiteRatingChange
-	blackUser.EloRating += blackRatingChange
+	return whiteRating, blackRating, nil
+}
 
-	// Save updated ratings
-	err = userRepo.Update(ctx, whiteUser)
-	if err != nil {
-		return err
+// GetGameDetails returns the full state of a game, looking first at live
+// games and then at persisted ones. Live private games are only visible to
+// their players; finished games are public.
+func (s *GameService) GetGameDetails(
+	ctx context.Context,
+	gameID string,
+	viewerID string,
+	userRepo repositories.UserRepository,
+) (*GameDetails, error) {
+	s.mu.Lock()
+	game, live := s.games[gameID]
+	state := s.gameStates[gameID]
+	var details *GameDetails
+	if live && state != nil {
+		if state.Private && viewerID != state.WhitePlayer && viewerID != state.BlackPlayer {
+			s.mu.Unlock()
+			return nil, ErrGameForbidden
+		}
+
+		details = &GameDetails{
+			ID:            gameID,
+			Live:          game.Outcome() == chess.NoOutcome,
+			Private:       state.Private,
+			FEN:           game.FEN(),
+			PGN:           pgnOf(game, state),
+			Moves:         moveHistory(game),
+			WhitePlayer:   state.WhitePlayer,
+			BlackPlayer:   state.BlackPlayer,
+			TimeControl:   state.TimeSettings,
+			WhiteTimeLeft: state.TimeControl.WhiteTimeLeft,
+			BlackTimeLeft: state.TimeControl.BlackTimeLeft,
+		}
+		if details.Live {
+			details.Turn = game.Position().Turn().Name()
+		} else {
+			details.Outcome = resultOf(game, state)
+			details.Method = state.EndMethod
+		}
+	}
+	s.mu.Unlock()
+
+	if details != nil {
+		// Look up current ratings outside the game lock
+		if userRepo != nil {
+			if white, err := userRepo.GetByID(ctx, details.WhitePlayer); err == nil {
+				details.WhiteRating = white.EloRating
+			}
+			if black, err := userRepo.GetByID(ctx, details.BlackPlayer); err == nil {
+				details.BlackRating = black.EloRating
+			}
+		}
+		return details, nil
 	}
 
-	err = userRepo.Update(ctx, blackUser)
+	if s.gameRepo == nil {
+		return nil, ErrGameNotFound
+	}
+
+	record, err := s.gameRepo.GetByID(ctx, gameID)
 	if err != nil {
-		return err
+		if err == repositories.ErrGameNotFound {
+			return nil, ErrGameNotFound
+		}
+		return nil, err
 	}
 
-	return nil
+	details = &GameDetails{
+		ID:          record.ID,
+		Private:     record.IsPrivate,
+		FEN:         record.FEN,
+		PGN:         record.PGN,
+		WhitePlayer: record.WhiteID,
+		BlackPlayer: record.BlackID,
+		WhiteRating: record.WhiteRating,
+		BlackRating: record.BlackRating,
+		TimeControl: TimeControl{
+			Initial:        record.InitialTime,
+			Increment:      record.Increment,
+			BlackInitial:   record.BlackInitial,
+			BlackIncrement: record.BlackIncrement,
+		},
+		Outcome: record.Outcome,
+		Method:  record.Method,
+	}
+
+	// Rebuild the move list from the stored PGN
+	if pgn, err := chess.PGN(strings.NewReader(record.PGN)); err == nil {
+		details.Moves = moveHistory(chess.NewGame(pgn))
+	}
+
+	return details, nil
+}
+
+// bareUnderpromotion matches a pawn promotion written without the "=", such
+// as bxa8N, which the chess library would otherwise take for a queen
+var bareUnderpromotion = regexp.MustCompile(`^([a-h](?:x[a-h])?[18])([NBRQnbrq])([+#]?)$`)
+
+// normalizePromotion writes a promotion the way the chess library reads it,
+// so the piece the player chose is the one they get
+func normalizePromotion(moveStr string) string {
+	match := bareUnderpromotion.FindStringSubmatch(moveStr)
+	if match == nil {
+		return moveStr
+	}
+	return match[1] + "=" + strings.ToUpper(match[2]) + match[3]
+}
+
+// lastMoveSAN returns a game's last move in standard algebraic notation,
+// as its PGN and move history have it, promotions included
+func lastMoveSAN(game *chess.Game) string {
+	moves := game.Moves()
+	positions := game.Positions()
+	n := len(moves)
+	if n == 0 || n > len(positions) {
+		return ""
+	}
+	return chess.AlgebraicNotation{}.Encode(positions[n-1], moves[n-1])
+}
+
+// moveHistory returns the game's main line moves in standard algebraic notation
+func moveHistory(game *chess.Game) []string {
+	moves := game.Moves()
+	positions := game.Positions()
+
+	history := make([]string, 0, len(moves))
+	for i, move := range moves {
+		if i >= len(positions) {
+			break
+		}
+		history = append(history, chess.AlgebraicNotation{}.Encode(positions[i], move))
+	}
+	return history
 }
@@ -2,15 +2,19 @@ package services
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/corentings/chess/v2"
 	"github.com/gorilla/websocket"
 )
 
+// MessageService tracks which games each WebSocket connection takes part
+// in. A connection may play or watch several games at once, so every
+// message names the game it's for.
 type MessageService struct {
 	gameService     *GameService
-	connToGameID    map[*websocket.Conn]string
+	connToGameID    map[*websocket.Conn]map[string]bool // Games each connection is in
 	mu              sync.Mutex
 	messageChannels map[*websocket.Conn]chan interface{}
 }
@@ -18,17 +22,15 @@ type MessageService struct {
 func NewMessageService(gameService *GameService) *MessageService {
 	return &MessageService{
 		gameService:     gameService,
-		connToGameID:    make(map[*websocket.Conn]string),
+		connToGameID:    make(map[*websocket.Conn]map[string]bool),
 		messageChannels: make(map[*websocket.Conn]chan interface{}),
 	}
 }
 
-func (s *MessageService) GetGame(conn *websocket.Conn) *chess.Game {
-	s.mu.Lock()
-	gameID, ok := s.connToGameID[conn]
-	s.mu.Unlock()
-
-	if !ok {
+// GetGame returns a snapshot of one of the games a connection is in, or nil
+// if it isn't in that game
+func (s *MessageService) GetGame(conn *websocket.Conn, gameID string) *GameView {
+	if !s.InGame(conn, gameID) {
 		return nil
 	}
 
@@ -39,33 +41,71 @@ func (s *MessageService) GetGame(conn *websocket.Conn) *chess.Game {
 	return game
 }
 
-func (s *MessageService) GetGameState(conn *websocket.Conn) interface{} {
-	s.mu.Lock()
-	gameID, ok := s.connToGameID[conn]
-	s.mu.Unlock()
-
-	if !ok {
+// GetGameState returns a snapshot of one of the games a connection is in,
+// or nil if it isn't in that game
+func (s *MessageService) GetGameState(conn *websocket.Conn, gameID string) *GameSnapshot {
+	if !s.InGame(conn, gameID) {
 		return nil
 	}
 
-	game, err := s.gameService.GetGame(gameID)
+	snapshot, err := s.gameService.Snapshot(gameID)
 	if err != nil {
 		return nil
 	}
+	return snapshot
+}
 
-	return struct {
-		Board string `json:"board"`
-	}{
-		Board: game.Position().Board().Draw(),
+// BoardDrawing is a board rendered as text for terminal clients
+type BoardDrawing struct {
+	ASCII   string `json:"ascii"`   // Piece letters, uppercase for white
+	Unicode string `json:"unicode"` // Chess symbols
+}
+
+// DrawBoard renders the current board of a game as ASCII and Unicode art
+func (s *MessageService) DrawBoard(gameID string, viewerID string) (*BoardDrawing, error) {
+	board, err := s.gameService.GetBoard(gameID, viewerID)
+	if err != nil {
+		return nil, err
 	}
+
+	return &BoardDrawing{
+		ASCII:   drawBoard(board, false),
+		Unicode: drawBoard(board, true),
+	}, nil
 }
 
-func (s *MessageService) GetGameResult(conn *websocket.Conn) string {
-	s.mu.Lock()
-	gameID, ok := s.connToGameID[conn]
-	s.mu.Unlock()
+// drawBoard renders a board in the same layout as chess.Board.Draw, using
+// piece letters instead of symbols unless unicode is set
+func drawBoard(board *chess.Board, unicode bool) string {
+	if unicode {
+		return board.Draw()
+	}
 
-	if !ok {
+	var b strings.Builder
+	b.WriteString("\n A B C D E F G H\n")
+	for r := 7; r >= 0; r-- {
+		b.WriteString(chess.Rank(r).String())
+		for f := 0; f < 8; f++ {
+			piece := board.Piece(chess.NewSquare(chess.File(f), chess.Rank(r)))
+			switch {
+			case piece == chess.NoPiece:
+				b.WriteString("-")
+			case piece.Color() == chess.White:
+				b.WriteString(strings.ToUpper(piece.Type().String()))
+			default:
+				b.WriteString(piece.Type().String())
+			}
+			b.WriteString(" ")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// GetGameResult describes the result of one of the games a connection is
+// in, or returns an empty string if it isn't in that game
+func (s *MessageService) GetGameResult(conn *websocket.Conn, gameID string) string {
+	if !s.InGame(conn, gameID) {
 		return ""
 	}
 
@@ -73,7 +113,7 @@ func (s *MessageService) GetGameResult(conn *websocket.Conn) string {
 	if err != nil {
 		return ""
 	}
-	return fmt.Sprintf("%s by %s", game.Outcome(), game.Method())
+	return fmt.Sprintf("%s by %s", OutcomeName(game.Outcome), MethodName(game.Method))
 }
 
 func (s *MessageService) GetMessageChannel(conn *websocket.Conn) chan interface{} {
@@ -87,12 +127,9 @@ func (s *MessageService) GetMessageChannel(conn *websocket.Conn) chan interface{
 	return ch
 }
 
-func (s *MessageService) IsGameOver(conn *websocket.Conn) bool {
-	s.mu.Lock()
-	gameID, ok := s.connToGameID[conn]
-	s.mu.Unlock()
-
-	if !ok {
+// IsGameOver reports whether one of the games a connection is in has ended
+func (s *MessageService) IsGameOver(conn *websocket.Conn, gameID string) bool {
+	if !s.InGame(conn, gameID) {
 		return false
 	}
 
@@ -100,17 +137,62 @@ func (s *MessageService) IsGameOver(conn *websocket.Conn) bool {
 	if err != nil {
 		return false
 	}
-	return game.Outcome() != chess.NoOutcome
+	return game.Over()
 }
 
-// AssociateConnection associates a WebSocket connection with a game ID
+// AddConnection registers an open WebSocket connection, in no games yet
+func (s *MessageService) AddConnection(conn *websocket.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.connToGameID[conn]; !ok {
+		s.connToGameID[conn] = make(map[string]bool)
+	}
+}
+
+// AssociateConnection adds a game to those a WebSocket connection is in,
+// keeping the others
 func (s *MessageService) AssociateConnection(conn *websocket.Conn, gameID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.connToGameID[conn] = gameID
+	games, ok := s.connToGameID[conn]
+	if !ok {
+		games = make(map[string]bool)
+		s.connToGameID[conn] = games
+	}
+	games[gameID] = true
+}
+
+// DissociateConnection takes a game off those a WebSocket connection is
+// in. The connection stays known to the service until it's removed.
+func (s *MessageService) DissociateConnection(conn *websocket.Conn, gameID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if games, ok := s.connToGameID[conn]; ok {
+		delete(games, gameID)
+	}
+}
+
+// InGame reports whether a WebSocket connection is in a game
+func (s *MessageService) InGame(conn *websocket.Conn, gameID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connToGameID[conn][gameID]
+}
+
+// GameIDs returns the games a WebSocket connection is in, in no particular
+// order
+func (s *MessageService) GameIDs(conn *websocket.Conn) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gameIDs := make([]string, 0, len(s.connToGameID[conn]))
+	for gameID := range s.connToGameID[conn] {
+		gameIDs = append(gameIDs, gameID)
+	}
+	return gameIDs
 }
 
-// RemoveConnection removes a WebSocket connection from the service
+// RemoveConnection removes a WebSocket connection from the service, along
+// with every game it was in
 func (s *MessageService) RemoveConnection(conn *websocket.Conn) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+)
+
+// Notification is a kind of optional message users can opt out of
+type Notification string
+
+const (
+	NotifyGameInvite    Notification = "game_invite"
+	NotifyGameSummary   Notification = "game_summary"
+	NotifySecurityAlert Notification = "security_alert"
+)
+
+// Email is a message to a single recipient
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// EmailSender delivers emails
+type EmailSender interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// LogEmailSender logs emails instead of delivering them, for development and
+// until a mail provider is configured
+type LogEmailSender struct{}
+
+// Send logs the email
+func (LogEmailSender) Send(ctx context.Context, email Email) error {
+	logging.Infof("Email to %s: %s", email.To, email.Subject)
+	return nil
+}
+
+// sendInBackground delivers an email without holding up the request that
+// triggered it. Failures are logged, as there's nobody left to report to.
+func sendInBackground(ctx context.Context, sender EmailSender, email Email) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := sender.Send(ctx, email); err != nil {
+			logging.Warnf("Failed to send %q email: %v", email.Subject, err)
+		}
+	}()
+}
+
+// expiresIn describes how long a token emailed to a user stays valid
+func expiresIn(ttl time.Duration) string {
+	if ttl%time.Hour == 0 {
+		if hours := int(ttl / time.Hour); hours != 1 {
+			return fmt.Sprintf("%d hours", hours)
+		}
+		return "1 hour"
+	}
+	return ttl.String()
+}
+
+// NotificationService sends optional emails, honoring each user's
+// notification preferences. Emails a user can't do without, such as
+// verification and password reset tokens, go to the EmailSender directly.
+type NotificationService struct {
+	userRepo repositories.UserRepository
+	sender   EmailSender
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService(userRepo repositories.UserRepository, sender EmailSender) *NotificationService {
+	return &NotificationService{
+		userRepo: userRepo,
+		sender:   sender,
+	}
+}
+
+// Notify emails a user unless they've turned that kind of notification off.
+// It reports whether the email was sent.
+func (s *NotificationService) Notify(
+	ctx context.Context,
+	userID string,
+	kind Notification,
+	subject string,
+	body string,
+) (bool, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if err == repositories.ErrUserNotFound {
+			return false, ErrUserNotFound
+		}
+		return false, err
+	}
+
+	if !wantsNotification(user.NotificationPreferences, kind) {
+		return false, nil
+	}
+	if err := s.sender.Send(ctx, Email{To: user.Email, Subject: subject, Body: body}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// wantsNotification reports whether preferences allow a kind of notification
+func wantsNotification(prefs models.NotificationPreferences, kind Notification) bool {
+	switch kind {
+	case NotifyGameInvite:
+		return prefs.GameInvites
+	case NotifyGameSummary:
+		return prefs.GameSummaries
+	case NotifySecurityAlert:
+		return prefs.SecurityAlerts
+	default:
+		return false
+	}
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"chess-ws-go/internal/logging"
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+
+	"github.com/corentings/chess/v2"
+)
+
+var (
+	ErrPuzzleNotFound    = errors.New("puzzle not found")
+	ErrNoActivePuzzle    = errors.New("no puzzle in progress")
+	ErrPuzzleIllegalMove = errors.New("illegal move")
+)
+
+// puzzleRatingWindow is how far from the user's puzzle rating a random
+// puzzle is looked for before falling back to any puzzle
+const puzzleRatingWindow = 200
+
+// defaultPuzzleAttemptTTL is how long a puzzle may sit without a move before
+// it's taken to be abandoned and forgotten
+const defaultPuzzleAttemptTTL = 30 * time.Minute
+
+// PuzzleMoveResult is the outcome of a move submitted for a puzzle
+type PuzzleMoveResult struct {
+	Correct      bool   `json:"correct"`
+	Solved       bool   `json:"solved"`
+	Reply        string `json:"reply,omitempty"`    // Opponent's answer to a correct move
+	Solution     string `json:"solution,omitempty"` // Revealed once the puzzle is over
+	FEN          string `json:"fen"`
+	PuzzleRating int    `json:"puzzle_rating,omitempty"` // Set once the puzzle is over
+}
+
+// puzzleAttempt tracks a user's progress through a puzzle
+type puzzleAttempt struct {
+	puzzle   *models.Puzzle
+	solution []string
+	game     *chess.Game
+	ply      int       // Index of the next expected solver move in solution
+	touched  time.Time // When the puzzle was started or last moved in
+}
+
+// PuzzleService serves tactics puzzles and checks solutions move by move.
+// Each user has at most one puzzle in progress. Finished puzzles are dropped
+// straight away, and abandoned ones once they've gone untouched for the
+// attempt TTL.
+type PuzzleService struct {
+	puzzleRepo repositories.PuzzleRepository
+	userRepo   repositories.UserRepository
+	attempts   map[string]*puzzleAttempt // Keyed by user ID
+	attemptTTL time.Duration
+	lastPruned time.Time
+	mu         sync.Mutex
+}
+
+// NewPuzzleService creates a new puzzle service
+func NewPuzzleService(puzzleRepo repositories.PuzzleRepository, userRepo repositories.UserRepository) *PuzzleService {
+	return &PuzzleService{
+		puzzleRepo: puzzleRepo,
+		userRepo:   userRepo,
+		attempts:   make(map[string]*puzzleAttempt),
+		attemptTTL: defaultPuzzleAttemptTTL,
+	}
+}
+
+// SetAttemptTTL sets how long a puzzle may go without a move before it's
+// abandoned
+func (s *PuzzleService) SetAttemptTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attemptTTL = ttl
+}
+
+// StartPuzzle starts the given puzzle for a user, or a random one near their
+// puzzle rating when puzzleID is empty. Any puzzle already in progress is
+// abandoned.
+func (s *PuzzleService) StartPuzzle(ctx context.Context, userID string, puzzleID string) (*models.Puzzle, error) {
+	var puzzle *models.Puzzle
+	var err error
+
+	if puzzleID != "" {
+		puzzle, err = s.puzzleRepo.GetByID(ctx, puzzleID)
+	} else {
+		puzzle, err = s.randomPuzzle(ctx, userID)
+	}
+	if err != nil {
+		if err == repositories.ErrPuzzleNotFound {
+			return nil, ErrPuzzleNotFound
+		}
+		return nil, err
+	}
+
+	fen, err := chess.FEN(puzzle.FEN)
+	if err != nil {
+		logging.Errorf("Puzzle %s has an invalid FEN: %v", puzzle.ID, err)
+		return nil, ErrPuzzleNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pruneAttempts(now)
+	s.attempts[userID] = &puzzleAttempt{
+		puzzle:   puzzle,
+		solution: strings.Fields(puzzle.Solution),
+		game:     chess.NewGame(fen),
+		touched:  now,
+	}
+
+	return puzzle, nil
+}
+
+// pruneAttempts forgets the puzzles users abandoned. The whole map is only
+// swept every half TTL, so starting a puzzle stays cheap. Callers must hold
+// s.mu.
+func (s *PuzzleService) pruneAttempts(now time.Time) {
+	if now.Sub(s.lastPruned) < s.attemptTTL/2 {
+		return
+	}
+	s.lastPruned = now
+	for userID, attempt := range s.attempts {
+		if now.Sub(attempt.touched) >= s.attemptTTL {
+			delete(s.attempts, userID)
+		}
+	}
+}
+
+// randomPuzzle picks a puzzle close to the user's puzzle rating
+func (s *PuzzleService) randomPuzzle(ctx context.Context, userID string) (*models.Puzzle, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	puzzle, err := s.puzzleRepo.GetRandom(ctx, user.PuzzleRating-puzzleRatingWindow, user.PuzzleRating+puzzleRatingWindow)
+	if err == repositories.ErrPuzzleNotFound {
+		return s.puzzleRepo.GetRandom(ctx, 0, 1<<30)
+	}
+	return puzzle, err
+}
+
+// SubmitMove checks a move against the expected solution. A correct move is
+// answered with the opponent's reply; a wrong move, or the final correct
+// move, ends the puzzle and adjusts the user's puzzle rating. A move that
+// checkmates is always accepted.
+func (s *PuzzleService) SubmitMove(ctx context.Context, userID string, move string) (*PuzzleMoveResult, error) {
+	s.mu.Lock()
+	attempt, exists := s.attempts[userID]
+	now := time.Now()
+	if exists && now.Sub(attempt.touched) >= s.attemptTTL {
+		// Abandoned, though not yet pruned
+		delete(s.attempts, userID)
+		exists = false
+	}
+	if !exists {
+		s.mu.Unlock()
+		return nil, ErrNoActivePuzzle
+	}
+	attempt.touched = now
+
+	played := attempt.game.Clone()
+	if err := played.PushMove(move, nil); err != nil {
+		s.mu.Unlock()
+		return nil, ErrPuzzleIllegalMove
+	}
+
+	expected := attempt.game.Clone()
+	correct := attempt.ply < len(attempt.solution) &&
+		expected.PushMove(attempt.solution[attempt.ply], nil) == nil &&
+		expected.FEN() == played.FEN()
+	if played.Method() == chess.Checkmate {
+		correct = true
+	}
+
+	result := &PuzzleMoveResult{Correct: correct}
+
+	if correct {
+		attempt.game = played
+		attempt.ply++
+
+		// Play the opponent's reply, if the line continues
+		if attempt.ply < len(attempt.solution) && played.Outcome() == chess.NoOutcome {
+			reply := attempt.solution[attempt.ply]
+			if err := attempt.game.PushMove(reply, nil); err == nil {
+				result.Reply = reply
+				attempt.ply++
+			}
+		}
+
+		result.Solved = attempt.ply >= len(attempt.solution) || attempt.game.Outcome() != chess.NoOutcome
+	}
+	result.FEN = attempt.game.FEN()
+
+	over := !correct || result.Solved
+	if over {
+		delete(s.attempts, userID)
+		result.Solution = attempt.puzzle.Solution
+	}
+	s.mu.Unlock()
+
+	if over {
+		rating, err := s.updatePuzzleRating(ctx, userID, attempt.puzzle.Rating, result.Solved)
+		if err != nil {
+			logging.Warnf("Failed to update puzzle rating for user %s: %v", userID, err)
+		} else {
+			result.PuzzleRating = rating
+		}
+	}
+
+	return result, nil
+}
+
+// updatePuzzleRating scores a finished puzzle as a game against the puzzle
+func (s *PuzzleService) updatePuzzleRating(ctx context.Context, userID string, puzzleRating int, solved bool) (int, error) {
+	outcome := 0.0
+	if solved {
+		outcome = 1.0
+	}
+
+	ctx = repositories.ReadPrimary(ctx)
+	for attempt := 0; ; attempt++ {
+		user, err := s.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+
+		rating := user.PuzzleRating + calculateEloChange(user.PuzzleRating, puzzleRating, outcome, establishedKFactor)
+		user.PuzzleRating = floorRating(user.PuzzleRating, rating)
+
+		err = s.userRepo.Update(ctx, user)
+		if err == repositories.ErrUserConflict && attempt < maxUpdateRetries {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		return user.PuzzleRating, nil
+	}
+}
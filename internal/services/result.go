@@ -0,0 +1,144 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/corentings/chess/v2"
+)
+
+// Ways a game can end, as reported in game-over messages and stored with
+// finished games. These are part of the API and must not change; the chess
+// library's own names are mapped onto them by MethodName.
+const (
+	MethodCheckmate                     = "checkmate"
+	MethodResignation                   = "resignation"
+	MethodTimeout                       = "timeout"
+	MethodTimeoutVsInsufficientMaterial = "timeout_vs_insufficient_material"
+	MethodAbandoned                     = "abandoned"
+	MethodAborted                       = "aborted"
+	MethodAdminTerminated               = "admin_terminated"
+	MethodMaxDuration                   = "max_duration"
+	MethodForfeit                       = "forfeit" // A tournament player didn't turn up to play
+	MethodDrawAgreement                 = "draw_agreement"
+	MethodStalemate                     = "stalemate"
+	MethodInsufficientMaterial          = "insufficient_material"
+	MethodFiftyMove                     = "fifty_move"
+	MethodSeventyFiveMove               = "seventy_five_move"
+	MethodThreefold                     = "threefold"
+	MethodFivefold                      = "fivefold"
+)
+
+// Game results, in PGN notation
+const (
+	OutcomeWhiteWon = "1-0"
+	OutcomeBlackWon = "0-1"
+	OutcomeDraw     = "1/2-1/2"
+	OutcomeNone     = "*" // Aborted and terminated games have no result
+)
+
+// MethodName returns the API name of a method the chess library ended a
+// game with, or an empty string if the game isn't over
+func MethodName(method chess.Method) string {
+	switch method {
+	case chess.Checkmate:
+		return MethodCheckmate
+	case chess.Resignation:
+		return MethodResignation
+	case chess.DrawOffer:
+		return MethodDrawAgreement
+	case chess.Stalemate:
+		return MethodStalemate
+	case chess.ThreefoldRepetition:
+		return MethodThreefold
+	case chess.FivefoldRepetition:
+		return MethodFivefold
+	case chess.FiftyMoveRule:
+		return MethodFiftyMove
+	case chess.SeventyFiveMoveRule:
+		return MethodSeventyFiveMove
+	case chess.InsufficientMaterial:
+		return MethodInsufficientMaterial
+	default:
+		return ""
+	}
+}
+
+// OutcomeName returns the API name of a game result, or an empty string if
+// the game isn't over
+func OutcomeName(outcome chess.Outcome) string {
+	switch outcome {
+	case chess.WhiteWon:
+		return OutcomeWhiteWon
+	case chess.BlackWon:
+		return OutcomeBlackWon
+	case chess.Draw:
+		return OutcomeDraw
+	default:
+		return ""
+	}
+}
+
+// SetAbortThreshold makes games that a player leaves, or that are agreed
+// drawn, before plies half-moves have been played end as aborted: they have
+// no result and leave ratings alone. Zero turns this off, though games in
+// which nobody moves can still be aborted outright.
+func (s *GameService) SetAbortThreshold(plies int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.abortPlies = plies
+}
+
+// abortable reports whether a game ending this way can count as aborted when
+// it ends early. Results reached on the board always stand.
+func abortable(method string) bool {
+	switch method {
+	case MethodResignation, MethodAbandoned, MethodTimeout, MethodTimeoutVsInsufficientMaterial, MethodDrawAgreement, MethodMaxDuration:
+		return true
+	default:
+		return false
+	}
+}
+
+// Result returns the API names of a finished game's result and of how it
+// ended. The result of an aborted game is OutcomeNone, whatever the chess
+// library recorded.
+func (s *GameService) Result(gameID string) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		return "", "", ErrGameNotFound
+	}
+	if game.Outcome() == chess.NoOutcome {
+		return "", "", fmt.Errorf("game is not over yet")
+	}
+	return resultOf(game, state), state.EndMethod, nil
+}
+
+// resultOf returns the API name of a game's result, accounting for aborted
+// and terminated games that the chess library considers drawn
+func resultOf(game *chess.Game, state *GameState) string {
+	if voided(state.EndMethod) {
+		return OutcomeNone
+	}
+	return OutcomeName(game.Outcome())
+}
+
+// pgnOf returns the PGN of a game. Voided games are ended as agreed draws
+// for the chess library, so their result is rewritten to OutcomeNone.
+func pgnOf(game *chess.Game, state *GameState) string {
+	pgn := game.String()
+	if state == nil || !voided(state.EndMethod) {
+		return pgn
+	}
+	return strings.TrimSuffix(pgn, game.Outcome().String()) + OutcomeNone
+}
+
+// voided reports whether a game ending this way has no result and leaves
+// ratings alone
+func voided(method string) bool {
+	return method == MethodAborted || method == MethodAdminTerminated
+}
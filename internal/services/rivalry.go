@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+)
+
+// rivalryRecentGames is how many of their latest games a head-to-head
+// record lists
+const rivalryRecentGames = 10
+
+// Rival is one side of a head-to-head record
+type Rival struct {
+	ID          string `json:"id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+}
+
+// Rivalry is a user's record against another in finished games, with the
+// last few games they played each other
+type Rivalry struct {
+	Player   Rival `json:"player"`
+	Opponent Rival `json:"opponent"`
+	models.HeadToHead
+	RecentGames []*models.GameRecord `json:"recent_games"`
+}
+
+// RivalryService looks up head-to-head records between users
+type RivalryService struct {
+	userRepo repositories.UserRepository
+	gameRepo repositories.GameRepository
+}
+
+// NewRivalryService creates a new rivalry service
+func NewRivalryService(userRepo repositories.UserRepository, gameRepo repositories.GameRepository) *RivalryService {
+	return &RivalryService{
+		userRepo: userRepo,
+		gameRepo: gameRepo,
+	}
+}
+
+// HeadToHead returns the record of one user against another, counted from
+// the first user's side, as the viewer may see it: private games count only
+// when the viewer is one of the two. Users who never played each other get a
+// record of zeros; ErrUserNotFound is returned if either user doesn't exist.
+func (s *RivalryService) HeadToHead(ctx context.Context, viewerID string, username string, opponentName string) (*Rivalry, error) {
+	player, err := s.rival(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	opponent, err := s.rival(ctx, opponentName)
+	if err != nil {
+		return nil, err
+	}
+
+	includePrivate := viewerID == player.ID || viewerID == opponent.ID
+	record, err := s.gameRepo.CountBetween(ctx, player.ID, opponent.ID, includePrivate)
+	if err != nil {
+		return nil, err
+	}
+	games, err := s.gameRepo.ListBetween(ctx, player.ID, opponent.ID, includePrivate, rivalryRecentGames)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rivalry{
+		Player:      *player,
+		Opponent:    *opponent,
+		HeadToHead:  *record,
+		RecentGames: games,
+	}, nil
+}
+
+// rival looks a user up by username
+func (s *RivalryService) rival(ctx context.Context, username string) (*Rival, error) {
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		if err == repositories.ErrUserNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &Rival{ID: user.ID, Username: user.Username, DisplayName: user.DisplayName}, nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+
+	"chess-ws-go/internal/models"
+
+	"github.com/corentings/chess/v2"
+)
+
+// ErrSeatsClosed is returned when taking over a seat in a game that doesn't allow it
+var ErrSeatsClosed = errors.New("players can't be replaced in this game")
+
+// SetCasual makes a game unrated. With openSeats, a spectator may take over
+// the seat of a player who abandoned it; rated games never allow that, since
+// the result would count against someone who didn't finish it.
+func (s *GameService) SetCasual(gameID string, openSeats bool) error {
+	s.mu.Lock()
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		s.mu.Unlock()
+		return ErrGameNotFound
+	}
+	state.Casual = true
+	state.OpenSeats = openSeats
+	snapshot := s.correspondenceSnapshot(gameID, game, state)
+	s.mu.Unlock()
+
+	s.saveCorrespondence(snapshot)
+	return nil
+}
+
+// ReplacePlayer seats a different user as one side of a game in progress
+func (s *GameService) ReplacePlayer(gameID string, color chess.Color, userID string) error {
+	s.mu.Lock()
+	var snapshot *models.CorrespondenceGame
+	defer func() {
+		s.mu.Unlock()
+		s.saveCorrespondence(snapshot)
+	}()
+
+	game, exists := s.games[gameID]
+	state := s.gameStates[gameID]
+	if !exists || state == nil {
+		return ErrGameNotFound
+	}
+	if game.Outcome() != chess.NoOutcome {
+		return ErrGameOver
+	}
+	if !state.Casual || !state.OpenSeats {
+		return ErrSeatsClosed
+	}
+
+	if color == chess.White {
+		state.WhitePlayer = userID
+	} else {
+		state.BlackPlayer = userID
+	}
+	snapshot = s.correspondenceSnapshot(gameID, game, state)
+	return nil
+}
@@ -0,0 +1,520 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"chess-ws-go/internal/repositories"
+
+	"github.com/corentings/chess/v2"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTournamentNotFound    = errors.New("tournament not found")
+	ErrTournamentForbidden   = errors.New("only the tournament creator can do this")
+	ErrTournamentStarted     = errors.New("tournament has already started")
+	ErrTournamentFinished    = errors.New("tournament is finished")
+	ErrAlreadyRegistered     = errors.New("already registered for this tournament")
+	ErrNotEnoughPlayers      = errors.New("a tournament needs at least two players")
+	ErrRoundInProgress       = errors.New("current round still has unreported results")
+	ErrPairingNotFound       = errors.New("game is not part of the current round")
+	ErrResultAlreadyPresent  = errors.New("result already reported")
+	ErrInvalidResult         = errors.New("invalid result")
+	ErrResultMismatch        = errors.New("result does not match the game's outcome")
+	ErrWrongTournamentFormat = errors.New("not supported for this tournament format")
+)
+
+// Tournament formats
+const (
+	FormatSwiss    = "swiss"
+	FormatKnockout = "knockout" // Single elimination
+)
+
+// Tournament statuses
+const (
+	TournamentRegistering = "registering"
+	TournamentRunning     = "running"
+	TournamentFinished    = "finished"
+)
+
+// Byes score a full point, as is usual in Swiss events
+const byeScore = 1.0
+
+// Tournament is either a Swiss-system tournament played over a fixed number
+// of rounds or a single-elimination bracket
+type Tournament struct {
+	ID           string              `json:"id"`
+	Name         string              `json:"name"`
+	Format       string              `json:"format"`
+	CreatorID    string              `json:"creator_id"`
+	TimeControl  TimeControl         `json:"time_control"`
+	Rounds       int                 `json:"rounds"`
+	SeedByRating bool                `json:"seed_by_rating"`
+	Status       string              `json:"status"`
+	Players      []*TournamentPlayer `json:"players"`
+	Pairings     [][]*Pairing        `json:"pairings,omitempty"` // Swiss: one slice per round played
+	Bracket      [][]*BracketMatch   `json:"bracket,omitempty"`  // Knockout: one slice per round
+	WinnerID     string              `json:"winner_id,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+}
+
+// TournamentOptions configures a new tournament
+type TournamentOptions struct {
+	Name         string
+	Format       string
+	Rounds       int // Swiss only; knockout rounds follow from the player count
+	SeedByRating bool
+	TimeControl  TimeControl
+}
+
+// TournamentPlayer is a registered player and their running score
+type TournamentPlayer struct {
+	UserID    string  `json:"user_id"`
+	Username  string  `json:"username"`
+	Rating    int     `json:"rating"`
+	Score     float64 `json:"score"`
+	HadBye    bool    `json:"had_bye"`
+	Seed      int     `json:"seed,omitempty"` // Knockout seeding, 1 is the top seed
+	WhiteDiff int     `json:"-"`              // Games as white minus games as black
+}
+
+// Pairing is a single board in a round. A bye has no black player or game.
+type Pairing struct {
+	WhiteID string `json:"white_id"`
+	BlackID string `json:"black_id,omitempty"`
+	GameID  string `json:"game_id,omitempty"`
+	Result  string `json:"result,omitempty"` // "1-0", "0-1" or "1/2-1/2"
+}
+
+// TournamentService runs Swiss tournaments whose games are created through
+// GameService. Tournaments are kept in memory.
+type TournamentService struct {
+	tournaments   map[string]*Tournament
+	gameService   *GameService
+	userRepo      repositories.UserRepository
+	noShowTimeout time.Duration          // How long bracket games wait for their first moves
+	noShowTimers  map[string]*time.Timer // Game ID -> its pending no-show check
+	mu            sync.Mutex
+}
+
+// NewTournamentService creates a new tournament service. Knockout brackets
+// advance as their games end, via GameService's game-over hook.
+func NewTournamentService(gameService *GameService, userRepo repositories.UserRepository) *TournamentService {
+	s := &TournamentService{
+		tournaments:   make(map[string]*Tournament),
+		gameService:   gameService,
+		userRepo:      userRepo,
+		noShowTimeout: defaultNoShowTimeout,
+		noShowTimers:  make(map[string]*time.Timer),
+	}
+	gameService.OnGameOver(s.handleGameOver)
+	return s
+}
+
+// SetNoShowTimeout sets how long bracket games started from now on wait
+// for their first moves before the absent player forfeits
+func (s *TournamentService) SetNoShowTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noShowTimeout = timeout
+}
+
+// CreateTournament creates a tournament open for registration
+func (s *TournamentService) CreateTournament(creatorID string, opts TournamentOptions) *Tournament {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if opts.Format == "" {
+		opts.Format = FormatSwiss
+	}
+
+	t := &Tournament{
+		ID:           uuid.New().String(),
+		Name:         opts.Name,
+		Format:       opts.Format,
+		CreatorID:    creatorID,
+		TimeControl:  opts.TimeControl,
+		Rounds:       opts.Rounds,
+		SeedByRating: opts.SeedByRating,
+		Status:       TournamentRegistering,
+		Players:      []*TournamentPlayer{},
+		CreatedAt:    time.Now(),
+	}
+	s.tournaments[t.ID] = t
+
+	return t.clone()
+}
+
+// GetTournament returns a copy of a tournament with players in standings order
+func (s *TournamentService) GetTournament(tournamentID string) (*Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, exists := s.tournaments[tournamentID]
+	if !exists {
+		return nil, ErrTournamentNotFound
+	}
+
+	c := t.clone()
+	sortStandings(c.Players)
+	return c, nil
+}
+
+// Register adds a player to a tournament that hasn't started yet
+func (s *TournamentService) Register(ctx context.Context, tournamentID string, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if err == repositories.ErrUserNotFound {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, exists := s.tournaments[tournamentID]
+	if !exists {
+		return ErrTournamentNotFound
+	}
+	if t.Status != TournamentRegistering {
+		return ErrTournamentStarted
+	}
+	if t.player(userID) != nil {
+		return ErrAlreadyRegistered
+	}
+
+	category := t.TimeControl.Category()
+	t.Players = append(t.Players, &TournamentPlayer{
+		UserID:   user.ID,
+		Username: user.Username,
+		Rating:   user.Rating(category),
+	})
+
+	return nil
+}
+
+// StartRound pairs the next Swiss round and creates its games. Only the
+// creator may start rounds, and every result of the previous round must be
+// in. For knockout tournaments use StartBracket.
+func (s *TournamentService) StartRound(tournamentID string, userID string) ([]*Pairing, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, exists := s.tournaments[tournamentID]
+	if !exists {
+		return nil, ErrTournamentNotFound
+	}
+	if t.CreatorID != userID {
+		return nil, ErrTournamentForbidden
+	}
+	if t.Format != FormatSwiss {
+		return nil, ErrWrongTournamentFormat
+	}
+	if t.Status == TournamentFinished || len(t.Pairings) >= t.Rounds {
+		return nil, ErrTournamentFinished
+	}
+	if len(t.Players) < 2 {
+		return nil, ErrNotEnoughPlayers
+	}
+	if !t.roundComplete() {
+		return nil, ErrRoundInProgress
+	}
+
+	round := t.pairRound()
+	for _, p := range round {
+		if p.BlackID == "" {
+			continue
+		}
+		p.GameID = s.gameService.CreateGameWithTimeControl(p.WhiteID, p.BlackID, t.TimeControl)
+	}
+
+	t.Status = TournamentRunning
+	t.Pairings = append(t.Pairings, round)
+
+	result := make([]*Pairing, len(round))
+	for i, p := range round {
+		pairing := *p
+		result[i] = &pairing
+	}
+	return result, nil
+}
+
+// ReportResult records the result of a game in the current round. Either
+// player or the creator may report it, but only the creator may override the
+// board: a player's report must match the finished game in GameService. An
+// empty result is taken from that game.
+func (s *TournamentService) ReportResult(tournamentID string, userID string, gameID string, result string) error {
+	live := chess.NoOutcome
+	if isOver, outcome, _, err := s.gameService.IsGameOver(gameID); err == nil && isOver {
+		live = outcome
+	}
+	if result == "" {
+		if live == chess.NoOutcome {
+			return ErrInvalidResult
+		}
+		result = live.String()
+	}
+
+	var whitePoints float64
+	switch chess.Outcome(result) {
+	case chess.WhiteWon:
+		whitePoints = 1
+	case chess.BlackWon:
+		whitePoints = 0
+	case chess.Draw:
+		whitePoints = 0.5
+	default:
+		return ErrInvalidResult
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, exists := s.tournaments[tournamentID]
+	if !exists {
+		return ErrTournamentNotFound
+	}
+	if t.Format == FormatKnockout {
+		return s.reportBracketResult(t, userID, gameID, chess.Outcome(result))
+	}
+	if len(t.Pairings) == 0 || t.Status == TournamentFinished {
+		return ErrPairingNotFound
+	}
+
+	var pairing *Pairing
+	for _, p := range t.Pairings[len(t.Pairings)-1] {
+		if p.GameID != "" && p.GameID == gameID {
+			pairing = p
+			break
+		}
+	}
+	if pairing == nil {
+		return ErrPairingNotFound
+	}
+	if userID != t.CreatorID {
+		if userID != pairing.WhiteID && userID != pairing.BlackID {
+			return ErrTournamentForbidden
+		}
+		if chess.Outcome(result) != live {
+			return ErrResultMismatch
+		}
+	}
+	if pairing.Result != "" {
+		return ErrResultAlreadyPresent
+	}
+
+	pairing.Result = result
+	t.player(pairing.WhiteID).Score += whitePoints
+	t.player(pairing.BlackID).Score += 1 - whitePoints
+
+	if len(t.Pairings) == t.Rounds && t.roundComplete() {
+		t.Status = TournamentFinished
+	}
+
+	return nil
+}
+
+// pairRound builds the next round's pairings: players are ranked by score
+// (then rating), the lowest-ranked player without a bye sits out if the
+// count is odd, and the rest are paired top-down with opponents they haven't
+// met yet wherever possible. Callers must hold the service lock.
+func (t *Tournament) pairRound() []*Pairing {
+	ranked := make([]*TournamentPlayer, len(t.Players))
+	copy(ranked, t.Players)
+	sortStandings(ranked)
+
+	round := []*Pairing{}
+
+	if len(ranked)%2 == 1 {
+		byeIndex := len(ranked) - 1
+		for i := len(ranked) - 1; i >= 0; i-- {
+			if !ranked[i].HadBye {
+				byeIndex = i
+				break
+			}
+		}
+
+		bye := ranked[byeIndex]
+		bye.HadBye = true
+		bye.Score += byeScore
+		round = append(round, &Pairing{WhiteID: bye.UserID, Result: chess.WhiteWon.String()})
+		ranked = append(ranked[:byeIndex], ranked[byeIndex+1:]...)
+	}
+
+	// Search for pairings without rematches. If there are none, or finding
+	// them takes too long, pair greedily, allowing a rematch only where a
+	// player has met everyone still unpaired.
+	played := t.playedPairs()
+	budget := pairingSearchBudget
+	pairs, ok := pairPlayers(ranked, played, &budget)
+	if !ok {
+		pairs = pairGreedily(ranked, played)
+	}
+
+	for _, pair := range pairs {
+		// Give white to whoever has had it less often
+		white, black := pair[0], pair[1]
+		if white.WhiteDiff > black.WhiteDiff {
+			white, black = black, white
+		}
+		white.WhiteDiff++
+		black.WhiteDiff--
+
+		round = append(round, &Pairing{WhiteID: white.UserID, BlackID: black.UserID})
+	}
+
+	return round
+}
+
+// pairingSearchBudget caps how many steps the search for rematch-free
+// pairings may take. It backtracks, so where there are none it could
+// otherwise try every way of pairing the field before giving up.
+const pairingSearchBudget = 10000
+
+// pairPlayers pairs the top-ranked player with the best-ranked opponent
+// they haven't met that still lets the rest be paired, backtracking when a
+// choice leads to a dead end. It gives up once the budget of steps is spent.
+func pairPlayers(ranked []*TournamentPlayer, played map[string]bool, budget *int) ([][2]*TournamentPlayer, bool) {
+	if len(ranked) == 0 {
+		return nil, true
+	}
+	if *budget <= 0 {
+		return nil, false
+	}
+	*budget--
+
+	player := ranked[0]
+	for i := 1; i < len(ranked); i++ {
+		opponent := ranked[i]
+		if played[pairKey(player.UserID, opponent.UserID)] {
+			continue
+		}
+
+		rest := make([]*TournamentPlayer, 0, len(ranked)-2)
+		rest = append(rest, ranked[1:i]...)
+		rest = append(rest, ranked[i+1:]...)
+
+		if pairs, ok := pairPlayers(rest, played, budget); ok {
+			return append([][2]*TournamentPlayer{{player, opponent}}, pairs...), true
+		}
+		if *budget <= 0 {
+			break
+		}
+	}
+
+	return nil, false
+}
+
+// pairGreedily pairs players top-down without backtracking, each with the
+// best-ranked opponent they haven't met, or the next in line if they've met
+// everyone left
+func pairGreedily(ranked []*TournamentPlayer, played map[string]bool) [][2]*TournamentPlayer {
+	rest := make([]*TournamentPlayer, len(ranked))
+	copy(rest, ranked)
+
+	pairs := make([][2]*TournamentPlayer, 0, len(ranked)/2)
+	for len(rest) > 1 {
+		player := rest[0]
+		choice := 1
+		for i := 1; i < len(rest); i++ {
+			if !played[pairKey(player.UserID, rest[i].UserID)] {
+				choice = i
+				break
+			}
+		}
+		pairs = append(pairs, [2]*TournamentPlayer{player, rest[choice]})
+		rest = append(rest[1:choice], rest[choice+1:]...)
+	}
+
+	return pairs
+}
+
+// playedPairs returns the set of player pairs that have already met
+func (t *Tournament) playedPairs() map[string]bool {
+	played := make(map[string]bool)
+	for _, round := range t.Pairings {
+		for _, p := range round {
+			if p.BlackID != "" {
+				played[pairKey(p.WhiteID, p.BlackID)] = true
+			}
+		}
+	}
+	return played
+}
+
+// roundComplete reports whether every game of the latest round has a result
+func (t *Tournament) roundComplete() bool {
+	if len(t.Pairings) == 0 {
+		return true
+	}
+	for _, p := range t.Pairings[len(t.Pairings)-1] {
+		if p.Result == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// player returns the registered player with the given user ID, if any
+func (t *Tournament) player(userID string) *TournamentPlayer {
+	for _, p := range t.Players {
+		if p.UserID == userID {
+			return p
+		}
+	}
+	return nil
+}
+
+// clone returns a deep copy of the tournament
+func (t *Tournament) clone() *Tournament {
+	c := *t
+
+	c.Players = make([]*TournamentPlayer, len(t.Players))
+	for i, p := range t.Players {
+		player := *p
+		c.Players[i] = &player
+	}
+
+	c.Pairings = make([][]*Pairing, len(t.Pairings))
+	for i, round := range t.Pairings {
+		c.Pairings[i] = make([]*Pairing, len(round))
+		for j, p := range round {
+			pairing := *p
+			c.Pairings[i][j] = &pairing
+		}
+	}
+
+	c.Bracket = make([][]*BracketMatch, len(t.Bracket))
+	for i, round := range t.Bracket {
+		c.Bracket[i] = make([]*BracketMatch, len(round))
+		for j, m := range round {
+			match := *m
+			c.Bracket[i][j] = &match
+		}
+	}
+
+	return &c
+}
+
+// sortStandings orders players by score, then rating
+func sortStandings(players []*TournamentPlayer) {
+	sort.SliceStable(players, func(i, j int) bool {
+		if players[i].Score != players[j].Score {
+			return players[i].Score > players[j].Score
+		}
+		return players[i].Rating > players[j].Rating
+	})
+}
+
+// pairKey identifies a pair of players regardless of color
+func pairKey(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
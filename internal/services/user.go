@@ -3,7 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"chess-ws-go/internal/auth"
 	"chess-ws-go/internal/models"
 	"chess-ws-go/internal/repositories"
 
@@ -11,21 +15,46 @@ import (
 )
 
 var (
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound  = errors.New("user not found")
+	ErrInvalidCursor = repositories.ErrInvalidCursor // Cursors are decoded by the repository
+	ErrInvalidLimit  = errors.New("limit must be at least 1")
+	ErrUserConflict  = errors.New("user was modified concurrently")
+	ErrEmailTaken    = errors.New("email already taken")
 )
 
 // UserService handles user-related operations
 type UserService struct {
-	userRepo repositories.UserRepository
+	userRepo    repositories.UserRepository
+	auditLogger *AuditLogger
+	sender      EmailSender
 }
 
-// NewUserService creates a new user service
-func NewUserService(userRepo repositories.UserRepository) *UserService {
+// NewUserService creates a new user service. Emails are only logged until
+// SetEmailSender is given a real sender.
+func NewUserService(userRepo repositories.UserRepository, auditLogger *AuditLogger) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		auditLogger: auditLogger,
+		sender:      LogEmailSender{},
 	}
 }
 
+// SetEmailSender sets how account emails, such as password reset tokens
+// and email change confirmations, are delivered
+func (s *UserService) SetEmailSender(sender EmailSender) {
+	s.sender = sender
+}
+
+// maxUpdateRetries is how many times a user update is retried after losing
+// an optimistic locking race
+const maxUpdateRetries = 3
+
+// normalizeEmail returns the form emails are stored and looked up in, so
+// addresses differing only in case or surrounding space are one account
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 // UpdateProfile updates a user's profile information
 func (s *UserService) UpdateProfile(
 	ctx context.Context,
@@ -33,36 +62,137 @@ func (s *UserService) UpdateProfile(
 	displayName *string,
 	email *string,
 ) (*models.User, error) {
+	// The user is read back to be updated, so it has to be current
+	ctx = repositories.ReadPrimary(ctx)
+	for attempt := 0; ; attempt++ {
+		user, err := s.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			if err == repositories.ErrUserNotFound {
+				return nil, ErrUserNotFound
+			}
+			return nil, err
+		}
+
+		if displayName != nil {
+			user.DisplayName = *displayName
+		}
+		changeRequested := false
+		if email != nil {
+			normalized := normalizeEmail(*email)
+			if normalized == user.Email {
+				// Asking for the current address cancels a pending change
+				user.PendingEmail = ""
+				user.EmailChangeToken = ""
+				user.EmailChangeTokenExpiresAt = nil
+			} else {
+				// Check if email is already taken
+				existingUser, err := s.userRepo.GetByEmail(ctx, normalized)
+				if err == nil && existingUser.ID != userID {
+					return nil, ErrEmailTaken
+				}
+				// The current address stays in use until the new one is
+				// confirmed, so a typo can't lock the user out
+				user.PendingEmail = normalized
+				expiresAt := time.Now().Add(emailChangeTokenTTL)
+				user.EmailChangeToken = uuid.New().String()
+				user.EmailChangeTokenExpiresAt = &expiresAt
+				changeRequested = true
+			}
+		}
+
+		err = s.userRepo.Update(ctx, user)
+		if err == repositories.ErrUserConflict && attempt < maxUpdateRetries {
+			// Someone else updated the user in the meantime; reload and reapply
+			continue
+		}
+		if err != nil {
+			if err == repositories.ErrUserConflict {
+				return nil, ErrUserConflict
+			}
+			if err == repositories.ErrUserAlreadyExists {
+				return nil, ErrEmailTaken
+			}
+			return nil, err
+		}
+
+		if changeRequested {
+			s.sendEmailChange(ctx, user)
+		}
+		return user, nil
+	}
+}
+
+// sendEmailChange mails the token confirming a pending email change to the
+// new address, proving the user can receive mail there
+func (s *UserService) sendEmailChange(ctx context.Context, user *models.User) {
+	sendInBackground(ctx, s.sender, Email{
+		To:      user.PendingEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Confirm that %s is the new email address of %s with this token: %s\n\nIt expires in %s. If you didn't ask for the change, ignore this email.",
+			user.PendingEmail, user.Username, user.EmailChangeToken, expiresIn(emailChangeTokenTTL)),
+	})
+}
+
+// NotificationPreferenceUpdate changes some of a user's notification
+// preferences; nil fields are left as they are
+type NotificationPreferenceUpdate struct {
+	GameInvites    *bool
+	GameSummaries  *bool
+	SecurityAlerts *bool
+}
+
+// NotificationPreferences returns which optional emails a user receives
+func (s *UserService) NotificationPreferences(ctx context.Context, userID string) (models.NotificationPreferences, error) {
 	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		if err == repositories.ErrUserNotFound {
-			return nil, ErrUserNotFound
+			return models.NotificationPreferences{}, ErrUserNotFound
 		}
-		return nil, err
+		return models.NotificationPreferences{}, err
 	}
+	return user.NotificationPreferences, nil
+}
 
-	if displayName != nil {
-		user.DisplayName = *displayName
-	}
-	if email != nil {
-		// Check if email is already taken
-		existingUser, err := s.userRepo.GetByEmail(ctx, *email)
-		if err == nil && existingUser.ID != userID {
-			return nil, errors.New("email already taken")
+// UpdateNotificationPreferences changes which optional emails a user receives
+func (s *UserService) UpdateNotificationPreferences(
+	ctx context.Context,
+	userID string,
+	update NotificationPreferenceUpdate,
+) (models.NotificationPreferences, error) {
+	ctx = repositories.ReadPrimary(ctx)
+	for attempt := 0; ; attempt++ {
+		user, err := s.userRepo.GetByID(ctx, userID)
+		if err != nil {
+			if err == repositories.ErrUserNotFound {
+				return models.NotificationPreferences{}, ErrUserNotFound
+			}
+			return models.NotificationPreferences{}, err
 		}
-		user.Email = *email
-		// Optionally require email verification again
-		user.IsVerified = false
-		user.VerificationToken = uuid.New().String()
-		// TODO: Send verification email
-	}
 
-	err = s.userRepo.Update(ctx, user)
-	if err != nil {
-		return nil, err
-	}
+		prefs := &user.NotificationPreferences
+		if update.GameInvites != nil {
+			prefs.GameInvites = *update.GameInvites
+		}
+		if update.GameSummaries != nil {
+			prefs.GameSummaries = *update.GameSummaries
+		}
+		if update.SecurityAlerts != nil {
+			prefs.SecurityAlerts = *update.SecurityAlerts
+		}
 
-	return user, nil
+		err = s.userRepo.Update(ctx, user)
+		if err == repositories.ErrUserConflict && attempt < maxUpdateRetries {
+			// Someone else updated the user in the meantime; reload and reapply
+			continue
+		}
+		if err != nil {
+			if err == repositories.ErrUserConflict {
+				return models.NotificationPreferences{}, ErrUserConflict
+			}
+			return models.NotificationPreferences{}, err
+		}
+		return user.NotificationPreferences, nil
+	}
 }
 
 // DeleteUser deletes a user account
@@ -83,23 +213,85 @@ func (s *UserService) DeleteUser(ctx context.Context, userID string) error {
 	}
 
 	// Delete user
-	return s.userRepo.Delete(ctx, userID)
+	err = s.userRepo.Delete(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	s.auditLogger.Log(ctx, userID, AuditAccountDeleted, userID, "")
+	return nil
 }
 
-// ListUsers returns a paginated list of users with optional search
+// ListUsers returns a page of users with optional search, using offset
+// pagination. Prefer ListUsersAfter for public-facing lists.
 func (s *UserService) ListUsers(
 	ctx context.Context,
 	page int,
 	limit int,
 	search string,
 ) ([]*models.User, int, error) {
-	// TODO: Implement repository method for paginated user listing with search
-	return nil, 0, errors.New("not implemented")
+	total, err := s.userRepo.Count(ctx, search)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users, err := s.userRepo.List(ctx, repositories.UserListOptions{
+		Search: search,
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// ListUsersAfter returns up to limit users following the given cursor (or from
+// the start when the cursor is empty), plus the cursor for the next page.
+// The next cursor is empty when there are no more users.
+func (s *UserService) ListUsersAfter(
+	ctx context.Context,
+	cursor string,
+	limit int,
+	search string,
+) ([]*models.User, string, error) {
+	if limit < 1 {
+		return nil, "", ErrInvalidLimit
+	}
+
+	opts := repositories.UserListOptions{
+		Search: search,
+		Limit:  limit + 1, // Fetch one extra to know whether another page exists
+	}
+
+	if cursor != "" {
+		after, err := repositories.DecodeUserCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		opts.After = after
+	}
+
+	users, err := s.userRepo.List(ctx, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(users) > limit {
+		users = users[:limit]
+		last := users[len(users)-1]
+		nextCursor = repositories.UserCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+
+	return users, nextCursor, nil
 }
 
 // RequestPasswordReset initiates the password reset process
 func (s *UserService) RequestPasswordReset(ctx context.Context, email string) error {
-	user, err := s.userRepo.GetByEmail(ctx, email)
+	ctx = repositories.ReadPrimary(ctx)
+	user, err := s.userRepo.GetByEmail(ctx, normalizeEmail(email))
 	if err != nil {
 		if err == repositories.ErrUserNotFound {
 			return nil // Return success to prevent email enumeration
@@ -108,25 +300,102 @@ func (s *UserService) RequestPasswordReset(ctx context.Context, email string) er
 	}
 
 	// Generate reset token
-	resetToken := uuid.New().String()
-	user.PasswordResetToken = resetToken
-	// TODO: Set expiration time for reset token
-	// TODO: Send password reset email
+	expiresAt := time.Now().Add(passwordResetTokenTTL)
+	user.PasswordResetToken = uuid.New().String()
+	user.PasswordResetTokenExpiresAt = &expiresAt
 
 	err = s.userRepo.Update(ctx, user)
 	if err != nil {
 		return err
 	}
 
+	sendInBackground(ctx, s.sender, Email{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Reset the password of %s with this token: %s\n\nIt expires in %s. If you didn't ask for a reset, ignore this email.",
+			user.Username, user.PasswordResetToken, expiresIn(passwordResetTokenTTL)),
+	})
+
+	s.auditLogger.Log(ctx, user.ID, AuditPasswordResetRequested, user.ID, "")
 	return nil
 }
 
+// ConfirmEmailChange switches a user to the pending email address the
+// token was sent to. Receiving the token proves the address works, so the
+// user counts as verified afterwards.
+func (s *UserService) ConfirmEmailChange(ctx context.Context, userID, token string) (*models.User, error) {
+	user, err := s.userRepo.GetByEmailChangeToken(ctx, token)
+	if err != nil {
+		if err == repositories.ErrTokenNotFound {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if user.ID != userID {
+		return nil, ErrInvalidToken
+	}
+
+	// Someone may have registered the address since the change was requested
+	existingUser, err := s.userRepo.GetByEmail(ctx, user.PendingEmail)
+	if err == nil && existingUser.ID != user.ID {
+		return nil, ErrEmailTaken
+	}
+
+	oldEmail := user.Email
+	user.Email = user.PendingEmail
+	user.IsVerified = true
+	user.PendingEmail = ""
+	user.EmailChangeToken = ""
+	user.EmailChangeTokenExpiresAt = nil
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		if err == repositories.ErrUserAlreadyExists {
+			return nil, ErrEmailTaken
+		}
+		if err == repositories.ErrUserConflict {
+			return nil, ErrUserConflict
+		}
+		return nil, err
+	}
+
+	s.auditLogger.Log(ctx, user.ID, AuditEmailChanged, user.ID, "from "+oldEmail)
+	return user, nil
+}
+
 // ConfirmPasswordReset completes the password reset process
 func (s *UserService) ConfirmPasswordReset(
 	ctx context.Context,
 	token string,
 	newPassword string,
 ) error {
-	// TODO: Implement repository method to find user by reset token
-	return errors.New("not implemented")
+	user, err := s.userRepo.GetByPasswordResetToken(ctx, token)
+	if err != nil {
+		if err == repositories.ErrTokenNotFound {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	passwordHash, err := auth.HashPassword(newPassword, nil)
+	if err != nil {
+		return err
+	}
+
+	// Set the new password and burn the token
+	user.PasswordHash = passwordHash
+	user.PasswordResetToken = ""
+	user.PasswordResetTokenExpiresAt = nil
+	user.FailedLoginAttempts = 0
+
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	// Sign out every existing session
+	if err := s.userRepo.DeleteUserRefreshTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	s.auditLogger.Log(ctx, user.ID, AuditPasswordChanged, user.ID, "password reset")
+	return nil
 }
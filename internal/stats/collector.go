@@ -1,37 +1,66 @@
 package stats
 
 import (
-	"log"
 	"sync"
 	"time"
+
+	"chess-ws-go/internal/logging"
 )
 
 // Stats holds the server statistics
 type Stats struct {
 	ActiveConnections int
 	ActiveGames       int
+	Games             GameCounts
 	TotalRequests     uint64
 	StartTime         time.Time
 	mu                sync.RWMutex
 }
 
+// GameCounts breaks down the games in progress
+type GameCounts struct {
+	Total      int            // Games in memory, finished ones awaiting cleanup included
+	InProgress int            // Games not yet over
+	ByCategory map[string]int // Games in progress by speed, correspondence ones apart
+	Rated      int            // Games in progress that will change ratings
+	Casual     int            // Games in progress that won't
+}
+
+// clone returns a copy that shares no map with the original
+func (g GameCounts) clone() GameCounts {
+	byCategory := make(map[string]int, len(g.ByCategory))
+	for category, count := range g.ByCategory {
+		byCategory[category] = count
+	}
+	g.ByCategory = byCategory
+	return g
+}
+
 // Collector manages server statistics
 type Collector struct {
-	stats    *Stats
-	interval time.Duration
-	getGames func() int // Callback to get current number of games
-	getConns func() int // Callback to get current number of connections
+	stats          *Stats
+	interval       time.Duration
+	getGames       func() GameCounts // Callback to get the current games, copied so no lock is held while they're logged
+	getConns       func() int        // Callback to get current number of connections
+	moveProcessing *Summary          // Time GameService.MakeMove takes
+	moveHandling   *Summary          // Time a WebSocket move takes end to end, lock waits included
+	messages       *Counters         // WebSocket messages handled, by type
+	rejected       *Counters         // WebSocket messages rejected, by reason
 }
 
 // NewCollector creates a new statistics collector
-func NewCollector(interval time.Duration, getGames, getConns func() int) *Collector {
+func NewCollector(interval time.Duration, getGames func() GameCounts, getConns func() int) *Collector {
 	return &Collector{
 		stats: &Stats{
 			StartTime: time.Now(),
 		},
-		interval: interval,
-		getGames: getGames,
-		getConns: getConns,
+		interval:       interval,
+		getGames:       getGames,
+		getConns:       getConns,
+		moveProcessing: NewSummary(),
+		moveHandling:   NewSummary(),
+		messages:       NewCounters(),
+		rejected:       NewCounters(),
 	}
 }
 
@@ -47,24 +76,38 @@ func (c *Collector) Start() {
 
 // collect gathers current statistics
 func (c *Collector) collect() {
-	c.stats.mu.Lock()
-	defer c.stats.mu.Unlock()
+	games := c.getGames()
+	conns := c.getConns()
 
-	c.stats.ActiveGames = c.getGames()
-	c.stats.ActiveConnections = c.getConns()
+	c.stats.mu.Lock()
+	c.stats.ActiveGames = games.Total
+	c.stats.Games = games
+	c.stats.ActiveConnections = conns
+	uptime := time.Since(c.stats.StartTime)
+	c.stats.mu.Unlock()
 
 	// Log current stats
-	log.Printf("Server Stats - Active Connections: %d, Active Games: %d, Uptime: %v",
-		c.stats.ActiveConnections,
-		c.stats.ActiveGames,
-		time.Since(c.stats.StartTime))
+	logging.Infof("Server Stats - Active Connections: %d, Active Games: %d (%d in progress: %d rated, %d casual; %v), Uptime: %v",
+		conns,
+		games.Total,
+		games.InProgress,
+		games.Rated,
+		games.Casual,
+		games.ByCategory,
+		uptime)
 }
 
 // GetStats returns a copy of current statistics
 func (c *Collector) GetStats() Stats {
 	c.stats.mu.RLock()
 	defer c.stats.mu.RUnlock()
-	return *c.stats
+	return Stats{
+		ActiveConnections: c.stats.ActiveConnections,
+		ActiveGames:       c.stats.ActiveGames,
+		Games:             c.stats.Games.clone(),
+		TotalRequests:     c.stats.TotalRequests,
+		StartTime:         c.stats.StartTime,
+	}
 }
 
 // IncrementRequests increases the total request counter
@@ -73,3 +116,43 @@ func (c *Collector) IncrementRequests() {
 	c.stats.TotalRequests++
 	c.stats.mu.Unlock()
 }
+
+// ObserveMoveProcessing records how long the game service took to apply a move
+func (c *Collector) ObserveMoveProcessing(d time.Duration) {
+	c.moveProcessing.Observe(d)
+}
+
+// ObserveMoveHandling records how long a WebSocket move took to handle
+func (c *Collector) ObserveMoveHandling(d time.Duration) {
+	c.moveHandling.Observe(d)
+}
+
+// MoveProcessing returns the game service move latency distribution
+func (c *Collector) MoveProcessing() SummarySnapshot {
+	return c.moveProcessing.Snapshot()
+}
+
+// MoveHandling returns the WebSocket move latency distribution
+func (c *Collector) MoveHandling() SummarySnapshot {
+	return c.moveHandling.Snapshot()
+}
+
+// CountMessage records a WebSocket message of the given type being handled
+func (c *Collector) CountMessage(messageType string) {
+	c.messages.Inc(messageType)
+}
+
+// CountRejectedMessage records a WebSocket message rejected for the given reason
+func (c *Collector) CountRejectedMessage(reason string) {
+	c.rejected.Inc(reason)
+}
+
+// Messages returns how many WebSocket messages of each type were handled
+func (c *Collector) Messages() map[string]uint64 {
+	return c.messages.Snapshot()
+}
+
+// RejectedMessages returns how many WebSocket messages were rejected for each reason
+func (c *Collector) RejectedMessages() map[string]uint64 {
+	return c.rejected.Snapshot()
+}
@@ -0,0 +1,48 @@
+package stats
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Counters counts events by label. Counting an existing label only takes a
+// read lock, so concurrent increments don't contend. Callers must keep the
+// set of labels bounded.
+type Counters struct {
+	counts map[string]*atomic.Uint64
+	mu     sync.RWMutex
+}
+
+// NewCounters creates an empty set of counters
+func NewCounters() *Counters {
+	return &Counters{counts: make(map[string]*atomic.Uint64)}
+}
+
+// Inc adds one to a label's count
+func (c *Counters) Inc(label string) {
+	c.mu.RLock()
+	count, ok := c.counts[label]
+	c.mu.RUnlock()
+
+	if !ok {
+		c.mu.Lock()
+		if count, ok = c.counts[label]; !ok {
+			count = new(atomic.Uint64)
+			c.counts[label] = count
+		}
+		c.mu.Unlock()
+	}
+	count.Add(1)
+}
+
+// Snapshot returns the current count of every label seen so far
+func (c *Counters) Snapshot() map[string]uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := make(map[string]uint64, len(c.counts))
+	for label, count := range c.counts {
+		snapshot[label] = count.Load()
+	}
+	return snapshot
+}
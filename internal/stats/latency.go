@@ -0,0 +1,70 @@
+package stats
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindow is how many recent samples a Summary keeps for quantiles
+const latencyWindow = 1024
+
+// Summary tracks a latency distribution. Quantiles are computed over the
+// most recent samples; the count and sum cover every sample observed.
+type Summary struct {
+	samples []time.Duration
+	next    int // Slot the next sample overwrites once the window is full
+	count   uint64
+	sum     time.Duration
+	mu      sync.Mutex
+}
+
+// NewSummary creates an empty latency summary
+func NewSummary() *Summary {
+	return &Summary{samples: make([]time.Duration, 0, latencyWindow)}
+}
+
+// Observe records one sample
+func (s *Summary) Observe(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.samples) < latencyWindow {
+		s.samples = append(s.samples, d)
+	} else {
+		s.samples[s.next] = d
+		s.next = (s.next + 1) % latencyWindow
+	}
+	s.count++
+	s.sum += d
+}
+
+// SummarySnapshot is a point-in-time view of a Summary
+type SummarySnapshot struct {
+	P50   time.Duration
+	P95   time.Duration
+	Count uint64
+	Sum   time.Duration
+}
+
+// Snapshot returns the current quantiles, count and sum
+func (s *Summary) Snapshot() SummarySnapshot {
+	s.mu.Lock()
+	sorted := append([]time.Duration(nil), s.samples...)
+	snapshot := SummarySnapshot{Count: s.count, Sum: s.sum}
+	s.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	snapshot.P50 = quantile(sorted, 0.5)
+	snapshot.P95 = quantile(sorted, 0.95)
+	return snapshot
+}
+
+// quantile picks the nearest-rank quantile q of sorted samples
+func quantile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(q*float64(len(sorted))+0.5) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
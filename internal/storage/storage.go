@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Store keeps uploaded files under slash-separated keys and says where
+// clients can fetch them
+type Store interface {
+	Put(ctx context.Context, key string, contentType string, data []byte) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// FileStore keeps files in a local directory that the server publishes
+// under a URL prefix
+type FileStore struct {
+	dir       string
+	urlPrefix string
+}
+
+// NewFileStore creates a store writing to dir, creating it if needed
+func NewFileStore(dir, urlPrefix string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating storage directory: %w", err)
+	}
+	return &FileStore{dir: dir, urlPrefix: strings.TrimSuffix(urlPrefix, "/")}, nil
+}
+
+// path maps a key to a file inside the store's directory. Keys are cleaned
+// first so "../" can't reach outside it.
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+// Put writes a file, replacing any existing one with the same key. The data
+// goes to a temporary file first so readers never see a partial write.
+func (s *FileStore) Put(ctx context.Context, key string, contentType string, data []byte) error {
+	target := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// Delete removes a file. Deleting a missing file is not an error.
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// URL returns the path the file is served under
+func (s *FileStore) URL(key string) string {
+	return s.urlPrefix + path.Clean("/"+key)
+}
+
+// File is a file held by a MemoryStore
+type File struct {
+	ContentType string
+	Data        []byte
+}
+
+// MemoryStore keeps files in memory. It serves nothing over HTTP, so it's
+// only meant for tests and local experiments.
+type MemoryStore struct {
+	files     map[string]File
+	urlPrefix string
+	mu        sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore(urlPrefix string) *MemoryStore {
+	return &MemoryStore{
+		files:     make(map[string]File),
+		urlPrefix: strings.TrimSuffix(urlPrefix, "/"),
+	}
+}
+
+// Put stores a copy of the data
+func (s *MemoryStore) Put(ctx context.Context, key string, contentType string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[key] = File{ContentType: contentType, Data: append([]byte(nil), data...)}
+	return nil
+}
+
+// Delete removes a file. Deleting a missing file is not an error.
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.files, key)
+	return nil
+}
+
+// URL returns the URL the file would be served under
+func (s *MemoryStore) URL(key string) string {
+	return s.urlPrefix + path.Clean("/"+key)
+}
+
+// Get returns a stored file
+func (s *MemoryStore) Get(key string) (File, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	file, ok := s.files[key]
+	return file, ok
+}
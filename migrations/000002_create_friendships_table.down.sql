@@ -0,0 +1 @@
+DROP TABLE IF EXISTS friendships;
\ No newline at end of file
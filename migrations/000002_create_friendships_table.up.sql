@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS friendships (
+    requester_id VARCHAR(36) NOT NULL,
+    addressee_id VARCHAR(36) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (requester_id, addressee_id),
+    FOREIGN KEY (requester_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (addressee_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create indexes
+CREATE INDEX idx_friendships_addressee_id ON friendships(addressee_id);
@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS games (
+    id VARCHAR(36) PRIMARY KEY,
+    white_id VARCHAR(36) NOT NULL,
+    black_id VARCHAR(36) NOT NULL,
+    white_rating INTEGER NOT NULL,
+    black_rating INTEGER NOT NULL,
+    initial_time DOUBLE PRECISION NOT NULL,
+    increment DOUBLE PRECISION NOT NULL,
+    fen TEXT NOT NULL,
+    pgn TEXT NOT NULL,
+    outcome VARCHAR(10) NOT NULL,
+    method VARCHAR(50) NOT NULL,
+    is_private BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (white_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (black_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Create indexes
+CREATE INDEX idx_games_white_id ON games(white_id);
+CREATE INDEX idx_games_black_id ON games(black_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_users_password_reset_token;
+DROP INDEX IF EXISTS idx_users_verification_token;
+
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_token_expires_at;
+ALTER TABLE users DROP COLUMN IF EXISTS verification_token_expires_at;
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_token;
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS rapid_rating;
+ALTER TABLE users DROP COLUMN IF EXISTS blitz_rating;
+ALTER TABLE users DROP COLUMN IF EXISTS bullet_rating;
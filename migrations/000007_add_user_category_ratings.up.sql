@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bullet_rating INTEGER NOT NULL DEFAULT 1200;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS blitz_rating INTEGER NOT NULL DEFAULT 1200;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS rapid_rating INTEGER NOT NULL DEFAULT 1200;
+
+-- Existing players start every category from their current rating
+UPDATE users SET bullet_rating = elo_rating, blitz_rating = elo_rating, rapid_rating = elo_rating;
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS puzzle_rating;
+
+DROP TABLE IF EXISTS puzzles;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS puzzles (
+    id VARCHAR(36) PRIMARY KEY,
+    fen TEXT NOT NULL,
+    solution TEXT NOT NULL,
+    rating INTEGER NOT NULL DEFAULT 1500,
+    created_at TIMESTAMP NOT NULL
+);
+
+-- Existing players start at the same rating as new ones (models.DefaultRating)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS puzzle_rating INTEGER NOT NULL DEFAULT 1200;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_puzzles_rating ON puzzles(rating);
@@ -0,0 +1,4 @@
+-- Describes the device a session was started from, shown in the sessions list
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS label TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_token ON refresh_tokens(token);
@@ -0,0 +1,7 @@
+-- Emails are stored lowercased and trimmed. This fails if two accounts differ
+-- only by email case; those have to be merged by hand first.
+UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email));
+
+-- Usernames keep their case for display but are unique regardless of it
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username));
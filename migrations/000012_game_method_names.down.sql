@@ -0,0 +1,15 @@
+UPDATE games SET method = CASE method
+    WHEN 'checkmate' THEN 'Checkmate'
+    WHEN 'resignation' THEN 'Resignation'
+    WHEN 'draw_agreement' THEN 'DrawOffer'
+    WHEN 'stalemate' THEN 'Stalemate'
+    WHEN 'threefold' THEN 'ThreefoldRepetition'
+    WHEN 'fivefold' THEN 'FivefoldRepetition'
+    WHEN 'fifty_move' THEN 'FiftyMoveRule'
+    WHEN 'seventy_five_move' THEN 'SeventyFiveMoveRule'
+    WHEN 'insufficient_material' THEN 'InsufficientMaterial'
+    WHEN 'timeout' THEN 'Timeout'
+    WHEN 'timeout_vs_insufficient_material' THEN 'TimeoutVsInsufficientMaterial'
+    WHEN 'abandoned' THEN 'Abandoned'
+    ELSE method
+END;
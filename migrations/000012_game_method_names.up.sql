@@ -0,0 +1,17 @@
+-- Game-ending methods were stored under the chess library's names; they now
+-- use the API's stable snake_case names
+UPDATE games SET method = CASE method
+    WHEN 'Checkmate' THEN 'checkmate'
+    WHEN 'Resignation' THEN 'resignation'
+    WHEN 'DrawOffer' THEN 'draw_agreement'
+    WHEN 'Stalemate' THEN 'stalemate'
+    WHEN 'ThreefoldRepetition' THEN 'threefold'
+    WHEN 'FivefoldRepetition' THEN 'fivefold'
+    WHEN 'FiftyMoveRule' THEN 'fifty_move'
+    WHEN 'SeventyFiveMoveRule' THEN 'seventy_five_move'
+    WHEN 'InsufficientMaterial' THEN 'insufficient_material'
+    WHEN 'Timeout' THEN 'timeout'
+    WHEN 'TimeoutVsInsufficientMaterial' THEN 'timeout_vs_insufficient_material'
+    WHEN 'Abandoned' THEN 'abandoned'
+    ELSE method
+END;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_email_change_token;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_change_token_expires_at;
+ALTER TABLE users DROP COLUMN IF EXISTS email_change_token;
+ALTER TABLE users DROP COLUMN IF EXISTS pending_email;
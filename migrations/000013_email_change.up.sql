@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_email VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_change_token VARCHAR(36) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_change_token_expires_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_users_email_change_token ON users(email_change_token);
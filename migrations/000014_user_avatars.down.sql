@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_key;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_thumbnail_url;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_url;
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS notify_security_alerts;
+ALTER TABLE users DROP COLUMN IF EXISTS notify_game_summaries;
+ALTER TABLE users DROP COLUMN IF EXISTS notify_game_invites;
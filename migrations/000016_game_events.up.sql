@@ -0,0 +1,2 @@
+-- Event log of each finished game, as JSON
+ALTER TABLE games ADD COLUMN IF NOT EXISTS events TEXT NOT NULL DEFAULT '';
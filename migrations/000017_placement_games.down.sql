@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS placement_games_remaining;
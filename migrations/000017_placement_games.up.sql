@@ -0,0 +1,2 @@
+-- Existing users already have established ratings
+ALTER TABLE users ADD COLUMN IF NOT EXISTS placement_games_remaining INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE games DROP COLUMN IF EXISTS black_increment;
+ALTER TABLE games DROP COLUMN IF EXISTS black_initial_time;
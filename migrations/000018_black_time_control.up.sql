@@ -0,0 +1,3 @@
+-- Black's clock when a game was played at time odds; NULL means the same as white's
+ALTER TABLE games ADD COLUMN IF NOT EXISTS black_initial_time DOUBLE PRECISION;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS black_increment DOUBLE PRECISION;
@@ -0,0 +1,16 @@
+-- Correspondence games in progress, kept so they outlive a server restart
+CREATE TABLE IF NOT EXISTS correspondence_games (
+    id VARCHAR(36) PRIMARY KEY,
+    white_id VARCHAR(36) NOT NULL,
+    black_id VARCHAR(36) NOT NULL,
+    days_per_move INTEGER NOT NULL,
+    pgn TEXT NOT NULL,
+    move_deadline TIMESTAMP NOT NULL,
+    is_private BOOLEAN NOT NULL DEFAULT FALSE,
+    casual BOOLEAN NOT NULL DEFAULT FALSE,
+    events TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (white_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (black_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,171 @@
+// migrations.go : applies the embedded SQL migrations in this directory
+
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"chess-ws-go/internal/logging"
+)
+
+//go:embed *.up.sql
+var files embed.FS
+
+// lockKey identifies the advisory lock held while migrating, so that of
+// several instances starting at once only one applies the migrations and
+// the others find them done
+const lockKey int64 = 0x63686573736d6967 // "chessmig"
+
+// migration is a single up migration parsed from a "000001_name.up.sql" file
+type migration struct {
+	version int64
+	file    string
+}
+
+// Up applies every embedded up migration that hasn't been recorded in the
+// schema_migrations table yet, in version order. Each migration runs in its
+// own transaction together with its bookkeeping row. The run holds a
+// Postgres advisory lock, waiting for any other instance migrating the same
+// database to finish first.
+func Up(ctx context.Context, db *sql.DB) error {
+	// Advisory locks belong to a session, so everything runs on one connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+	defer func() {
+		// The connection goes back to the pool, so the lock must be let go
+		// of even when ctx is done
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			logging.Warnf("Failed to release the migration lock: %v", err)
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	pending, err := load()
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range pending {
+		if applied[m.version] {
+			continue
+		}
+
+		if err := apply(ctx, conn, m); err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.file, err)
+		}
+		logging.Infof("Applied migration %s", m.file)
+	}
+
+	return nil
+}
+
+// load parses and sorts the embedded migration files
+func load() ([]migration, error) {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(names))
+	seen := make(map[int64]string)
+	for _, file := range names {
+		prefix, _, ok := strings.Cut(strings.TrimSuffix(file, ".up.sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", file)
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", file, err)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("duplicate migration version %d (%s, %s)", version, other, file)
+		}
+		seen[version] = file
+
+		migrations = append(migrations, migration{version: version, file: file})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// appliedVersions returns the set of versions already recorded
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// apply runs one migration and records it atomically
+func apply(ctx context.Context, conn *sql.Conn, m migration) error {
+	body, err := files.ReadFile(m.file)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, string(body)); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)`,
+		m.version, time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"chess-ws-go/internal/auth"
+)
+
+func TestPresetsHashAndVerify(t *testing.T) {
+	t.Cleanup(func() { _ = auth.SetPasswordPreset(auth.PasswordPresetDefault) })
+
+	tests := []struct {
+		preset string
+		params string // Cost parameters the preset's hashes record
+	}{
+		{auth.PasswordPresetFast, "m=8192,t=1,p=1"},
+		{auth.PasswordPresetDefault, "m=65536,t=3,p=2"},
+		{auth.PasswordPresetStrong, "m=262144,t=4,p=4"},
+	}
+	var hashes []string
+	for _, tt := range tests {
+		if err := auth.SetPasswordPreset(tt.preset); err != nil {
+			t.Fatalf("SetPasswordPreset(%s): %v", tt.preset, err)
+		}
+		hash, err := auth.HashPassword("correct horse battery staple", nil)
+		if err != nil {
+			t.Fatalf("%s: HashPassword: %v", tt.preset, err)
+		}
+		if !strings.Contains(hash, "$"+tt.params+"$") {
+			t.Errorf("%s: hash %s doesn't record %s", tt.preset, hash, tt.params)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	// Hashes keep verifying whichever preset is now in use
+	for i, hash := range hashes {
+		if ok, err := auth.VerifyPassword("correct horse battery staple", hash); err != nil || !ok {
+			t.Errorf("%s hash rejected the right password: %v, %v", tests[i].preset, ok, err)
+		}
+		if ok, err := auth.VerifyPassword("correct horse battery stapler", hash); err != nil || ok {
+			t.Errorf("%s hash accepted the wrong password: %v, %v", tests[i].preset, ok, err)
+		}
+	}
+}
+
+func TestUnknownPresetRejected(t *testing.T) {
+	if err := auth.SetPasswordPreset("paranoid"); err == nil {
+		t.Error("an unknown preset was accepted")
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/services"
+)
+
+func TestEarlyAbandonAborted(t *testing.T) {
+	for _, tt := range []struct {
+		moves       []string
+		wantOutcome string
+		wantMethod  string
+	}{
+		{[]string{"e4"}, services.OutcomeNone, services.MethodAborted},
+		{[]string{"e4", "e5"}, services.OutcomeWhiteWon, services.MethodAbandoned},
+	} {
+		cfg := testConfig()
+		cfg.AbandonTimeout = 100 * time.Millisecond
+		s := newTestServer(t, cfg)
+		s.games.SetAbortThreshold(2)
+		white, black, gameID := s.startGame(t, "alice", "bob")
+		play(t, white, black, gameID, tt.moves...)
+
+		black.conn.Close()
+		var over gameOver
+		white.expect("gameOver", &over)
+		if over.Outcome != tt.wantOutcome || over.Method != tt.wantMethod {
+			t.Errorf("black left after %d plies: game ended %s by %s, want %s by %s",
+				len(tt.moves), over.Outcome, over.Method, tt.wantOutcome, tt.wantMethod)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+// clockResumed is the payload announcing a reconnected player's clock runs again
+type clockResumed struct {
+	Color   string  `json:"color"`
+	Frozen  float64 `json:"frozen"`
+	Charged float64 `json:"charged"`
+}
+
+// awayFor disconnects white, who is to move, for a while and reconnects
+// them, returning what black was told about the pause and resumption
+func awayFor(t *testing.T, s *testServer, away time.Duration) (grace float64, resumed clockResumed, gameID string) {
+	t.Helper()
+	s.games.SetServerClock(true)
+	white, black, gameID := s.startGame(t, "alice", "bob")
+
+	white.conn.Close()
+	var paused struct {
+		Color string  `json:"color"`
+		Grace float64 `json:"grace"`
+	}
+	black.expect("clockPaused", &paused)
+	if paused.Color != "w" {
+		t.Fatalf("paused %s's clock, want white's", paused.Color)
+	}
+	time.Sleep(away)
+
+	white = s.dial(t, white.user)
+	white.send("reconnect", map[string]any{"gameId": gameID})
+	white.expect("gameState", nil)
+	black.expect("clockResumed", &resumed)
+	return paused.Grace, resumed, gameID
+}
+
+func TestClockFrozenWithinGrace(t *testing.T) {
+	cfg := testConfig()
+	cfg.DisconnectGrace = 10 * time.Second
+	s := newTestServer(t, cfg)
+
+	grace, resumed, gameID := awayFor(t, s, 300*time.Millisecond)
+	if grace != 10 {
+		t.Errorf("announced %gs of grace, want 10s", grace)
+	}
+	if resumed.Charged != 0 || resumed.Frozen < 0.3 {
+		t.Errorf("got %+v, want the whole absence frozen", resumed)
+	}
+	timeLeft, err := s.games.TimeLeft(gameID, chess.White)
+	if err != nil {
+		t.Fatalf("TimeLeft: %v", err)
+	}
+	if initial := float64(services.DefaultTimeControl.Initial); initial-timeLeft > 0.2 {
+		t.Errorf("white has %.2fs of %gs left after a frozen absence", timeLeft, initial)
+	}
+}
+
+func TestClockChargedBeyondGrace(t *testing.T) {
+	cfg := testConfig()
+	cfg.DisconnectGrace = 100 * time.Millisecond
+	s := newTestServer(t, cfg)
+
+	_, resumed, gameID := awayFor(t, s, 400*time.Millisecond)
+	if math.Abs(resumed.Frozen-0.1) > 0.01 {
+		t.Errorf("froze the clock for %.2fs, want the 0.1s grace", resumed.Frozen)
+	}
+	if resumed.Charged < 0.3 {
+		t.Errorf("charged %.2fs, want the absence beyond the grace", resumed.Charged)
+	}
+	timeLeft, err := s.games.TimeLeft(gameID, chess.White)
+	if err != nil {
+		t.Fatalf("TimeLeft: %v", err)
+	}
+	if spent := float64(services.DefaultTimeControl.Initial) - timeLeft; spent < resumed.Charged {
+		t.Errorf("white spent %.2fs, less than the %.2fs charged", spent, resumed.Charged)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/config"
+	"chess-ws-go/internal/services"
+)
+
+// timeAdded is the payload telling everyone in a game a player gave their
+// opponent time
+type timeAdded struct {
+	Color    string  `json:"color"`
+	AddedBy  string  `json:"addedBy"`
+	Seconds  float64 `json:"seconds"`
+	TimeLeft float64 `json:"timeLeft"`
+}
+
+// addTimeConfig lets players add 15s in any game
+func addTimeConfig() *config.Config {
+	cfg := testConfig()
+	cfg.AddTimeAmount = 15 * time.Second
+	return cfg
+}
+
+func TestAddTimeRateLimited(t *testing.T) {
+	s := newTestServer(t, addTimeConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+
+	// The burst goes through; the next one within the refill is refused
+	for i := range 3 {
+		white.send("add_time", map[string]any{"gameId": gameID})
+		var added timeAdded
+		black.expect("timeAdded", &added)
+		want := services.DefaultTimeControl.Initial + float64(i+1)*15
+		if added.Color != "b" || added.AddedBy != "w" || added.Seconds != 15 || added.TimeLeft != want {
+			t.Errorf("grant %d: %+v, want black at %.0fs", i, added, want)
+		}
+	}
+	white.send("add_time", map[string]any{"gameId": gameID})
+	white.expectError("too often")
+
+	// Each side has its own allowance
+	black.send("add_time", map[string]any{"gameId": gameID})
+	var added timeAdded
+	white.expect("timeAdded", &added)
+	if added.Color != "w" || added.AddedBy != "b" {
+		t.Errorf("black's grant: %+v", added)
+	}
+}
+
+func TestAddTimeRefused(t *testing.T) {
+	disabled := testConfig()
+	rated := addTimeConfig()
+	rated.AddTimeCasualOnly = true
+
+	for _, tc := range []struct {
+		name string
+		cfg  *config.Config
+		want string
+	}{
+		{"disabled", disabled, "disabled"},
+		{"rated", rated, "casual games"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer(t, tc.cfg)
+			white, black, gameID := s.startGame(t, "alice", "bob")
+			white.send("add_time", map[string]any{"gameId": gameID})
+			white.expectError(tc.want)
+			black.expectNone("timeAdded")
+		})
+	}
+
+	t.Run("correspondence", func(t *testing.T) {
+		s := newTestServer(t, addTimeConfig())
+		white, _, gameID := s.challenge(t, "alice", "bob", services.TimeControl{DaysPerMove: 3})
+		white.send("add_time", map[string]any{"gameId": gameID})
+		white.expectError("no clock")
+	})
+}
+
+func TestAddTimeToServerClock(t *testing.T) {
+	s := newTestServer(t, addTimeConfig())
+	s.games.SetServerClock(true)
+	white, black, gameID := s.challenge(t, "alice", "bob", services.TimeControl{Initial: 0.3})
+
+	// White is to move and would flag at 0.3s; the added time pushes that back
+	black.send("add_time", map[string]any{"gameId": gameID})
+	var added timeAdded
+	white.expect("timeAdded", &added)
+	if added.Color != "w" || added.TimeLeft > 15.3 || added.TimeLeft < 14.3 {
+		t.Errorf("white's grant: %+v, want just under 15.3s left", added)
+	}
+	time.Sleep(500 * time.Millisecond)
+	play(t, white, black, gameID, "e4")
+}
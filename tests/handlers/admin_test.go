@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminRouter serves the admin moderation endpoints over a test server's
+// WebSocket handler, without the auth middleware in front of them
+func (s *testServer) adminRouter() *gin.Engine {
+	admin := handlers.NewAdminHandler(nil, s.handler)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/admin/games/:id/terminate", admin.TerminateGame)
+	router.POST("/admin/games/:id/abort", admin.AbortGame)
+	router.POST("/admin/users/:id/disconnect", admin.DisconnectUser)
+	return router
+}
+
+// moderate posts a moderation request with a reason and returns the response
+func moderate(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"reason":"test"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminTerminateStatuses(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	_, black, gameID := s.startGame(t, "alice", "bob")
+	router := s.adminRouter()
+
+	if rec := moderate(router, "/admin/games/"+gameID+"/terminate"); rec.Code != http.StatusOK {
+		t.Fatalf("terminate: status %d: %s", rec.Code, rec.Body)
+	}
+	black.expect("gameOver", nil)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/admin/games/" + gameID + "/terminate", http.StatusConflict},
+		{"/admin/games/no-such-game/terminate", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rec := moderate(router, tt.path); rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+
+	// A reason is required
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/games/"+gameID+"/terminate", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("no reason: status %d, want 400", rec.Code)
+	}
+}
+
+func TestAdminAbortStatuses(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	s.games.SetAbortThreshold(2)
+	_, fresh, freshID := s.startGame(t, "alice", "bob")
+	white, black, startedID := s.startGame(t, "carol", "dave")
+	play(t, white, black, startedID, "e4", "e5")
+	router := s.adminRouter()
+
+	if rec := moderate(router, "/admin/games/"+freshID+"/abort"); rec.Code != http.StatusOK {
+		t.Fatalf("abort: status %d: %s", rec.Code, rec.Body)
+	}
+	fresh.expect("gameOver", nil)
+
+	tests := []struct {
+		path string
+		want int
+		body string
+	}{
+		{"/admin/games/" + freshID + "/abort", http.StatusConflict, "already over"},
+		{"/admin/games/" + startedID + "/abort", http.StatusConflict, "too far along"},
+		{"/admin/games/no-such-game/abort", http.StatusNotFound, "not found"},
+	}
+	for _, tt := range tests {
+		rec := moderate(router, tt.path)
+		if rec.Code != tt.want || !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("%s: status %d %s, want %d mentioning %q", tt.path, rec.Code, rec.Body, tt.want, tt.body)
+		}
+	}
+}
+
+func TestAdminDisconnectReportsConnectionsClosed(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	alice := s.dial(t, "alice")
+	router := s.adminRouter()
+
+	rec := moderate(router, "/admin/users/alice/disconnect")
+	var body struct {
+		Closed int `json:"connections_closed"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK || body.Closed != 1 {
+		t.Errorf("status %d, body %s, want 1 connection closed", rec.Code, rec.Body)
+	}
+	alice.expectClosed(2 * time.Second)
+}
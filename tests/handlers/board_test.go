@@ -0,0 +1,34 @@
+package handlers
+
+import "testing"
+
+func TestGetBoardOnlyForPlayersAndSpectators(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	request := map[string]any{"gameId": gameID}
+
+	for _, player := range []*client{white, black} {
+		player.send("get_board_ascii", request)
+		player.expect("boardAscii", nil)
+	}
+
+	var refusal string
+	stranger := s.dial(t, "carol")
+	stranger.send("get_board_ascii", request)
+	stranger.expect("error", &refusal)
+	if refusal != "Not playing or watching this game" {
+		t.Errorf("stranger got %q", refusal)
+	}
+
+	guest := s.dialGuest(t)
+	guest.send("get_board_ascii", request)
+	guest.expect("error", &refusal)
+	if refusal != "Not playing or watching this game" {
+		t.Errorf("guest who isn't spectating got %q", refusal)
+	}
+
+	guest.send("spectate", request)
+	guest.expect("spectating", nil)
+	guest.send("get_board_ascii", request)
+	guest.expect("boardAscii", nil)
+}
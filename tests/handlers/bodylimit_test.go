@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chess-ws-go/internal/handlers"
+	"chess-ws-go/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+const bodyLimit = 1024
+
+// endlessBody streams an unterminated JSON string, counting what's read
+type endlessBody struct {
+	read    int64
+	started bool
+}
+
+func (b *endlessBody) Read(p []byte) (int, error) {
+	if !b.started {
+		b.started = true
+		return copy(p, `{"username":"`), nil
+	}
+	for i := range p {
+		p[i] = 'a'
+	}
+	b.read += int64(len(p))
+	return len(p), nil
+}
+
+// register posts a body to the registration endpoint behind the body limit
+func register(body io.Reader, contentLength int64) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.BodyLimit(bodyLimit))
+	// Bodies are rejected before the service is reached
+	router.POST("/register", handlers.NewAuthHandler(nil, nil).Register)
+
+	req := httptest.NewRequest(http.MethodPost, "/register", body)
+	req.ContentLength = contentLength
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOversizedBodyRejected(t *testing.T) {
+	big := `{"username":"` + strings.Repeat("a", 2*bodyLimit) + `"}`
+
+	tests := []struct {
+		name          string
+		body          io.Reader
+		contentLength int64
+		want          int
+	}{
+		{"declared too large", strings.NewReader(big), int64(len(big)), http.StatusRequestEntityTooLarge},
+		{"streamed too large", strings.NewReader(big), -1, http.StatusRequestEntityTooLarge},
+		{"small but invalid", strings.NewReader(`{"username":`), -1, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := register(tt.body, tt.contentLength).Code; got != tt.want {
+				t.Errorf("status %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndlessBodyReadOnlyToLimit(t *testing.T) {
+	body := &endlessBody{}
+	if got := register(body, -1).Code; got != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want %d", got, http.StatusRequestEntityTooLarge)
+	}
+	// The decoder may buffer a little past the cut-off, but no more
+	if read := body.read; read > 64*1024 {
+		t.Errorf("read %d bytes of an endless body with a %d byte limit", read, bodyLimit)
+	}
+}
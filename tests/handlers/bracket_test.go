@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+)
+
+func TestBracketForfeitAnnounced(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	ts := services.NewTournamentService(s.games, s.users)
+	ts.SetNoShowTimeout(300 * time.Millisecond)
+	for _, id := range []string{"alice", "bob"} {
+		s.users.add(&models.User{ID: id, Username: id, EloRating: 1500})
+	}
+	tournament := ts.CreateTournament("organizer", services.TournamentOptions{
+		Name:        "Cup",
+		Format:      services.FormatKnockout,
+		TimeControl: services.DefaultTimeControl,
+	})
+	for _, id := range []string{"alice", "bob"} {
+		if err := ts.Register(context.Background(), tournament.ID, id); err != nil {
+			t.Fatalf("Register %s: %v", id, err)
+		}
+	}
+	bracket, err := ts.StartBracket(tournament.ID, "organizer")
+	if err != nil {
+		t.Fatalf("StartBracket: %v", err)
+	}
+	match := bracket[0][0]
+
+	// White turns up and moves; black never does
+	white := s.dial(t, match.WhiteID)
+	white.send("join", map[string]any{"gameId": match.GameID})
+	white.expect("gameState", nil)
+	white.send("move", map[string]any{"gameId": match.GameID, "move": "e4"})
+	white.expect("move", nil)
+
+	var over struct {
+		Outcome string `json:"outcome"`
+		Method  string `json:"method"`
+		Winner  string `json:"winner"`
+	}
+	white.expect("gameOver", &over)
+	if over.Outcome != services.OutcomeWhiteWon || over.Method != services.MethodForfeit || over.Winner != "white" {
+		t.Errorf("white was told %+v, want a win by forfeit", over)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+// TestBroadcastsSurviveMissingOpponent has white carry on alone after black
+// goes away, so every broadcast finds one player unreachable
+func TestBroadcastsSurviveMissingOpponent(t *testing.T) {
+	tests := []struct {
+		name  string
+		leave func(t *testing.T, s *testServer, black *client)
+	}{
+		{"closed", func(t *testing.T, s *testServer, black *client) {
+			black.conn.Close()
+		}},
+		{"unwritable", func(t *testing.T, s *testServer, black *client) {
+			s.breakWrites(t, black.user)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, testConfig())
+			white, black, gameID := s.startGame(t, "alice", "bob")
+			tt.leave(t, s, black)
+
+			white.send("time_update", map[string]any{"gameId": gameID, "timeLeft": 30})
+			white.expect("timeUpdate", nil)
+			white.send("chat", map[string]any{"gameId": gameID, "message": "still there?"})
+			white.expect("chat", nil)
+			white.send("draw_offer", map[string]any{"gameId": gameID})
+			white.send("move", map[string]any{"gameId": gameID, "move": "e4"})
+			white.expect("move", nil)
+			white.send("resign", map[string]any{"gameId": gameID})
+			white.expect("gameOver", nil)
+
+			// The server is still taking games
+			s.startGame(t, "carol", "dave")
+		})
+	}
+}
+
+// receiver counts the messages a client reads without decoding them
+func receiver(c *client, received chan<- struct{}) {
+	go func() {
+		for {
+			if _, _, err := c.conn.ReadMessage(); err != nil {
+				return
+			}
+			received <- struct{}{}
+		}
+	}()
+}
+
+// BenchmarkBroadcastAllocs reports the allocations a broadcast costs per
+// recipient. Messages are encoded once however many receive them, so the
+// figure should fall as recipients are added.
+func BenchmarkBroadcastAllocs(b *testing.B) {
+	for _, spectators := range []int{0, 10, 100} {
+		b.Run(fmt.Sprintf("spectators=%d", spectators), func(b *testing.B) {
+			s := newTestServer(b, testConfig())
+			white, black, gameID := s.startGame(b, "alice", "bob")
+			watching := make([]*client, spectators)
+			for i := range watching {
+				watching[i] = s.spectate(b, gameID)
+			}
+
+			recipients := spectators + 2
+			received := make(chan struct{}, recipients)
+			for _, c := range append(watching, white, black) {
+				receiver(c, received)
+			}
+
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				white.send("time_update", map[string]any{"gameId": gameID, "timeLeft": 60})
+				for range recipients {
+					<-received
+				}
+			}
+			b.StopTimer()
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(after.Mallocs-before.Mallocs)/float64(b.N*recipients), "allocs/recipient")
+		})
+	}
+}
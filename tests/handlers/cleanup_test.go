@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFinishedGameReleasedAfterDelay(t *testing.T) {
+	cfg := testConfig()
+	cfg.GameCleanupDelay = 300 * time.Millisecond
+	s := newTestServer(t, cfg)
+	white, black, gameID := s.finishedGame(t, "alice", "bob")
+
+	// Until the delay runs out the game is kept for a rematch
+	if _, err := s.games.GetGameState(gameID); err != nil {
+		t.Fatalf("finished game released straight away: %v", err)
+	}
+	white.send("rematch_offer", map[string]any{"gameId": gameID, "rated": true})
+	black.expect("rematchOffer", nil)
+
+	time.Sleep(2 * cfg.GameCleanupDelay)
+	if _, err := s.games.GetGameState(gameID); err == nil {
+		t.Fatal("finished game still in memory after the cleanup delay")
+	}
+	black.send("rematch_response", map[string]any{"gameId": gameID, "accept": true, "rated": true})
+	black.expectError("game not found")
+}
+
+func TestFinishedGameKeptWithoutDelay(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.finishedGame(t, "alice", "bob")
+
+	time.Sleep(300 * time.Millisecond)
+	if _, err := s.games.GetGameState(gameID); err != nil {
+		t.Fatalf("finished game released with cleanup disabled: %v", err)
+	}
+	rematch(t, white, black, gameID, true)
+}
+
+func TestRematchReleasesGameBeforeDelay(t *testing.T) {
+	cfg := testConfig()
+	cfg.GameCleanupDelay = time.Hour
+	s := newTestServer(t, cfg)
+	white, black, gameID := s.finishedGame(t, "alice", "bob")
+
+	rematch(t, white, black, gameID, true)
+	if _, err := s.games.GetGameState(gameID); err == nil {
+		t.Error("rematched game still in memory")
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/services"
+)
+
+// timeUpdate is the payload reporting a side's clock
+type timeUpdate struct {
+	Color    string  `json:"color"`
+	TimeLeft float64 `json:"timeLeft"`
+}
+
+// challenge has one user challenge another with a time control and returns
+// the clients, challenger as white, with the game ID
+func (s *testServer) challenge(t *testing.T, challenger, target string, tc services.TimeControl) (white *client, black *client, gameID string) {
+	t.Helper()
+
+	white, black = s.dial(t, challenger), s.dial(t, target)
+	white.send("challenge", map[string]any{"target": target, "timeControl": tc})
+	var received struct {
+		ChallengeID string `json:"challengeId"`
+	}
+	black.expect("challengeReceived", &received)
+	black.send("challenge_response", map[string]any{"challengeId": received.ChallengeID, "accept": true})
+
+	var start gameStart
+	white.expect("gameStart", &start)
+	black.expect("gameStart", nil)
+	return white, black, start.GameID
+}
+
+func TestClientReportedClock(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+
+	white.send("time_update", map[string]any{"gameId": gameID, "timeLeft": 42.5})
+	var update timeUpdate
+	black.expect("timeUpdate", &update)
+	if update.Color != "w" || update.TimeLeft != 42.5 {
+		t.Errorf("got %+v, want white's reported 42.5s", update)
+	}
+
+	// A report of no time left flags the reporter
+	white.send("time_update", map[string]any{"gameId": gameID, "timeLeft": 0})
+	var over gameOver
+	black.expect("gameOver", &over)
+	if over.Method != services.MethodTimeout || over.Winner != "black" {
+		t.Errorf("game ended by %s won by %q, want black winning on time", over.Method, over.Winner)
+	}
+}
+
+func TestServerClockIgnoresReports(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	s.games.SetServerClock(true)
+	white, black, gameID := s.startGame(t, "alice", "bob")
+
+	// Neither a flag nor an inflated clock is taken from the client
+	white.send("time_update", map[string]any{"gameId": gameID, "timeLeft": 0})
+	white.send("time_update", map[string]any{"gameId": gameID, "timeLeft": 9999})
+	play(t, white, black, gameID, "e4")
+
+	var update timeUpdate
+	black.expect("timeUpdate", &update)
+	initial := services.DefaultTimeControl.Initial + services.DefaultTimeControl.Increment
+	if update.Color != "w" || update.TimeLeft > initial || update.TimeLeft < initial-2 {
+		t.Errorf("got %+v, want white's server clock just under %.0fs", update, initial)
+	}
+}
+
+func TestServerClockFlagsIdlePlayer(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	s.games.SetServerClock(true)
+	white, black, _ := s.challenge(t, "alice", "bob", services.TimeControl{Initial: 0.3})
+
+	// Nobody reports anything; the server notices white's time is up
+	var over gameOver
+	black.expect("gameOver", &over)
+	if over.Method != services.MethodTimeout || over.Winner != "black" {
+		t.Errorf("game ended by %s won by %q, want black winning on time", over.Method, over.Winner)
+	}
+	white.expect("gameOver", nil)
+}
+
+func TestClientClockDoesntFlagOnItsOwn(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.challenge(t, "alice", "bob", services.TimeControl{Initial: 0.3})
+
+	// Without a report the server doesn't know white ran out
+	time.Sleep(500 * time.Millisecond)
+	play(t, white, black, gameID, "e4")
+}
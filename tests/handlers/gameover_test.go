@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// toBareKings is a game whose last move captures the last piece on the
+// board but the kings
+const toBareKings = "Nc3 b6 a4 e6 d4 Bb7 g4 Bxh1 e4 Bxe4 Nxe4 h5 gxh5 Rxh5 Qxh5 Qg5 " +
+	"Nc5 Qxg1 Nxe6 dxe6 Qxf7+ Kxf7 Rb1 Qxf1+ Kxf1 Bb4 Bg5 Nf6 Bxf6 gxf6 a5 bxa5 " +
+	"Ra1 Bd6 Rxa5 Nc6 Rxa7 Bf8 Rxa8 Nxd4 Rxf8+ Ke7 Rxf6 Nxc2 Rxe6+ Kf7 Re2 c6 " +
+	"Rxc2 c5 Rxc5 Kf6 Rg5 Kxg5 Kg2 Kf5 h4 Kf6 Kh1 Kg6 Kh2 Kg7 Kg1 Kh8 Kh2 Kg7 " +
+	"b3 Kg8 Kg3 Kg7 Kg4 Kf8 f3 Ke7 Kh3 Kd8 Kg2 Ke7 f4 Ke6 Kh1 Kf5 h5 Kxf4 Kh2 " +
+	"Ke3 Kg3 Ke2 Kh3 Kd3 h6 Kd4 h7 Ke5 h8=Q+ Kf4 Qc3 Ke4 Qd3+ Kxd3 Kg4 Ke4 Kh4 " +
+	"Kf3 Kh3 Ke4 Kg2 Kd3 Kh2 Ke4 Kg2 Kd5 Kh1 Kd4 b4 Kc4 Kg1 Kxb4"
+
+func TestInsufficientMaterialDrawsAutomatically(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+
+	play(t, white, black, gameID, strings.Fields(toBareKings)...)
+
+	for _, player := range []*client{white, black} {
+		var over gameOver
+		player.expect("gameOver", &over)
+		if over.Outcome != "1/2-1/2" || over.Method != "insufficient_material" || over.Winner != "draw" {
+			t.Errorf("%s was told the game ended %s by %s, winner %q", player.user, over.Outcome, over.Method, over.Winner)
+		}
+		if over.FEN != "8/8/8/8/1k6/8/8/6K1 w - - 0 60" {
+			t.Errorf("%s got final position %s", player.user, over.FEN)
+		}
+	}
+}
+
+func TestMoveAfterCheckmateRejected(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	white.hello(2)
+	black.hello(2)
+
+	play(t, white, black, gameID, "f3", "e5", "g4", "Qh4#")
+	var over gameOver
+	white.expect("gameOver", &over)
+	black.expect("gameOver", nil)
+
+	// A late move and a late premove both bounce off the finished game
+	white.send("move", map[string]any{"gameId": gameID, "move": "e4"})
+	if reply := white.expectProtocolError(); reply.Code != "GAME_ALREADY_OVER" {
+		t.Errorf("late move got %+v, want GAME_ALREADY_OVER", reply)
+	}
+	black.send("premove", map[string]any{"gameId": gameID, "move": "Nc6"})
+	if reply := black.expectProtocolError(); reply.Code != "GAME_ALREADY_OVER" {
+		t.Errorf("late premove got %+v, want GAME_ALREADY_OVER", reply)
+	}
+
+	game, err := s.games.GetGame(gameID)
+	if err != nil {
+		t.Fatalf("GetGame: %v", err)
+	}
+	if game.FEN != over.FEN || game.PGN != over.PGN {
+		t.Errorf("game changed after it ended: %s\n%s", game.FEN, game.PGN)
+	}
+}
+
+// coded is a message with the error code it carries, if any
+type coded struct {
+	Type string `json:"type"`
+	Code string `json:"code"`
+}
+
+// collect reads every message that arrives within the given time. The read
+// deadline breaks the connection, so it must be the last read.
+func (c *client) collect(wait time.Duration) []coded {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(wait))
+	var got []coded
+	for {
+		var msg coded
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return got
+		}
+		got = append(got, msg)
+	}
+}
+
+func TestRacingResignationsEndGameOnce(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	white.hello(2)
+	black.hello(2)
+	play(t, white, black, gameID, "e4", "e5")
+
+	white.send("resign", map[string]any{"gameId": gameID})
+	black.send("resign", map[string]any{"gameId": gameID})
+
+	refused := 0
+	for _, player := range []*client{white, black} {
+		overs := 0
+		for _, msg := range player.collect(500 * time.Millisecond) {
+			switch {
+			case msg.Type == "gameOver":
+				overs++
+			case msg.Type == "error" && msg.Code == "GAME_ALREADY_OVER":
+				refused++
+			case msg.Type == "error":
+				t.Errorf("%s got error %+v", player.user, msg)
+			}
+		}
+		if overs != 1 {
+			t.Errorf("%s was told the game ended %d times", player.user, overs)
+		}
+	}
+	if refused != 1 {
+		t.Errorf("%d resignations refused with GAME_ALREADY_OVER, want 1", refused)
+	}
+}
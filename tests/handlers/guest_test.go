@@ -0,0 +1,48 @@
+package handlers
+
+import "testing"
+
+func TestGuestCantPlay(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	guest := s.spectate(t, gameID)
+	guest.hello(2)
+
+	refused := []struct {
+		msgType string
+		payload map[string]any
+	}{
+		{"move", map[string]any{"gameId": gameID, "move": "e4"}},
+		{"join", nil},
+		{"chat", map[string]any{"gameId": gameID, "message": "hello from nowhere"}},
+		{"resign", map[string]any{"gameId": gameID}},
+		{"draw_offer", map[string]any{"gameId": gameID}},
+		{"take_seat", map[string]any{"gameId": gameID}},
+		{"challenge", map[string]any{"target": "alice"}},
+	}
+	for _, tt := range refused {
+		guest.send(tt.msgType, tt.payload)
+		if reply := guest.expectProtocolError(); reply.Code != "AUTH_REQUIRED" {
+			t.Errorf("guest %s: got %+v, want AUTH_REQUIRED", tt.msgType, reply)
+		}
+	}
+
+	// Nothing the guest sent reached the game, which plays on as before
+	play(t, white, black, gameID, "e4")
+	black.expectNone("chat")
+}
+
+func TestGuestCanOnlyWatch(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	guest := s.spectate(t, gameID)
+
+	play(t, white, black, gameID, "e4")
+	guest.expect("move", nil)
+	guest.send("get_board_ascii", map[string]any{"gameId": gameID})
+	guest.expect("boardAscii", nil)
+
+	// Without a code, older clients are told in words
+	guest.send("join", nil)
+	guest.expectError("Sign in to do more than watch games")
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/config"
+	"chess-ws-go/internal/handlers"
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+	"chess-ws-go/internal/services"
+
+	"github.com/gorilla/websocket"
+)
+
+// memUsers is an in-memory user repository. Methods the tests don't need
+// fall through to the nil embedded interface and panic if called.
+type memUsers struct {
+	repositories.UserRepository
+	mu    sync.Mutex
+	users map[string]*models.User
+
+	// Called on every GetByID, if set, outside the repository's lock
+	onLookup func()
+}
+
+func (r *memUsers) add(user *models.User) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.ID]; !ok {
+		r.users[user.ID] = user
+	}
+}
+
+func (r *memUsers) GetByID(ctx context.Context, id string) (*models.User, error) {
+	r.mu.Lock()
+	onLookup := r.onLookup
+	r.mu.Unlock()
+	if onLookup != nil {
+		onLookup()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repositories.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memUsers) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if strings.EqualFold(user.Username, username) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrUserNotFound
+}
+
+func (r *memUsers) UpdateRatingsTx(ctx context.Context, whiteID string, blackID string, apply func(white, black *models.User) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	white, okWhite := r.users[whiteID]
+	black, okBlack := r.users[blackID]
+	if !okWhite || !okBlack {
+		return repositories.ErrUserNotFound
+	}
+	w, b := *white, *black
+	if err := apply(&w, &b); err != nil {
+		return err
+	}
+	r.users[whiteID], r.users[blackID] = &w, &b
+	return nil
+}
+
+// user returns the stored copy of a user
+func (r *memUsers) user(id string) models.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.users[id]
+}
+
+// testServer serves a WebSocketHandler. Players connect to /ws as the user
+// named in the query; /spectate takes guests.
+type testServer struct {
+	handler  *handlers.WebSocketHandler
+	games    *services.GameService
+	messages *services.MessageService
+	users    *memUsers
+	server   *httptest.Server
+	conns    sync.Map // user ID -> *breakableConn
+}
+
+// testConfig returns handler settings that keep timers out of the way
+func testConfig() *config.Config {
+	return &config.Config{
+		AbandonTimeout:     time.Minute,
+		WSHandshakeTimeout: time.Second,
+	}
+}
+
+func newTestServer(t testing.TB, cfg *config.Config) *testServer {
+	t.Helper()
+
+	s := &testServer{
+		games: services.NewGameService(nil),
+		users: &memUsers{users: make(map[string]*models.User)},
+	}
+	s.messages = services.NewMessageService(s.games)
+	s.handler = handlers.NewWebSocketHandler(s.messages, s.games, s.users, cfg)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		userID := r.URL.Query().Get("user")
+		ctx := context.WithValue(r.Context(), "user_id", userID)
+		ctx = context.WithValue(ctx, "username", userID)
+		hijacked := &hijacker{ResponseWriter: w, stored: func(conn *breakableConn) {
+			s.conns.Store(userID, conn)
+		}}
+		s.handler.UpgradeHandler(hijacked, r.WithContext(ctx))
+	})
+	mux.HandleFunc("/spectate", s.handler.SpectateHandler)
+	s.server = httptest.NewServer(mux)
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// url returns the WebSocket URL of a path on the server
+func (s *testServer) url(path string) string {
+	return "ws" + strings.TrimPrefix(s.server.URL, "http") + path
+}
+
+// breakWrites makes every later write to a user's connection fail, as if
+// the peer had vanished without closing it
+func (s *testServer) breakWrites(t *testing.T, userID string) {
+	t.Helper()
+	conn, ok := s.conns.Load(userID)
+	if !ok {
+		t.Fatalf("%s isn't connected", userID)
+	}
+	conn.(*breakableConn).broken.Store(true)
+}
+
+// stallWrites makes every later write to a user's connection hang until
+// the server closes it, as if the peer had stopped reading
+func (s *testServer) stallWrites(t *testing.T, userID string) {
+	t.Helper()
+	conn, ok := s.conns.Load(userID)
+	if !ok {
+		t.Fatalf("%s isn't connected", userID)
+	}
+	conn.(*breakableConn).stalled.Store(true)
+}
+
+var errBrokenConn = errors.New("broken connection")
+
+// breakableConn is the server's end of a connection, whose writes can be
+// made to fail or hang
+type breakableConn struct {
+	net.Conn
+	broken  atomic.Bool
+	stalled atomic.Bool
+	closed  atomic.Bool
+}
+
+func (c *breakableConn) Write(p []byte) (int, error) {
+	if c.broken.Load() {
+		return 0, errBrokenConn
+	}
+	for c.stalled.Load() && !c.closed.Load() {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return c.Conn.Write(p)
+}
+
+func (c *breakableConn) Close() error {
+	c.closed.Store(true)
+	return c.Conn.Close()
+}
+
+// hijacker hands the WebSocket upgrader a breakableConn
+type hijacker struct {
+	http.ResponseWriter
+	stored func(conn *breakableConn)
+}
+
+func (w *hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	breakable := &breakableConn{Conn: conn}
+	w.stored(breakable)
+	return breakable, rw, nil
+}
+
+// dial connects as a user, who's given a username equal to their ID and an
+// established rating of 1500 unless added beforehand
+func (s *testServer) dial(t testing.TB, userID string) *client {
+	t.Helper()
+	s.users.add(&models.User{ID: userID, Username: userID, EloRating: 1500})
+	c := s.connect(t, "/ws?user="+userID)
+	c.user = userID
+	return c
+}
+
+// dialGuest connects an anonymous spectator
+func (s *testServer) dialGuest(t testing.TB) *client {
+	t.Helper()
+	return s.connect(t, "/spectate")
+}
+
+func (s *testServer) connect(t testing.TB, path string) *client {
+	t.Helper()
+	conn, resp, err := websocket.DefaultDialer.Dial(s.url(path), nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", path, err)
+	}
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+	return &client{t: t, conn: conn}
+}
+
+// message is a server message with its payload left for the test to decode
+type message struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// client is a test's end of a WebSocket
+type client struct {
+	t    testing.TB
+	conn *websocket.Conn
+	user string // Empty for guests
+}
+
+func (c *client) send(msgType string, payload map[string]any) {
+	c.t.Helper()
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	if err := c.conn.WriteJSON(map[string]any{"type": msgType, "payload": payload}); err != nil {
+		c.t.Fatalf("send %s: %v", msgType, err)
+	}
+}
+
+// expect reads messages until one of the given type arrives and decodes its
+// payload into v, if not nil
+func (c *client) expect(msgType string, v any) {
+	c.t.Helper()
+	msg, ok := c.next(msgType, 2*time.Second)
+	if !ok {
+		c.t.Fatalf("no %s message arrived", msgType)
+	}
+	if v != nil {
+		if err := json.Unmarshal(msg.Payload, v); err != nil {
+			c.t.Fatalf("decode %s payload %s: %v", msgType, msg.Payload, err)
+		}
+	}
+}
+
+// expectNone checks that no message of a type arrives for a short while
+func (c *client) expectNone(msgType string) {
+	c.t.Helper()
+	if msg, ok := c.next(msgType, 200*time.Millisecond); ok {
+		c.t.Fatalf("unexpected %s message: %s", msgType, msg.Payload)
+	}
+}
+
+func (c *client) next(msgType string, wait time.Duration) (message, bool) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(wait))
+	defer c.conn.SetReadDeadline(time.Time{})
+	for {
+		var msg message
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return message{}, false
+		}
+		if msg.Type == msgType {
+			return msg, true
+		}
+	}
+}
+
+// gameStart is the payload telling a player their game began
+type gameStart struct {
+	GameID              string               `json:"gameId"`
+	Color               string               `json:"color"`
+	Opponent            string               `json:"opponent"`
+	Rating              int                  `json:"rating"`
+	OpponentRating      int                  `json:"opponentRating"`
+	Provisional         bool                 `json:"provisional"`
+	OpponentProvisional bool                 `json:"opponentProvisional"`
+	TimeControl         services.TimeControl `json:"timeControl"`
+}
+
+// startGame pairs two users through matchmaking and returns white's and
+// black's clients with the game ID
+func (s *testServer) startGame(t testing.TB, a, b string) (white *client, black *client, gameID string) {
+	t.Helper()
+
+	first, second := s.dial(t, a), s.dial(t, b)
+	first.send("join", nil)
+	first.expect("waiting", nil)
+	second.send("join", nil)
+
+	var firstStart, secondStart gameStart
+	first.expect("gameStart", &firstStart)
+	second.expect("gameStart", &secondStart)
+	if firstStart.Color == "white" {
+		return first, second, firstStart.GameID
+	}
+	return second, first, firstStart.GameID
+}
+
+// play makes moves in a game, alternating from white, and waits for both
+// players to see each one
+func play(t testing.TB, white, black *client, gameID string, moves ...string) {
+	t.Helper()
+	for i, move := range moves {
+		mover := white
+		if i%2 == 1 {
+			mover = black
+		}
+		mover.send("move", map[string]any{"gameId": gameID, "move": move})
+		white.expect("move", nil)
+		black.expect("move", nil)
+	}
+}
+
+// gameOver is the payload telling everyone in a game how it ended
+type gameOver struct {
+	Outcome string `json:"outcome"`
+	Method  string `json:"method"`
+	Winner  string `json:"winner"`
+	FEN     string `json:"fen"`
+	PGN     string `json:"pgn"`
+}
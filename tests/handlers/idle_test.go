@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/services"
+)
+
+// idleWarning is the payload telling players a game will be ended unless
+// someone moves
+type idleWarning struct {
+	GameID string  `json:"gameId"`
+	Action string  `json:"action"`
+	Turn   string  `json:"turn"`
+	EndsIn float64 `json:"endsIn"`
+}
+
+// idleServer returns a server that warns about games left idle for 100ms
+// and ends them 100ms later. Games are challenged with a clock short enough
+// not to stretch the window.
+func idleServer(t *testing.T) (*testServer, services.TimeControl) {
+	cfg := testConfig()
+	cfg.IdleGameTimeout = 100 * time.Millisecond
+	cfg.IdleGameGrace = 100 * time.Millisecond
+	return newTestServer(t, cfg), services.TimeControl{Initial: 0.1}
+}
+
+func TestIdleGameWithoutMovesAborted(t *testing.T) {
+	s, tc := idleServer(t)
+	white, black, gameID := s.challenge(t, "alice", "bob", tc)
+
+	for _, player := range []*client{white, black} {
+		var warning idleWarning
+		player.expect("idleWarning", &warning)
+		if warning.GameID != gameID || warning.Action != "abort" || warning.Turn != "w" || warning.EndsIn != 0.1 {
+			t.Errorf("got warning %+v, want an abort of %s in 0.1s", warning, gameID)
+		}
+	}
+
+	var aborted struct {
+		Reason string `json:"reason"`
+	}
+	white.expect("gameAborted", &aborted)
+	if aborted.Reason != services.AbortReasonNoShow {
+		t.Errorf("aborted because %q, want %q", aborted.Reason, services.AbortReasonNoShow)
+	}
+	var over gameOver
+	white.expect("gameOver", &over)
+	if over.Outcome != services.OutcomeNone || over.Method != services.MethodAborted {
+		t.Errorf("game ended %s by %s, want * by aborted", over.Outcome, over.Method)
+	}
+	if !strings.HasSuffix(over.PGN, services.OutcomeNone) {
+		t.Errorf("aborted game's PGN %q doesn't end in *", over.PGN)
+	}
+}
+
+func TestIdleGameForfeitedBySideToMove(t *testing.T) {
+	s, tc := idleServer(t)
+	white, black, gameID := s.challenge(t, "alice", "bob", tc)
+	play(t, white, black, gameID, "e4")
+
+	var warning idleWarning
+	black.expect("idleWarning", &warning)
+	if warning.Action != "forfeit" || warning.Turn != "b" {
+		t.Errorf("got warning %+v, want black to forfeit", warning)
+	}
+
+	var over gameOver
+	white.expect("gameOver", &over)
+	if over.Outcome != services.OutcomeWhiteWon || over.Method != services.MethodAbandoned {
+		t.Errorf("game ended %s by %s, want 1-0 by abandoned", over.Outcome, over.Method)
+	}
+}
+
+func TestMoveAfterIdleWarningKeepsGame(t *testing.T) {
+	s, tc := idleServer(t)
+	white, black, gameID := s.challenge(t, "alice", "bob", tc)
+
+	white.expect("idleWarning", nil)
+	black.expect("idleWarning", nil)
+	play(t, white, black, gameID, "e4")
+
+	// The move rearms the countdown, so the abort never comes
+	white.expectNone("gameAborted")
+}
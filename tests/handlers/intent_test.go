@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// expectClosed waits for the server to close a connection
+func (c *client) expectClosed(within time.Duration) {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(within))
+	for {
+		_, _, err := c.conn.ReadMessage()
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			c.t.Fatalf("connection still open after %s", within)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func TestSilentConnectionReaped(t *testing.T) {
+	cfg := testConfig()
+	cfg.WSIntentTimeout = 200 * time.Millisecond
+	s := newTestServer(t, cfg)
+
+	silent := s.dial(t, "alice")
+	pinging := s.dial(t, "bob")
+	active := s.dial(t, "carol")
+	active.send("list_games", nil)
+	active.expect("gameList", nil)
+
+	// Pings alone don't show intent
+	for i := 0; i < 3; i++ {
+		pinging.send("ping", nil)
+		time.Sleep(100 * time.Millisecond)
+	}
+	silent.expectClosed(time.Second)
+	pinging.expectClosed(time.Second)
+
+	time.Sleep(100 * time.Millisecond)
+	active.send("list_games", nil)
+	active.expect("gameList", nil)
+}
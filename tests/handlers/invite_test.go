@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+)
+
+// mailbox is an EmailSender that hands sent emails to the test
+type mailbox chan services.Email
+
+func (m mailbox) Send(ctx context.Context, email services.Email) error {
+	m <- email
+	return nil
+}
+
+func TestChallengeEmailsInviteUnlessTurnedOff(t *testing.T) {
+	for _, invites := range []bool{true, false} {
+		s := newTestServer(t, testConfig())
+		mail := make(mailbox, 1)
+		s.handler.SetNotifier(services.NewNotificationService(s.users, mail))
+		s.users.add(&models.User{ID: "bob", Username: "bob", Email: "bob@example.com", EloRating: 1500,
+			NotificationPreferences: models.NotificationPreferences{GameInvites: invites}})
+
+		s.challenge(t, "alice", "bob", services.DefaultTimeControl)
+
+		select {
+		case email := <-mail:
+			if !invites {
+				t.Errorf("invite emailed to %s, who turned invites off", email.To)
+			} else if email.To != "bob@example.com" {
+				t.Errorf("invite emailed to %s, want bob@example.com", email.To)
+			}
+		case <-time.After(200 * time.Millisecond):
+			if invites {
+				t.Error("no invite emailed")
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"testing"
+
+	"chess-ws-go/internal/services"
+)
+
+func TestJoinReturnsToAbandonedGame(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	play(t, white, black, gameID, "e4")
+
+	white.conn.Close()
+	black.expect("opponentDisconnected", nil)
+
+	// White asks for a new game while bob waits on them; they're sent back
+	// to the board instead of being queued
+	white = s.dial(t, white.user)
+	white.send("join", nil)
+	var state services.GameSnapshot
+	white.expect("gameState", &state)
+	if state.WhitePlayer != white.user || state.Turn != "b" {
+		t.Errorf("white returned to %+v", state)
+	}
+	black.expect("opponentReconnected", nil)
+
+	carol := s.dial(t, "carol")
+	carol.send("join", nil)
+	carol.expect("waiting", nil)
+	play(t, black, white, gameID, "e5")
+}
+
+func TestJoinNextGameWhileSeated(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	alice := s.dial(t, "alice")
+	first, _ := pair(t, alice, s.dial(t, "bob"))
+
+	// Still at the board, alice may start another game alongside
+	second, _ := pair(t, alice, s.dial(t, "carol"))
+	if first.start.GameID == second.start.GameID {
+		t.Fatal("alice was put back into the game she's playing")
+	}
+}
+
+func TestJoinFullGame(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	_, _, gameID := s.startGame(t, "alice", "bob")
+
+	carol := s.dial(t, "carol")
+	carol.send("join", map[string]any{"gameId": gameID})
+	var full struct {
+		GameID      string `json:"gameId"`
+		CanSpectate bool   `json:"canSpectate"`
+	}
+	carol.expect("gameFull", &full)
+	if full.GameID != gameID || !full.CanSpectate {
+		t.Errorf("carol was told %+v, want the game offered to watch", full)
+	}
+}
+
+func TestJoinReattachesGameWithoutSession(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	alice, bob := s.dial(t, "alice"), s.dial(t, "bob")
+	gameID := s.games.CreateGameWithTimeControl("alice", "bob", services.TimeControl{DaysPerMove: 3})
+
+	// Joining a game by ID seats its players as they arrive
+	alice.send("join", map[string]any{"gameId": gameID})
+	alice.expect("gameState", nil)
+	bob.send("join", map[string]any{"gameId": gameID})
+	bob.expect("gameState", nil)
+	play(t, alice, bob, gameID, "e4", "e5")
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// expectError reads messages until an error arrives and checks it mentions want
+func (c *client) expectError(want string) {
+	c.t.Helper()
+	var payload string
+	c.expect("error", &payload)
+	if !strings.Contains(payload, want) {
+		c.t.Fatalf("got error %q, want one mentioning %q", payload, want)
+	}
+}
+
+func TestConcurrentGameCap(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxConcurrentGames = 1
+	s := newTestServer(t, cfg)
+	alice, bob := s.dial(t, "alice"), s.dial(t, "bob")
+	white, _ := pair(t, alice, bob)
+
+	// At the cap, alice can neither queue nor be paired
+	alice.send("join", nil)
+	alice.expectError("more than 1 games at once")
+	carol := s.dial(t, "carol")
+	carol.send("join", nil)
+	carol.expect("waiting", nil)
+	alice.send("join", nil)
+	alice.expectError("more than 1 games at once")
+
+	// Finishing the game frees the slot
+	alice.send("resign", map[string]any{"gameId": white.start.GameID})
+	alice.expect("gameOver", nil)
+	alice.send("join", nil)
+	var aliceStart, carolStart gameStart
+	alice.expect("gameStart", &aliceStart)
+	carol.expect("gameStart", &carolStart)
+	if aliceStart.Opponent != "carol" || carolStart.GameID != aliceStart.GameID || aliceStart.GameID == white.start.GameID {
+		t.Errorf("alice started %+v and carol %+v, want a new game between them", aliceStart, carolStart)
+	}
+}
+
+func TestConnectionCapRefusesWithRetryAfter(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxConnections = 2
+	s := newTestServer(t, cfg)
+	alice := s.dial(t, "alice")
+	s.dial(t, "bob")
+
+	_, resp, err := websocket.DefaultDialer.Dial(s.url("/ws?user=carol"), nil)
+	if err == nil {
+		t.Fatal("connection accepted over the cap")
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "30" {
+		t.Errorf("refused with %d, Retry-After %q; want 503 and 30", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if open, limit := s.handler.ConnectionCount(); open != 2 || limit != 2 {
+		t.Errorf("%d of %d connections open, want 2 of 2", open, limit)
+	}
+
+	// A slot frees up once the server has seen alice go
+	alice.conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for open, _ := s.handler.ConnectionCount(); open != 1; open, _ = s.handler.ConnectionCount() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d connections still open after alice left", open)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.dial(t, "carol")
+}
+
+func TestConnectionCapUnderConcurrentDials(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxConnections = 5
+	s := newTestServer(t, cfg)
+
+	const dialers = 20
+	var accepted atomic.Int32
+	var wg sync.WaitGroup
+	for i := range dialers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, resp, err := websocket.DefaultDialer.Dial(s.url(fmt.Sprintf("/ws?user=user%d", i)), nil)
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if err != nil {
+				if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+					t.Errorf("dial %d: %v", i, err)
+				}
+				return
+			}
+			accepted.Add(1)
+			t.Cleanup(func() { conn.Close() })
+		}()
+	}
+	wg.Wait()
+	if n := accepted.Load(); n != 5 {
+		t.Errorf("%d connections accepted, want exactly 5", n)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// protocolError is an error reply under ProtocolV2
+type protocolError struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Field   string `json:"field"`
+	Payload string `json:"payload"`
+}
+
+// hello switches a client to a protocol version
+func (c *client) hello(version int) {
+	c.t.Helper()
+	c.send("hello", map[string]any{"version": version})
+	c.expect("hello", nil)
+}
+
+// sendRaw writes a text frame as is
+func (c *client) sendRaw(data string) {
+	c.t.Helper()
+	if err := c.conn.WriteMessage(websocket.TextMessage, []byte(data)); err != nil {
+		c.t.Fatalf("send %s: %v", data, err)
+	}
+}
+
+// expectProtocolError reads messages until an error arrives and decodes it
+func (c *client) expectProtocolError() protocolError {
+	c.t.Helper()
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.t.Fatalf("no error arrived: %v", err)
+		}
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "error" {
+			continue
+		}
+		var reply protocolError
+		if err := json.Unmarshal(data, &reply); err != nil {
+			c.t.Fatalf("decode error %s: %v", data, err)
+		}
+		return reply
+	}
+}
+
+func TestMalformedMessages(t *testing.T) {
+	cfg := testConfig()
+	cfg.WSStrictMessages = true
+	s := newTestServer(t, cfg)
+	alice := s.dial(t, "alice")
+	alice.hello(2)
+
+	tests := []struct {
+		name    string
+		message string
+		field   string
+		reason  string
+	}{
+		{"malformed JSON", `{"type": "move", "payload": {`, "", "malformed JSON"},
+		{"missing type", `{"payload": {}}`, "type", "is required"},
+		{"payload not an object", `{"type": "join", "payload": [1]}`, "payload", "must be an object"},
+		{"missing required field", `{"type": "move", "payload": {"gameId": "g1"}}`, "payload.move", "is required"},
+		{"null required field", `{"type": "resign", "payload": {"gameId": null}}`, "payload.gameId", "is required"},
+		{"wrong field type", `{"type": "resign", "payload": {"gameId": 7}}`, "payload.gameId", "must be a string"},
+		{"unknown field in strict mode", `{"type": "join", "payload": {"colour": "white"}}`, "payload.colour", "is not a known field"},
+		{"unknown top-level field", `{"type": "join", "payload": {}, "extra": 1}`, "extra", "is not a known field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alice.t = t
+			alice.sendRaw(tt.message)
+			reply := alice.expectProtocolError()
+			if reply.Code != "INVALID_MESSAGE" || reply.Field != tt.field || !strings.Contains(reply.Payload, tt.reason) {
+				t.Errorf("got %+v, want INVALID_MESSAGE on %q saying %q", reply, tt.field, tt.reason)
+			}
+		})
+	}
+
+	// The connection survives bad messages
+	alice.t = t
+	alice.send("join", nil)
+	alice.expect("waiting", nil)
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMoveHandlingObserved(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	samples := make(chan time.Duration, 10)
+	s.handler.ObserveMoves(func(d time.Duration) { samples <- d })
+	white, black, gameID := s.startGame(t, "alice", "bob")
+
+	// Timing stops once the move is broadcast, so samples may trail the
+	// messages slightly
+	play(t, white, black, gameID, "e4", "e5")
+	for i := range 2 {
+		select {
+		case d := <-samples:
+			if d <= 0 {
+				t.Errorf("sample %d is %s", i, d)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("got %d samples after 2 moves", i)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/gorilla/websocket"
+)
+
+// expectCloseReason waits for the server to close a connection and checks
+// the code and reason it gave
+func (c *client) expectCloseReason(code int, reason string) {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		_, _, err := c.conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) {
+			c.t.Fatalf("connection ended without a close frame: %v", err)
+		}
+		if closeErr.Code != code || closeErr.Text != reason {
+			c.t.Errorf("closed with %d %q, want %d %q", closeErr.Code, closeErr.Text, code, reason)
+		}
+		return
+	}
+}
+
+func TestTerminateGameEndsItForEveryone(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	play(t, white, black, gameID, "e4", "e5")
+	spectator := s.spectate(t, gameID)
+
+	if err := s.handler.TerminateGame(context.Background(), gameID); err != nil {
+		t.Fatalf("TerminateGame: %v", err)
+	}
+	for _, c := range []*client{white, black, spectator} {
+		var over gameOver
+		c.expect("gameOver", &over)
+		if over.Outcome != services.OutcomeNone || over.Method != services.MethodAdminTerminated {
+			t.Errorf("game ended %s by %s, want * by admin_terminated", over.Outcome, over.Method)
+		}
+	}
+
+	if err := s.handler.TerminateGame(context.Background(), gameID); err != services.ErrGameOver {
+		t.Errorf("terminating a finished game: got %v, want ErrGameOver", err)
+	}
+	if err := s.handler.TerminateGame(context.Background(), "no-such-game"); err != services.ErrGameNotFound {
+		t.Errorf("terminating an unknown game: got %v, want ErrGameNotFound", err)
+	}
+}
+
+func TestAbortGameEndsItForEveryone(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	s.games.SetAbortThreshold(2)
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	play(t, white, black, gameID, "e4")
+	spectator := s.spectate(t, gameID)
+
+	if err := s.handler.AbortGame(context.Background(), gameID, "wrong pairing"); err != nil {
+		t.Fatalf("AbortGame: %v", err)
+	}
+	// Everyone learns why before learning the game is over
+	for _, c := range []*client{white, black, spectator} {
+		var aborted struct {
+			GameID string `json:"gameId"`
+			Reason string `json:"reason"`
+		}
+		c.expect("gameAborted", &aborted)
+		if aborted.GameID != gameID || aborted.Reason != "wrong pairing" {
+			t.Errorf("told %+v, want %s aborted for a wrong pairing", aborted, gameID)
+		}
+		var over gameOver
+		c.expect("gameOver", &over)
+		if over.Outcome != services.OutcomeNone || over.Method != services.MethodAborted {
+			t.Errorf("game ended %s by %s, want * by aborted", over.Outcome, over.Method)
+		}
+	}
+
+	if err := s.handler.AbortGame(context.Background(), gameID, "again"); err != services.ErrGameOver {
+		t.Errorf("aborting a finished game: got %v, want ErrGameOver", err)
+	}
+}
+
+func TestDisconnectUserClosesEveryConnection(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, _ := s.startGame(t, "alice", "bob")
+	second := s.dial(t, white.user)
+
+	if closed := s.handler.DisconnectUser(white.user); closed != 2 {
+		t.Errorf("closed %d connections, want 2", closed)
+	}
+	for _, c := range []*client{white, second} {
+		c.expectCloseReason(websocket.ClosePolicyViolation, "disconnected by an administrator")
+	}
+
+	// The game carries on as after any disconnect
+	var away opponentAway
+	black.expect("opponentDisconnected", &away)
+	if away.Color != "w" {
+		t.Errorf("black told %s left, want white", away.Color)
+	}
+
+	if closed := s.handler.DisconnectUser("nobody"); closed != 0 {
+		t.Errorf("closed %d connections of a user who has none", closed)
+	}
+}
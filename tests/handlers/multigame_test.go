@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// seated is one side of a game started through matchmaking
+type seated struct {
+	client *client
+	start  gameStart
+}
+
+// pair queues two clients in turn and returns them as white and black
+func pair(t *testing.T, first, second *client) (white, black seated) {
+	t.Helper()
+	first.send("join", nil)
+	first.expect("waiting", nil)
+	second.send("join", nil)
+
+	a, b := seated{client: first}, seated{client: second}
+	first.expect("gameStart", &a.start)
+	second.expect("gameStart", &b.start)
+	if a.start.Color == "white" {
+		return a, b
+	}
+	return b, a
+}
+
+// movesBefore reads a client's messages until one of the given type and
+// returns the moves among them
+func movesBefore(t *testing.T, c *client, msgType string) []string {
+	t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	moves := []string{}
+	for {
+		var msg message
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("no %s message arrived: %v", msgType, err)
+		}
+		switch msg.Type {
+		case msgType:
+			return moves
+		case "move":
+			var move struct {
+				Move string `json:"move"`
+			}
+			if err := json.Unmarshal(msg.Payload, &move); err != nil {
+				t.Fatalf("decode move %s: %v", msg.Payload, err)
+			}
+			moves = append(moves, move.Move)
+		}
+	}
+}
+
+func TestConnectionPlaysTwoGames(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	alice := s.dial(t, "alice")
+	bob, carol := s.dial(t, "bob"), s.dial(t, "carol")
+	white1, _ := pair(t, alice, bob)
+	white2, _ := pair(t, alice, carol)
+	game1, game2 := white1.start.GameID, white2.start.GameID
+	if game1 == game2 {
+		t.Fatal("both pairings started the same game")
+	}
+	if n := s.messages.GetActiveConnectionsCount(); n != 3 {
+		t.Errorf("message service counts %d connections, want 3", n)
+	}
+
+	// White opens each game; alice, on the other side, replies in both
+	openings := map[string][2]string{game1: {"e4", "e5"}, game2: {"d4", "d5"}}
+	for _, white := range []seated{white1, white2} {
+		gameID := white.start.GameID
+		first := white.client
+		if first == alice {
+			continue
+		}
+		first.send("move", map[string]any{"gameId": gameID, "move": openings[gameID][0]})
+		first.expect("move", nil)
+		alice.expect("move", nil)
+		alice.send("move", map[string]any{"gameId": gameID, "move": openings[gameID][1]})
+		alice.expect("move", nil)
+	}
+	for _, white := range []seated{white1, white2} {
+		if white.client != alice {
+			continue
+		}
+		gameID := white.start.GameID
+		alice.send("move", map[string]any{"gameId": gameID, "move": openings[gameID][0]})
+		alice.expect("move", nil)
+	}
+
+	// Closing the connection leaves both games, whose opponents saw only
+	// their own game's moves
+	alice.conn.Close()
+	for opponent, gameID := range map[*client]string{bob: game1, carol: game2} {
+		got := movesBefore(t, opponent, "opponentDisconnected")
+		var want []string
+		if opponent == white1.client || opponent == white2.client {
+			want = []string{openings[gameID][1]}
+		} else {
+			want = []string{openings[gameID][0]}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s saw moves %v, want %v", opponent.user, got, want)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for s.messages.GetActiveConnectionsCount() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("message service still counts %d connections", s.messages.GetActiveConnectionsCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialFrom opens a spectator connection as a browser on the origin would,
+// or as a native client when origin is empty, reporting the HTTP status
+func (s *testServer) dialFrom(t *testing.T, origin string) int {
+	t.Helper()
+	header := http.Header{}
+	if origin != "" {
+		header.Set("Origin", origin)
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial(s.url("/spectate"), header)
+	if err == nil {
+		conn.Close()
+	}
+	if resp == nil {
+		t.Fatalf("dial from %q: %v", origin, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestWebSocketOriginsChecked(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		ws        string
+		origin    string
+		wantAdmit bool
+	}{
+		{"unset refuses browsers", "", "https://evil.example.com", false},
+		{"listed origin", "https://play.example.com", "https://play.example.com", true},
+		{"unlisted origin", "https://play.example.com", "https://evil.example.com", false},
+		{"wildcard", "*", "https://evil.example.com", true},
+		{"native client", "", "", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.AllowedWSOrigins = tc.ws
+			s := newTestServer(t, cfg)
+
+			status := s.dialFrom(t, tc.origin)
+			if admitted := status == http.StatusSwitchingProtocols; admitted != tc.wantAdmit {
+				t.Errorf("origin %q against %q: got status %d", tc.origin, tc.ws, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chess-ws-go/internal/handlers"
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+	"chess-ws-go/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageRecorder is a user repository that records the page it's asked for
+type pageRecorder struct {
+	repositories.UserRepository
+	opts repositories.UserListOptions
+}
+
+func (r *pageRecorder) Count(ctx context.Context, search string) (int, error) {
+	return 1000, nil
+}
+
+func (r *pageRecorder) List(ctx context.Context, opts repositories.UserListOptions) ([]*models.User, error) {
+	r.opts = opts
+	return nil, nil
+}
+
+func TestListUsersClampsPaging(t *testing.T) {
+	const maxPageSize = 100
+	tests := []struct {
+		query      string
+		wantLimit  int
+		wantOffset int
+	}{
+		{"page=1&limit=1000000", maxPageSize, 0},
+		{"page=3&limit=101", maxPageSize, 200},
+		{"page=2&limit=20", 20, 20},
+		{"page=2&limit=-5", 10, 10},
+		{"page=2&limit=lots", 10, 10},
+		{"page=0&limit=20", 20, 0},
+		{"page=-7&limit=20", 20, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			users := &pageRecorder{}
+			handler := handlers.NewUserHandler(services.NewUserService(users, nil), nil, maxPageSize)
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.GET("/users", handler.ListUsers)
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users?"+tt.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+			if users.opts.Limit != tt.wantLimit || users.opts.Offset != tt.wantOffset {
+				t.Errorf("queried limit %d offset %d, want %d and %d",
+					users.opts.Limit, users.opts.Offset, tt.wantLimit, tt.wantOffset)
+			}
+
+			var body struct {
+				Pagination struct {
+					Limit int `json:"limit"`
+				} `json:"pagination"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.Pagination.Limit != tt.wantLimit {
+				t.Errorf("reported limit %d, want %d", body.Pagination.Limit, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialProtocols connects as a user offering the given subprotocols
+func (s *testServer) dialProtocols(t *testing.T, userID string, protocols ...string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	dialer := *websocket.DefaultDialer
+	dialer.Subprotocols = protocols
+	conn, resp, err := dialer.Dial(s.url("/ws?user="+userID), nil)
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+func TestSubprotocolNegotiation(t *testing.T) {
+	s := newTestServer(t, testConfig())
+
+	tests := []struct {
+		name    string
+		offered []string
+		want    string
+	}{
+		{"none offered", nil, ""},
+		{"newest supported", []string{"chess.v1", "chess.v2"}, "chess.v2"},
+		{"supported among unsupported", []string{"chess.v9", "chess.v1"}, "chess.v1"},
+		{"token only", []string{"token.abc"}, "token.abc"},
+		{"version with token", []string{"token.abc", "chess.v2"}, "chess.v2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, resp, err := s.dialProtocols(t, "alice", tt.offered...)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			resp.Body.Close()
+			if got := conn.Subprotocol(); got != tt.want {
+				t.Errorf("accepted subprotocol %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsupportedSubprotocolsRejected(t *testing.T) {
+	s := newTestServer(t, testConfig())
+
+	tests := []struct {
+		name          string
+		offered       []string
+		wantRequested int
+	}{
+		{"future version", []string{"chess.v9"}, 9},
+		{"future version with token", []string{"token.abc", "chess.v7", "chess.v9"}, 9},
+		{"unknown protocol", []string{"graphql-ws"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, resp, err := s.dialProtocols(t, "alice", tt.offered...)
+			if err == nil {
+				t.Fatal("upgrade accepted")
+			}
+			if resp == nil {
+				t.Fatalf("no response: %v", err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+			}
+
+			var body struct {
+				Requested    int      `json:"requested"`
+				Supported    []int    `json:"supported"`
+				Subprotocols []string `json:"subprotocols"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("decode body: %v", err)
+			}
+			if body.Requested != tt.wantRequested {
+				t.Errorf("requested %d, want %d", body.Requested, tt.wantRequested)
+			}
+			if len(body.Supported) != 2 || body.Supported[0] != 1 || body.Supported[1] != 2 {
+				t.Errorf("supported %v, want [1 2]", body.Supported)
+			}
+			if len(body.Subprotocols) != 2 || body.Subprotocols[0] != "chess.v1" || body.Subprotocols[1] != "chess.v2" {
+				t.Errorf("subprotocols %v, want [chess.v1 chess.v2]", body.Subprotocols)
+			}
+		})
+	}
+}
+
+func TestRejectedSubprotocolsDontTakeSlots(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxConnections = 1
+	s := newTestServer(t, cfg)
+
+	for range 3 {
+		if _, resp, err := s.dialProtocols(t, "alice", "chess.v9"); err == nil || resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("future version not refused: %v", err)
+		}
+	}
+	s.dial(t, "bob")
+}
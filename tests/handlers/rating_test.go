@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"chess-ws-go/internal/models"
+)
+
+func TestGameStartCarriesRatings(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	s.users.add(&models.User{ID: "alice", Username: "alice", EloRating: 1720})
+	s.users.add(&models.User{ID: "bob", Username: "bob", EloRating: 1480, PlacementGamesRemaining: 3})
+
+	alice, bob := s.dial(t, "alice"), s.dial(t, "bob")
+	alice.send("join", nil)
+	alice.expect("waiting", nil)
+	bob.send("join", nil)
+
+	var aliceStart, bobStart gameStart
+	alice.expect("gameStart", &aliceStart)
+	bob.expect("gameStart", &bobStart)
+	if aliceStart.Rating != 1720 || aliceStart.OpponentRating != 1480 ||
+		aliceStart.Provisional || !aliceStart.OpponentProvisional {
+		t.Errorf("alice's start: %+v", aliceStart)
+	}
+	if bobStart.Rating != 1480 || bobStart.OpponentRating != 1720 ||
+		!bobStart.Provisional || bobStart.OpponentProvisional {
+		t.Errorf("bob's start: %+v", bobStart)
+	}
+}
+
+func TestGameStartOmitsMissingRating(t *testing.T) {
+	s := newTestServer(t, testConfig())
+
+	// Nobody by this ID is on record, so there's no rating to show
+	ghost := s.connect(t, "/ws?user=ghost")
+	ghost.send("join", nil)
+	ghost.expect("waiting", nil)
+	bob := s.dial(t, "bob")
+	bob.send("join", nil)
+
+	var ghostStart, bobStart map[string]json.RawMessage
+	ghost.expect("gameStart", &ghostStart)
+	bob.expect("gameStart", &bobStart)
+	if _, ok := ghostStart["rating"]; ok {
+		t.Errorf("unrated player was sent their rating %s", ghostStart["rating"])
+	}
+	if _, ok := bobStart["opponentRating"]; ok {
+		t.Errorf("bob was sent the unrated opponent's rating %s", bobStart["opponentRating"])
+	}
+	if string(bobStart["rating"]) != "1500" {
+		t.Errorf("bob's rating is %s, want 1500", bobStart["rating"])
+	}
+}
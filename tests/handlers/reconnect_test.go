@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnectToGameWithoutSession(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	alice := s.dial(t, "alice")
+
+	// A game created outside matchmaking has no session yet; reconnecting
+	// attaches one, and looking its players up must not hold the handler
+	// lock, which IsInGame also takes
+	gameID := s.games.CreateGame("alice", "bob")
+	s.users.mu.Lock()
+	s.users.onLookup = func() { s.handler.IsInGame("alice") }
+	s.users.mu.Unlock()
+
+	alice.send("reconnect", map[string]any{"gameId": gameID})
+	alice.expect("gameState", nil)
+}
+
+// opponentAway is the payload telling a player their opponent disconnected
+type opponentAway struct {
+	Color       string  `json:"color"`
+	ReconnectIn float64 `json:"reconnectIn"`
+}
+
+func TestOpponentToldOfDisconnectAndReturn(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+
+	white.conn.Close()
+	var away opponentAway
+	black.expect("opponentDisconnected", &away)
+	abandon := testConfig().AbandonTimeout.Seconds()
+	if away.Color != "w" || away.ReconnectIn > abandon || away.ReconnectIn < abandon-1 {
+		t.Errorf("black was told %+v, want white with about %.0fs to return", away, abandon)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	white = s.dial(t, white.user)
+	white.send("reconnect", map[string]any{"gameId": gameID})
+	var back struct {
+		Color string  `json:"color"`
+		Away  float64 `json:"away"`
+	}
+	black.expect("opponentReconnected", &back)
+	if back.Color != "w" || back.Away < 0.1 {
+		t.Errorf("black was told %+v, want white back after at least 0.1s", back)
+	}
+}
+
+func TestReturningPlayerToldOpponentIsAway(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+
+	black.conn.Close()
+	white.expect("opponentDisconnected", nil)
+	time.Sleep(100 * time.Millisecond)
+	white.conn.Close()
+
+	white = s.dial(t, white.user)
+	white.send("reconnect", map[string]any{"gameId": gameID})
+	var away opponentAway
+	white.expect("opponentDisconnected", &away)
+	abandon := testConfig().AbandonTimeout.Seconds()
+	if away.Color != "b" || away.ReconnectIn > abandon-0.1 {
+		t.Errorf("white was told %+v, want black with under %.1fs left", away, abandon-0.1)
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+// rematchStarted is the payload telling both players their rematch began
+type rematchStarted struct {
+	GameID    string `json:"gameId"`
+	RematchID string `json:"rematchId"`
+	Rated     bool   `json:"rated"`
+}
+
+// finishedGame starts a game between two users and has black resign it
+func (s *testServer) finishedGame(t *testing.T, a, b string) (white *client, black *client, gameID string) {
+	t.Helper()
+	white, black, gameID = s.startGame(t, a, b)
+	play(t, white, black, gameID, "e4", "e5")
+	black.send("resign", map[string]any{"gameId": gameID})
+	white.expect("gameOver", nil)
+	black.expect("gameOver", nil)
+	return white, black, gameID
+}
+
+// rematch has offerer propose a rematch at the given stakes and accepter take
+// it, returning both players' starts of the new game
+func rematch(t *testing.T, offerer, accepter *client, gameID string, rated bool) (offererStart, accepterStart gameStart) {
+	t.Helper()
+	offerer.send("rematch_offer", map[string]any{"gameId": gameID, "rated": rated})
+	accepter.expect("rematchOffer", nil)
+	accepter.send("rematch_response", map[string]any{"gameId": gameID, "accept": true, "rated": rated})
+
+	offerer.expect("gameStart", &offererStart)
+	accepter.expect("gameStart", &accepterStart)
+	var offererStarted, accepterStarted rematchStarted
+	offerer.expect("rematchStarted", &offererStarted)
+	accepter.expect("rematchStarted", &accepterStarted)
+	if offererStarted.Rated != rated || accepterStarted.Rated != rated {
+		t.Errorf("rematch started rated %v/%v, want %v", offererStarted.Rated, accepterStarted.Rated, rated)
+	}
+	return offererStart, accepterStart
+}
+
+func colorOf(start gameStart) chess.Color {
+	if start.Color == "white" {
+		return chess.White
+	}
+	return chess.Black
+}
+
+func TestRematchRecomputesTimeOdds(t *testing.T) {
+	cfg := testConfig()
+	cfg.TimeOddsRatingGap = 300
+	s := newTestServer(t, cfg)
+	s.users.add(&models.User{ID: "strong", Username: "strong", EloRating: 2000})
+	s.users.add(&models.User{ID: "weak", Username: "weak", EloRating: 1500})
+
+	white, black, gameID := s.finishedGame(t, "strong", "weak")
+	strong, weak := white, black
+	if black.user == "strong" {
+		strong, weak = black, white
+	}
+
+	strongStart, weakStart := rematch(t, strong, weak, gameID, true)
+	if (strong == white) != (strongStart.Color == "black") {
+		t.Fatalf("colors didn't swap: strong plays %s again", strongStart.Color)
+	}
+	tc := strongStart.TimeControl
+	if !tc.HasOdds() {
+		t.Fatalf("rematch has no time odds: %+v", tc)
+	}
+	strongClock, weakClock := tc.InitialFor(colorOf(strongStart)), tc.InitialFor(colorOf(weakStart))
+	if strongClock >= weakClock {
+		t.Errorf("stronger player starts with %gs against %gs; odds didn't follow the swap", strongClock, weakClock)
+	}
+	if tc.IncrementFor(colorOf(strongStart)) != 0 {
+		t.Errorf("stronger player keeps an increment of %gs", tc.IncrementFor(colorOf(strongStart)))
+	}
+}
+
+func TestRatedRematchShowsRatings(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	s.users.add(&models.User{ID: "alice", Username: "alice", EloRating: 1720})
+	s.users.add(&models.User{ID: "bob", Username: "bob", EloRating: 1480})
+
+	white, black, gameID := s.finishedGame(t, "alice", "bob")
+	whiteStart, blackStart := rematch(t, white, black, gameID, true)
+
+	if whiteStart.Color != "black" || blackStart.Color != "white" {
+		t.Fatalf("colors didn't swap: %s and %s", whiteStart.Color, blackStart.Color)
+	}
+	if whiteStart.Rating == 0 || whiteStart.OpponentRating == 0 ||
+		whiteStart.Rating != blackStart.OpponentRating || blackStart.Rating != whiteStart.OpponentRating {
+		t.Errorf("rated rematch starts %+v and %+v", whiteStart, blackStart)
+	}
+	if state, err := s.games.GetGameState(whiteStart.GameID); err != nil || state.Casual {
+		t.Errorf("rated rematch state %+v, %v", state, err)
+	}
+}
+
+func TestCasualRematchHidesRatings(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.finishedGame(t, "alice", "bob")
+
+	whiteStart, blackStart := rematch(t, black, white, gameID, false)
+	if whiteStart.Rating != 0 || whiteStart.OpponentRating != 0 ||
+		blackStart.Rating != 0 || blackStart.OpponentRating != 0 {
+		t.Errorf("casual rematch shows ratings: %+v and %+v", whiteStart, blackStart)
+	}
+	state, err := s.games.GetGameState(whiteStart.GameID)
+	if err != nil || !state.Casual || state.OpenSeats {
+		t.Errorf("casual rematch state %+v, %v", state, err)
+	}
+}
+
+func TestPrivateGameRematchedPrivately(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black := s.dial(t, "alice"), s.dial(t, "bob")
+	white.send("challenge", map[string]any{"target": "bob", "private": true})
+	var received struct {
+		ChallengeID string `json:"challengeId"`
+	}
+	black.expect("challengeReceived", &received)
+	black.send("challenge_response", map[string]any{"challengeId": received.ChallengeID, "accept": true})
+	var start gameStart
+	white.expect("gameStart", &start)
+	black.expect("gameStart", nil)
+
+	// Private as soon as the players hear of it
+	gameID := start.GameID
+	if _, err := s.games.GetLiveGame(gameID, "stranger"); err != services.ErrGameForbidden {
+		t.Errorf("GetLiveGame by a stranger: got %v, want ErrGameForbidden", err)
+	}
+
+	play(t, white, black, gameID, "e4", "e5")
+	black.send("resign", map[string]any{"gameId": gameID})
+	white.expect("gameOver", nil)
+	black.expect("gameOver", nil)
+
+	whiteStart, _ := rematch(t, white, black, gameID, true)
+	if _, err := s.games.GetLiveGame(whiteStart.GameID, "stranger"); err != services.ErrGameForbidden {
+		t.Errorf("GetLiveGame of the rematch by a stranger: got %v, want ErrGameForbidden", err)
+	}
+}
+
+func TestRematchStakesMustMatch(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.finishedGame(t, "alice", "bob")
+
+	white.send("rematch_offer", map[string]any{"gameId": gameID, "rated": true})
+	black.expect("rematchOffer", nil)
+
+	// Accepting a casual game when a rated one was offered starts nothing
+	black.send("rematch_response", map[string]any{"gameId": gameID, "accept": true, "rated": false})
+	black.expectError("other stakes")
+
+	// A counter-offer replaces the original, so white can take it
+	black.send("rematch_offer", map[string]any{"gameId": gameID, "rated": false})
+	var counter struct {
+		OfferedBy string `json:"offeredBy"`
+		Rated     bool   `json:"rated"`
+	}
+	white.expect("rematchOffer", &counter)
+	if counter.OfferedBy != "b" || counter.Rated {
+		t.Errorf("counter-offer %+v", counter)
+	}
+	white.send("rematch_response", map[string]any{"gameId": gameID, "accept": true, "rated": true})
+	white.expectError("other stakes")
+	white.send("rematch_response", map[string]any{"gameId": gameID, "accept": true, "rated": false})
+
+	var started rematchStarted
+	white.expect("rematchStarted", &started)
+	black.expect("rematchStarted", nil)
+	if started.Rated {
+		t.Error("counter-offered casual rematch started rated")
+	}
+}
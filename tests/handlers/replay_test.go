@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// numbered is a game message with its sequence number
+type numbered struct {
+	Type    string          `json:"type"`
+	Seq     int             `json:"seq"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// nextNumbered reads messages until one of the given type arrives, or
+// returns the next message of any type when msgType is empty
+func (c *client) nextNumbered(msgType string) numbered {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer c.conn.SetReadDeadline(time.Time{})
+	for {
+		var msg numbered
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			c.t.Fatalf("no %s message arrived: %v", msgType, err)
+		}
+		if msgType == "" || msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+// awayWhileChatting plays e4, drops black's connection and has white send
+// chat messages while black is gone. It returns black's user ID, the game ID
+// and the sequence number of the move black saw last.
+func (s *testServer) awayWhileChatting(t *testing.T, chats int) (away string, gameID string, lastSeq int) {
+	t.Helper()
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	white.send("move", map[string]any{"gameId": gameID, "move": "e4"})
+	white.expect("move", nil)
+	lastSeq = black.nextNumbered("move").Seq
+
+	black.conn.Close()
+	white.expect("opponentDisconnected", nil)
+	for i := range chats {
+		white.send("chat", map[string]any{"gameId": gameID, "message": fmt.Sprintf("chat %d", i)})
+		white.expect("chat", nil)
+	}
+	return black.user, gameID, lastSeq
+}
+
+func TestReconnectReplaysMessagesAfterLastSeq(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	away, gameID, lastSeq := s.awayWhileChatting(t, 3)
+
+	black := s.dial(t, away)
+	black.send("reconnect", map[string]any{"gameId": gameID, "lastSeq": lastSeq})
+
+	// What was sent to black's seat comes back in order, ahead of anything
+	// black's return sets off
+	seq := lastSeq
+	for i := 0; i < 3; {
+		msg := black.nextNumbered("")
+		if msg.Seq == 0 {
+			continue // Not a game message
+		}
+		if msg.Seq <= seq {
+			t.Fatalf("got #%d %s after #%d", msg.Seq, msg.Type, seq)
+		}
+		seq = msg.Seq
+		if msg.Type != "chat" {
+			continue
+		}
+		var chat struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(msg.Payload, &chat)
+		if want := fmt.Sprintf("chat %d", i); chat.Message != want {
+			t.Errorf("replayed %q, want %q", chat.Message, want)
+		}
+		i++
+	}
+	if resumed := black.nextNumbered("clockResumed"); resumed.Seq <= seq {
+		t.Errorf("clockResumed #%d sent ahead of replayed #%d", resumed.Seq, seq)
+	}
+	black.expectNone("gameState")
+}
+
+func TestReconnectWithoutLastSeqGetsSnapshot(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	away, gameID, _ := s.awayWhileChatting(t, 1)
+
+	black := s.dial(t, away)
+	black.send("reconnect", map[string]any{"gameId": gameID})
+	black.expect("gameState", nil)
+}
+
+func TestReconnectAfterOutboxOverflowGetsSnapshot(t *testing.T) {
+	const chats = 101 // One more than a seat's outbox holds
+	s := newTestServer(t, testConfig())
+	away, gameID, lastSeq := s.awayWhileChatting(t, chats)
+
+	black := s.dial(t, away)
+	black.send("reconnect", map[string]any{"gameId": gameID, "lastSeq": lastSeq})
+	state := black.nextNumbered("gameState")
+	if state.Seq < lastSeq+chats {
+		t.Errorf("snapshot reflects messages up to #%d, want at least #%d", state.Seq, lastSeq+chats)
+	}
+	black.expectNone("chat")
+}
+
+func TestReconnectWithUnknownLastSeqGetsSnapshot(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	away, gameID, lastSeq := s.awayWhileChatting(t, 1)
+
+	black := s.dial(t, away)
+	black.send("reconnect", map[string]any{"gameId": gameID, "lastSeq": lastSeq + 1000})
+	black.expect("gameState", nil)
+}
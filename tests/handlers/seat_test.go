@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+// vacateBlack starts a casual game with open seats and has black walk out,
+// leaving their seat to spectators once the grace period is over
+func (s *testServer) vacateBlack(t *testing.T) (white *client, left string, gameID string) {
+	t.Helper()
+	return s.leaveBlack(t, func(gameID string) error { return s.games.SetCasual(gameID, true) })
+}
+
+// leaveBlack starts a game, sets it up and has black walk out
+func (s *testServer) leaveBlack(t *testing.T, setup func(gameID string) error) (white *client, left string, gameID string) {
+	t.Helper()
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	if err := setup(gameID); err != nil {
+		t.Fatalf("setting up the game: %v", err)
+	}
+	black.conn.Close()
+	white.expect("opponentDisconnected", nil)
+	return white, black.user, gameID
+}
+
+// A player's state requests keep being answered while a spectator takes
+// over the other seat, and afterwards the state goes to the new player only
+func TestGetStateFollowsTakeSeat(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, left, gameID := s.vacateBlack(t)
+	carol := s.dial(t, "carol")
+	carol.send("spectate", map[string]any{"gameId": gameID})
+	carol.expect("spectating", nil)
+
+	const requests = 50
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		carol.send("take_seat", map[string]any{"gameId": gameID})
+	}()
+	for range requests {
+		white.send("get_state", map[string]any{"gameId": gameID})
+	}
+	for range requests {
+		white.expect("gameState", nil)
+	}
+	carol.expect("seatTaken", nil)
+	// The request reaching the server doesn't mean the write returned, and
+	// carol's connection takes one writer at a time
+	<-sent
+
+	carol.send("get_state", map[string]any{"gameId": gameID})
+	carol.expect("gameState", nil)
+	previous := s.dial(t, left)
+	previous.send("get_state", map[string]any{"gameId": gameID})
+	previous.expectError("Player not in this game")
+}
+
+// seatSeeker has carol watch a game, speaking ProtocolV2 for error codes
+func (s *testServer) seatSeeker(t *testing.T, gameID string) *client {
+	t.Helper()
+	carol := s.dial(t, "carol")
+	carol.hello(2)
+	carol.send("spectate", map[string]any{"gameId": gameID})
+	carol.expect("spectating", nil)
+	return carol
+}
+
+func TestTakeVacatedSeat(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, left, gameID := s.vacateBlack(t)
+	carol := s.seatSeeker(t, gameID)
+
+	carol.send("take_seat", map[string]any{"gameId": gameID, "color": "black"})
+	var taken struct {
+		Color    string `json:"color"`
+		Previous string `json:"previous"`
+		Player   string `json:"player"`
+	}
+	white.expect("seatTaken", &taken)
+	if taken.Color != "b" || taken.Previous != left || taken.Player != "carol" {
+		t.Errorf("white was told %+v", taken)
+	}
+	carol.expect("gameState", nil)
+
+	// The game carries on with carol as black
+	play(t, white, carol, gameID, "e4", "e5")
+}
+
+func TestTakeSeatRefused(t *testing.T) {
+	tests := []struct {
+		name  string
+		grace time.Duration
+		setup func(s *testServer, gameID string) error
+		color string
+		want  string
+	}{
+		{"rated game", 0, func(s *testServer, gameID string) error { return nil }, "", "SEATS_CLOSED"},
+		{"casual game without open seats", 0, func(s *testServer, gameID string) error {
+			return s.games.SetCasual(gameID, false)
+		}, "", "SEATS_CLOSED"},
+		{"within grace", time.Minute, func(s *testServer, gameID string) error {
+			return s.games.SetCasual(gameID, true)
+		}, "", "SEAT_NOT_VACANT"},
+		{"seat still occupied", 0, func(s *testServer, gameID string) error {
+			return s.games.SetCasual(gameID, true)
+		}, "white", "SEAT_NOT_VACANT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DisconnectGrace = tt.grace
+			s := newTestServer(t, cfg)
+			white, _, gameID := s.leaveBlack(t, func(gameID string) error { return tt.setup(s, gameID) })
+			carol := s.seatSeeker(t, gameID)
+
+			carol.send("take_seat", map[string]any{"gameId": gameID, "color": tt.color})
+			if reply := carol.expectProtocolError(); reply.Code != tt.want {
+				t.Errorf("got %+v, want %s", reply, tt.want)
+			}
+
+			// The game is left as it was
+			white.send("move", map[string]any{"gameId": gameID, "move": "e4"})
+			white.expect("move", nil)
+		})
+	}
+}
+
+func TestTakeSeatNeedsSpectating(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	_, _, gameID := s.vacateBlack(t)
+	carol := s.dial(t, "carol")
+
+	carol.send("take_seat", map[string]any{"gameId": gameID})
+	carol.expectError("Only spectators of a game can take a seat in it")
+}
+
+// Run with -race: black's draw offers and resignation read white's seat,
+// which carol is taking over at the same time
+func TestResignAlongsideTakeSeat(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	if err := s.games.SetCasual(gameID, true); err != nil {
+		t.Fatalf("SetCasual: %v", err)
+	}
+	white.conn.Close()
+	black.expect("opponentDisconnected", nil)
+	carol := s.dial(t, "carol")
+	carol.send("spectate", map[string]any{"gameId": gameID})
+	carol.expect("spectating", nil)
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for range 100 {
+			black.send("draw_offer", map[string]any{"gameId": gameID})
+		}
+	}()
+	carol.send("take_seat", map[string]any{"gameId": gameID})
+	<-sent
+	black.send("resign", map[string]any{"gameId": gameID})
+
+	// Whichever came first, the game ends and carol hears of it, seated or not
+	black.expect("gameOver", nil)
+	carol.expect("gameOver", nil)
+}
+
+// The player a seat was taken from can no longer act for it
+func TestReplacedPlayerCantActForSeat(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, left, gameID := s.vacateBlack(t)
+	carol := s.dial(t, "carol")
+	carol.send("spectate", map[string]any{"gameId": gameID})
+	carol.expect("spectating", nil)
+	carol.send("take_seat", map[string]any{"gameId": gameID})
+	white.expect("seatTaken", nil)
+
+	previous := s.dial(t, left)
+	for _, msgType := range []string{"resign", "draw_offer"} {
+		previous.send(msgType, map[string]any{"gameId": gameID})
+		previous.expectError("Player not in this game")
+	}
+	play(t, white, carol, gameID, "e4", "e5")
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+)
+
+// joinWithColors matches two new users asking for the given colors and
+// returns the color the first was seated as
+func (s *testServer) joinWithColors(t *testing.T, first, second, firstColor, secondColor string) string {
+	t.Helper()
+
+	a, b := s.dial(t, first), s.dial(t, second)
+	a.send("join", map[string]any{"color": firstColor})
+	a.expect("waiting", nil)
+	b.send("join", map[string]any{"color": secondColor})
+
+	var aStart, bStart gameStart
+	a.expect("gameStart", &aStart)
+	b.expect("gameStart", &bStart)
+	if aStart.Color == bStart.Color {
+		t.Fatalf("both players seated as %s", aStart.Color)
+	}
+	return aStart.Color
+}
+
+func TestCompatibleColorPreferences(t *testing.T) {
+	tests := []struct {
+		first, second string
+		want          string // First player's color
+	}{
+		{"white", "black", "white"},
+		{"black", "white", "black"},
+		{"white", "random", "white"},
+		{"black", "", "black"},
+		{"random", "white", "black"},
+		{"", "black", "white"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.first+"/"+tt.second, func(t *testing.T) {
+			s := newTestServer(t, testConfig())
+			if got := s.joinWithColors(t, "alice", "bob", tt.first, tt.second); got != tt.want {
+				t.Errorf("first player seated as %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConflictingColorPreferencesRandomized(t *testing.T) {
+	for _, color := range []string{"white", "black"} {
+		t.Run(color, func(t *testing.T) {
+			s := newTestServer(t, testConfig())
+
+			// 30 coin tosses all landing the same way would be a 1 in 2^29 fluke
+			seen := make(map[string]int)
+			for i := range 30 {
+				seen[s.joinWithColors(t, fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i), color, color)]++
+			}
+			if seen["white"] == 0 || seen["black"] == 0 {
+				t.Errorf("both asking for %s always seated the first player the same way: %v", color, seen)
+			}
+		})
+	}
+}
+
+func TestUnknownColorPreferenceRejected(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	alice := s.dial(t, "alice")
+
+	alice.send("join", map[string]any{"color": "green"})
+	alice.expectError("Color must be white, black or random")
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+)
+
+// spectate has a guest watch a game
+func (s *testServer) spectate(t testing.TB, gameID string) *client {
+	t.Helper()
+	guest := s.dialGuest(t)
+	guest.send("spectate", map[string]any{"gameId": gameID})
+	guest.expect("spectating", nil)
+	return guest
+}
+
+func TestSpectatorCaps(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxGameSpectators = 2
+	cfg.MaxSpectators = 3
+	s := newTestServer(t, cfg)
+	_, _, first := s.startGame(t, "alice", "bob")
+	_, _, second := s.startGame(t, "carol", "dave")
+
+	s.spectate(t, first)
+	s.spectate(t, first)
+	guest := s.dialGuest(t)
+	guest.send("spectate", map[string]any{"gameId": first})
+	guest.expectError("This game has reached its spectator limit")
+	guest.hello(2)
+	guest.send("spectate", map[string]any{"gameId": first})
+	if reply := guest.expectProtocolError(); reply.Code != "SPECTATOR_LIMIT" {
+		t.Errorf("got %+v, want SPECTATOR_LIMIT", reply)
+	}
+
+	// The other game has room of its own, until the server runs out
+	s.spectate(t, second)
+	guest.send("spectate", map[string]any{"gameId": second})
+	guest.expectError("The server has reached its spectator limit")
+}
+
+// drain discards everything a client receives, so a benchmark can ignore
+// the players' copies of what it broadcasts
+func drain(c *client) {
+	go func() {
+		for {
+			if _, _, err := c.conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// BenchmarkSpectatorFanout measures a broadcast from a move in a game with
+// many spectators, until every spectator has read it
+func BenchmarkSpectatorFanout(b *testing.B) {
+	for _, spectators := range []int{1, 10, 100, 500} {
+		b.Run(fmt.Sprintf("spectators=%d", spectators), func(b *testing.B) {
+			s := newTestServer(b, testConfig())
+			white, black, gameID := s.startGame(b, "alice", "bob")
+			watching := make([]*client, spectators)
+			for i := range watching {
+				watching[i] = s.spectate(b, gameID)
+			}
+			drain(white)
+			drain(black)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				white.send("time_update", map[string]any{"gameId": gameID, "timeLeft": 60})
+				for _, spectator := range watching {
+					spectator.expect("timeUpdate", nil)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/services"
+)
+
+func TestSweepDrawsOverlongGame(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	s.games.SetMaxDuration(time.Hour)
+	s.games.SetAbortThreshold(2)
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	play(t, white, black, gameID, "e4", "e5")
+
+	s.handler.Sweep(time.Now().Add(2 * time.Hour))
+	var over gameOver
+	white.expect("gameOver", &over)
+	black.expect("gameOver", nil)
+	if over.Outcome != services.OutcomeDraw || over.Method != services.MethodMaxDuration {
+		t.Errorf("game ended %s by %s, want drawn for running too long", over.Outcome, over.Method)
+	}
+}
+
+func TestSweepAbortsOverlongGameWithoutMoves(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	s.games.SetMaxDuration(time.Hour)
+	s.games.SetAbortThreshold(2)
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	play(t, white, black, gameID, "e4")
+
+	s.handler.Sweep(time.Now().Add(2 * time.Hour))
+	var over gameOver
+	black.expect("gameOver", &over)
+	white.expect("gameOver", nil)
+	if over.Outcome != services.OutcomeNone || over.Method != services.MethodAborted {
+		t.Errorf("game ended %s by %s, want aborted", over.Outcome, over.Method)
+	}
+}
+
+func TestSweepTimesOutCorrespondenceGame(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	s.games.SetMaxDuration(time.Hour)
+	white, black, _ := s.challenge(t, "alice", "bob", services.TimeControl{DaysPerMove: 1})
+
+	// Correspondence games are exempt from the cap, but not their deadlines
+	s.handler.Sweep(time.Now().Add(2 * time.Hour))
+	s.handler.Sweep(time.Now().Add(48 * time.Hour))
+	var over gameOver
+	black.expect("gameOver", &over)
+	white.expect("gameOver", nil)
+	if over.Method != services.MethodTimeout || over.Winner != "black" {
+		t.Errorf("game ended by %s won by %q, want black winning on time", over.Method, over.Winner)
+	}
+}
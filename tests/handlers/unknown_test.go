@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// unknownType is the ProtocolV2 reply to a message type the server doesn't know
+type unknownType struct {
+	Type        string `json:"type"`
+	Code        string `json:"code"`
+	Field       string `json:"field"`
+	MessageType string `json:"messageType"`
+}
+
+func TestUnknownMessageTypeReply(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	alice := s.dial(t, "alice")
+
+	alice.send("bogus", nil)
+	alice.expectError(`unknown message type "bogus"`)
+
+	alice.hello(2)
+	alice.send("bogus", nil)
+	_ = alice.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, data, err := alice.conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("no reply: %v", err)
+	}
+	var reply unknownType
+	if err := json.Unmarshal(data, &reply); err != nil {
+		t.Fatalf("decode %s: %v", data, err)
+	}
+	want := unknownType{Type: "error", Code: "UNKNOWN_MESSAGE_TYPE", Field: "type", MessageType: "bogus"}
+	if reply != want {
+		t.Errorf("got %+v, want %+v", reply, want)
+	}
+}
+
+func TestUnknownMessageTypeRepliesLimited(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	alice := s.dial(t, "alice")
+
+	const probes = 30
+	for range probes {
+		alice.send("bogus", nil)
+	}
+	alice.send("list_games", nil)
+
+	// Only the burst is answered; the connection keeps working
+	errors := 0
+	for {
+		var msg message
+		_ = alice.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if err := alice.conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("gameList never arrived: %v", err)
+		}
+		if msg.Type == "gameList" {
+			break
+		}
+		if msg.Type == "error" {
+			errors++
+		}
+	}
+	if errors < 10 || errors > 12 {
+		t.Errorf("%d of %d unknown types answered, want about the burst of 10", errors, probes)
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoneWaiterTimedOut(t *testing.T) {
+	cfg := testConfig()
+	cfg.WaitingTimeout = 200 * time.Millisecond
+	s := newTestServer(t, cfg)
+	alice := s.dial(t, "alice")
+
+	start := time.Now()
+	alice.send("join", nil)
+	alice.expect("waiting", nil)
+	var timedOut struct {
+		Waited float64 `json:"waited"`
+	}
+	alice.expect("noOpponentFound", &timedOut)
+	if elapsed := time.Since(start); elapsed < cfg.WaitingTimeout {
+		t.Errorf("timed out after %s, before the configured %s", elapsed, cfg.WaitingTimeout)
+	}
+	if timedOut.Waited != cfg.WaitingTimeout.Seconds() {
+		t.Errorf("told they waited %.2fs, want %.2fs", timedOut.Waited, cfg.WaitingTimeout.Seconds())
+	}
+
+	// Alice left the queue, so bob waits in her place
+	bob := s.dial(t, "bob")
+	bob.send("join", nil)
+	bob.expect("waiting", nil)
+}
+
+func TestOpponentCancelsWaitingTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.WaitingTimeout = 200 * time.Millisecond
+	s := newTestServer(t, cfg)
+
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	time.Sleep(2 * cfg.WaitingTimeout)
+	play(t, white, black, gameID, "e4")
+	white.expectNone("noOpponentFound")
+}
+
+func TestWaiterLeavingCancelsTimeout(t *testing.T) {
+	cfg := testConfig()
+	cfg.WaitingTimeout = 200 * time.Millisecond
+	s := newTestServer(t, cfg)
+
+	alice := s.dial(t, "alice")
+	alice.send("join", nil)
+	alice.expect("waiting", nil)
+	alice.conn.Close()
+
+	// Bob isn't matched with the departed alice
+	bob := s.dial(t, "bob")
+	bob.send("join", nil)
+	bob.expect("waiting", nil)
+	carol := s.dial(t, "carol")
+	carol.send("join", nil)
+	carol.expect("gameStart", nil)
+}
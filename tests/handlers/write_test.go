@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailedWriteDropsConnection(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+
+	white.send("move", map[string]any{"gameId": gameID, "move": "e4"})
+	white.expect("move", nil)
+	black.expect("move", nil)
+
+	// White's peer vanishes without closing the connection, so the server
+	// only finds out when relaying black's reply fails
+	s.breakWrites(t, white.user)
+	black.send("move", map[string]any{"gameId": gameID, "move": "e5"})
+	black.expect("opponentDisconnected", nil)
+
+	_ = white.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := white.conn.ReadMessage(); err == nil {
+		t.Error("server kept the connection open after a failed write")
+	}
+}
+
+func TestStalledPeerDoesntHoldUpOthers(t *testing.T) {
+	s := newTestServer(t, testConfig())
+	white, black, gameID := s.startGame(t, "alice", "bob")
+	other1, other2, otherID := s.startGame(t, "carol", "dave")
+
+	// Black stops reading; writes to them hang instead of failing
+	s.stallWrites(t, black.user)
+	white.send("move", map[string]any{"gameId": gameID, "move": "e4"})
+	white.expect("move", nil)
+
+	// Nothing waits on black meanwhile, in their game or any other
+	start := time.Now()
+	play(t, other1, other2, otherID, "d4", "d5")
+	white.send("chat", map[string]any{"gameId": gameID, "message": "still there?"})
+	white.expect("chat", nil)
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("other messages took %s with a peer stalled", waited)
+	}
+
+	// Once black falls too far behind they're dropped, long before a
+	// write to them would time out. White reads each echo as it goes, so
+	// only black falls behind.
+	dropped := false
+	for i := 0; i < 300 && !dropped; i++ {
+		white.send("time_update", map[string]any{"gameId": gameID, "timeLeft": 100})
+		_ = white.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for echoed := false; !echoed && !dropped; {
+			var msg message
+			if err := white.conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("white's connection failed: %v", err)
+			}
+			echoed = msg.Type == "timeUpdate"
+			dropped = msg.Type == "opponentDisconnected"
+		}
+	}
+	_ = white.conn.SetReadDeadline(time.Time{})
+	if !dropped {
+		white.expect("opponentDisconnected", nil)
+	}
+	if waited := time.Since(start); waited > 5*time.Second {
+		t.Errorf("stalled peer dropped after %s", waited)
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"chess-ws-go/internal/config"
+	"chess-ws-go/internal/middleware"
+)
+
+func cidrs(t *testing.T, ranges ...string) []*net.IPNet {
+	t.Helper()
+	nets := make([]*net.IPNet, len(ranges))
+	for i, r := range ranges {
+		_, ipNet, err := net.ParseCIDR(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nets[i] = ipNet
+	}
+	return nets
+}
+
+func TestIPFilterOnAuthMiddleware(t *testing.T) {
+	filter := middleware.NewIPFilter(
+		cidrs(t, "10.0.0.0/8", "fd00::/8"),
+		cidrs(t, "203.0.113.0/24", "2001:db8:bad::/48", "10.6.6.0/24"),
+	)
+	router := newRouter(middleware.AuthMiddleware(&config.JWTConfig{SecretKey: strings.Repeat("k", 32)}, filter))
+
+	// statuses returns the codes of six unauthenticated requests in a row
+	statuses := func(remoteAddr string) []int {
+		codes := make([]int, 6)
+		for i := range codes {
+			codes[i] = get(router, remoteAddr, nil).Code
+		}
+		return codes
+	}
+
+	for _, denied := range []string{"203.0.113.9:1", "[2001:db8:bad::1]:1", "10.6.6.6:1"} {
+		if code := statuses(denied)[0]; code != http.StatusForbidden {
+			t.Errorf("%s (denied, even if allowlisted) got %d, want 403", denied, code)
+		}
+	}
+	for _, allowed := range []string{"10.1.2.3:1", "[fd00::1]:1", "[::ffff:10.1.2.4]:1"} {
+		if code := statuses(allowed)[5]; code != http.StatusUnauthorized {
+			t.Errorf("%s (allowlisted) got %d on its sixth attempt, want it never rate limited", allowed, code)
+		}
+	}
+	for _, neutral := range []string{"192.0.2.1:1", "[2001:db8::1]:1"} {
+		codes := statuses(neutral)
+		if codes[0] != http.StatusUnauthorized || codes[5] != http.StatusTooManyRequests {
+			t.Errorf("%s (neutral) got %v, want 401 until rate limited", neutral, codes)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/config"
+	"chess-ws-go/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newRouter serves 200 on /ping behind the given middleware
+func newRouter(handlers ...gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.GET("/ping", append(handlers, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})...)
+	return router
+}
+
+// get requests /ping from an address
+func get(router http.Handler, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.RemoteAddr = remoteAddr
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimitBoundary(t *testing.T) {
+	router := newRouter(middleware.RateLimit(rate.Every(200*time.Millisecond), 3))
+
+	for i := 1; i <= 3; i++ {
+		if rec := get(router, "192.0.2.1:1234", nil); rec.Code != http.StatusOK {
+			t.Fatalf("request %d within the burst got %d", i, rec.Code)
+		}
+	}
+	rec := get(router, "192.0.2.1:1234", nil)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("request past the burst got %d with Retry-After %q, want 429 retrying in 1s", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// Other clients have their own allowance
+	if rec := get(router, "192.0.2.2:1234", nil); rec.Code != http.StatusOK {
+		t.Errorf("another client got %d", rec.Code)
+	}
+
+	// Rejected requests don't push the window out, so a retry once it's
+	// passed succeeds
+	get(router, "192.0.2.1:1234", nil)
+	time.Sleep(250 * time.Millisecond)
+	if rec := get(router, "192.0.2.1:1234", nil); rec.Code != http.StatusOK {
+		t.Errorf("retry after the window got %d", rec.Code)
+	}
+}
+
+func TestRetryAfterOnAuthMiddleware(t *testing.T) {
+	cfg := &config.JWTConfig{SecretKey: strings.Repeat("k", 32)}
+	router := newRouter(middleware.AuthMiddleware(cfg, nil))
+
+	// Five attempts a minute; the sixth is told to wait for the next one
+	for i := 1; i <= 5; i++ {
+		if rec := get(router, "192.0.2.1:1234", nil); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d got %d, want 401 for the missing token", i, rec.Code)
+		}
+	}
+	rec := get(router, "192.0.2.1:1234", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("attempt 6 got %d, want 429", rec.Code)
+	}
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 55 || retryAfter > 60 {
+		t.Errorf("Retry-After %q, want about a minute", rec.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/middleware"
+
+	"golang.org/x/time/rate"
+)
+
+func TestRealIP(t *testing.T) {
+	proxies := cidrs(t, "10.0.0.0/8")
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		want       string
+	}{
+		{"direct", "198.51.100.7:4000", "", "", "198.51.100.7"},
+		{"spoofed forwarded for", "198.51.100.7:4000", "1.2.3.4", "", "198.51.100.7"},
+		{"spoofed real IP", "198.51.100.7:4000", "", "1.2.3.4", "198.51.100.7"},
+		{"through a proxy", "10.0.0.1:4000", "198.51.100.7", "", "198.51.100.7"},
+		{"client prepends a fake hop", "10.0.0.1:4000", "1.2.3.4, 198.51.100.7", "", "198.51.100.7"},
+		{"through two proxies", "10.0.0.1:4000", "198.51.100.7, 10.0.0.2", "", "198.51.100.7"},
+		{"malformed hop", "10.0.0.1:4000", "garbage, 10.0.0.2", "", "10.0.0.1"},
+		{"proxy sets real IP", "10.0.0.1:4000", "", "198.51.100.7", "198.51.100.7"},
+		{"IPv6 through a proxy", "10.0.0.1:4000", "2001:db8::7", "", "2001:db8::7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := middleware.RealIP(req, proxies); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpoofedForwardedForDoesntEvadeRateLimit(t *testing.T) {
+	router := newRouter(middleware.RateLimit(rate.Every(time.Minute), 1))
+	if err := middleware.TrustProxies(router, cidrs(t, "10.0.0.0/8")); err != nil {
+		t.Fatalf("TrustProxies: %v", err)
+	}
+
+	get(router, "198.51.100.7:4000", nil)
+	spoofed := http.Header{"X-Forwarded-For": {"1.2.3.4"}}
+	if rec := get(router, "198.51.100.7:4000", spoofed); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("a client claiming another address got %d, want 429", rec.Code)
+	}
+
+	// Behind a trusted proxy, each forwarded client has its own allowance
+	for _, client := range []string{"198.51.100.8", "198.51.100.9"} {
+		if rec := get(router, "10.0.0.1:4000", http.Header{"X-Forwarded-For": {client}}); rec.Code != http.StatusOK {
+			t.Errorf("%s through the proxy got %d", client, rec.Code)
+		}
+	}
+}
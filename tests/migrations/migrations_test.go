@@ -0,0 +1,130 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/migrations"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// latestVersion returns the version of the newest migration, as many as
+// there are up migrations in the directory
+func latestVersion(t *testing.T) int {
+	t.Helper()
+	files, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	return len(files)
+}
+
+// appliedUpTo returns the schema_migrations rows of a database migrated up
+// to a version
+func appliedUpTo(version int) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"version"})
+	for v := 1; v <= version; v++ {
+		rows.AddRow(v)
+	}
+	return rows
+}
+
+func TestUpHoldsLockWhileMigrating(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+	latest := latestVersion(t)
+
+	// Expectations are met in order, so all the work happens under the lock
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT version FROM schema_migrations`).WillReturnRows(appliedUpTo(latest - 1))
+	mock.ExpectBegin()
+	mock.ExpectExec(`.+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations`).WithArgs(latest, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := migrations.Up(context.Background(), db); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpReleasesLockAfterFailedMigration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+	latest := latestVersion(t)
+
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT version FROM schema_migrations`).WillReturnRows(appliedUpTo(latest - 1))
+	mock.ExpectBegin()
+	mock.ExpectExec(`.+`).WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := migrations.Up(context.Background(), db); err == nil {
+		t.Fatal("Up succeeded despite the failed migration")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpFailsWithoutLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	// Nothing else is run if the lock can't be had
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(sqlmock.AnyArg()).WillReturnError(errors.New("connection reset"))
+
+	if err := migrations.Up(context.Background(), db); err == nil {
+		t.Fatal("Up succeeded without the lock")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRatingDefaultsMatchDefaultRating(t *testing.T) {
+	files, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	column := regexp.MustCompile(`(\w+_rating) INTEGER NOT NULL DEFAULT (\d+)`)
+	found := 0
+	for _, file := range files {
+		body, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range column.FindAllStringSubmatch(string(body), -1) {
+			found++
+			if match[2] != strconv.Itoa(models.DefaultRating) {
+				t.Errorf("%s: users.%s defaults to %s, want %d", filepath.Base(file), match[1], match[2], models.DefaultRating)
+			}
+		}
+	}
+	if found == 0 {
+		t.Fatal("no rating columns found")
+	}
+}
@@ -0,0 +1,31 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"chess-ws-go/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestBetweenFiltersPrivateGames(t *testing.T) {
+	for _, includePrivate := range []bool{false, true} {
+		db, mock := newMockDB(t)
+		games := repositories.NewSQLGameRepository(db)
+
+		mock.ExpectQuery(`FROM games\s+WHERE \(\(white_id = \$1 AND black_id = \$2\) OR \(white_id = \$2 AND black_id = \$1\)\)\s+AND \(is_private = FALSE OR \$3\)`).
+			WithArgs("a", "b", includePrivate).
+			WillReturnRows(sqlmock.NewRows([]string{"wins", "losses", "draws"}).AddRow(2, 1, 0))
+		if _, err := games.CountBetween(context.Background(), "a", "b", includePrivate); err != nil {
+			t.Fatalf("CountBetween: %v", err)
+		}
+
+		mock.ExpectQuery(`SELECT \* FROM games\s+WHERE \(\(white_id = \$1 AND black_id = \$2\) OR \(white_id = \$2 AND black_id = \$1\)\)\s+AND \(is_private = FALSE OR \$3\)\s+ORDER BY ended_at DESC\s+LIMIT \$4`).
+			WithArgs("a", "b", includePrivate, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "white_id", "black_id", "is_private"}).AddRow("g1", "a", "b", includePrivate))
+		if _, err := games.ListBetween(context.Background(), "a", "b", includePrivate, 10); err != nil {
+			t.Fatalf("ListBetween: %v", err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"chess-ws-go/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpdateRatingsTxRollsBackOnFailedWrite(t *testing.T) {
+	users, mock := newSQLUsers(t)
+	errWrite := errors.New("connection reset")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM users WHERE id IN \(\$1, \$2\) ORDER BY id FOR UPDATE`).
+		WithArgs("white", "black").
+		WillReturnRows(userRows(
+			models.User{ID: "black", EloRating: 1500},
+			models.User{ID: "white", EloRating: 1500},
+		))
+	// White's rating is written, then the connection fails on black's
+	mock.ExpectExec(`UPDATE users SET`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET`).WillReturnError(errWrite)
+	mock.ExpectRollback()
+
+	err := users.UpdateRatingsTx(context.Background(), "white", "black", func(white, black *models.User) error {
+		white.EloRating, black.EloRating = 1516, 1484
+		return nil
+	})
+	if !errors.Is(err, errWrite) {
+		t.Errorf("got error %v, want the failed write", err)
+	}
+}
+
+func TestUpdateRatingsTxRollsBackWhenApplyFails(t *testing.T) {
+	users, mock := newSQLUsers(t)
+	errApply := errors.New("rating out of range")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM users WHERE id IN`).
+		WithArgs("white", "black").
+		WillReturnRows(userRows(models.User{ID: "black"}, models.User{ID: "white"}))
+	mock.ExpectRollback()
+
+	err := users.UpdateRatingsTx(context.Background(), "white", "black", func(white, black *models.User) error {
+		return errApply
+	})
+	if !errors.Is(err, errApply) {
+		t.Errorf("got error %v, want apply's", err)
+	}
+}
+
+func TestUpdateRatingsTxCommitsBothRatings(t *testing.T) {
+	users, mock := newSQLUsers(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM users WHERE id IN`).
+		WithArgs("white", "black").
+		WillReturnRows(userRows(models.User{ID: "black", Version: 3}, models.User{ID: "white", Version: 7}))
+	mock.ExpectExec(`UPDATE users SET`).WithArgs(append(anyArgs(6), "white")...).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET`).WithArgs(append(anyArgs(6), "black")...).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var white, black *models.User
+	err := users.UpdateRatingsTx(context.Background(), "white", "black", func(w, b *models.User) error {
+		white, black = w, b
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateRatingsTx: %v", err)
+	}
+	if white.Version != 8 || black.Version != 4 {
+		t.Errorf("versions after commit: white %d, black %d", white.Version, black.Version)
+	}
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+)
+
+// newSQLUsers returns a SQL user repository over a mock database. The test
+// fails if any query it expects wasn't made.
+func newSQLUsers(t *testing.T) (repositories.UserRepository, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock := newMockDB(t)
+	return repositories.NewSQLUserRepository(db), mock
+}
+
+func newMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return sqlx.NewDb(db, "postgres"), mock
+}
+
+// userRows returns the rows a SELECT * on users gives for the users,
+// limited to the columns the tests look at
+func userRows(users ...models.User) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "username", "email", "elo_rating", "placement_games_remaining", "version"})
+	for _, user := range users {
+		rows.AddRow(user.ID, user.Username, user.Email, user.EloRating, user.PlacementGamesRemaining, user.Version)
+	}
+	return rows
+}
+
+// anyArgs matches a query's arguments whatever they are
+func anyArgs(n int) []driver.Value {
+	args := make([]driver.Value, n)
+	for i := range args {
+		args[i] = sqlmock.AnyArg()
+	}
+	return args
+}
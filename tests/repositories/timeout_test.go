@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/repositories"
+)
+
+func TestSlowQueryCancelledAtDeadline(t *testing.T) {
+	repositories.SetQueryTimeout(50 * time.Millisecond)
+	t.Cleanup(func() { repositories.SetQueryTimeout(5 * time.Second) })
+	users, mock := newSQLUsers(t)
+
+	mock.ExpectQuery(`SELECT \* FROM users WHERE id = \$1`).WithArgs("u1").
+		WillDelayFor(time.Minute).
+		WillReturnRows(userRows())
+
+	start := time.Now()
+	_, err := users.GetByID(context.Background(), "u1")
+	if err == nil {
+		t.Fatal("a query past its deadline succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("query returned after %s, want it cancelled at the 50ms deadline", elapsed)
+	}
+}
+
+func TestCallerDeadlineWins(t *testing.T) {
+	repositories.SetQueryTimeout(time.Minute)
+	t.Cleanup(func() { repositories.SetQueryTimeout(5 * time.Second) })
+	users, mock := newSQLUsers(t)
+
+	mock.ExpectQuery(`SELECT \* FROM users WHERE id = \$1`).WithArgs("u1").
+		WillDelayFor(time.Minute).
+		WillReturnRows(userRows())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := users.GetByID(ctx, "u1"); err == nil {
+		t.Fatal("a query past its caller's deadline succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("query returned after %s, want it cancelled at the caller's deadline", elapsed)
+	}
+}
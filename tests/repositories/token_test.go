@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// recent is a sqlmock.Argument matching a time within a second of now, the
+// expiry cutoff a token lookup passes
+type recent struct{}
+
+func (recent) Match(v driver.Value) bool {
+	at, ok := v.(time.Time)
+	return ok && time.Since(at).Abs() < time.Second
+}
+
+func TestTokenLookupsFilterExpiredInSQL(t *testing.T) {
+	lookups := []struct {
+		name   string
+		column string
+		get    func(repositories.UserRepository, string) (*models.User, error)
+	}{
+		{"verification", "verification_token", func(users repositories.UserRepository, token string) (*models.User, error) {
+			return users.GetByVerificationToken(context.Background(), token)
+		}},
+		{"password reset", "password_reset_token", func(users repositories.UserRepository, token string) (*models.User, error) {
+			return users.GetByPasswordResetToken(context.Background(), token)
+		}},
+	}
+	for _, lookup := range lookups {
+		t.Run(lookup.name, func(t *testing.T) {
+			users, mock := newSQLUsers(t)
+			query := `WHERE ` + lookup.column + ` = \$1 AND ` + lookup.column + `_expires_at > \$2`
+
+			mock.ExpectQuery(query).WithArgs("valid", recent{}).
+				WillReturnRows(userRows(models.User{ID: "u1"}))
+			user, err := lookup.get(users, "valid")
+			if err != nil || user.ID != "u1" {
+				t.Errorf("valid token: got %v, %v", user, err)
+			}
+
+			// The database returns nothing for an expired or unknown token
+			mock.ExpectQuery(query).WithArgs("expired", recent{}).
+				WillReturnRows(userRows())
+			if _, err := lookup.get(users, "expired"); !errors.Is(err, repositories.ErrTokenNotFound) {
+				t.Errorf("expired token: got error %v, want ErrTokenNotFound", err)
+			}
+
+			// An empty token never reaches the database
+			if _, err := lookup.get(users, ""); !errors.Is(err, repositories.ErrTokenNotFound) {
+				t.Errorf("empty token: got error %v, want ErrTokenNotFound", err)
+			}
+		})
+	}
+}
+
+func TestClearExpiredUserTokensOnlyClearsLapsedOnes(t *testing.T) {
+	users, mock := newSQLUsers(t)
+
+	// Each token is cleared on its own expiry, so a user with a lapsed
+	// verification token keeps a live password reset token
+	mock.ExpectExec(`UPDATE users SET\s+` +
+		`verification_token = CASE WHEN verification_token_expires_at < \$1 THEN '' ELSE verification_token END,.*` +
+		`password_reset_token = CASE WHEN password_reset_token_expires_at < \$1 THEN '' ELSE password_reset_token END,.*` +
+		`pending_email = CASE WHEN email_change_token_expires_at < \$1 THEN '' ELSE pending_email END,.*` +
+		`email_change_token = CASE WHEN email_change_token_expires_at < \$1 THEN '' ELSE email_change_token END,.*` +
+		`version = version \+ 1\s+` +
+		`WHERE verification_token_expires_at < \$1\s+OR password_reset_token_expires_at < \$1\s+OR email_change_token_expires_at < \$1`).
+		WithArgs(recent{}).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	cleared, err := users.ClearExpiredUserTokens(context.Background())
+	if err != nil || cleared != 3 {
+		t.Errorf("got %d, %v, want 3 users cleared", cleared, err)
+	}
+}
+
+func TestClearExpiredUserTokensReportsErrors(t *testing.T) {
+	users, mock := newSQLUsers(t)
+	failure := errors.New("connection reset")
+	mock.ExpectExec(`UPDATE users SET`).WillReturnError(failure)
+
+	if _, err := users.ClearExpiredUserTokens(context.Background()); !errors.Is(err, failure) {
+		t.Errorf("got error %v, want %v", err, failure)
+	}
+}
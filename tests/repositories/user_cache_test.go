@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+)
+
+// countingUserRepo serves one user and counts the lookups that reach it
+type countingUserRepo struct {
+	repositories.UserRepository
+	user    models.User
+	lookups int
+}
+
+func (r *countingUserRepo) GetByID(ctx context.Context, id string) (*models.User, error) {
+	r.lookups++
+	if id != r.user.ID {
+		return nil, repositories.ErrUserNotFound
+	}
+	user := r.user
+	return &user, nil
+}
+
+func (r *countingUserRepo) Update(ctx context.Context, user *models.User) error {
+	r.user = *user
+	return nil
+}
+
+func (r *countingUserRepo) ClearExpiredUserTokens(ctx context.Context) (int64, error) {
+	r.user.VerificationToken = ""
+	return 1, nil
+}
+
+func TestCachedUserRepositoryEvictsOnWrites(t *testing.T) {
+	inner := &countingUserRepo{user: models.User{ID: "u1", EloRating: 1500, VerificationToken: "lapsed"}}
+	repo := repositories.NewCachedUserRepository(inner, 10, time.Minute)
+	ctx := context.Background()
+
+	user, err := repo.GetByID(ctx, "u1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	user.EloRating = 1600
+	if err := repo.Update(ctx, user); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if user, _ := repo.GetByID(ctx, "u1"); user.EloRating != 1600 {
+		t.Errorf("rating after Update: got %d, want 1600", user.EloRating)
+	}
+
+	if _, err := repo.ClearExpiredUserTokens(ctx); err != nil {
+		t.Fatalf("ClearExpiredUserTokens: %v", err)
+	}
+	if user, _ := repo.GetByID(ctx, "u1"); user.VerificationToken != "" {
+		t.Errorf("cache kept the cleared token %q", user.VerificationToken)
+	}
+	if inner.lookups != 3 {
+		t.Errorf("lookups reached the repository %d times, want 3", inner.lookups)
+	}
+}
+
+func TestCachedUserRepositoryReadPrimarySkipsCache(t *testing.T) {
+	inner := &countingUserRepo{user: models.User{ID: "u1", EloRating: 1500}}
+	repo := repositories.NewCachedUserRepository(inner, 10, time.Minute)
+	ctx := context.Background()
+
+	if _, err := repo.GetByID(ctx, "u1"); err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, "u1"); err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if inner.lookups != 1 {
+		t.Fatalf("cached lookups reached the repository %d times, want 1", inner.lookups)
+	}
+
+	inner.user.EloRating = 1600
+	user, err := repo.GetByID(repositories.ReadPrimary(ctx), "u1")
+	if err != nil {
+		t.Fatalf("GetByID from primary: %v", err)
+	}
+	if inner.lookups != 2 || user.EloRating != 1600 {
+		t.Errorf("primary read: lookups %d, rating %d; want 2 and 1600", inner.lookups, user.EloRating)
+	}
+}
@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestUpdateBumpsVersion(t *testing.T) {
+	users, mock := newSQLUsers(t)
+
+	mock.ExpectExec(`UPDATE users SET .* WHERE id = \$29 AND version = \$30`).
+		WithArgs(append(anyArgs(28), "u1", 4)...).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	user := &models.User{ID: "u1", Version: 4}
+	if err := users.Update(context.Background(), user); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if user.Version != 5 {
+		t.Errorf("version %d after update, want 5", user.Version)
+	}
+}
+
+func TestUpdateWithStaleVersionConflicts(t *testing.T) {
+	users, mock := newSQLUsers(t)
+
+	mock.ExpectExec(`UPDATE users SET`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	user := &models.User{ID: "u1", Version: 4}
+	if err := users.Update(context.Background(), user); !errors.Is(err, repositories.ErrUserConflict) {
+		t.Errorf("got error %v, want ErrUserConflict", err)
+	}
+	if user.Version != 4 {
+		t.Errorf("version bumped to %d by a failed update", user.Version)
+	}
+}
+
+func TestUpdateOfMissingUserIsNotFound(t *testing.T) {
+	users, mock := newSQLUsers(t)
+
+	mock.ExpectExec(`UPDATE users SET`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS`).WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	if err := users.Update(context.Background(), &models.User{ID: "u1"}); !errors.Is(err, repositories.ErrUserNotFound) {
+		t.Errorf("got error %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestCreateDuplicateIsAlreadyExists(t *testing.T) {
+	users, mock := newSQLUsers(t)
+
+	mock.ExpectExec(`INSERT INTO users`).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "users_username_key"})
+
+	err := users.Create(context.Background(), &models.User{Username: "alice"})
+	if !errors.Is(err, repositories.ErrUserAlreadyExists) {
+		t.Errorf("got error %v, want ErrUserAlreadyExists", err)
+	}
+}
+
+func TestCreateKeepsOtherErrors(t *testing.T) {
+	users, mock := newSQLUsers(t)
+	errCheck := &pq.Error{Code: "23514", Constraint: "users_elo_rating_check"}
+
+	mock.ExpectExec(`INSERT INTO users`).WillReturnError(errCheck)
+
+	if err := users.Create(context.Background(), &models.User{Username: "alice"}); !errors.Is(err, errCheck) {
+		t.Errorf("got error %v, want the check violation", err)
+	}
+}
+
+func TestLookupsIgnoreCase(t *testing.T) {
+	users, mock := newSQLUsers(t)
+	alice := models.User{ID: "u1", Username: "alice", Email: "alice@example.com"}
+
+	mock.ExpectQuery(`WHERE LOWER\(username\) = LOWER\(\$1\)`).WithArgs("ALICE").
+		WillReturnRows(userRows(alice))
+	mock.ExpectQuery(`WHERE LOWER\(email\) = LOWER\(\$1\)`).WithArgs("Alice@Example.com").
+		WillReturnRows(userRows(alice))
+
+	if user, err := users.GetByUsername(context.Background(), "ALICE"); err != nil || user.ID != "u1" {
+		t.Errorf("GetByUsername: %v, %v", user, err)
+	}
+	if user, err := users.GetByEmail(context.Background(), "Alice@Example.com"); err != nil || user.ID != "u1" {
+		t.Errorf("GetByEmail: %v, %v", user, err)
+	}
+}
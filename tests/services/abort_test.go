@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestEarlyEndsAbortedBelowThreshold(t *testing.T) {
+	tests := []struct {
+		name        string
+		moves       string
+		end         func(gs *services.GameService, gameID string, users *memUsers) error
+		wantOutcome string
+		wantMethod  string
+	}{
+		{
+			name:  "resigned after one ply",
+			moves: "e4",
+			end: func(gs *services.GameService, gameID string, users *memUsers) error {
+				return gs.ResignGame(gameID, chess.Black, context.Background(), users)
+			},
+			wantOutcome: services.OutcomeNone,
+			wantMethod:  services.MethodAborted,
+		},
+		{
+			name: "abandoned before a move",
+			end: func(gs *services.GameService, gameID string, users *memUsers) error {
+				_, err := gs.AbandonGame(gameID, chess.White, context.Background(), users)
+				return err
+			},
+			wantOutcome: services.OutcomeNone,
+			wantMethod:  services.MethodAborted,
+		},
+		{
+			name:  "drawn after one ply",
+			moves: "e4",
+			end: func(gs *services.GameService, gameID string, users *memUsers) error {
+				if err := gs.OfferDraw(gameID, chess.Black); err != nil {
+					return err
+				}
+				return gs.AcceptDraw(gameID, chess.White, context.Background(), users)
+			},
+			wantOutcome: services.OutcomeNone,
+			wantMethod:  services.MethodAborted,
+		},
+		{
+			name:  "resigned at the threshold",
+			moves: "e4 e5",
+			end: func(gs *services.GameService, gameID string, users *memUsers) error {
+				return gs.ResignGame(gameID, chess.Black, context.Background(), users)
+			},
+			wantOutcome: services.OutcomeWhiteWon,
+			wantMethod:  services.MethodResignation,
+		},
+		{
+			name:  "abandoned at the threshold",
+			moves: "e4 e5",
+			end: func(gs *services.GameService, gameID string, users *memUsers) error {
+				_, err := gs.AbandonGame(gameID, chess.White, context.Background(), users)
+				return err
+			},
+			wantOutcome: services.OutcomeBlackWon,
+			wantMethod:  services.MethodAbandoned,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newMemUsers(
+				&models.User{ID: "white", EloRating: 1500},
+				&models.User{ID: "black", EloRating: 1500},
+			)
+			gs := services.NewGameService(nil)
+			gs.SetAbortThreshold(2)
+			gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+			for _, move := range strings.Fields(tt.moves) {
+				if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+					t.Fatalf("move %s: %v", move, err)
+				}
+			}
+			if err := tt.end(gs, gameID, users); err != nil {
+				t.Fatalf("ending the game: %v", err)
+			}
+
+			outcome, method, err := gs.Result(gameID)
+			if err != nil {
+				t.Fatalf("Result: %v", err)
+			}
+			if outcome != tt.wantOutcome || method != tt.wantMethod {
+				t.Errorf("got %s by %s, want %s by %s", outcome, method, tt.wantOutcome, tt.wantMethod)
+			}
+			rated := users.user("white").EloRating != 1500
+			if aborted := tt.wantMethod == services.MethodAborted; rated == aborted {
+				t.Errorf("rated %v for a game ending %s by %s", rated, outcome, method)
+			}
+		})
+	}
+}
+
+func TestAbortRefusedOnceThresholdReached(t *testing.T) {
+	gs := services.NewGameService(nil)
+	gs.SetAbortThreshold(2)
+	gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+	for _, move := range []string{"e4", "e5"} {
+		if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+			t.Fatalf("move %s: %v", move, err)
+		}
+	}
+	if err := gs.AbortGame(gameID, "bad pairing", nil, nil); err != services.ErrGameStarted {
+		t.Errorf("AbortGame at the threshold: got %v, want ErrGameStarted", err)
+	}
+}
+
+func TestGameWithoutMovesAbortableWithThresholdOff(t *testing.T) {
+	gs := services.NewGameService(nil)
+	gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+	if err := gs.AbortGame(gameID, services.AbortReasonNoShow, nil, nil); err != nil {
+		t.Fatalf("AbortGame: %v", err)
+	}
+	if outcome, method, _ := gs.Result(gameID); outcome != services.OutcomeNone || method != services.MethodAborted {
+		t.Errorf("got %s by %s, want * by aborted", outcome, method)
+	}
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/auth"
+	"chess-ws-go/internal/config"
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+	"chess-ws-go/internal/services"
+)
+
+// newAuthService returns an auth service over a user repository, usually an
+// in-memory one
+func newAuthService(users repositories.UserRepository) *services.AuthService {
+	return services.NewAuthService(users, &config.JWTConfig{
+		SecretKey:            strings.Repeat("k", 32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+	}, nil)
+}
+
+func TestRegisterMailsVerificationToken(t *testing.T) {
+	mail := make(mailbox, 1)
+	authService := newAuthService(newMemUsers())
+	authService.SetEmailSender(mail)
+
+	user, err := authService.RegisterUser(context.Background(), "alice", "alice@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+
+	email := mail.receive(t)
+	if email.To != "alice@example.com" {
+		t.Errorf("email went to %q", email.To)
+	}
+	if !strings.Contains(email.Body, user.VerificationToken) {
+		t.Errorf("email body %q doesn't carry the verification token", email.Body)
+	}
+}
+
+func TestVerifyEmailTokens(t *testing.T) {
+	later, earlier := time.Now().Add(time.Hour), time.Now().Add(-time.Minute)
+	users := newMemUsers(
+		&models.User{ID: "u1", Username: "alice", VerificationToken: "valid", VerificationTokenExpiresAt: &later},
+		&models.User{ID: "u2", Username: "bob", VerificationToken: "expired", VerificationTokenExpiresAt: &earlier},
+	)
+	authService := newAuthService(users)
+
+	for _, token := range []string{"expired", "unknown", ""} {
+		if err := authService.VerifyEmail(context.Background(), token); !errors.Is(err, services.ErrInvalidToken) {
+			t.Errorf("token %q: got error %v, want ErrInvalidToken", token, err)
+		}
+	}
+	if users.user("u2").IsVerified {
+		t.Error("an expired token verified its user")
+	}
+
+	if err := authService.VerifyEmail(context.Background(), "valid"); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	user := users.user("u1")
+	if !user.IsVerified || user.VerificationToken != "" {
+		t.Errorf("got verified %v with token %q, want verified with the token burnt", user.IsVerified, user.VerificationToken)
+	}
+	if err := authService.VerifyEmail(context.Background(), "valid"); !errors.Is(err, services.ErrInvalidToken) {
+		t.Errorf("reusing the token: got error %v, want ErrInvalidToken", err)
+	}
+}
+
+// registrationRace holds every email lookup until both registrations have
+// made theirs, so both get past the existence check
+type registrationRace struct {
+	*memUsers
+	checked sync.WaitGroup
+}
+
+func (r *registrationRace) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	user, err := r.memUsers.GetByEmail(ctx, email)
+	r.checked.Done()
+	r.checked.Wait()
+	return user, err
+}
+
+func TestConcurrentRegistrationsOneWins(t *testing.T) {
+	users := &registrationRace{memUsers: newMemUsers()}
+	users.checked.Add(2)
+	authService := services.NewAuthService(users, &config.JWTConfig{
+		SecretKey:            strings.Repeat("k", 32),
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+	}, nil)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := authService.RegisterUser(context.Background(), "alice", "alice@example.com", "correct horse battery staple")
+			errs <- err
+		}()
+	}
+
+	var succeeded, existed int
+	for i := 0; i < 2; i++ {
+		switch err := <-errs; {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, services.ErrUserExists):
+			existed++
+		default:
+			t.Errorf("RegisterUser: %v", err)
+		}
+	}
+	if succeeded != 1 || existed != 1 {
+		t.Errorf("%d registrations succeeded and %d got ErrUserExists, want one of each", succeeded, existed)
+	}
+}
+
+// loggedIn registers a verified user and logs them in
+func loggedIn(t *testing.T, authService *services.AuthService, users *memUsers) *auth.TokenPair {
+	t.Helper()
+	hash, err := auth.HashPassword("correct horse battery staple", nil)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	users.users["u1"] = &models.User{ID: "u1", Username: "alice", Email: "alice@example.com", PasswordHash: hash, IsVerified: true}
+
+	tokens, err := authService.Login(context.Background(), "alice", "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return tokens
+}
+
+func TestConcurrentRefreshesExchangeTokenOnce(t *testing.T) {
+	users := newMemUsers()
+	authService := newAuthService(users)
+	tokens := loggedIn(t, authService, users)
+
+	const refreshes = 8
+	results := make(chan error, refreshes)
+	start := make(chan struct{})
+	for i := 0; i < refreshes; i++ {
+		go func() {
+			<-start
+			_, err := authService.RefreshToken(context.Background(), tokens.RefreshToken)
+			results <- err
+		}()
+	}
+	close(start)
+
+	succeeded := 0
+	for i := 0; i < refreshes; i++ {
+		switch err := <-results; {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, services.ErrInvalidToken):
+			t.Errorf("RefreshToken: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d refreshes with one token succeeded, want 1", succeeded)
+	}
+	if sessions, _ := authService.ListSessions(context.Background(), "u1"); len(sessions) != 1 {
+		t.Errorf("the session forked into %d", len(sessions))
+	}
+}
+
+func TestRevokedSessionCantRefresh(t *testing.T) {
+	users := newMemUsers()
+	authService := newAuthService(users)
+	first := loggedIn(t, authService, users)
+	second, err := authService.Login(context.Background(), "alice", "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	sessions, err := authService.ListSessions(context.Background(), "u1")
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("got sessions %+v, %v, want two", sessions, err)
+	}
+	stored, err := users.GetRefreshTokenByToken(context.Background(), first.RefreshToken)
+	if err != nil {
+		t.Fatalf("GetRefreshTokenByToken: %v", err)
+	}
+	if err := authService.RevokeSession(context.Background(), "mallory", stored.ID); !errors.Is(err, services.ErrSessionNotFound) {
+		t.Errorf("revoking someone else's session: got error %v, want ErrSessionNotFound", err)
+	}
+	if err := authService.RevokeSession(context.Background(), "u1", stored.ID); err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+
+	if _, err := authService.RefreshToken(context.Background(), first.RefreshToken); !errors.Is(err, services.ErrInvalidToken) {
+		t.Errorf("refreshing the revoked session: got error %v, want ErrInvalidToken", err)
+	}
+	if _, err := authService.RefreshToken(context.Background(), second.RefreshToken); err != nil {
+		t.Errorf("refreshing the other session: %v", err)
+	}
+}
+
+func TestEmailCasingIsOneAccount(t *testing.T) {
+	users := newMemUsers()
+	authService := newAuthService(users)
+	ctx := context.Background()
+
+	user, err := authService.RegisterUser(ctx, "alice", "  Alice@Example.COM ", "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("RegisterUser: %v", err)
+	}
+	if user.Email != "alice@example.com" {
+		t.Errorf("stored email %q, want it trimmed and lowercased", user.Email)
+	}
+
+	if _, err := authService.RegisterUser(ctx, "bob", "ALICE@example.com", "correct horse battery staple"); err != services.ErrUserExists {
+		t.Errorf("registering the email in other casing: got %v, want ErrUserExists", err)
+	}
+	if _, err := authService.RegisterUser(ctx, "ALICE", "other@example.com", "correct horse battery staple"); err != services.ErrUserExists {
+		t.Errorf("registering the username in other casing: got %v, want ErrUserExists", err)
+	}
+
+	users.users[user.ID].IsVerified = true
+	for _, login := range []string{"alice@example.com", " ALICE@example.Com ", "Alice"} {
+		if _, err := authService.Login(ctx, login, "correct horse battery staple"); err != nil {
+			t.Errorf("Login as %q: %v", login, err)
+		}
+	}
+}
+
+func TestUsernameFormat(t *testing.T) {
+	authService := newAuthService(newMemUsers())
+	authService.SetReservedUsernames([]string{"admin", "system"})
+
+	tests := []struct {
+		username string
+		want     error
+	}{
+		{"alice", nil},
+		{"a.l-i_ce9", nil},
+		{"has space", services.ErrInvalidUsername},
+		{"slash/name", services.ErrInvalidUsername},
+		{"_leading", services.ErrInvalidUsername},
+		{"trailing-", services.ErrInvalidUsername},
+		{"émile", services.ErrInvalidUsername},
+		{"admin", services.ErrReservedUsername},
+		{"ADMIN", services.ErrReservedUsername},
+		{"Ad_min", services.ErrReservedUsername},
+		{"sys.tem", services.ErrReservedUsername},
+		{"administrator", nil},
+	}
+	for i, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			email := fmt.Sprintf("user%d@example.com", i)
+			_, err := authService.RegisterUser(context.Background(), tt.username, email, "correct horse battery staple")
+			if err != tt.want {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+	"chess-ws-go/internal/storage"
+)
+
+// pngOf encodes a solid image of the given size
+func pngOf(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// storedSize decodes a stored PNG and returns its size
+func storedSize(t *testing.T, store *storage.MemoryStore, url string) image.Point {
+	t.Helper()
+	file, ok := store.Get(strings.TrimPrefix(url, "/avatars/"))
+	if !ok {
+		t.Fatalf("nothing stored for %s", url)
+	}
+	if file.ContentType != "image/png" {
+		t.Errorf("%s stored as %s", url, file.ContentType)
+	}
+	img, err := png.Decode(bytes.NewReader(file.Data))
+	if err != nil {
+		t.Fatalf("decode %s: %v", url, err)
+	}
+	return img.Bounds().Size()
+}
+
+func newAvatarService() (*services.AvatarService, *storage.MemoryStore) {
+	store := storage.NewMemoryStore("/avatars")
+	users := newMemUsers(&models.User{ID: "u1", Username: "alice"})
+	return services.NewAvatarService(users, store, 64*1024), store
+}
+
+func TestSetAvatarStoresSquareImageAndThumbnail(t *testing.T) {
+	avatars, store := newAvatarService()
+
+	user, err := avatars.SetAvatar(context.Background(), "u1", pngOf(t, 300, 200))
+	if err != nil {
+		t.Fatalf("SetAvatar: %v", err)
+	}
+	if !strings.HasPrefix(user.AvatarURL, "/avatars/u1/") || !strings.HasPrefix(user.AvatarThumbnailURL, "/avatars/u1/") {
+		t.Fatalf("avatar at %q, thumbnail at %q", user.AvatarURL, user.AvatarThumbnailURL)
+	}
+	if got := storedSize(t, store, user.AvatarURL); got != image.Pt(256, 256) {
+		t.Errorf("avatar is %v, want 256x256", got)
+	}
+	if got := storedSize(t, store, user.AvatarThumbnailURL); got != image.Pt(64, 64) {
+		t.Errorf("thumbnail is %v, want 64x64", got)
+	}
+}
+
+func TestSetAvatarRejectsBadUploads(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"over the byte limit", make([]byte, 64*1024+1), services.ErrAvatarTooLarge},
+		{"not an image", []byte("<svg xmlns='http://www.w3.org/2000/svg'/>"), services.ErrAvatarType},
+		{"truncated PNG", pngOf(t, 10, 10)[:40], services.ErrAvatarType},
+		{"too many pixels", pngOf(t, 5000, 1), services.ErrAvatarTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			avatars, _ := newAvatarService()
+			if _, err := avatars.SetAvatar(context.Background(), "u1", tt.data); err != tt.want {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplacedAvatarFilesDeleted(t *testing.T) {
+	avatars, store := newAvatarService()
+	ctx := context.Background()
+
+	first, err := avatars.SetAvatar(ctx, "u1", pngOf(t, 32, 32))
+	if err != nil {
+		t.Fatalf("SetAvatar: %v", err)
+	}
+	second, err := avatars.SetAvatar(ctx, "u1", pngOf(t, 48, 48))
+	if err != nil {
+		t.Fatalf("SetAvatar: %v", err)
+	}
+	if second.AvatarURL == first.AvatarURL {
+		t.Fatal("a new upload reused the old URL")
+	}
+	for _, url := range []string{first.AvatarURL, first.AvatarThumbnailURL} {
+		if _, ok := store.Get(strings.TrimPrefix(url, "/avatars/")); ok {
+			t.Errorf("%s kept after being replaced", url)
+		}
+	}
+
+	removed, err := avatars.RemoveAvatar(ctx, "u1")
+	if err != nil {
+		t.Fatalf("RemoveAvatar: %v", err)
+	}
+	if removed.AvatarURL != "" || removed.AvatarThumbnailURL != "" {
+		t.Errorf("removed avatar still at %q and %q", removed.AvatarURL, removed.AvatarThumbnailURL)
+	}
+	if _, ok := store.Get(strings.TrimPrefix(second.AvatarURL, "/avatars/")); ok {
+		t.Errorf("%s kept after the avatar was removed", second.AvatarURL)
+	}
+}
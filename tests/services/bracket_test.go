@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+// startKnockout runs a two-player knockout and returns the service and its
+// only match. A zero noShowTimeout leaves the default.
+func startKnockout(t *testing.T, noShowTimeout time.Duration) (*services.GameService, *services.TournamentService, string, *services.BracketMatch) {
+	t.Helper()
+
+	users := newMemUsers(
+		&models.User{ID: "alice", Username: "alice", EloRating: 1500},
+		&models.User{ID: "bob", Username: "bob", EloRating: 1500},
+	)
+	gs := services.NewGameService(nil)
+	ts := services.NewTournamentService(gs, users)
+	if noShowTimeout > 0 {
+		ts.SetNoShowTimeout(noShowTimeout)
+	}
+
+	tournament := ts.CreateTournament("organizer", services.TournamentOptions{
+		Name:        "Cup",
+		Format:      services.FormatKnockout,
+		TimeControl: services.DefaultTimeControl,
+	})
+	for _, id := range []string{"alice", "bob"} {
+		if err := ts.Register(context.Background(), tournament.ID, id); err != nil {
+			t.Fatalf("Register %s: %v", id, err)
+		}
+	}
+	bracket, err := ts.StartBracket(tournament.ID, "organizer")
+	if err != nil {
+		t.Fatalf("StartBracket: %v", err)
+	}
+	return gs, ts, tournament.ID, bracket[0][0]
+}
+
+// matchWinner waits for the game-over hook and returns the match winner
+func matchWinner(t *testing.T, ts *services.TournamentService, tournamentID string) string {
+	t.Helper()
+
+	var winner string
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		tournament, err := ts.GetTournament(tournamentID)
+		if err != nil {
+			t.Fatalf("GetTournament: %v", err)
+		}
+		if winner = tournament.Bracket[0][0].WinnerID; winner != "" {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return winner
+}
+
+func TestBracketNoShowForfeitsWhite(t *testing.T) {
+	gs, ts, tournamentID, match := startKnockout(t, 0)
+
+	if err := gs.AbortGame(match.GameID, services.AbortReasonNoShow, nil, nil); err != nil {
+		t.Fatalf("AbortGame: %v", err)
+	}
+	if winner := matchWinner(t, ts, tournamentID); winner != match.BlackID {
+		t.Errorf("winner = %q, want black %q", winner, match.BlackID)
+	}
+}
+
+func TestBracketOtherAbortsLeftForOrganizer(t *testing.T) {
+	gs, ts, tournamentID, match := startKnockout(t, 0)
+
+	if err := gs.AbortGame(match.GameID, "bad pairing", nil, nil); err != nil {
+		t.Fatalf("AbortGame: %v", err)
+	}
+	if winner := matchWinner(t, ts, tournamentID); winner != "" {
+		t.Errorf("an admin abort decided the match for %q", winner)
+	}
+}
+
+func TestBracketNoShowTimerForfeits(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		moves []string
+		black bool // Whether black wins
+	}{
+		{"white absent", nil, true},
+		{"black absent", []string{"e4"}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gs, ts, tournamentID, match := startKnockout(t, 100*time.Millisecond)
+			for _, move := range tc.moves {
+				if _, err := gs.MakeMove(match.GameID, move, nil, nil); err != nil {
+					t.Fatalf("%s: %v", move, err)
+				}
+			}
+
+			want := match.WhiteID
+			if tc.black {
+				want = match.BlackID
+			}
+			if winner := matchWinner(t, ts, tournamentID); winner != want {
+				t.Errorf("winner = %q, want %q", winner, want)
+			}
+			tournament, _ := ts.GetTournament(tournamentID)
+			if method := tournament.Bracket[0][0].Method; method != services.MatchForfeit {
+				t.Errorf("decided by %q, want a forfeit", method)
+			}
+			if over, _, _, _ := gs.IsGameOver(match.GameID); !over {
+				t.Error("forfeited game still in progress")
+			}
+		})
+	}
+}
+
+func TestBracketNoShowTimerSparesStartedGame(t *testing.T) {
+	gs, ts, tournamentID, match := startKnockout(t, 100*time.Millisecond)
+	for _, move := range []string{"e4", "e5"} {
+		if _, err := gs.MakeMove(match.GameID, move, nil, nil); err != nil {
+			t.Fatalf("%s: %v", move, err)
+		}
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	tournament, _ := ts.GetTournament(tournamentID)
+	if winner := tournament.Bracket[0][0].WinnerID; winner != "" {
+		t.Errorf("a game under way was forfeited to %q", winner)
+	}
+	if over, _, _, _ := gs.IsGameOver(match.GameID); over {
+		t.Error("a game under way was ended")
+	}
+}
+
+func TestForfeitNoShowRefusesStartedGame(t *testing.T) {
+	gs := services.NewGameService(nil)
+	gameID := gs.CreateGame("white", "black")
+	for _, move := range []string{"e4", "e5"} {
+		if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+			t.Fatalf("%s: %v", move, err)
+		}
+	}
+	if _, err := gs.ForfeitNoShow(gameID, context.Background(), nil); err != services.ErrGameStarted {
+		t.Errorf("got %v, want ErrGameStarted", err)
+	}
+	if _, err := gs.ForfeitNoShow("missing", context.Background(), nil); err != services.ErrGameNotFound {
+		t.Errorf("missing game: got %v, want ErrGameNotFound", err)
+	}
+}
+
+func TestForfeitNoShowStoredAsForfeit(t *testing.T) {
+	users := newMemUsers(
+		&models.User{ID: "white", Username: "white", EloRating: 1500, BlitzRating: 1500, RapidRating: 1500},
+		&models.User{ID: "black", Username: "black", EloRating: 1500, BlitzRating: 1500, RapidRating: 1500},
+	)
+	games := newMemGames()
+	gs := services.NewGameService(games)
+	// Games this short are otherwise aborted
+	gs.SetAbortThreshold(2)
+	gameID := gs.CreateGame("white", "black")
+	if _, err := gs.MakeMove(gameID, "e4", nil, nil); err != nil {
+		t.Fatalf("e4: %v", err)
+	}
+
+	absent, err := gs.ForfeitNoShow(gameID, context.Background(), users)
+	if err != nil || absent != chess.Black {
+		t.Fatalf("ForfeitNoShow: %v, %v; want black absent", absent, err)
+	}
+	if outcome, method, _ := gs.Result(gameID); outcome != services.OutcomeWhiteWon || method != services.MethodForfeit {
+		t.Errorf("result %s by %s, want 1-0 by forfeit", outcome, method)
+	}
+	record, err := games.GetByID(context.Background(), gameID)
+	if err != nil {
+		t.Fatalf("forfeit wasn't stored: %v", err)
+	}
+	if record.Outcome != services.OutcomeWhiteWon || record.Method != services.MethodForfeit {
+		t.Errorf("stored %s by %s, want 1-0 by forfeit", record.Outcome, record.Method)
+	}
+	for _, id := range []string{"white", "black"} {
+		if user := users.user(id); user.BlitzRating != 1500 || user.RapidRating != 1500 {
+			t.Errorf("%s was rated for a forfeit: %+v", id, user)
+		}
+	}
+}
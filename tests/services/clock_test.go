@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+// newServerClockGame starts a game whose clocks the server keeps
+func newServerClockGame(t *testing.T) (*services.GameService, string) {
+	t.Helper()
+	gs := services.NewGameService(nil)
+	gs.SetServerClock(true)
+	return gs, gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+}
+
+func timeLeft(t *testing.T, gs *services.GameService, gameID string, color chess.Color) float64 {
+	t.Helper()
+	left, err := gs.TimeLeft(gameID, color)
+	if err != nil {
+		t.Fatalf("TimeLeft: %v", err)
+	}
+	return left
+}
+
+func TestPausedClockDoesntRun(t *testing.T) {
+	gs, gameID := newServerClockGame(t)
+	time.Sleep(50 * time.Millisecond)
+
+	gs.PauseClock(gameID)
+	paused := timeLeft(t, gs, gameID, chess.White)
+	if spent := float64(services.DefaultTimeControl.Initial) - paused; spent < 0.05 {
+		t.Errorf("white spent %.3fs before the pause, want the time on the turn charged", spent)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if left := timeLeft(t, gs, gameID, chess.White); left != paused {
+		t.Errorf("white's clock ran from %.3fs to %.3fs while paused", paused, left)
+	}
+
+	gs.ResumeClock(gameID)
+	time.Sleep(50 * time.Millisecond)
+	if left := timeLeft(t, gs, gameID, chess.White); paused-left < 0.05 {
+		t.Errorf("white's clock went from %.3fs to %.3fs after resuming, want it running", paused, left)
+	}
+	if left := timeLeft(t, gs, gameID, chess.Black); left != float64(services.DefaultTimeControl.Initial) {
+		t.Errorf("black, not to move, has %.3fs left", left)
+	}
+}
+
+func TestAddTimeToRunningServerClock(t *testing.T) {
+	gs, gameID := newServerClockGame(t)
+	initial := float64(services.DefaultTimeControl.Initial)
+	time.Sleep(50 * time.Millisecond)
+
+	// White is to move, so the turn so far still comes off the added time
+	left, err := gs.AddTime(gameID, chess.White, 15)
+	if err != nil {
+		t.Fatalf("AddTime: %v", err)
+	}
+	if left > initial+15-0.05 || left < initial+15-1 {
+		t.Errorf("white has %.3fs after 15s were added, want just under %.0fs", left, initial+15)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if now := timeLeft(t, gs, gameID, chess.White); left-now < 0.05 {
+		t.Errorf("white's clock went from %.3fs to %.3fs, want it still running", left, now)
+	}
+
+	if left, err := gs.AddTime(gameID, chess.Black, 15); err != nil || left != initial+15 {
+		t.Errorf("black, not to move, has %.3fs (%v), want %.0fs", left, err, initial+15)
+	}
+}
+
+func TestAddTimeToClientClock(t *testing.T) {
+	gs := services.NewGameService(nil)
+	gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+	time.Sleep(50 * time.Millisecond)
+
+	want := float64(services.DefaultTimeControl.Initial) + 15
+	if left, err := gs.AddTime(gameID, chess.White, 15); err != nil || left != want {
+		t.Errorf("white has %.3fs (%v), want the reported time plus 15s: %.0fs", left, err, want)
+	}
+}
+
+func TestAddTimeRefused(t *testing.T) {
+	gs := services.NewGameService(nil)
+	correspondence := gs.CreateGameWithTimeControl("white", "black", services.TimeControl{DaysPerMove: 3})
+	if _, err := gs.AddTime(correspondence, chess.White, 15); !errors.Is(err, services.ErrNoClock) {
+		t.Errorf("adding time to a correspondence game: %v, want ErrNoClock", err)
+	}
+
+	finished := gs.CreateGame("white", "black")
+	if err := gs.ResignGame(finished, chess.White, nil, nil); err != nil {
+		t.Fatalf("ResignGame: %v", err)
+	}
+	if _, err := gs.AddTime(finished, chess.Black, 15); !errors.Is(err, services.ErrGameOver) {
+		t.Errorf("adding time to a finished game: %v, want ErrGameOver", err)
+	}
+
+	if _, err := gs.AddTime("missing", chess.White, 15); !errors.Is(err, services.ErrGameNotFound) {
+		t.Errorf("adding time to a missing game: %v, want ErrGameNotFound", err)
+	}
+}
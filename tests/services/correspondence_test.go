@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestCorrespondenceGameSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	store := newMemCorrespondence()
+	before := services.NewGameService(nil)
+	before.SetCorrespondenceRepository(store)
+
+	gameID := before.CreateGameWithTimeControl("white", "black", services.TimeControl{DaysPerMove: 3})
+	if err := before.SetPrivate(gameID); err != nil {
+		t.Fatalf("SetPrivate: %v", err)
+	}
+	for _, move := range []string{"e4", "e5"} {
+		if _, err := before.MakeMove(gameID, move, ctx, nil); err != nil {
+			t.Fatalf("move %s: %v", move, err)
+		}
+	}
+	want, err := before.Snapshot(gameID)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	after := services.NewGameService(nil)
+	after.SetCorrespondenceRepository(store)
+	restored, err := after.RestoreCorrespondenceGames(ctx)
+	if err != nil || restored != 1 {
+		t.Fatalf("RestoreCorrespondenceGames: restored %d, err %v", restored, err)
+	}
+
+	got, err := after.Snapshot(gameID)
+	if err != nil {
+		t.Fatalf("Snapshot after restart: %v", err)
+	}
+	if got.Position != want.Position || got.Turn != want.Turn {
+		t.Errorf("restored position %s (%s to move), want %s (%s to move)", got.Position, got.Turn, want.Position, want.Turn)
+	}
+	if got.WhitePlayer != "white" || got.BlackPlayer != "black" {
+		t.Errorf("restored players %s and %s", got.WhitePlayer, got.BlackPlayer)
+	}
+	if got.MoveDeadline == nil || !got.MoveDeadline.Equal(*want.MoveDeadline) {
+		t.Errorf("restored move deadline %v, want %v", got.MoveDeadline, want.MoveDeadline)
+	}
+	if _, err := after.GetLiveGame(gameID, "stranger"); err != services.ErrGameForbidden {
+		t.Errorf("restored game isn't private: GetLiveGame by a stranger gave %v", err)
+	}
+
+	// Play goes on where it left off, and the stored copy goes once it's over
+	if _, err := after.MakeMove(gameID, "Nf3", ctx, nil); err != nil {
+		t.Fatalf("move after restart: %v", err)
+	}
+	users := newMemUsers(&models.User{ID: "white", EloRating: 1500}, &models.User{ID: "black", EloRating: 1500})
+	if err := after.ResignGame(gameID, chess.Black, ctx, users); err != nil {
+		t.Fatalf("ResignGame: %v", err)
+	}
+	if n := store.stored(); n != 0 {
+		t.Errorf("%d correspondence games still stored after the game ended", n)
+	}
+}
+
+func TestCorrespondenceGameStoredOnceWithOptions(t *testing.T) {
+	store := newMemCorrespondence()
+	gs := services.NewGameService(nil)
+	gs.SetCorrespondenceRepository(store)
+
+	opts := services.GameOptions{Casual: true, Private: true}
+	gameID := gs.CreateGameWithOptions("white", "black", services.TimeControl{DaysPerMove: 3}, opts)
+	if n := store.saved(); n != 1 {
+		t.Errorf("new game stored %d times, want once", n)
+	}
+	games, err := store.List(context.Background())
+	if err != nil || len(games) != 1 {
+		t.Fatalf("List: %d games, err %v", len(games), err)
+	}
+	if game := games[0]; game.ID != gameID || !game.IsPrivate || !game.Casual {
+		t.Errorf("stored %+v, want private and casual from the start", game)
+	}
+}
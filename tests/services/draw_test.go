@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+// openedGame returns a game in which 1. e4 has been played
+func openedGame(t *testing.T) (*services.GameService, string) {
+	t.Helper()
+	gs := services.NewGameService(nil)
+	gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+	if _, err := gs.MakeMove(gameID, "e4", nil, nil); err != nil {
+		t.Fatalf("move e4: %v", err)
+	}
+	return gs, gameID
+}
+
+func TestOnlyOpponentDeclinesDraw(t *testing.T) {
+	gs, gameID := openedGame(t)
+
+	if err := gs.DeclineDraw(gameID, chess.Black); err != services.ErrNoDrawOffer {
+		t.Errorf("declining with no offer: got %v, want ErrNoDrawOffer", err)
+	}
+	if err := gs.OfferDraw(gameID, chess.White); err != nil {
+		t.Fatalf("OfferDraw: %v", err)
+	}
+	if err := gs.DeclineDraw(gameID, chess.White); err != services.ErrOwnDrawOffer {
+		t.Errorf("offerer declining: got %v, want ErrOwnDrawOffer", err)
+	}
+	if err := gs.DeclineDraw(gameID, chess.Black); err != nil {
+		t.Fatalf("opponent declining: %v", err)
+	}
+	if err := gs.AcceptDraw(gameID, chess.Black, nil, nil); err != services.ErrNoDrawOffer {
+		t.Errorf("accepting a declined offer: got %v, want ErrNoDrawOffer", err)
+	}
+}
+
+func TestOwnDeclineDoesntWithdrawOffer(t *testing.T) {
+	gs, gameID := openedGame(t)
+	if err := gs.OfferDraw(gameID, chess.White); err != nil {
+		t.Fatalf("OfferDraw: %v", err)
+	}
+	_ = gs.DeclineDraw(gameID, chess.White)
+	if err := gs.AcceptDraw(gameID, chess.Black, nil, nil); err != nil {
+		t.Errorf("accepting after the offerer's refused decline: %v", err)
+	}
+}
+
+// A draw offered as the opponent moves is either withdrawn by the move or
+// stands for the new position, and only the opponent can answer it
+func TestDrawOfferRacingMove(t *testing.T) {
+	for range 200 {
+		gs, gameID := openedGame(t)
+
+		var wg sync.WaitGroup
+		var offerErr, moveErr, declineErr error
+		wg.Add(3)
+		go func() { defer wg.Done(); offerErr = gs.OfferDraw(gameID, chess.White) }()
+		go func() { defer wg.Done(); _, moveErr = gs.MakeMove(gameID, "e5", nil, nil) }()
+		go func() { defer wg.Done(); declineErr = gs.DeclineDraw(gameID, chess.White) }()
+		wg.Wait()
+
+		if offerErr != nil || moveErr != nil {
+			t.Fatalf("offer: %v, move: %v", offerErr, moveErr)
+		}
+		if declineErr != services.ErrOwnDrawOffer && declineErr != services.ErrNoDrawOffer {
+			t.Fatalf("offerer declining their own offer: got %v", declineErr)
+		}
+
+		err := gs.AcceptDraw(gameID, chess.Black, nil, nil)
+		outcome, method, _ := gs.Result(gameID)
+		switch {
+		case err == nil:
+			// The offer came after the move, so it was made in this position
+			if outcome != services.OutcomeDraw || method != services.MethodDrawAgreement {
+				t.Fatalf("accepted draw ended %s by %s", outcome, method)
+			}
+		case errors.Is(err, services.ErrDrawOfferStale) || errors.Is(err, services.ErrNoDrawOffer):
+			// The move withdrew the offer, and play goes on
+			if outcome != "" {
+				t.Fatalf("refused draw still ended the game %s by %s", outcome, method)
+			}
+			if _, err := gs.MakeMove(gameID, "Nf3", nil, nil); err != nil {
+				t.Fatalf("playing on after the lapsed offer: %v", err)
+			}
+		default:
+			t.Fatalf("AcceptDraw: %v", err)
+		}
+	}
+}
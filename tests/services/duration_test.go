@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+)
+
+func TestOverlongGames(t *testing.T) {
+	gs := services.NewGameService(nil)
+	gs.SetMaxDuration(time.Hour)
+	live := gs.CreateGame("white", "black")
+	correspondence := gs.CreateGameWithTimeControl("white", "black", services.TimeControl{DaysPerMove: 3})
+	over := gs.CreateGame("white", "black")
+	if err := gs.AbortGame(over, services.AbortReasonNoShow, nil, nil); err != nil {
+		t.Fatalf("AbortGame: %v", err)
+	}
+
+	if got := gs.OverlongGames(time.Now()); len(got) != 0 {
+		t.Errorf("fresh games overlong: %v", got)
+	}
+	// Correspondence and finished games are never overlong
+	if got := gs.OverlongGames(time.Now().Add(2 * time.Hour)); !slices.Equal(got, []string{live}) {
+		t.Errorf("overlong after two hours: %v, want only %s (not %s)", got, live, correspondence)
+	}
+
+	gs.SetMaxDuration(0)
+	if got := gs.OverlongGames(time.Now().Add(2 * time.Hour)); len(got) != 0 {
+		t.Errorf("overlong with no cap: %v", got)
+	}
+}
+
+func TestEndOverlongGame(t *testing.T) {
+	tests := []struct {
+		name        string
+		moves       []string
+		wantOutcome string
+		wantMethod  string
+	}{
+		{"drawn", []string{"e4", "e5", "Nf3"}, services.OutcomeDraw, services.MethodMaxDuration},
+		{"aborted with too few moves", []string{"e4"}, services.OutcomeNone, services.MethodAborted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newMemUsers(
+				&models.User{ID: "white", EloRating: 1500},
+				&models.User{ID: "black", EloRating: 1600},
+			)
+			gs := services.NewGameService(nil)
+			gs.SetAbortThreshold(2)
+			gameID := gs.CreateGame("white", "black")
+			for _, move := range tt.moves {
+				if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+					t.Fatalf("move %s: %v", move, err)
+				}
+			}
+
+			if err := gs.EndOverlongGame(gameID, context.Background(), users); err != nil {
+				t.Fatalf("EndOverlongGame: %v", err)
+			}
+			outcome, method, err := gs.Result(gameID)
+			if err != nil || outcome != tt.wantOutcome || method != tt.wantMethod {
+				t.Errorf("got %s by %s (%v), want %s by %s", outcome, method, err, tt.wantOutcome, tt.wantMethod)
+			}
+			// A draw against a stronger opponent gains rating; an abort doesn't
+			rated := users.user("white").EloRating != 1500
+			if aborted := tt.wantMethod == services.MethodAborted; rated == aborted {
+				t.Errorf("rated %v for a game ending %s by %s", rated, outcome, method)
+			}
+
+			if err := gs.EndOverlongGame(gameID, context.Background(), users); !errors.Is(err, services.ErrGameOver) {
+				t.Errorf("ending it again: %v, want ErrGameOver", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+// memAudit is an audit repository with no entries
+type memAudit struct{}
+
+func (memAudit) Create(ctx context.Context, entry *models.AuditEntry) error { return nil }
+
+func (memAudit) List(ctx context.Context, filter repositories.AuditFilter) ([]*models.AuditEntry, error) {
+	return []*models.AuditEntry{}, nil
+}
+
+func TestExportIncludesChatFromStoredGames(t *testing.T) {
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+	alice := &models.User{
+		ID:                         "alice",
+		Username:                   "alice",
+		PasswordHash:               "hash-secret",
+		VerificationToken:          "verify-secret",
+		VerificationTokenExpiresAt: &expires,
+		PasswordResetToken:         "reset-secret",
+		EmailChangeToken:           "change-secret",
+		AvatarKey:                  "avatar-secret",
+	}
+	users := newMemUsers(alice, &models.User{ID: "bob", Username: "bob"})
+	games := newMemGames()
+	gs := services.NewGameService(games)
+
+	gameID := gs.CreateGame("alice", "bob")
+	gs.RecordEvent(gameID, services.EventChat, chess.White, "alice: good luck")
+	gs.RecordEvent(gameID, services.EventChat, chess.Black, "bob: you too")
+	if err := gs.ResignGame(gameID, chess.Black, ctx, users); err != nil {
+		t.Fatalf("ResignGame: %v", err)
+	}
+	gs.ReleaseGame(gameID)
+
+	exports := services.NewExportService(users, games, gs, services.NewAuditLogger(memAudit{}))
+	export, err := exports.Export(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	want := []services.SentChatMessage{{GameID: gameID, Message: "good luck"}}
+	if !reflect.DeepEqual(export.ChatMessages, want) {
+		t.Errorf("chat messages %+v, want %+v", export.ChatMessages, want)
+	}
+
+	data, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("encode export: %v", err)
+	}
+	for _, secret := range []string{"secret", "you too"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("export leaks %q: %s", secret, data)
+		}
+	}
+}
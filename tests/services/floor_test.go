@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+// loseRated has black resign a rated rapid game with both players on the
+// given ratings and returns both players afterwards
+func loseRated(t *testing.T, whiteRating, blackRating int) (white, black models.User) {
+	t.Helper()
+	users := newMemUsers(
+		&models.User{ID: "white", EloRating: whiteRating, RapidRating: whiteRating},
+		&models.User{ID: "black", EloRating: blackRating, RapidRating: blackRating},
+	)
+	gs := services.NewGameService(nil)
+	gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+	for _, move := range []string{"e4", "e5"} {
+		if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+			t.Fatalf("move %s: %v", move, err)
+		}
+	}
+	if err := gs.ResignGame(gameID, chess.Black, context.Background(), users); err != nil {
+		t.Fatalf("ResignGame: %v", err)
+	}
+	return users.user("white"), users.user("black")
+}
+
+func TestRatingFloorBoundary(t *testing.T) {
+	floor := models.RatingFloor
+	tests := []struct {
+		name   string
+		before int
+		check  func(after int) bool
+		want   string
+	}{
+		{"well above the floor", floor + 100, func(after int) bool { return after > floor && after < floor+100 }, "an ordinary loss"},
+		{"a point above the floor", floor + 1, func(after int) bool { return after == floor }, "the floor"},
+		{"at the floor", floor, func(after int) bool { return after == floor }, "the floor"},
+		{"below the floor", floor - 10, func(after int) bool { return after == floor-10 }, "unchanged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, black := loseRated(t, tt.before, tt.before)
+			for _, after := range []int{black.EloRating, black.RapidRating} {
+				if !tt.check(after) {
+					t.Errorf("loser rated %d fell to %d, want %s", tt.before, after, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWinnerBelowFloorStillGains(t *testing.T) {
+	floor := models.RatingFloor
+	white, _ := loseRated(t, floor-10, floor-10)
+	if white.EloRating <= floor-10 {
+		t.Errorf("winner rated %d went to %d, want a gain", floor-10, white.EloRating)
+	}
+}
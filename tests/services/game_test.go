@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestPrivateGameHiddenFromNonParticipants(t *testing.T) {
+	gs := services.NewGameService(nil)
+	gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+	if err := gs.SetPrivate(gameID); err != nil {
+		t.Fatalf("SetPrivate: %v", err)
+	}
+
+	if _, err := gs.GetLiveGame(gameID, "stranger"); err != services.ErrGameForbidden {
+		t.Errorf("GetLiveGame by a stranger: got %v, want ErrGameForbidden", err)
+	}
+	if _, err := gs.GetGameDetails(context.Background(), gameID, "stranger", nil); err != services.ErrGameForbidden {
+		t.Errorf("GetGameDetails by a stranger: got %v, want ErrGameForbidden", err)
+	}
+	for _, player := range []string{"white", "black"} {
+		if _, err := gs.GetLiveGame(gameID, player); err != nil {
+			t.Errorf("GetLiveGame by %s: %v", player, err)
+		}
+	}
+	if games := gs.ListPublicGames(""); len(games) != 0 {
+		t.Errorf("ListPublicGames listed %d private games", len(games))
+	}
+}
+
+func TestWithoutOddsRestoresBaseTimeControl(t *testing.T) {
+	base := services.TimeControl{Initial: 600, Increment: 5}
+	for _, ratings := range [][2]int{{2000, 1500}, {1500, 2000}} {
+		odds := services.WithRatingOdds(base, ratings[0], ratings[1], 200)
+		if !odds.HasOdds() {
+			t.Fatalf("ratings %v: expected odds", ratings)
+		}
+		plain := odds.WithoutOdds()
+		if plain.HasOdds() || plain.Initial != base.Initial || plain.Increment != base.Increment {
+			t.Errorf("ratings %v: WithoutOdds gave %+v, want %+v", ratings, plain, base)
+		}
+	}
+}
+
+func TestGetGameReturnsSnapshot(t *testing.T) {
+	gs := services.NewGameService(nil)
+	gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+
+	before, err := gs.GetGame(gameID)
+	if err != nil {
+		t.Fatalf("GetGame: %v", err)
+	}
+	if _, err := gs.MakeMove(gameID, "e4", nil, nil); err != nil {
+		t.Fatalf("MakeMove: %v", err)
+	}
+	after, err := gs.GetGame(gameID)
+	if err != nil {
+		t.Fatalf("GetGame: %v", err)
+	}
+
+	if len(before.Moves) != 0 || before.Turn != chess.White || before.FEN == after.FEN {
+		t.Errorf("snapshot taken before the move changed with it: %+v", before)
+	}
+	if len(after.Moves) != 1 || after.Moves[0] != "e2e4" || after.Turn != chess.Black || after.Over() {
+		t.Errorf("snapshot after e4 = %+v", after)
+	}
+}
+
+func TestFinishedGameKeepsTimeOdds(t *testing.T) {
+	games := newMemGames()
+	gs := services.NewGameService(games)
+	tc := services.WithRatingOdds(services.TimeControl{Initial: 300, Increment: 2}, 2000, 1500, 200)
+	gameID := gs.CreateGameWithTimeControl("white", "black", tc)
+	users := newMemUsers(&models.User{ID: "white", EloRating: 2000}, &models.User{ID: "black", EloRating: 1500})
+	if err := gs.ResignGame(gameID, chess.Black, context.Background(), users); err != nil {
+		t.Fatalf("ResignGame: %v", err)
+	}
+
+	// A fresh service has only the stored record to go on
+	details, err := services.NewGameService(games).GetGameDetails(context.Background(), gameID, "white", nil)
+	if err != nil {
+		t.Fatalf("GetGameDetails: %v", err)
+	}
+	for _, color := range []chess.Color{chess.White, chess.Black} {
+		if got, want := details.TimeControl.InitialFor(color), tc.InitialFor(color); got != want {
+			t.Errorf("%s initial time: got %v, want %v", color.Name(), got, want)
+		}
+		if got, want := details.TimeControl.IncrementFor(color), tc.IncrementFor(color); got != want {
+			t.Errorf("%s increment: got %v, want %v", color.Name(), got, want)
+		}
+	}
+}
+
+// lockProbe is a user repository whose rating transaction reads from the
+// game service, which deadlocks if ratings are updated under its lock
+type lockProbe struct {
+	*memUsers
+	games  *services.GameService
+	gameID string
+}
+
+func (r *lockProbe) UpdateRatingsTx(ctx context.Context, whiteID string, blackID string, apply func(white, black *models.User) error) error {
+	if _, err := r.games.GetGame(r.gameID); err != nil {
+		return err
+	}
+	return r.memUsers.UpdateRatingsTx(ctx, whiteID, blackID, apply)
+}
+
+func TestRatingsUpdatedOutsideGameLock(t *testing.T) {
+	gs := services.NewGameService(newMemGames())
+	gameID := gs.CreateGame("white", "black")
+	for _, move := range []string{"e4", "e5"} {
+		if _, err := gs.MakeMove(gameID, move, context.Background(), nil); err != nil {
+			t.Fatalf("move %s: %v", move, err)
+		}
+	}
+	users := &lockProbe{
+		memUsers: newMemUsers(&models.User{ID: "white", EloRating: 1500}, &models.User{ID: "black", EloRating: 1500}),
+		games:    gs,
+		gameID:   gameID,
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- gs.ResignGame(gameID, chess.Black, context.Background(), users) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ResignGame: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("ResignGame deadlocked updating ratings under the game lock")
+	}
+	if white := users.user("white"); white.EloRating <= 1500 {
+		t.Errorf("white's rating is %d after black resigned", white.EloRating)
+	}
+}
+
+// Run with -race: readers of a state copy must never share memory with the
+// game the moves and chat are changing
+func TestGameStateCopiesRaceFreeWithMoves(t *testing.T) {
+	gs := services.NewGameService(nil)
+	gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+	moves := strings.Fields("e4 e5 Nf3 Nc6 Bb5 a6 Ba4 Nf6 O-O Be7 Re1 b5 Bb3 d6 c3 O-O")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, move := range moves {
+			if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+				t.Errorf("move %s: %v", move, err)
+				return
+			}
+			if err := gs.AddChatMessage(gameID, "white", move); err != nil {
+				t.Errorf("AddChatMessage: %v", err)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			state, err := gs.GetGameState(gameID)
+			if err != nil {
+				t.Fatalf("GetGameState: %v", err)
+			}
+			if len(state.ChatHistory) != len(moves) || state.CurrentTurn != chess.White {
+				t.Errorf("after the moves: %d chat messages, %s to move", len(state.ChatHistory), state.CurrentTurn)
+			}
+			return
+		default:
+		}
+		state, err := gs.GetGameState(gameID)
+		if err != nil {
+			t.Fatalf("GetGameState: %v", err)
+		}
+		for _, message := range state.ChatHistory {
+			_ = message.Message
+		}
+		for _, event := range state.Events.Events {
+			_ = event.Type
+		}
+	}
+}
+
+func TestMoveObserverTimesEveryMove(t *testing.T) {
+	gs := services.NewGameService(nil)
+	var samples []time.Duration
+	gs.SetMoveObserver(func(d time.Duration) { samples = append(samples, d) })
+	gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+
+	for _, move := range []string{"e4", "e5", "Ke3"} {
+		_, _ = gs.MakeMove(gameID, move, nil, nil)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("got %d samples, want one per move, rejected ones included", len(samples))
+	}
+	for i, d := range samples {
+		if d <= 0 {
+			t.Errorf("sample %d is %s", i, d)
+		}
+	}
+}
+
+func TestMoveAfterCheckmateIsGameOver(t *testing.T) {
+	gs := services.NewGameService(nil)
+	gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+	for _, move := range []string{"f3", "e5", "g4", "Qh4#"} {
+		if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+			t.Fatalf("move %s: %v", move, err)
+		}
+	}
+	before, err := gs.GetGame(gameID)
+	if err != nil {
+		t.Fatalf("GetGame: %v", err)
+	}
+
+	if _, err := gs.MakeMove(gameID, "e4", nil, nil); !errors.Is(err, services.ErrGameOver) {
+		t.Errorf("late move got %v, want ErrGameOver", err)
+	}
+	after, err := gs.GetGame(gameID)
+	if err != nil {
+		t.Fatalf("GetGame: %v", err)
+	}
+	if after.FEN != before.FEN || after.PGN != before.PGN {
+		t.Errorf("late move changed the game to %s", after.FEN)
+	}
+}
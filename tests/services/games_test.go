@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+)
+
+// memGames is an in-memory finished game repository. Methods the tests
+// don't need fall through to the nil embedded interface and panic if called.
+type memGames struct {
+	repositories.GameRepository
+	mu    sync.Mutex
+	games map[string]*models.GameRecord
+}
+
+func newMemGames() *memGames {
+	return &memGames{games: make(map[string]*models.GameRecord)}
+}
+
+func (r *memGames) Create(ctx context.Context, game *models.GameRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *game
+	r.games[game.ID] = &copied
+	return nil
+}
+
+func (r *memGames) GetByID(ctx context.Context, id string) (*models.GameRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	game, ok := r.games[id]
+	if !ok {
+		return nil, repositories.ErrGameNotFound
+	}
+	copied := *game
+	return &copied, nil
+}
+
+// memCorrespondence is an in-memory store of correspondence games in progress
+type memCorrespondence struct {
+	mu    sync.Mutex
+	games map[string]models.CorrespondenceGame
+	saves int
+}
+
+func newMemCorrespondence() *memCorrespondence {
+	return &memCorrespondence{games: make(map[string]models.CorrespondenceGame)}
+}
+
+func (r *memCorrespondence) Save(ctx context.Context, game *models.CorrespondenceGame) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.games[game.ID] = *game
+	r.saves++
+	return nil
+}
+
+func (r *memCorrespondence) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.games, id)
+	return nil
+}
+
+func (r *memCorrespondence) List(ctx context.Context) ([]*models.CorrespondenceGame, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	games := []*models.CorrespondenceGame{}
+	for _, game := range r.games {
+		copied := game
+		games = append(games, &copied)
+	}
+	return games, nil
+}
+
+// stored returns how many games the store holds
+func (r *memCorrespondence) stored() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.games)
+}
+
+// saved returns how many times games were written to the store
+func (r *memCorrespondence) saved() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.saves
+}
+
+func (r *memGames) ListByPlayer(ctx context.Context, userID string, limit int) ([]*models.GameRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	games := []*models.GameRecord{}
+	for _, game := range r.games {
+		if game.WhiteID == userID || game.BlackID == userID {
+			copied := *game
+			games = append(games, &copied)
+		}
+	}
+	return games, nil
+}
+
+// between returns the games two users played each other that the filter
+// lets through; callers must hold r.mu
+func (r *memGames) between(userID string, opponentID string, includePrivate bool) []*models.GameRecord {
+	games := []*models.GameRecord{}
+	for _, game := range r.games {
+		if (game.WhiteID == userID && game.BlackID == opponentID) || (game.WhiteID == opponentID && game.BlackID == userID) {
+			if game.IsPrivate && !includePrivate {
+				continue
+			}
+			copied := *game
+			games = append(games, &copied)
+		}
+	}
+	return games
+}
+
+func (r *memGames) ListBetween(ctx context.Context, userID string, opponentID string, includePrivate bool, limit int) ([]*models.GameRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	games := r.between(userID, opponentID, includePrivate)
+	if len(games) > limit {
+		games = games[:limit]
+	}
+	return games, nil
+}
+
+func (r *memGames) CountBetween(ctx context.Context, userID string, opponentID string, includePrivate bool) (*models.HeadToHead, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var record models.HeadToHead
+	for _, game := range r.between(userID, opponentID, includePrivate) {
+		switch {
+		case game.Outcome == "1/2-1/2":
+			record.Draws++
+		case (game.WhiteID == userID) == (game.Outcome == "1-0"):
+			record.Wins++
+		default:
+			record.Losses++
+		}
+	}
+	return &record, nil
+}
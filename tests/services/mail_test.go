@@ -0,0 +1,29 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/services"
+)
+
+// mailbox is an EmailSender that hands sent emails to the test
+type mailbox chan services.Email
+
+func (m mailbox) Send(ctx context.Context, email services.Email) error {
+	m <- email
+	return nil
+}
+
+// receive waits for the next email
+func (m mailbox) receive(t *testing.T) services.Email {
+	t.Helper()
+	select {
+	case email := <-m:
+		return email
+	case <-time.After(time.Second):
+		t.Fatal("no email was sent")
+		return services.Email{}
+	}
+}
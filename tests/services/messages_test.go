@@ -0,0 +1,69 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chess-ws-go/internal/services"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialSelf returns the client end of a WebSocket to a throwaway server
+func dialSelf(t *testing.T) *websocket.Conn {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err == nil {
+			defer conn.Close()
+			_, _, _ = conn.ReadMessage()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestConnectionInTwoGames(t *testing.T) {
+	gs := services.NewGameService(nil)
+	ms := services.NewMessageService(gs)
+	conn := dialSelf(t)
+	first := gs.CreateGame("alice", "bob")
+	second := gs.CreateGame("carol", "alice")
+
+	ms.AddConnection(conn)
+	ms.AssociateConnection(conn, first)
+	ms.AssociateConnection(conn, second)
+	if n := len(ms.GameIDs(conn)); n != 2 {
+		t.Fatalf("connection is in %d games, want 2", n)
+	}
+	for _, gameID := range []string{first, second} {
+		if state := ms.GetGameState(conn, gameID); state == nil {
+			t.Errorf("no state of game %s for the connection", gameID)
+		}
+	}
+
+	ms.DissociateConnection(conn, first)
+	if ms.InGame(conn, first) || ms.GetGameState(conn, first) != nil {
+		t.Error("connection still in the game it left")
+	}
+	if !ms.InGame(conn, second) {
+		t.Error("leaving one game left the other too")
+	}
+	if n := ms.GetActiveConnectionsCount(); n != 1 {
+		t.Errorf("%d active connections, want 1", n)
+	}
+
+	ms.RemoveConnection(conn)
+	if ms.InGame(conn, second) || ms.GetActiveConnectionsCount() != 0 {
+		t.Error("removed connection still tracked")
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestDisabledPreferenceSuppressesEmail(t *testing.T) {
+	tests := []struct {
+		kind services.Notification
+		off  func(prefs *models.NotificationPreferences)
+	}{
+		{services.NotifyGameInvite, func(prefs *models.NotificationPreferences) { prefs.GameInvites = false }},
+		{services.NotifyGameSummary, func(prefs *models.NotificationPreferences) { prefs.GameSummaries = false }},
+		{services.NotifySecurityAlert, func(prefs *models.NotificationPreferences) { prefs.SecurityAlerts = false }},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			prefs := models.NotificationPreferences{GameInvites: true, GameSummaries: true, SecurityAlerts: true}
+			on := &models.User{ID: "on", Email: "on@example.com", NotificationPreferences: prefs}
+			tt.off(&prefs)
+			off := &models.User{ID: "off", Email: "off@example.com", NotificationPreferences: prefs}
+			mail := make(mailbox, 2)
+			notifier := services.NewNotificationService(newMemUsers(on, off), mail)
+
+			if sent, err := notifier.Notify(context.Background(), "off", tt.kind, "subject", "body"); err != nil || sent {
+				t.Errorf("opted out user: sent %v, err %v", sent, err)
+			}
+			if sent, err := notifier.Notify(context.Background(), "on", tt.kind, "subject", "body"); err != nil || !sent {
+				t.Errorf("opted in user: sent %v, err %v", sent, err)
+			}
+			if email := mail.receive(t); email.To != "on@example.com" {
+				t.Errorf("email went to %s", email.To)
+			}
+			if len(mail) != 0 {
+				t.Errorf("%d emails sent to the user who opted out", len(mail))
+			}
+		})
+	}
+}
+
+func TestGameSummaryOnlyToPlayersWhoWantIt(t *testing.T) {
+	white := &models.User{ID: "white", Email: "white@example.com", EloRating: 1200,
+		NotificationPreferences: models.NotificationPreferences{GameSummaries: true}}
+	black := &models.User{ID: "black", Email: "black@example.com", EloRating: 1200,
+		NotificationPreferences: models.DefaultNotificationPreferences}
+	users := newMemUsers(white, black)
+	mail := make(mailbox, 2)
+	gs := services.NewGameService(newMemGames())
+	gs.SetNotifier(services.NewNotificationService(users, mail))
+
+	gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+	for _, move := range []string{"e4", "e5"} {
+		if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+			t.Fatalf("move %s: %v", move, err)
+		}
+	}
+	if err := gs.ResignGame(gameID, chess.Black, context.Background(), users); err != nil {
+		t.Fatalf("ResignGame: %v", err)
+	}
+
+	email := mail.receive(t)
+	if email.To != "white@example.com" {
+		t.Errorf("summary went to %s, who turned summaries off", email.To)
+	}
+	select {
+	case email := <-mail:
+		t.Errorf("summary also went to %s", email.To)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestPlacementEndsAndKFactorSettles(t *testing.T) {
+	users := newMemUsers(
+		&models.User{ID: "newcomer", EloRating: 1500, RapidRating: 1500, PlacementGamesRemaining: 2},
+		&models.User{ID: "veteran", EloRating: 1500, RapidRating: 1500},
+	)
+	gs := services.NewGameService(nil)
+
+	// The newcomer wins every game, moving by the placement K-factor of 64
+	// while placed and by 32 once established. The veteran's rating only
+	// moves once the newcomer's means something.
+	steps := []struct {
+		newcomer, veteran int
+		remaining         int
+	}{
+		{1532, 1500, 1}, // 64 * (1 - 0.5)
+		{1561, 1500, 0}, // 64 * (1 - 0.546)
+		{1574, 1487, 0}, // 32 * (1 - 0.587)
+	}
+	for i, step := range steps {
+		gameID := gs.CreateGameWithTimeControl("newcomer", "veteran", services.DefaultTimeControl)
+		for _, move := range []string{"e4", "e5"} {
+			if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+				t.Fatalf("game %d, move %s: %v", i+1, move, err)
+			}
+		}
+		if err := gs.ResignGame(gameID, chess.Black, context.Background(), users); err != nil {
+			t.Fatalf("game %d: ResignGame: %v", i+1, err)
+		}
+
+		newcomer, veteran := users.user("newcomer"), users.user("veteran")
+		if newcomer.EloRating != step.newcomer || newcomer.RapidRating != step.newcomer ||
+			veteran.EloRating != step.veteran || veteran.RapidRating != step.veteran {
+			t.Errorf("after game %d: newcomer %d/%d, veteran %d/%d; want %d and %d",
+				i+1, newcomer.EloRating, newcomer.RapidRating, veteran.EloRating, veteran.RapidRating, step.newcomer, step.veteran)
+		}
+		if newcomer.PlacementGamesRemaining != step.remaining || newcomer.Provisional() != (step.remaining > 0) {
+			t.Errorf("after game %d: %d placement games left, want %d", i+1, newcomer.PlacementGamesRemaining, step.remaining)
+		}
+		if veteran.PlacementGamesRemaining != 0 {
+			t.Errorf("after game %d: veteran back in placement with %d games", i+1, veteran.PlacementGamesRemaining)
+		}
+	}
+}
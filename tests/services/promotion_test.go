@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+
+	"chess-ws-go/internal/services"
+)
+
+// toPromotion brings a white pawn to e7 with e8 empty and the bishop on f8
+// to capture, leaving white to move. A knight on e8 checks the king on f6.
+var toPromotion = []string{"f4", "e5", "fxe5", "Ke7", "e6", "Kf6", "e7", "a6"}
+
+func TestPromotion(t *testing.T) {
+	for _, tc := range []struct {
+		move    string
+		wantSAN string
+		want    string
+	}{
+		{"e8=Q", "e8=Q", "q"},
+		{"e8Q", "e8=Q", "q"},
+		{"e8=N", "e8=N+", "n"},
+		{"e8N", "e8=N+", "n"},
+		{"e8n", "e8=N+", "n"},
+		{"exf8N", "exf8=N", "n"},
+		{"exf8=R", "exf8=R", "r"},
+	} {
+		t.Run(tc.move, func(t *testing.T) {
+			gs := services.NewGameService(nil)
+			gameID := gs.CreateGame("white", "black")
+			for _, move := range toPromotion {
+				if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+					t.Fatalf("%s: %v", move, err)
+				}
+			}
+
+			san, err := gs.MakeMove(gameID, tc.move, nil, nil)
+			if err != nil {
+				t.Fatalf("%s: %v", tc.move, err)
+			}
+			if san != tc.wantSAN {
+				t.Errorf("recorded as %s, want %s", san, tc.wantSAN)
+			}
+			view, err := gs.GetGame(gameID)
+			if err != nil {
+				t.Fatalf("GetGame: %v", err)
+			}
+			if got := view.Promotion(); got != tc.want {
+				t.Errorf("promoted to %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNoPromotionOnOrdinaryMove(t *testing.T) {
+	gs := services.NewGameService(nil)
+	gameID := gs.CreateGame("white", "black")
+	if _, err := gs.MakeMove(gameID, "e4", nil, nil); err != nil {
+		t.Fatalf("e4: %v", err)
+	}
+	view, err := gs.GetGame(gameID)
+	if err != nil {
+		t.Fatalf("GetGame: %v", err)
+	}
+	if got := view.Promotion(); got != "" {
+		t.Errorf("e4 reported as promoting to %q", got)
+	}
+}
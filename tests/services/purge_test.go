@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// purgeCounter counts the expired token purges run against it. The first
+// refresh token purge fails, as a dropped database connection would.
+type purgeCounter struct {
+	*memUsers
+	refresh atomic.Int32
+	user    atomic.Int32
+}
+
+func (r *purgeCounter) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	if r.refresh.Add(1) == 1 {
+		return 0, errors.New("connection reset")
+	}
+	return 2, nil
+}
+
+func (r *purgeCounter) ClearExpiredUserTokens(ctx context.Context) (int64, error) {
+	r.user.Add(1)
+	return 1, nil
+}
+
+func TestTokenPurgerRunsBothPurgesEveryInterval(t *testing.T) {
+	users := &purgeCounter{memUsers: newMemUsers()}
+	newAuthService(users).StartTokenPurger(10 * time.Millisecond)
+
+	// A failed purge doesn't stop the other or the next round
+	deadline := time.Now().Add(2 * time.Second)
+	for users.refresh.Load() < 3 || users.user.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("after 2s, purged refresh tokens %d times and user tokens %d times, want 3 each",
+				users.refresh.Load(), users.user.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+	"chess-ws-go/internal/services"
+)
+
+// onePuzzle is a puzzle repository holding a single puzzle
+type onePuzzle struct {
+	puzzle models.Puzzle
+}
+
+func (r *onePuzzle) GetByID(ctx context.Context, id string) (*models.Puzzle, error) {
+	if id != r.puzzle.ID {
+		return nil, repositories.ErrPuzzleNotFound
+	}
+	puzzle := r.puzzle
+	return &puzzle, nil
+}
+
+func (r *onePuzzle) GetRandom(ctx context.Context, minRating int, maxRating int) (*models.Puzzle, error) {
+	return r.GetByID(ctx, r.puzzle.ID)
+}
+
+// newPuzzles serves an opening line as a puzzle to solve: e4, Nf3 and Bb5
+func newPuzzles(ttl time.Duration) *services.PuzzleService {
+	puzzles := &onePuzzle{puzzle: models.Puzzle{
+		ID:       "ruy-lopez",
+		FEN:      "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		Solution: "e4 e5 Nf3 Nc6 Bb5",
+		Rating:   1500,
+	}}
+	users := newMemUsers(
+		&models.User{ID: "alice", Username: "alice", PuzzleRating: 1500},
+		&models.User{ID: "bob", Username: "bob", PuzzleRating: 1500},
+	)
+	ps := services.NewPuzzleService(puzzles, users)
+	ps.SetAttemptTTL(ttl)
+	return ps
+}
+
+func TestAbandonedPuzzleForgotten(t *testing.T) {
+	ps := newPuzzles(50 * time.Millisecond)
+	ctx := context.Background()
+	if _, err := ps.StartPuzzle(ctx, "alice", "ruy-lopez"); err != nil {
+		t.Fatalf("StartPuzzle: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	// Bob starting a puzzle sweeps alice's away
+	if _, err := ps.StartPuzzle(ctx, "bob", "ruy-lopez"); err != nil {
+		t.Fatalf("StartPuzzle: %v", err)
+	}
+	if _, err := ps.SubmitMove(ctx, "alice", "e4"); err != services.ErrNoActivePuzzle {
+		t.Errorf("move in an abandoned puzzle: got %v, want ErrNoActivePuzzle", err)
+	}
+	if result, err := ps.SubmitMove(ctx, "bob", "e4"); err != nil || !result.Correct {
+		t.Errorf("bob's fresh puzzle: %+v, %v", result, err)
+	}
+}
+
+func TestPuzzleKeptWhileMoving(t *testing.T) {
+	ps := newPuzzles(200 * time.Millisecond)
+	ctx := context.Background()
+	if _, err := ps.StartPuzzle(ctx, "alice", "ruy-lopez"); err != nil {
+		t.Fatalf("StartPuzzle: %v", err)
+	}
+
+	// Each move resets the clock, so the puzzle outlives the TTL
+	var result *services.PuzzleMoveResult
+	for _, move := range []string{"e4", "Nf3", "Bb5"} {
+		time.Sleep(120 * time.Millisecond)
+		var err error
+		if result, err = ps.SubmitMove(ctx, "alice", move); err != nil {
+			t.Fatalf("%s: %v", move, err)
+		}
+	}
+	if !result.Solved {
+		t.Errorf("puzzle not solved: %+v", result)
+	}
+
+	// A solved puzzle is over
+	if _, err := ps.SubmitMove(ctx, "alice", "O-O"); err != services.ErrNoActivePuzzle {
+		t.Errorf("move after solving: got %v, want ErrNoActivePuzzle", err)
+	}
+}
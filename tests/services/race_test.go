@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+// countingGames counts the finished games stored
+type countingGames struct {
+	*memGames
+	creates atomic.Int32
+}
+
+func (r *countingGames) Create(ctx context.Context, game *models.GameRecord) error {
+	r.creates.Add(1)
+	return r.memGames.Create(ctx, game)
+}
+
+// countingRatings counts the rating updates applied
+type countingRatings struct {
+	*memUsers
+	updates atomic.Int32
+}
+
+func (r *countingRatings) UpdateRatingsTx(ctx context.Context, whiteID string, blackID string, apply func(white, black *models.User) error) error {
+	r.updates.Add(1)
+	return r.memUsers.UpdateRatingsTx(ctx, whiteID, blackID, apply)
+}
+
+func TestRacingGameEndsFinishItOnce(t *testing.T) {
+	for range 50 {
+		users := &countingRatings{memUsers: newMemUsers(
+			&models.User{ID: "white", EloRating: 1500},
+			&models.User{ID: "black", EloRating: 1500},
+		)}
+		games := &countingGames{memGames: newMemGames()}
+		gs := services.NewGameService(games)
+		gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+		for _, move := range []string{"e4", "e5"} {
+			if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+				t.Fatalf("move %s: %v", move, err)
+			}
+		}
+		if err := gs.OfferDraw(gameID, chess.White); err != nil {
+			t.Fatalf("OfferDraw: %v", err)
+		}
+
+		ctx := context.Background()
+		ends := []func() error{
+			func() error { return gs.ResignGame(gameID, chess.White, ctx, users) },
+			func() error { return gs.ResignGame(gameID, chess.Black, ctx, users) },
+			func() error { _, err := gs.AbandonGame(gameID, chess.White, ctx, users); return err },
+			func() error { return gs.AcceptDraw(gameID, chess.Black, ctx, users) },
+		}
+		errs := make([]error, len(ends))
+		var wg sync.WaitGroup
+		for i, end := range ends {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = end()
+			}()
+		}
+		wg.Wait()
+
+		finished := 0
+		for i, err := range errs {
+			switch err {
+			case nil:
+				finished++
+			case services.ErrGameOver:
+			default:
+				t.Fatalf("ending %d: %v", i, err)
+			}
+		}
+		if finished != 1 {
+			t.Fatalf("%d of the racing ends finished the game, want 1 (errors %v)", finished, errs)
+		}
+		if creates, updates := games.creates.Load(), users.updates.Load(); creates != 1 || updates != 1 {
+			t.Fatalf("game stored %d times and rated %d times, want once each", creates, updates)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+func TestMethodNames(t *testing.T) {
+	tests := []struct {
+		method chess.Method
+		want   string
+	}{
+		{chess.NoMethod, ""},
+		{chess.Checkmate, "checkmate"},
+		{chess.Resignation, "resignation"},
+		{chess.DrawOffer, "draw_agreement"},
+		{chess.Stalemate, "stalemate"},
+		{chess.ThreefoldRepetition, "threefold"},
+		{chess.FivefoldRepetition, "fivefold"},
+		{chess.FiftyMoveRule, "fifty_move"},
+		{chess.SeventyFiveMoveRule, "seventy_five_move"},
+		{chess.InsufficientMaterial, "insufficient_material"},
+	}
+	for _, tt := range tests {
+		if got := services.MethodName(tt.method); got != tt.want {
+			t.Errorf("MethodName(%s) = %q, want %q", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestOutcomeNames(t *testing.T) {
+	tests := []struct {
+		outcome chess.Outcome
+		want    string
+	}{
+		{chess.NoOutcome, ""},
+		{chess.WhiteWon, "1-0"},
+		{chess.BlackWon, "0-1"},
+		{chess.Draw, "1/2-1/2"},
+	}
+	for _, tt := range tests {
+		if got := services.OutcomeName(tt.outcome); got != tt.want {
+			t.Errorf("OutcomeName(%s) = %q, want %q", tt.outcome, got, tt.want)
+		}
+	}
+}
+
+func TestGameResults(t *testing.T) {
+	tests := []struct {
+		name        string
+		moves       string
+		end         func(gs *services.GameService, gameID string) error
+		wantOutcome string
+		wantMethod  string
+	}{
+		{
+			name:        "checkmate",
+			moves:       "f3 e5 g4 Qh4#",
+			wantOutcome: "0-1",
+			wantMethod:  "checkmate",
+		},
+		{
+			name:        "stalemate",
+			moves:       "e3 a5 Qh5 Ra6 Qxa5 h5 h4 Rah6 Qxc7 f6 Qxd7+ Kf7 Qxb7 Qd3 Qxb8 Qh7 Qxc8 Kg6 Qe6",
+			wantOutcome: "1/2-1/2",
+			wantMethod:  "stalemate",
+		},
+		{
+			name:        "fivefold repetition",
+			moves:       strings.Repeat("Nf3 Nf6 Ng1 Ng8 ", 4),
+			wantOutcome: "1/2-1/2",
+			wantMethod:  "fivefold",
+		},
+		{
+			name:  "resignation",
+			moves: "e4 e5",
+			end: func(gs *services.GameService, gameID string) error {
+				return gs.ResignGame(gameID, chess.White, nil, nil)
+			},
+			wantOutcome: "0-1",
+			wantMethod:  "resignation",
+		},
+		{
+			name:  "draw agreement",
+			moves: "e4 e5",
+			end: func(gs *services.GameService, gameID string) error {
+				if err := gs.OfferDraw(gameID, chess.White); err != nil {
+					return err
+				}
+				return gs.AcceptDraw(gameID, chess.Black, nil, nil)
+			},
+			wantOutcome: "1/2-1/2",
+			wantMethod:  "draw_agreement",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gs := services.NewGameService(nil)
+			gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+			for _, move := range strings.Fields(tt.moves) {
+				if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+					t.Fatalf("move %s: %v", move, err)
+				}
+			}
+			if tt.end != nil {
+				if err := tt.end(gs, gameID); err != nil {
+					t.Fatalf("ending the game: %v", err)
+				}
+			}
+
+			outcome, method, err := gs.Result(gameID)
+			if err != nil {
+				t.Fatalf("Result: %v", err)
+			}
+			if outcome != tt.wantOutcome || method != tt.wantMethod {
+				t.Errorf("got %s by %s, want %s by %s", outcome, method, tt.wantOutcome, tt.wantMethod)
+			}
+		})
+	}
+}
+
+func TestVoidedGamesStoredWithoutResult(t *testing.T) {
+	tests := []struct {
+		name  string
+		moves string
+		end   func(gs *services.GameService, gameID string, users *memUsers) error
+		want  string
+	}{
+		{
+			name: "aborted",
+			end: func(gs *services.GameService, gameID string, users *memUsers) error {
+				return gs.AbortGame(gameID, services.AbortReasonNoShow, context.Background(), users)
+			},
+			want: services.MethodAborted,
+		},
+		{
+			name:  "terminated",
+			moves: "e4 e5",
+			end: func(gs *services.GameService, gameID string, users *memUsers) error {
+				return gs.TerminateGame(gameID, context.Background(), users)
+			},
+			want: services.MethodAdminTerminated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := newMemUsers(&models.User{ID: "white"}, &models.User{ID: "black"})
+			games := newMemGames()
+			gs := services.NewGameService(games)
+			gameID := gs.CreateGameWithTimeControl("white", "black", services.DefaultTimeControl)
+			for _, move := range strings.Fields(tt.moves) {
+				if _, err := gs.MakeMove(gameID, move, nil, nil); err != nil {
+					t.Fatalf("move %s: %v", move, err)
+				}
+			}
+			if err := tt.end(gs, gameID, users); err != nil {
+				t.Fatalf("ending the game: %v", err)
+			}
+
+			record, err := games.GetByID(context.Background(), gameID)
+			if err != nil {
+				t.Fatalf("game not stored: %v", err)
+			}
+			if record.Outcome != services.OutcomeNone || record.Method != tt.want {
+				t.Errorf("stored %s by %s, want * by %s", record.Outcome, record.Method, tt.want)
+			}
+			if !strings.HasSuffix(record.PGN, services.OutcomeNone) {
+				t.Errorf("stored PGN %q doesn't end in *", record.PGN)
+			}
+			if strings.Contains(record.PGN, services.OutcomeDraw) {
+				t.Errorf("stored PGN %q calls the game drawn", record.PGN)
+			}
+		})
+	}
+}
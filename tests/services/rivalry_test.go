@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+)
+
+func TestHeadToHeadHidesPrivateGamesFromOthers(t *testing.T) {
+	users := newMemUsers(
+		&models.User{ID: "a", Username: "alice"},
+		&models.User{ID: "b", Username: "bob"},
+		&models.User{ID: "c", Username: "carol"},
+	)
+	games := newMemGames()
+	ctx := context.Background()
+	for _, game := range []*models.GameRecord{
+		{ID: "g1", WhiteID: "a", BlackID: "b", Outcome: "1-0"},
+		{ID: "g2", WhiteID: "b", BlackID: "a", Outcome: "1-0"},
+		{ID: "g3", WhiteID: "a", BlackID: "b", Outcome: "1/2-1/2"},
+		{ID: "g4", WhiteID: "b", BlackID: "a", Outcome: "0-1", IsPrivate: true},
+		{ID: "g5", WhiteID: "a", BlackID: "c", Outcome: "1-0"},
+	} {
+		if err := games.Create(ctx, game); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rivalries := services.NewRivalryService(users, games)
+
+	for _, tc := range []struct {
+		viewer string
+		want   models.HeadToHead
+		games  int
+	}{
+		{"a", models.HeadToHead{Wins: 2, Losses: 1, Draws: 1}, 4},
+		{"b", models.HeadToHead{Wins: 2, Losses: 1, Draws: 1}, 4},
+		{"c", models.HeadToHead{Wins: 1, Losses: 1, Draws: 1}, 3},
+		{"", models.HeadToHead{Wins: 1, Losses: 1, Draws: 1}, 3},
+	} {
+		rivalry, err := rivalries.HeadToHead(ctx, tc.viewer, "alice", "bob")
+		if err != nil {
+			t.Fatalf("viewer %q: %v", tc.viewer, err)
+		}
+		if rivalry.HeadToHead != tc.want || len(rivalry.RecentGames) != tc.games {
+			t.Errorf("viewer %q saw %+v over %d games, want %+v over %d", tc.viewer, rivalry.HeadToHead, len(rivalry.RecentGames), tc.want, tc.games)
+		}
+		for _, game := range rivalry.RecentGames {
+			if game.IsPrivate && tc.viewer != "a" && tc.viewer != "b" {
+				t.Errorf("viewer %q was shown private game %s", tc.viewer, game.ID)
+			}
+		}
+	}
+
+	if _, err := rivalries.HeadToHead(ctx, "a", "alice", "nobody"); err != services.ErrUserNotFound {
+		t.Errorf("unknown opponent: got %v, want ErrUserNotFound", err)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+)
+
+// startSwiss registers players for a Swiss tournament of the given length
+func startSwiss(t *testing.T, players int, rounds int) (*services.TournamentService, string) {
+	t.Helper()
+
+	users := newMemUsers()
+	for i := range players {
+		id := fmt.Sprintf("p%02d", i)
+		users.users[id] = &models.User{ID: id, Username: id, EloRating: 1500 + 10*i}
+	}
+	ts := services.NewTournamentService(services.NewGameService(nil), users)
+	tournament := ts.CreateTournament("organizer", services.TournamentOptions{
+		Name:        "Open",
+		Format:      services.FormatSwiss,
+		Rounds:      rounds,
+		TimeControl: services.DefaultTimeControl,
+	})
+	for i := range players {
+		if err := ts.Register(context.Background(), tournament.ID, fmt.Sprintf("p%02d", i)); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	return ts, tournament.ID
+}
+
+func TestSwissPairsEveryRoundQuickly(t *testing.T) {
+	const players = 31
+	ts, tournamentID := startSwiss(t, players, players+3)
+
+	// Play past the point where everyone has met everyone, so that the late
+	// rounds are paired greedily, with rematches
+	met := make(map[[2]string]int)
+	for round := range players + 3 {
+		start := time.Now()
+		pairings, err := ts.StartRound(tournamentID, "organizer")
+		if err != nil {
+			t.Fatalf("round %d: %v", round+1, err)
+		}
+		if took := time.Since(start); took > time.Second {
+			t.Errorf("pairing round %d took %s", round+1, took)
+		}
+
+		seen := make(map[string]bool)
+		byes := 0
+		for _, p := range pairings {
+			for _, id := range []string{p.WhiteID, p.BlackID} {
+				if id != "" && seen[id] {
+					t.Fatalf("round %d: %s paired twice", round+1, id)
+				}
+				seen[id] = true
+			}
+			if p.BlackID == "" {
+				byes++
+				continue
+			}
+			key := [2]string{min(p.WhiteID, p.BlackID), max(p.WhiteID, p.BlackID)}
+			met[key]++
+			if met[key] > 1 && round < players-1 {
+				t.Errorf("round %d: %s and %s met again before everyone had played", round+1, p.WhiteID, p.BlackID)
+			}
+			if err := ts.ReportResult(tournamentID, "organizer", p.GameID, "1-0"); err != nil {
+				t.Fatalf("round %d: ReportResult: %v", round+1, err)
+			}
+		}
+		if byes != 1 || len(seen) != players+1 {
+			t.Fatalf("round %d paired %d players with %d byes", round+1, len(seen)-1, byes)
+		}
+	}
+}
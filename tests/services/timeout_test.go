@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+
+	"github.com/corentings/chess/v2"
+)
+
+// toSameColorBishops is a game that leaves white with a king and two
+// bishops on dark squares, against a king and a pawn, with black to move
+const toSameColorBishops = "e3 g6 a3 e5 f3 a6 Bxa6 Bxa3 bxa3 e4 fxe4 Rxa6 Kf1 Rxa3 Nxa3 b6 " +
+	"Nf3 Qf6 g4 Qxf3+ Qxf3 Nc6 Qxf7+ Kxf7 Ke1 Ke7 g5 Nf6 gxf6+ Kxf6 c3 Nd4 cxd4 d6 " +
+	"Nc2 c6 Ra6 Rd8 Rxb6 h5 Rxc6 Bh3 Rxd6+ Rxd6 e5+ Kg7 exd6 Bf5 d7 Be6 d8=B Bg8 " +
+	"Bc7 Kh8 Ba3 Bb3 Bb2 Bxc2 Bg3 Be4 h4 Bxh1 Bc3 Bg2 Bf4 Kg7 Kd1 g5 hxg5 Bh1 Bh2 " +
+	"Bf3+ Kc1 Bc6 g6 Kxg6 Ba1 Bh1 d3 Kh6 Bf4+ Kg6 Bb8 Kh6 Kc2 h4 Be5 Kg6 Kb2 Be4 " +
+	"dxe4 Kg5 Kc3 Kh6 Bb2 Kh7 Bg7 Kg6 Kd3 Kxg7 Kd2 Kg8 Ba1 Kf7 Kc3 Ke7 Kd3 Kf6 " +
+	"Ke2 Ke6 d5+ Ke7 Bb2 Kf8 d6 Kg8 Bd4 Kf8 Kd2 Ke8 Bg7 Kf7 Kd3 Ke6 Bf8 Ke5 Kc4 " +
+	"Kxe4 d7 Kxe3 d8=B"
+
+// timeOut plays moves in a new game and then runs the clock of the side to
+// move out, returning the outcome, method and the white player's new rating
+func timeOut(t *testing.T, moves string) (chess.Outcome, string, int) {
+	t.Helper()
+	ctx := context.Background()
+	gs := services.NewGameService(nil)
+	gameID := gs.CreateGame("white", "black")
+	for _, move := range strings.Fields(moves) {
+		if _, err := gs.MakeMove(gameID, move, ctx, nil); err != nil {
+			t.Fatalf("move %s: %v", move, err)
+		}
+	}
+	state, err := gs.GetGameState(gameID)
+	if err != nil {
+		t.Fatalf("GetGameState: %v", err)
+	}
+
+	users := newMemUsers(&models.User{ID: "white", EloRating: 1500}, &models.User{ID: "black", EloRating: 1500})
+	outcome, method, err := gs.HandleTimeout(gameID, state.CurrentTurn, ctx, users)
+	if err != nil {
+		t.Fatalf("HandleTimeout: %v", err)
+	}
+	return outcome, method, users.user("white").EloRating
+}
+
+func TestTimeoutLosesAgainstMatingMaterial(t *testing.T) {
+	outcome, method, whiteRating := timeOut(t, "e4 e5 Nf3")
+	if outcome != chess.WhiteWon || method != services.MethodTimeout {
+		t.Errorf("black's flag fell: got %s by %s, want a white win on time", outcome, method)
+	}
+	if whiteRating <= 1500 {
+		t.Errorf("white's rating is %d after winning on time", whiteRating)
+	}
+}
+
+func TestTimeoutDrawsAgainstSameColorBishops(t *testing.T) {
+	outcome, method, whiteRating := timeOut(t, toSameColorBishops)
+	if outcome != chess.Draw || method != services.MethodTimeoutVsInsufficientMaterial {
+		t.Errorf("black's flag fell against two dark-squared bishops: got %s by %s, want a draw", outcome, method)
+	}
+	if whiteRating != 1500 {
+		t.Errorf("white's rating moved to %d in a draw between equals", whiteRating)
+	}
+}
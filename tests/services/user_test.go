@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/services"
+)
+
+func TestEmailChangeTokenSentToPendingAddress(t *testing.T) {
+	users := newMemUsers(&models.User{ID: "u1", Username: "alice", Email: "alice@old.example"})
+	mail := make(mailbox, 1)
+	userService := services.NewUserService(users, nil)
+	userService.SetEmailSender(mail)
+
+	newEmail := "Alice@New.example"
+	user, err := userService.UpdateProfile(context.Background(), "u1", nil, &newEmail)
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+
+	email := mail.receive(t)
+	if email.To != "alice@new.example" {
+		t.Errorf("email went to %q, want the pending address", email.To)
+	}
+	if user.EmailChangeToken == "" || !strings.Contains(email.Body, user.EmailChangeToken) {
+		t.Errorf("email body %q doesn't carry the change token", email.Body)
+	}
+}
+
+func TestPasswordResetTokenMailed(t *testing.T) {
+	users := newMemUsers(&models.User{ID: "u1", Username: "alice", Email: "alice@example.com"})
+	mail := make(mailbox, 1)
+	userService := services.NewUserService(users, nil)
+	userService.SetEmailSender(mail)
+
+	if err := userService.RequestPasswordReset(context.Background(), " ALICE@example.com "); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+
+	email := mail.receive(t)
+	token := users.user("u1").PasswordResetToken
+	if email.To != "alice@example.com" || token == "" || !strings.Contains(email.Body, token) {
+		t.Errorf("got email %+v, want the reset token %q sent to alice@example.com", email, token)
+	}
+}
+
+func TestListUsersAfterRejectsEmptyPage(t *testing.T) {
+	users := newMemUsers(
+		&models.User{ID: "u1", Username: "alice"},
+		&models.User{ID: "u2", Username: "bob"},
+	)
+	userService := services.NewUserService(users, nil)
+
+	for _, limit := range []int{0, -1} {
+		if _, _, err := userService.ListUsersAfter(context.Background(), "", limit, ""); !errors.Is(err, services.ErrInvalidLimit) {
+			t.Errorf("limit %d: got error %v, want ErrInvalidLimit", limit, err)
+		}
+	}
+
+	page, next, err := userService.ListUsersAfter(context.Background(), "", 1, "")
+	if err != nil || len(page) != 1 || page[0].ID != "u1" || next == "" {
+		t.Fatalf("first page: got %d users, cursor %q, error %v", len(page), next, err)
+	}
+	page, next, err = userService.ListUsersAfter(context.Background(), next, 1, "")
+	if err != nil || len(page) != 1 || page[0].ID != "u2" || next != "" {
+		t.Errorf("second page: got %d users, cursor %q, error %v", len(page), next, err)
+	}
+}
+
+func TestListUsersAfterRejectsBadCursor(t *testing.T) {
+	userService := services.NewUserService(newMemUsers(), nil)
+
+	for _, cursor := range []string{"not base64!", "bm8tc2VwYXJhdG9y"} {
+		if _, _, err := userService.ListUsersAfter(context.Background(), cursor, 10, ""); !errors.Is(err, services.ErrInvalidCursor) {
+			t.Errorf("cursor %q: got error %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
+
+func TestConcurrentPreferenceUpdatesDontLoseWrites(t *testing.T) {
+	users := newMemUsers(&models.User{ID: "u1", Username: "alice"})
+	userService := services.NewUserService(users, nil)
+	on := true
+
+	for i := 0; i < 50; i++ {
+		var wg sync.WaitGroup
+		for _, update := range []services.NotificationPreferenceUpdate{
+			{GameInvites: &on},
+			{GameSummaries: &on},
+		} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := userService.UpdateNotificationPreferences(context.Background(), "u1", update); err != nil {
+					t.Errorf("UpdateNotificationPreferences: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		prefs := users.user("u1").NotificationPreferences
+		if !prefs.GameInvites || !prefs.GameSummaries {
+			t.Fatalf("round %d: got %+v, one update was lost", i, prefs)
+		}
+		off := false
+		if _, err := userService.UpdateNotificationPreferences(context.Background(), "u1",
+			services.NotificationPreferenceUpdate{GameInvites: &off, GameSummaries: &off}); err != nil {
+			t.Fatalf("resetting preferences: %v", err)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"chess-ws-go/internal/models"
+	"chess-ws-go/internal/repositories"
+)
+
+// memUsers is an in-memory user repository. Methods the tests don't need
+// fall through to the nil embedded interface and panic if called.
+type memUsers struct {
+	repositories.UserRepository
+	mu     sync.Mutex
+	users  map[string]*models.User
+	tokens map[string]*models.RefreshToken // By ID
+}
+
+func newMemUsers(users ...*models.User) *memUsers {
+	r := &memUsers{users: make(map[string]*models.User), tokens: make(map[string]*models.RefreshToken)}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+func (r *memUsers) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.ID == user.ID || strings.EqualFold(existing.Username, user.Username) ||
+			strings.EqualFold(existing.Email, user.Email) {
+			return repositories.ErrUserAlreadyExists
+		}
+	}
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *memUsers) GetByID(ctx context.Context, id string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, repositories.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memUsers) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if strings.EqualFold(user.Username, username) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrUserNotFound
+}
+
+func (r *memUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrUserNotFound
+}
+
+// GetByVerificationToken finds the user holding an unexpired verification
+// token, as the SQL query does
+func (r *memUsers) GetByVerificationToken(ctx context.Context, token string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if token != "" && user.VerificationToken == token &&
+			user.VerificationTokenExpiresAt != nil && user.VerificationTokenExpiresAt.After(time.Now()) {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrTokenNotFound
+}
+
+func (r *memUsers) Update(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return repositories.ErrUserNotFound
+	}
+	// Mirror the SQL repository's optimistic locking
+	if stored.Version != user.Version {
+		return repositories.ErrUserConflict
+	}
+	user.Version++
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+func (r *memUsers) UpdateRatingsTx(ctx context.Context, whiteID string, blackID string, apply func(white, black *models.User) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	white, okWhite := r.users[whiteID]
+	black, okBlack := r.users[blackID]
+	if !okWhite || !okBlack {
+		return repositories.ErrUserNotFound
+	}
+	w, b := *white, *black
+	if err := apply(&w, &b); err != nil {
+		return err
+	}
+	r.users[whiteID], r.users[blackID] = &w, &b
+	return nil
+}
+
+// List returns users in creation order, keyset-paginated when After is set
+func (r *memUsers) List(ctx context.Context, opts repositories.UserListOptions) ([]*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []*models.User
+	for _, user := range r.users {
+		if opts.After != nil && !opts.After.CreatedAt.Before(user.CreatedAt) &&
+			(!user.CreatedAt.Equal(opts.After.CreatedAt) || user.ID <= opts.After.ID) {
+			continue
+		}
+		copied := *user
+		users = append(users, &copied)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.Before(users[j].CreatedAt)
+		}
+		return users[i].ID < users[j].ID
+	})
+	if opts.Limit > 0 && len(users) > opts.Limit {
+		users = users[:opts.Limit]
+	}
+	return users, nil
+}
+
+func (r *memUsers) GetPermissions(ctx context.Context, userID string) ([]string, error) {
+	return nil, nil
+}
+
+func (r *memUsers) SaveRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *token
+	r.tokens[token.ID] = &copied
+	return nil
+}
+
+func (r *memUsers) GetRefreshTokenByToken(ctx context.Context, token string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.tokens {
+		if stored.Token == token {
+			copied := *stored
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrRefreshTokenNotFound
+}
+
+func (r *memUsers) ListRefreshTokens(ctx context.Context, userID string) ([]*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var tokens []*models.RefreshToken
+	for _, stored := range r.tokens {
+		if stored.UserID == userID {
+			copied := *stored
+			tokens = append(tokens, &copied)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *memUsers) DeleteUserRefreshToken(ctx context.Context, userID string, tokenID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.tokens[tokenID]
+	if !ok || stored.UserID != userID {
+		return repositories.ErrRefreshTokenNotFound
+	}
+	delete(r.tokens, tokenID)
+	return nil
+}
+
+// user returns the stored copy of a user
+func (r *memUsers) user(id string) models.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.users[id]
+}
@@ -0,0 +1,62 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/stats"
+)
+
+func TestSummaryQuantiles(t *testing.T) {
+	s := stats.NewSummary()
+	if got := s.Snapshot(); got != (stats.SummarySnapshot{}) {
+		t.Errorf("empty summary reported %+v", got)
+	}
+
+	for i := 1; i <= 100; i++ {
+		s.Observe(time.Duration(i) * time.Millisecond)
+	}
+	got := s.Snapshot()
+	want := stats.SummarySnapshot{
+		P50:   50 * time.Millisecond,
+		P95:   95 * time.Millisecond,
+		Count: 100,
+		Sum:   5050 * time.Millisecond,
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestSummaryForgetsOldSamples(t *testing.T) {
+	s := stats.NewSummary()
+
+	// A slow start shouldn't hold the quantiles up forever
+	for range 2000 {
+		s.Observe(time.Second)
+	}
+	for range 2000 {
+		s.Observe(time.Millisecond)
+	}
+	got := s.Snapshot()
+	if got.P95 != time.Millisecond {
+		t.Errorf("p95 %s, want the recent 1ms", got.P95)
+	}
+	if got.Count != 4000 || got.Sum != 2000*time.Second+2000*time.Millisecond {
+		t.Errorf("count %d and sum %s, want every sample totalled", got.Count, got.Sum)
+	}
+}
+
+func TestCollectorRecordsMoves(t *testing.T) {
+	c := stats.NewCollector(time.Minute, func() stats.GameCounts { return stats.GameCounts{} }, func() int { return 0 })
+	c.ObserveMoveProcessing(2 * time.Millisecond)
+	c.ObserveMoveHandling(3 * time.Millisecond)
+	c.ObserveMoveHandling(5 * time.Millisecond)
+
+	if got := c.MoveProcessing(); got.Count != 1 || got.P50 != 2*time.Millisecond {
+		t.Errorf("move processing %+v, want the one 2ms sample", got)
+	}
+	if got := c.MoveHandling(); got.Count != 2 || got.Sum != 8*time.Millisecond {
+		t.Errorf("move handling %+v, want both samples", got)
+	}
+}
@@ -0,0 +1,101 @@
+package stats
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"chess-ws-go/internal/handlers"
+	"chess-ws-go/internal/services"
+	"chess-ws-go/internal/stats"
+
+	"github.com/corentings/chess/v2"
+	"github.com/gin-gonic/gin"
+)
+
+type noConnections struct{}
+
+func (noConnections) ConnectionCount() (int, int) { return 0, 0 }
+
+func scrape(t *testing.T, router *gin.Engine) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("scrape returned %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+// Run with -race: games start, move and end while the collector counts them
+// and the gauges are scraped
+func TestGameGaugesScrapedWhilePlaying(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gs := services.NewGameService(nil)
+	collector := stats.NewCollector(5*time.Millisecond, gs.GameCounts, func() int { return 0 })
+	collector.Start()
+	router := gin.New()
+	router.GET("/metrics", handlers.NewMetricsHandler(collector, noConnections{}).Metrics)
+
+	const players = 8
+	var wg sync.WaitGroup
+	for i := range players {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				gameID := gs.CreateGame(fmt.Sprintf("w%d-%d", i, j), fmt.Sprintf("b%d-%d", i, j))
+				if _, err := gs.MakeMove(gameID, "e4", nil, nil); err != nil {
+					t.Errorf("move in %s: %v", gameID, err)
+					return
+				}
+				if j%2 == 1 {
+					continue
+				}
+				// Half the games are casual and end straight away
+				if err := gs.SetCasual(gameID, false); err != nil {
+					t.Errorf("make %s casual: %v", gameID, err)
+					return
+				}
+				if err := gs.ResignGame(gameID, chess.Black, nil, nil); err != nil {
+					t.Errorf("resign %s: %v", gameID, err)
+					return
+				}
+			}
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for scraping := true; scraping; {
+		select {
+		case <-done:
+			scraping = false
+		default:
+			scrape(t, router)
+		}
+	}
+
+	// Once play stops, the next collection counts what's left: the rated
+	// half, in progress
+	want := fmt.Sprintf("chess_games_in_progress_by_stakes{stakes=\"rated\"} %d\n", players*5)
+	deadline := time.Now().Add(time.Second)
+	for {
+		body := scrape(t, router)
+		if strings.Contains(body, want) &&
+			strings.Contains(body, "chess_games_in_progress_by_stakes{stakes=\"casual\"} 0\n") &&
+			strings.Contains(body, fmt.Sprintf("chess_active_games %d\n", players*10)) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("gauges never settled:\n%s", body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"chess-ws-go/internal/storage"
+)
+
+func TestFileStoreKeepsKeysInsideDirectory(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "avatars")
+	store, err := storage.NewFileStore(dir, "/avatars/")
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "../../escape.png", "image/png", []byte("x")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escape.png")); err == nil {
+		t.Error("a key with ../ wrote outside the store")
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "escape.png")); err != nil || string(data) != "x" {
+		t.Errorf("file not kept inside the store: %q, %v", data, err)
+	}
+	if got := store.URL("../../escape.png"); got != "/avatars/escape.png" {
+		t.Errorf("URL %q, want /avatars/escape.png", got)
+	}
+}
+
+func TestFileStoreReplacesAndDeletes(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.NewFileStore(dir, "/avatars")
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, data := range []string{"first", "second"} {
+		if err := store.Put(ctx, "u1/a.png", "image/png", []byte(data)); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "u1", "a.png")); err != nil || string(data) != "second" {
+		t.Errorf("got %q, %v, want the second write", data, err)
+	}
+
+	if err := store.Delete(ctx, "u1/a.png"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(ctx, "u1/a.png"); err != nil {
+		t.Errorf("deleting a missing file: %v", err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "u1"))
+	if len(entries) != 0 {
+		t.Errorf("left behind %d files", len(entries))
+	}
+}